@@ -0,0 +1,87 @@
+package api
+
+import (
+	"strconv"
+
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdQuery 客户端获取广告的查询参数
+type AdQuery struct {
+	ProductID *uint  `form:"product_id"`
+	Limit     int    `form:"limit" binding:"omitempty,min=1,max=10"`
+	Country   string `form:"country"`
+	Region    string `form:"region"`
+	City      string `form:"city"`
+	Device    string `form:"device"`
+	OS        string `form:"os"`
+	Language  string `form:"language"`
+	Age       *int   `form:"age" binding:"omitempty,min=0,max=120"`
+}
+
+// AdController 客户端广告控制器
+type AdController struct {
+	adService *services.AdService
+}
+
+// NewAdController 创建客户端广告控制器
+func NewAdController() *AdController {
+	return &AdController{
+		adService: services.NewAdService(),
+	}
+}
+
+// List 根据客户上下文获取可投放的广告
+func (ac *AdController) List(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+
+	var req AdQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	// 未显式传入语言时使用请求头
+	language := req.Language
+	if language == "" {
+		language = parseAcceptLanguage(c.GetHeader("Accept-Language"))
+	}
+
+	ctx := &models.TargetingContext{
+		Country:  req.Country,
+		Region:   req.Region,
+		City:     req.City,
+		Device:   req.Device,
+		OS:       req.OS,
+		Language: language,
+		Age:      req.Age,
+	}
+
+	ads, err := ac.adService.Serve(userID.(uint), ctx, req.ProductID, req.Limit)
+	if err != nil {
+		utils.InternalServerError(c, "获取广告失败")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Ad-Count", strconv.Itoa(len(ads)))
+	utils.Success(c, ads)
+}
+
+// parseAcceptLanguage 取 Accept-Language 中优先级最高的语言
+func parseAcceptLanguage(header string) string {
+	for i, ch := range header {
+		if ch == ',' || ch == ';' {
+			return header[:i]
+		}
+	}
+	return header
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"time"
+
+	"backend/services"
+	"backend/types"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AssetQuery 素材库查询参数（search 匹配文件名、地址或哈希前缀，category 为 image/video/document/other）
+type AssetQuery struct {
+	types.FilterRequest
+	MimeType   string `form:"mime_type"`
+	OwnerID    *uint  `form:"owner_id" binding:"omitempty,min=1"`
+	Referenced *bool  `form:"referenced"` // true 只看被引用的，false 只看无引用的
+}
+
+// AssetController 素材库控制器
+type AssetController struct {
+	assetService *services.AssetService
+}
+
+// NewAssetController 创建素材库控制器
+func NewAssetController() *AssetController {
+	return &AssetController{
+		assetService: services.NewAssetService(),
+	}
+}
+
+// respondAssetError 统一处理素材相关错误
+func respondAssetError(c *gin.Context, err error, message string) {
+	if err.Error() == "素材不存在" {
+		utils.NotFound(c, err.Error())
+	} else if _, ok := err.(*services.ServiceError); ok {
+		utils.BadRequest(c, err.Error())
+	} else {
+		utils.InternalServerError(c, message)
+	}
+}
+
+// List 素材库列表
+func (ac *AssetController) List(c *gin.Context) {
+	var query AssetQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	assets, total, err := ac.assetService.List(&query.FilterRequest, query.MimeType, query.OwnerID, query.Referenced)
+	if err != nil {
+		utils.InternalServerError(c, "获取素材列表失败")
+		return
+	}
+
+	utils.PagedSuccess(c, assets, total, query.GetPage(), query.GetSize())
+}
+
+// GetByID 素材详情，包含引用该素材的产品、计划与模板
+func (ac *AssetController) GetByID(c *gin.Context) {
+	var req types.IDRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	asset, err := ac.assetService.GetByID(req.ID)
+	if err != nil {
+		respondAssetError(c, err, "获取素材失败")
+		return
+	}
+
+	utils.Success(c, asset)
+}
+
+// Delete 删除未被引用的素材及其文件
+func (ac *AssetController) Delete(c *gin.Context) {
+	var req types.IDRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	if err := ac.assetService.Delete(req.ID); err != nil {
+		respondAssetError(c, err, "删除素材失败")
+		return
+	}
+
+	utils.Deleted(c)
+}
+
+// Cleanup 立即清理无引用超过宽限期的素材
+func (ac *AssetController) Cleanup(c *gin.Context) {
+	count, err := ac.assetService.CleanupOrphans(time.Now())
+	if err != nil {
+		utils.InternalServerError(c, "清理素材失败")
+		return
+	}
+
+	utils.Success(c, gin.H{"deleted": count})
+}
@@ -1,6 +1,11 @@
 package api
 
 import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"backend/middleware"
 	"backend/models"
 	"backend/services"
 	"backend/types"
@@ -10,8 +15,14 @@ import (
 
 // GenerateAuthCodeRequest 生成授权码请求结构
 type GenerateAuthCodeRequest struct {
-	Count     int `json:"count" binding:"required,min=1,max=1000"`
-	ValidDays int `json:"valid_days" binding:"min=1,max=365"`
+	Count           int                         `json:"count" binding:"required,min=1,max=1000"`
+	ValidDays       int                         `json:"valid_days" binding:"omitempty,min=1,max=365"`
+	Name            string                      `json:"name" binding:"max=255"`
+	Prefix          string                      `json:"prefix" binding:"max=8"`
+	Notes           string                      `json:"notes"`
+	Entitlement     *models.AuthCodeEntitlement `json:"entitlement"`
+	MaxUses         *int                        `json:"max_uses" binding:"omitempty,min=0,max=100000"` // 不传默认 1，0 表示不限
+	PerCustomerUses int                         `json:"per_customer_uses" binding:"omitempty,min=1"`
 }
 
 // VerifyAuthCodeRequest 验证授权码请求结构
@@ -22,12 +33,14 @@ type VerifyAuthCodeRequest struct {
 // AuthCodeController 授权码控制器
 type AuthCodeController struct {
 	authCodeService *services.AuthCodeService
+	exportService   *services.ExportService
 }
 
 // NewAuthCodeController 创建授权码控制器
 func NewAuthCodeController() *AuthCodeController {
 	return &AuthCodeController{
 		authCodeService: services.NewAuthCodeService(),
+		exportService:   services.NewExportService(),
 	}
 }
 
@@ -82,19 +95,111 @@ func (acc *AuthCodeController) Generate(c *gin.Context) {
 		req.ValidDays = 7 // 默认7天
 	}
 
-	authCodes, err := acc.authCodeService.GenerateBatch(req.Count, req.ValidDays)
+	if req.Name == "" {
+		req.Name = fmt.Sprintf("批量生成 %s", time.Now().Format("2006-01-02 15:04:05"))
+	}
+
+	maxUses := 1
+	if req.MaxUses != nil {
+		maxUses = *req.MaxUses
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	batch := &models.AuthCodeBatch{
+		Name:            req.Name,
+		Prefix:          req.Prefix,
+		Count:           req.Count,
+		ValidDays:       req.ValidDays,
+		CreatedBy:       adminID,
+		Notes:           req.Notes,
+		Entitlement:     req.Entitlement,
+		MaxUses:         maxUses,
+		PerCustomerUses: req.PerCustomerUses,
+	}
+
+	authCodes, err := acc.authCodeService.CreateBatch(batch)
 	if err != nil {
-		utils.InternalServerError(c, "生成授权码失败")
+		if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
+		} else {
+			utils.InternalServerError(c, "生成授权码失败")
+		}
 		return
 	}
 
 	utils.SuccessWithMessage(c, "授权码生成成功", map[string]interface{}{
+		"batch":      batch,
 		"count":      len(authCodes),
 		"valid_days": req.ValidDays,
 		"auth_codes": authCodes,
 	})
 }
 
+// ListBatches 获取授权码批次列表
+func (acc *AuthCodeController) ListBatches(c *gin.Context) {
+	var req types.FilterRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	batches, total, err := acc.authCodeService.ListBatches(&req)
+	if err != nil {
+		utils.InternalServerError(c, "获取授权码批次列表失败")
+		return
+	}
+
+	utils.PagedSuccess(c, batches, total, req.GetPage(), req.GetSize())
+}
+
+// GetBatch 获取授权码批次详情
+func (acc *AuthCodeController) GetBatch(c *gin.Context) {
+	var req types.IDRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	batch, err := acc.authCodeService.GetBatch(req.ID)
+	if err != nil {
+		if err.Error() == "授权码批次不存在" {
+			utils.NotFound(c, "授权码批次不存在")
+		} else {
+			utils.InternalServerError(c, "获取授权码批次详情失败")
+		}
+		return
+	}
+
+	utils.Success(c, batch)
+}
+
+// GetBatchCodes 获取批次下的授权码
+func (acc *AuthCodeController) GetBatchCodes(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var req types.FilterRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	authCodes, total, err := acc.authCodeService.GetBatchCodes(uriReq.ID, &req)
+	if err != nil {
+		if err.Error() == "授权码批次不存在" {
+			utils.NotFound(c, "授权码批次不存在")
+		} else {
+			utils.InternalServerError(c, "获取批次授权码失败")
+		}
+		return
+	}
+
+	utils.PagedSuccess(c, authCodes, total, req.GetPage(), req.GetSize())
+}
+
 // Verify 验证授权码
 func (acc *AuthCodeController) Verify(c *gin.Context) {
 	var req VerifyAuthCodeRequest
@@ -109,7 +214,7 @@ func (acc *AuthCodeController) Verify(c *gin.Context) {
 		userID = uid.(uint)
 	}
 
-	result, err := acc.authCodeService.VerifyCode(req.Code, userID)
+	result, err := acc.authCodeService.VerifyCode(req.Code, userID, c.ClientIP())
 	if err != nil {
 		utils.BadRequest(c, err.Error())
 		return
@@ -163,35 +268,13 @@ func (acc *AuthCodeController) Export(c *gin.Context) {
 		return
 	}
 
-	// 只导出未使用的授权码
-	status := int(models.AuthCodeStatusUnused)
-	req.Status = &status
-
-	// 设置导出数量限制
-	req.Size = 10000
-
-	authCodes, _, err := acc.authCodeService.List(&req)
-	if err != nil {
-		utils.InternalServerError(c, "导出授权码失败")
-		return
+	// 默认只导出未使用（含部分兑换）的授权码
+	if req.Status == nil {
+		status := int(models.AuthCodeStatusUnused)
+		req.Status = &status
 	}
 
-	// 简化导出数据
-	exportData := make([]map[string]interface{}, 0)
-	for _, code := range authCodes {
-		exportData = append(exportData, map[string]interface{}{
-			"code":       code.Code,
-			"status":     code.Status,
-			"expired_at": code.ExpiredAt,
-			"created_at": code.CreatedAt,
-		})
-	}
-
-	utils.Success(c, map[string]interface{}{
-		"auth_codes":  exportData,
-		"count":       len(exportData),
-		"exported_at": "now",
-	})
+	handleExport(c, acc.exportService, services.ExportResourceAuthCodes, &req)
 }
 
 // GetStatistics 获取授权码统计
@@ -205,7 +288,7 @@ func (acc *AuthCodeController) GetStatistics(c *gin.Context) {
 	utils.Success(c, stats)
 }
 
-// GetUsageHistory 获取授权码使用历史
+// GetUsageHistory 获取授权码兑换历史
 func (acc *AuthCodeController) GetUsageHistory(c *gin.Context) {
 	var req types.FilterRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
@@ -213,17 +296,22 @@ func (acc *AuthCodeController) GetUsageHistory(c *gin.Context) {
 		return
 	}
 
-	// 只获取已使用的授权码
-	status := int(models.AuthCodeStatusUsed)
-	req.Status = &status
+	// 可按授权码筛选
+	var authCodeID *uint
+	if idStr := c.Query("auth_code_id"); idStr != "" {
+		if id, err := strconv.ParseUint(idStr, 10, 32); err == nil {
+			uid := uint(id)
+			authCodeID = &uid
+		}
+	}
 
-	usedCodes, total, err := acc.authCodeService.List(&req)
+	redemptions, total, err := acc.authCodeService.GetUsageHistory(&req, authCodeID)
 	if err != nil {
 		utils.InternalServerError(c, "获取使用历史失败")
 		return
 	}
 
-	utils.PagedSuccess(c, usedCodes, total, req.GetPage(), req.GetSize())
+	utils.PagedSuccess(c, redemptions, total, req.GetPage(), req.GetSize())
 }
 
 // GetExpiredCodes 获取已过期的授权码
@@ -288,7 +376,7 @@ func (acc *AuthCodeController) ValidateCodeFormat(c *gin.Context) {
 	}
 
 	isValid := acc.authCodeService.ValidateCodeFormat(req.Code)
-	
+
 	utils.Success(c, map[string]interface{}{
 		"code":     req.Code,
 		"is_valid": isValid,
@@ -338,4 +426,4 @@ func (acc *AuthCodeController) CheckCodeAvailability(c *gin.Context) {
 		"is_available": isAvailable,
 		"info":         info,
 	})
-}
\ No newline at end of file
+}
@@ -5,7 +5,9 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"time"
 
+	"backend/middleware"
 	"backend/models"
 	"backend/services"
 	"backend/types"
@@ -16,28 +18,55 @@ import (
 
 // CampaignRequest 计划请求结构
 type CampaignRequest struct {
-	Name            string                 `json:"name" binding:"required,max=255"`
-	ProductID       uint                   `json:"product_id" binding:"required,min=1"`
-	Description     string                 `json:"description"`
-	Status          models.CampaignStatus  `json:"status" binding:"min=0,max=3"`
-	MainImage       string                 `json:"main_image"`
-	Video           string                 `json:"video"`
-	DeliveryContent models.CustomFieldList `json:"delivery_content"`
-	DeliveryRules   models.CustomFieldList `json:"delivery_rules"`
-	UserTargeting   models.CustomFieldList `json:"user_targeting"`
+	Name              string                   `json:"name" binding:"required,max=255"`
+	ProductID         uint                     `json:"product_id" binding:"required,min=1"`
+	Description       string                   `json:"description"`
+	Status            models.CampaignStatus    `json:"status" binding:"min=0,max=3"`
+	MainImage         string                   `json:"main_image"`
+	Video             string                   `json:"video"`
+	DeliveryContent   models.CustomFieldList   `json:"delivery_content"`
+	DeliveryRules     models.CustomFieldList   `json:"delivery_rules"`
+	UserTargeting     models.CustomFieldList   `json:"user_targeting"`
+	Targeting         models.CampaignTargeting `json:"targeting"`
+	BillingType       string                   `json:"billing_type" binding:"omitempty,oneof=cpm cpc cpa"`
+	BidPrice          float64                  `json:"bid_price" binding:"min=0"`
+	StartAt           *time.Time               `json:"start_at"`
+	EndAt             *time.Time               `json:"end_at"`
+	Timezone          string                   `json:"timezone" binding:"max=64"`
+	DayParts          models.DayPartList       `json:"day_parts"`
+	TotalBudget       float64                  `json:"total_budget" binding:"min=0"`
+	DailyBudget       float64                  `json:"daily_budget" binding:"min=0"`
+	Pacing            string                   `json:"pacing" binding:"omitempty,oneof=standard accelerated"`
+	Weight            int                      `json:"weight" binding:"min=0,max=10000"`
+	FrequencyCap      int                      `json:"frequency_cap" binding:"min=0"`
+	FrequencyWindow   int                      `json:"frequency_window" binding:"min=0,max=720"`
+	AutoPromote       bool                     `json:"auto_promote"`
+	PromoteMetric     string                   `json:"promote_metric" binding:"omitempty,oneof=ctr cvr"`
+	PromoteMinSample  int                      `json:"promote_min_sample" binding:"min=0"`
+	PromoteConfidence float64                  `json:"promote_confidence" binding:"omitempty,min=0.5,max=0.999"`
 }
 
 // CampaignController 计划控制器
 type CampaignController struct {
-	campaignService *services.CampaignService
-	productService  *services.ProductService
+	campaignService    *services.CampaignService
+	productService     *services.ProductService
+	budgetService      *services.CampaignBudgetService
+	creativeService    *services.CampaignCreativeService
+	reviewService      *services.CampaignReviewService
+	videoUploadService *services.CampaignVideoUploadService
+	assetService       *services.AssetService
 }
 
 // NewCampaignController 创建计划控制器
 func NewCampaignController() *CampaignController {
 	return &CampaignController{
-		campaignService: services.NewCampaignService(),
-		productService:  services.NewProductService(),
+		campaignService:    services.NewCampaignService(),
+		productService:     services.NewProductService(),
+		budgetService:      services.NewCampaignBudgetService(),
+		creativeService:    services.NewCampaignCreativeService(),
+		reviewService:      services.NewCampaignReviewService(),
+		videoUploadService: services.NewCampaignVideoUploadService(),
+		assetService:       services.NewAssetService(),
 	}
 }
 
@@ -117,19 +146,43 @@ func (cc *CampaignController) Create(c *gin.Context) {
 	}
 
 	campaign := &models.Campaign{
-		Name:            req.Name,
-		ProductID:       req.ProductID,
-		Description:     req.Description,
-		Status:          req.Status,
-		MainImage:       req.MainImage,
-		Video:           req.Video,
-		DeliveryContent: req.DeliveryContent,
-		DeliveryRules:   req.DeliveryRules,
-		UserTargeting:   req.UserTargeting,
+		Name:              req.Name,
+		ProductID:         req.ProductID,
+		Description:       req.Description,
+		Status:            req.Status,
+		MainImage:         req.MainImage,
+		Video:             req.Video,
+		DeliveryContent:   req.DeliveryContent,
+		DeliveryRules:     req.DeliveryRules,
+		UserTargeting:     req.UserTargeting,
+		Targeting:         req.Targeting,
+		BillingType:       req.BillingType,
+		BidPrice:          req.BidPrice,
+		StartAt:           req.StartAt,
+		EndAt:             req.EndAt,
+		Timezone:          req.Timezone,
+		DayParts:          req.DayParts,
+		TotalBudget:       req.TotalBudget,
+		DailyBudget:       req.DailyBudget,
+		Pacing:            req.Pacing,
+		Weight:            req.Weight,
+		FrequencyCap:      req.FrequencyCap,
+		FrequencyWindow:   req.FrequencyWindow,
+		AutoPromote:       req.AutoPromote,
+		PromoteMetric:     req.PromoteMetric,
+		PromoteMinSample:  req.PromoteMinSample,
+		PromoteConfidence: req.PromoteConfidence,
+	}
+	if campaign.BillingType == "" {
+		campaign.BillingType = models.BillingTypeCPC
 	}
 
 	if err := cc.campaignService.Create(campaign); err != nil {
-		utils.InternalServerError(c, "创建计划失败")
+		if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
+		} else {
+			utils.InternalServerError(c, "创建计划失败")
+		}
 		return
 	}
 
@@ -185,12 +238,51 @@ func (cc *CampaignController) Update(c *gin.Context) {
 	campaign.Description = req.Description
 	campaign.Status = req.Status
 	campaign.MainImage = req.MainImage
+	if campaign.Video != req.Video {
+		// 视频地址被直接修改时元数据已不对应
+		campaign.VideoMeta = models.CampaignVideoMeta{}
+	}
 	campaign.Video = req.Video
 	campaign.DeliveryContent = req.DeliveryContent
 	campaign.DeliveryRules = req.DeliveryRules
 	campaign.UserTargeting = req.UserTargeting
+	campaign.Targeting = req.Targeting
+	if req.BillingType != "" {
+		campaign.BillingType = req.BillingType
+	}
+	campaign.BidPrice = req.BidPrice
+	campaign.StartAt = req.StartAt
+	campaign.EndAt = req.EndAt
+	campaign.Timezone = req.Timezone
+	campaign.DayParts = req.DayParts
+	campaign.TotalBudget = req.TotalBudget
+	campaign.DailyBudget = req.DailyBudget
+	if req.Pacing != "" {
+		campaign.Pacing = req.Pacing
+	}
+	if req.Weight > 0 {
+		campaign.Weight = req.Weight
+	}
+	campaign.FrequencyCap = req.FrequencyCap
+	if req.FrequencyWindow > 0 {
+		campaign.FrequencyWindow = req.FrequencyWindow
+	}
+	campaign.AutoPromote = req.AutoPromote
+	if req.PromoteMetric != "" {
+		campaign.PromoteMetric = req.PromoteMetric
+	}
+	if req.PromoteMinSample > 0 {
+		campaign.PromoteMinSample = req.PromoteMinSample
+	}
+	if req.PromoteConfidence > 0 {
+		campaign.PromoteConfidence = req.PromoteConfidence
+	}
 
 	if err := cc.campaignService.Update(campaign); err != nil {
+		if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
+			return
+		}
 		log.Printf("Update campaign error: %v", err)
 		utils.InternalServerError(c, fmt.Sprintf("更新计划失败: %v", err))
 		return
@@ -199,6 +291,42 @@ func (cc *CampaignController) Update(c *gin.Context) {
 	utils.Updated(c, campaign)
 }
 
+// CloneCampaignRequest 复制计划请求结构
+type CloneCampaignRequest struct {
+	Name string `json:"name" binding:"max=255"`
+}
+
+// Clone 复制计划（含创意），新计划为未激活状态
+func (cc *CampaignController) Clone(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var req CloneCampaignRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidateError(c, err)
+			return
+		}
+	}
+
+	campaign, err := cc.campaignService.Clone(uriReq.ID, req.Name)
+	if err != nil {
+		if err.Error() == "计划不存在" {
+			utils.NotFound(c, "计划不存在")
+		} else if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
+		} else {
+			utils.InternalServerError(c, "复制计划失败")
+		}
+		return
+	}
+
+	utils.Created(c, campaign)
+}
+
 // Delete 删除计划
 func (cc *CampaignController) Delete(c *gin.Context) {
 	var req types.IDRequest
@@ -247,15 +375,17 @@ func (cc *CampaignController) UploadMainImage(c *gin.Context) {
 	}
 
 	// 保存文件
-	uploadResp, err := utils.SaveUploadedFile(c, file, "campaigns/images")
+	uploadResp, err := utils.SaveUploadedImage(c, file, "campaigns/images")
 	if err != nil {
 		utils.BadRequest(c, "上传失败: "+err.Error())
 		return
 	}
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	cc.assetService.Register(uploadResp, file.Filename, adminID)
 
-	// 删除旧主图文件
-	if campaign.MainImage != "" {
-		utils.DeleteFile(campaign.MainImage)
+	// 释放旧主图文件
+	if campaign.MainImage != "" && campaign.MainImage != uploadResp.URL {
+		cc.assetService.ReleaseFile(campaign.MainImage)
 	}
 
 	// 更新计划主图字段
@@ -301,14 +431,31 @@ func (cc *CampaignController) UploadVideo(c *gin.Context) {
 		utils.BadRequest(c, "上传失败: "+err.Error())
 		return
 	}
+	if !strings.HasPrefix(uploadResp.MimeType, "video/") {
+		utils.DeleteFile(uploadResp.URL)
+		utils.BadRequest(c, "请上传视频文件")
+		return
+	}
+
+	// 提取时长与分辨率，无法解析时只记录格式与大小
+	meta := models.CampaignVideoMeta{MimeType: uploadResp.MimeType, Size: file.Size}
+	if src, err := file.Open(); err == nil {
+		if info, err := utils.ProbeVideo(src, file.Size); err == nil {
+			meta.Format, meta.Duration, meta.Width, meta.Height = info.Format, info.Duration, info.Width, info.Height
+		}
+		src.Close()
+	}
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	cc.assetService.Register(uploadResp, file.Filename, adminID)
 
-	// 删除旧视频文件
-	if campaign.Video != "" {
-		utils.DeleteFile(campaign.Video)
+	// 释放旧视频文件
+	if campaign.Video != "" && campaign.Video != uploadResp.URL {
+		cc.assetService.ReleaseFile(campaign.Video)
 	}
 
 	// 更新计划视频字段
 	campaign.Video = uploadResp.URL
+	campaign.VideoMeta = meta
 	if err := cc.campaignService.Update(campaign); err != nil {
 		utils.InternalServerError(c, "更新计划视频失败")
 		return
@@ -325,7 +472,19 @@ func (cc *CampaignController) GetStatistics(c *gin.Context) {
 		return
 	}
 
-	stats, err := cc.campaignService.GetCampaignStats(req.ID)
+	// 可选的统计日期范围（YYYY-MM-DD）
+	startDate, err := parseQueryDate(c, "start_date")
+	if err != nil {
+		utils.BadRequest(c, "start_date 格式应为 YYYY-MM-DD")
+		return
+	}
+	endDate, err := parseQueryDate(c, "end_date")
+	if err != nil {
+		utils.BadRequest(c, "end_date 格式应为 YYYY-MM-DD")
+		return
+	}
+
+	stats, err := cc.campaignService.GetCampaignStats(req.ID, startDate, endDate)
 	if err != nil {
 		if err.Error() == "计划不存在" {
 			utils.NotFound(c, "计划不存在")
@@ -338,6 +497,119 @@ func (cc *CampaignController) GetStatistics(c *gin.Context) {
 	utils.Success(c, stats)
 }
 
+// parseQueryDate 解析查询参数中的日期，未传时返回nil
+func parseQueryDate(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+// GetStatusLogs 获取计划状态变更记录
+func (cc *CampaignController) GetStatusLogs(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var req types.PageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	logs, total, err := cc.campaignService.GetStatusLogs(uriReq.ID, &req)
+	if err != nil {
+		if err.Error() == "计划不存在" {
+			utils.NotFound(c, "计划不存在")
+		} else {
+			utils.InternalServerError(c, "获取状态变更记录失败")
+		}
+		return
+	}
+
+	utils.PagedSuccess(c, logs, total, req.GetPage(), req.GetSize())
+}
+
+// EvaluateTargeting 评估客户上下文是否命中计划定向
+func (cc *CampaignController) EvaluateTargeting(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var req models.TargetingContext
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	result, err := cc.campaignService.EvaluateTargeting(uriReq.ID, &req)
+	if err != nil {
+		if err.Error() == "计划不存在" {
+			utils.NotFound(c, "计划不存在")
+		} else if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
+		} else {
+			utils.InternalServerError(c, "评估定向失败")
+		}
+		return
+	}
+
+	utils.Success(c, result)
+}
+
+// GetBudget 获取计划预算状态
+func (cc *CampaignController) GetBudget(c *gin.Context) {
+	var req types.IDRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	status, err := cc.budgetService.GetStatus(req.ID)
+	if err != nil {
+		if err.Error() == "计划不存在" {
+			utils.NotFound(c, "计划不存在")
+		} else {
+			utils.InternalServerError(c, "获取预算状态失败")
+		}
+		return
+	}
+
+	utils.Success(c, status)
+}
+
+// GetBudgetAlerts 获取预算提醒列表，可按 campaign_id 筛选
+func (cc *CampaignController) GetBudgetAlerts(c *gin.Context) {
+	var req types.PageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var campaignID *uint
+	if id, err := strconv.ParseUint(c.Query("campaign_id"), 10, 32); err == nil {
+		uid := uint(id)
+		campaignID = &uid
+	}
+
+	alerts, total, err := cc.budgetService.ListAlerts(&req, campaignID)
+	if err != nil {
+		utils.InternalServerError(c, "获取预算提醒失败")
+		return
+	}
+
+	utils.PagedSuccess(c, alerts, total, req.GetPage(), req.GetSize())
+}
+
 // UpdateStatus 更新计划状态
 func (cc *CampaignController) UpdateStatus(c *gin.Context) {
 	var uriReq types.IDRequest
@@ -355,6 +627,8 @@ func (cc *CampaignController) UpdateStatus(c *gin.Context) {
 	if err := cc.campaignService.UpdateStatus(uriReq.ID, models.CampaignStatus(req.Status)); err != nil {
 		if err.Error() == "计划不存在" {
 			utils.NotFound(c, "计划不存在")
+		} else if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
 		} else {
 			utils.InternalServerError(c, "更新计划状态失败")
 		}
@@ -375,6 +649,8 @@ func (cc *CampaignController) Pause(c *gin.Context) {
 	if err := cc.campaignService.UpdateStatus(req.ID, models.CampaignStatusPaused); err != nil {
 		if err.Error() == "计划不存在" {
 			utils.NotFound(c, "计划不存在")
+		} else if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
 		} else {
 			utils.InternalServerError(c, "暂停计划失败")
 		}
@@ -395,6 +671,8 @@ func (cc *CampaignController) Resume(c *gin.Context) {
 	if err := cc.campaignService.UpdateStatus(req.ID, models.CampaignStatusActive); err != nil {
 		if err.Error() == "计划不存在" {
 			utils.NotFound(c, "计划不存在")
+		} else if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
 		} else {
 			utils.InternalServerError(c, "恢复计划失败")
 		}
@@ -413,21 +691,28 @@ func (cc *CampaignController) UploadFile(c *gin.Context) {
 		return
 	}
 
-	// 根据文件类型决定保存目录
-	subDir := "campaigns"
-	fileExt := strings.ToLower(file.Header.Get("Content-Type"))
-	if strings.HasPrefix(fileExt, "video/") {
-		subDir = "campaigns/videos"
-	} else if strings.HasPrefix(fileExt, "image/") {
-		subDir = "campaigns/images"
+	// 根据检测到的文件类型决定保存目录，图片同时生成缩略图与 WebP 变体
+	contentType, err := utils.DetectUploadType(file)
+	if err != nil {
+		utils.BadRequest(c, "读取上传文件失败")
+		return
 	}
 
-	// 保存文件
-	uploadResp, err := utils.SaveUploadedFile(c, file, subDir)
+	var uploadResp *types.UploadResponse
+	switch {
+	case utils.IsImageFile(contentType):
+		uploadResp, err = utils.SaveUploadedImage(c, file, "campaigns/images")
+	case utils.IsVideoFile(contentType):
+		uploadResp, err = utils.SaveUploadedFile(c, file, "campaigns/videos")
+	default:
+		uploadResp, err = utils.SaveUploadedFile(c, file, "campaigns")
+	}
 	if err != nil {
 		utils.BadRequest(c, "上传失败: "+err.Error())
 		return
 	}
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	cc.assetService.Register(uploadResp, file.Filename, adminID)
 
 	utils.Success(c, uploadResp)
 }
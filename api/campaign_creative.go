@@ -0,0 +1,181 @@
+package api
+
+import (
+	"backend/models"
+	"backend/services"
+	"backend/types"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CampaignCreativeRequest 计划创意请求结构
+type CampaignCreativeRequest struct {
+	Name            string                 `json:"name" binding:"required,max=100"`
+	MainImage       string                 `json:"main_image" binding:"max=500"`
+	Video           string                 `json:"video" binding:"max=500"`
+	DeliveryContent models.CustomFieldList `json:"delivery_content"`
+	Weight          *int                   `json:"weight" binding:"omitempty,min=0,max=10000"`
+	Status          *int                   `json:"status" binding:"omitempty,oneof=0 1"`
+}
+
+// CampaignCreativeURI 计划创意路径参数
+type CampaignCreativeURI struct {
+	ID         uint `uri:"id" binding:"required,min=1"`
+	CreativeID uint `uri:"creative_id" binding:"required,min=1"`
+}
+
+// apply 将请求内容写入创意
+func (req *CampaignCreativeRequest) apply(creative *models.CampaignCreative) {
+	creative.Name = req.Name
+	creative.MainImage = req.MainImage
+	creative.Video = req.Video
+	creative.DeliveryContent = req.DeliveryContent
+	if req.Weight != nil {
+		creative.Weight = *req.Weight
+	}
+	if req.Status != nil {
+		creative.Status = models.CreativeStatus(*req.Status)
+	}
+}
+
+// respondCreativeError 统一处理创意相关错误
+func respondCreativeError(c *gin.Context, err error, message string) {
+	if err.Error() == "计划不存在" || err.Error() == "创意不存在" {
+		utils.NotFound(c, err.Error())
+	} else if _, ok := err.(*services.ServiceError); ok {
+		utils.BadRequest(c, err.Error())
+	} else {
+		utils.InternalServerError(c, message)
+	}
+}
+
+// ListCreatives 获取计划的创意列表
+func (cc *CampaignController) ListCreatives(c *gin.Context) {
+	var req types.IDRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	creatives, err := cc.creativeService.List(req.ID)
+	if err != nil {
+		respondCreativeError(c, err, "获取创意列表失败")
+		return
+	}
+
+	utils.Success(c, creatives)
+}
+
+// CreateCreative 为计划创建创意
+func (cc *CampaignController) CreateCreative(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var req CampaignCreativeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	creative := &models.CampaignCreative{
+		CampaignID: uriReq.ID,
+		Weight:     1,
+		Status:     models.CreativeStatusActive,
+	}
+	req.apply(creative)
+
+	if err := cc.creativeService.Create(creative); err != nil {
+		respondCreativeError(c, err, "创建创意失败")
+		return
+	}
+
+	utils.Created(c, creative)
+}
+
+// UpdateCreative 更新计划创意
+func (cc *CampaignController) UpdateCreative(c *gin.Context) {
+	var uriReq CampaignCreativeURI
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var req CampaignCreativeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	creative, err := cc.creativeService.GetByID(uriReq.ID, uriReq.CreativeID)
+	if err != nil {
+		respondCreativeError(c, err, "获取创意失败")
+		return
+	}
+	req.apply(creative)
+
+	if err := cc.creativeService.Update(creative); err != nil {
+		respondCreativeError(c, err, "更新创意失败")
+		return
+	}
+
+	utils.Updated(c, creative)
+}
+
+// DeleteCreative 删除计划创意
+func (cc *CampaignController) DeleteCreative(c *gin.Context) {
+	var uriReq CampaignCreativeURI
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	if err := cc.creativeService.Delete(uriReq.ID, uriReq.CreativeID); err != nil {
+		respondCreativeError(c, err, "删除创意失败")
+		return
+	}
+
+	utils.Deleted(c)
+}
+
+// CompareCreatives 对比计划的创意变体并计算显著性，可通过 metric=ctr|cvr 指定指标
+func (cc *CampaignController) CompareCreatives(c *gin.Context) {
+	var req types.IDRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	metric := c.Query("metric")
+	if metric != "" && metric != models.CreativeMetricCTR && metric != models.CreativeMetricCVR {
+		utils.BadRequest(c, "无效的对比指标")
+		return
+	}
+
+	result, err := cc.creativeService.Compare(req.ID, metric)
+	if err != nil {
+		respondCreativeError(c, err, "对比创意失败")
+		return
+	}
+
+	utils.Success(c, result)
+}
+
+// PromoteCreative 采用指定创意：暂停其余创意并同步到计划
+func (cc *CampaignController) PromoteCreative(c *gin.Context) {
+	var uriReq CampaignCreativeURI
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	if err := cc.creativeService.Promote(uriReq.ID, uriReq.CreativeID); err != nil {
+		respondCreativeError(c, err, "采用创意失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "创意已采用", nil)
+}
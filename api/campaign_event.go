@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"backend/services"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// campaignEventMaxBody 事件上报请求体大小上限
+const campaignEventMaxBody = 1 << 20
+
+// CampaignEventRequest 事件批量上报请求
+type CampaignEventRequest struct {
+	Events []services.CampaignEventInput `json:"events"`
+}
+
+// CampaignEventController 广告事件上报控制器
+type CampaignEventController struct {
+	eventService *services.CampaignEventService
+}
+
+// NewCampaignEventController 创建广告事件上报控制器
+func NewCampaignEventController() *CampaignEventController {
+	return &CampaignEventController{
+		eventService: services.NewCampaignEventService(),
+	}
+}
+
+// Track 批量上报展示/点击/转化事件
+// 请求头：X-Product-ID、X-Timestamp（Unix秒）、X-Signature（hex(HMAC-SHA256(tracking_key, timestamp + "." + body))）
+func (cec *CampaignEventController) Track(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, campaignEventMaxBody))
+	if err != nil {
+		utils.BadRequest(c, "请求体过大或读取失败")
+		return
+	}
+
+	productID, _ := strconv.ParseUint(c.GetHeader("X-Product-ID"), 10, 32)
+	product, err := cec.eventService.VerifySignature(uint(productID), c.GetHeader("X-Timestamp"), c.GetHeader("X-Signature"), body)
+	if err != nil {
+		utils.Unauthorized(c, err.Error())
+		return
+	}
+
+	var req CampaignEventRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		utils.BadRequest(c, "请求格式错误")
+		return
+	}
+
+	result, err := cec.eventService.Ingest(product, req.Events, c.ClientIP())
+	if err != nil {
+		if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
+		} else {
+			utils.InternalServerError(c, "事件上报失败")
+		}
+		return
+	}
+
+	utils.Success(c, result)
+}
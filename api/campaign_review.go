@@ -0,0 +1,211 @@
+package api
+
+import (
+	"backend/middleware"
+	"backend/services"
+	"backend/types"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SubmitReviewRequest 提交审核请求结构
+type SubmitReviewRequest struct {
+	ReviewerID *uint  `json:"reviewer_id" binding:"omitempty,min=1"` // 不指定时自动分配
+	Remark     string `json:"remark" binding:"max=500"`
+}
+
+// ReviewDecisionRequest 审核操作请求结构
+type ReviewDecisionRequest struct {
+	Remark string `json:"remark" binding:"max=500"`
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// respondReviewError 统一处理审核相关错误
+func respondReviewError(c *gin.Context, err error, message string) {
+	if err.Error() == "计划不存在" {
+		utils.NotFound(c, "计划不存在")
+	} else if serviceErr, ok := err.(*services.ServiceError); ok {
+		if serviceErr.Code == 403 {
+			utils.Forbidden(c, serviceErr.Message)
+		} else {
+			utils.BadRequest(c, serviceErr.Message)
+		}
+	} else {
+		utils.InternalServerError(c, message)
+	}
+}
+
+// bindReviewDecision 绑定可选的审核请求体
+func bindReviewDecision(c *gin.Context) (*ReviewDecisionRequest, bool) {
+	var req ReviewDecisionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidateError(c, err)
+			return nil, false
+		}
+	}
+	return &req, true
+}
+
+// SubmitReview 提交计划审核
+func (cc *CampaignController) SubmitReview(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var req SubmitReviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidateError(c, err)
+			return
+		}
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	campaign, err := cc.reviewService.Submit(uriReq.ID, adminID, req.ReviewerID, req.Remark)
+	if err != nil {
+		respondReviewError(c, err, "提交审核失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "已提交审核", campaign)
+}
+
+// WithdrawReview 撤回计划审核
+func (cc *CampaignController) WithdrawReview(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	campaign, err := cc.reviewService.Withdraw(uriReq.ID, adminID)
+	if err != nil {
+		respondReviewError(c, err, "撤回审核失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "已撤回审核", campaign)
+}
+
+// ApproveReview 审核通过
+func (cc *CampaignController) ApproveReview(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+	req, ok := bindReviewDecision(c)
+	if !ok {
+		return
+	}
+
+	adminID, _, role, _ := middleware.GetCurrentAdmin(c)
+	campaign, err := cc.reviewService.Approve(uriReq.ID, adminID, role, req.Remark)
+	if err != nil {
+		respondReviewError(c, err, "审核失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "审核已通过", campaign)
+}
+
+// RejectReview 驳回审核
+func (cc *CampaignController) RejectReview(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+	req, ok := bindReviewDecision(c)
+	if !ok {
+		return
+	}
+
+	adminID, _, role, _ := middleware.GetCurrentAdmin(c)
+	campaign, err := cc.reviewService.Reject(uriReq.ID, adminID, role, req.Reason)
+	if err != nil {
+		respondReviewError(c, err, "驳回失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "审核已驳回", campaign)
+}
+
+// GetReviewDiff 获取计划相对上次审核通过的变更
+func (cc *CampaignController) GetReviewDiff(c *gin.Context) {
+	var req types.IDRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	diff, err := cc.reviewService.Diff(req.ID)
+	if err != nil {
+		respondReviewError(c, err, "获取变更失败")
+		return
+	}
+
+	utils.Success(c, diff)
+}
+
+// GetReviews 获取计划审核记录
+func (cc *CampaignController) GetReviews(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var req types.PageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	reviews, total, err := cc.reviewService.History(uriReq.ID, &req)
+	if err != nil {
+		respondReviewError(c, err, "获取审核记录失败")
+		return
+	}
+
+	utils.PagedSuccess(c, reviews, total, req.GetPage(), req.GetSize())
+}
+
+// GetPendingReviews 获取待审核计划，mine=1 时只返回分配给当前管理员的计划
+func (cc *CampaignController) GetPendingReviews(c *gin.Context) {
+	var req types.PageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var reviewerID *uint
+	if c.Query("mine") == "1" || c.Query("mine") == "true" {
+		adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+		reviewerID = &adminID
+	}
+
+	campaigns, total, err := cc.reviewService.Pending(&req, reviewerID)
+	if err != nil {
+		utils.InternalServerError(c, "获取待审核计划失败")
+		return
+	}
+
+	utils.PagedSuccess(c, campaigns, total, req.GetPage(), req.GetSize())
+}
+
+// GetReviewers 获取可分配的审核人
+func (cc *CampaignController) GetReviewers(c *gin.Context) {
+	reviewers, err := cc.reviewService.Reviewers()
+	if err != nil {
+		utils.InternalServerError(c, "获取审核人失败")
+		return
+	}
+
+	utils.Success(c, reviewers)
+}
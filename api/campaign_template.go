@@ -0,0 +1,164 @@
+package api
+
+import (
+	"backend/middleware"
+	"backend/services"
+	"backend/types"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CampaignTemplateRequest 计划模板请求结构
+type CampaignTemplateRequest struct {
+	CampaignID *uint  `json:"campaign_id" binding:"omitempty,min=1"` // 创建时必填；更新时传入则用该计划覆盖模板内容
+	Name       string `json:"name" binding:"required,max=100"`
+	Remark     string `json:"remark" binding:"max=500"`
+}
+
+// InstantiateTemplateRequest 按模板创建计划请求结构
+type InstantiateTemplateRequest struct {
+	ProductID uint   `json:"product_id" binding:"required,min=1"`
+	Name      string `json:"name" binding:"max=255"`
+}
+
+// CampaignTemplateController 计划模板控制器
+type CampaignTemplateController struct {
+	templateService *services.CampaignTemplateService
+}
+
+// NewCampaignTemplateController 创建计划模板控制器
+func NewCampaignTemplateController() *CampaignTemplateController {
+	return &CampaignTemplateController{
+		templateService: services.NewCampaignTemplateService(),
+	}
+}
+
+// respondTemplateError 统一处理模板相关错误
+func respondTemplateError(c *gin.Context, err error, message string) {
+	if err.Error() == "模板不存在" || err.Error() == "计划不存在" {
+		utils.NotFound(c, err.Error())
+	} else if _, ok := err.(*services.ServiceError); ok {
+		utils.BadRequest(c, err.Error())
+	} else {
+		utils.InternalServerError(c, message)
+	}
+}
+
+// List 获取模板列表
+func (ctc *CampaignTemplateController) List(c *gin.Context) {
+	var req types.PageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	templates, total, err := ctc.templateService.List(&req)
+	if err != nil {
+		utils.InternalServerError(c, "获取模板列表失败")
+		return
+	}
+
+	utils.PagedSuccess(c, templates, total, req.GetPage(), req.GetSize())
+}
+
+// GetByID 获取模板详情
+func (ctc *CampaignTemplateController) GetByID(c *gin.Context) {
+	var req types.IDRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	template, err := ctc.templateService.GetByID(req.ID)
+	if err != nil {
+		respondTemplateError(c, err, "获取模板失败")
+		return
+	}
+
+	utils.Success(c, template)
+}
+
+// Create 将计划保存为模板
+func (ctc *CampaignTemplateController) Create(c *gin.Context) {
+	var req CampaignTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+	if req.CampaignID == nil {
+		utils.BadRequest(c, "请指定来源计划")
+		return
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	template, err := ctc.templateService.CreateFromCampaign(*req.CampaignID, req.Name, req.Remark, adminID)
+	if err != nil {
+		respondTemplateError(c, err, "创建模板失败")
+		return
+	}
+
+	utils.Created(c, template)
+}
+
+// Update 更新模板
+func (ctc *CampaignTemplateController) Update(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var req CampaignTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	template, err := ctc.templateService.Update(uriReq.ID, req.Name, req.Remark, req.CampaignID)
+	if err != nil {
+		respondTemplateError(c, err, "更新模板失败")
+		return
+	}
+
+	utils.Updated(c, template)
+}
+
+// Delete 删除模板
+func (ctc *CampaignTemplateController) Delete(c *gin.Context) {
+	var req types.IDRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	if err := ctc.templateService.Delete(req.ID); err != nil {
+		respondTemplateError(c, err, "删除模板失败")
+		return
+	}
+
+	utils.Deleted(c)
+}
+
+// Instantiate 按模板为指定产品创建未激活的计划
+func (ctc *CampaignTemplateController) Instantiate(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var req InstantiateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	campaign, err := ctc.templateService.Instantiate(uriReq.ID, req.ProductID, req.Name)
+	if err != nil {
+		respondTemplateError(c, err, "按模板创建计划失败")
+		return
+	}
+
+	utils.Created(c, campaign)
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/middleware"
+	"backend/models"
+	"backend/services"
+	"backend/types"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tusOffsetContentType tus PATCH 请求的内容类型
+const tusOffsetContentType = "application/offset+octet-stream"
+
+// VideoUploadURI 视频断点续传路径参数
+type VideoUploadURI struct {
+	ID       uint   `uri:"id" binding:"required,min=1"`
+	UploadID string `uri:"upload_id" binding:"required,uuid"`
+}
+
+// parseUploadMetadata 解析 Upload-Metadata 头：逗号分隔的 "键 base64值"，值可省略
+func parseUploadMetadata(header string) (map[string]string, bool) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, false
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, true
+}
+
+// setTusHeaders 设置 tus 通用响应头
+func setTusHeaders(c *gin.Context, upload *models.CampaignVideoUpload) {
+	c.Header("Tus-Resumable", services.TusVersion)
+	if upload == nil {
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if upload.Status == models.VideoUploadStatusUploading {
+		c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// checkTusVersion 请求声明了 Tus-Resumable 时必须与服务端版本一致
+func checkTusVersion(c *gin.Context) bool {
+	if version := c.GetHeader("Tus-Resumable"); version != "" && version != services.TusVersion {
+		c.Header("Tus-Version", services.TusVersion)
+		utils.Error(c, http.StatusPreconditionFailed, "不支持的 tus 协议版本")
+		return false
+	}
+	return true
+}
+
+// respondVideoUploadError 统一处理视频断点续传错误，tus 约定的状态码（409/410/413/460 等）原样返回
+func respondVideoUploadError(c *gin.Context, err error, message string) {
+	c.Header("Tus-Resumable", services.TusVersion)
+	if err.Error() == "计划不存在" || err.Error() == "上传会话不存在" {
+		utils.NotFound(c, err.Error())
+	} else if serviceErr, ok := err.(*services.ServiceError); ok {
+		utils.Error(c, serviceErr.Code, serviceErr.Message)
+	} else {
+		utils.InternalServerError(c, message)
+	}
+}
+
+// VideoUploadOptions 返回服务端支持的 tus 版本与扩展
+func (cc *CampaignController) VideoUploadOptions(c *gin.Context) {
+	c.Header("Tus-Resumable", services.TusVersion)
+	c.Header("Tus-Version", services.TusVersion)
+	c.Header("Tus-Extension", services.TusExtensions)
+	c.Header("Tus-Checksum-Algorithm", services.TusChecksumAlgorithms)
+	c.Header("Tus-Max-Size", strconv.FormatInt(services.MaxVideoUploadSize(), 10))
+	c.Status(http.StatusNoContent)
+}
+
+// CreateVideoUpload 创建视频断点续传会话（tus creation）
+// 请求头 Upload-Length 为文件大小，Upload-Metadata 需包含 filename，可包含整个文件的 checksum（"sha256 base64摘要"）
+func (cc *CampaignController) CreateVideoUpload(c *gin.Context) {
+	if !checkTusVersion(c) {
+		return
+	}
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "缺少或无效的 Upload-Length")
+		return
+	}
+	metadata, ok := parseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	if !ok {
+		utils.BadRequest(c, "Upload-Metadata 格式无效")
+		return
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	upload, err := cc.videoUploadService.Create(uriReq.ID, metadata["filename"], length, metadata["checksum"], adminID)
+	if err != nil {
+		respondVideoUploadError(c, err, "创建上传会话失败")
+		return
+	}
+
+	setTusHeaders(c, upload)
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+upload.UploadID)
+	utils.Created(c, upload)
+}
+
+// HeadVideoUpload 查询已接收的字节数（tus HEAD），用于断点续传
+func (cc *CampaignController) HeadVideoUpload(c *gin.Context) {
+	var uriReq VideoUploadURI
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	upload, err := cc.videoUploadService.Get(uriReq.ID, uriReq.UploadID)
+	if err != nil {
+		setTusHeaders(c, nil)
+		if err.Error() == "上传会话不存在" {
+			c.Status(http.StatusNotFound)
+		} else {
+			c.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setTusHeaders(c, upload)
+	if upload.Status != models.VideoUploadStatusUploading && upload.Status != models.VideoUploadStatusCompleted {
+		c.Status(http.StatusGone)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// GetVideoUpload 获取上传会话详情（状态、合并后的地址与视频元数据）
+func (cc *CampaignController) GetVideoUpload(c *gin.Context) {
+	var uriReq VideoUploadURI
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	upload, err := cc.videoUploadService.Get(uriReq.ID, uriReq.UploadID)
+	if err != nil {
+		respondVideoUploadError(c, err, "获取上传会话失败")
+		return
+	}
+	setTusHeaders(c, upload)
+	utils.Success(c, upload)
+}
+
+// PatchVideoUpload 上传一段数据（tus PATCH），可通过 Upload-Checksum 校验本段数据；
+// 最后一段上传完成后合并文件、提取时长与分辨率并更新计划视频
+func (cc *CampaignController) PatchVideoUpload(c *gin.Context) {
+	if !checkTusVersion(c) {
+		return
+	}
+	var uriReq VideoUploadURI
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+	if c.ContentType() != tusOffsetContentType {
+		utils.Error(c, http.StatusUnsupportedMediaType, "Content-Type 必须为 "+tusOffsetContentType)
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		utils.BadRequest(c, "缺少或无效的 Upload-Offset")
+		return
+	}
+
+	upload, err := cc.videoUploadService.Patch(uriReq.ID, uriReq.UploadID, offset, c.GetHeader("Upload-Checksum"), c.Request.Body)
+	if err != nil {
+		if upload != nil {
+			setTusHeaders(c, upload)
+		}
+		respondVideoUploadError(c, err, "上传失败")
+		return
+	}
+
+	setTusHeaders(c, upload)
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteVideoUpload 取消上传并删除已接收的数据（tus termination）
+func (cc *CampaignController) DeleteVideoUpload(c *gin.Context) {
+	var uriReq VideoUploadURI
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	if err := cc.videoUploadService.Cancel(uriReq.ID, uriReq.UploadID); err != nil {
+		respondVideoUploadError(c, err, "取消上传失败")
+		return
+	}
+	setTusHeaders(c, nil)
+	c.Status(http.StatusNoContent)
+}
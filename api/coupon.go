@@ -1,9 +1,12 @@
 package api
 
 import (
+	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
+	"backend/middleware"
 	"backend/models"
 	"backend/services"
 	"backend/types"
@@ -25,6 +28,8 @@ type CouponRequest struct {
 	DateRange       *models.DateRange     `json:"date_range"`
 	Status          models.CouponStatus   `json:"status" binding:"min=0,max=3"`
 	TotalCount      int                   `json:"total_count" binding:"min=0"`
+	Stackable       bool                  `json:"stackable"`
+	StackGroup      string                `json:"stack_group" binding:"max=64"`
 }
 
 // ClaimCouponRequest 领取优惠券请求
@@ -35,6 +40,7 @@ type ClaimCouponRequest struct {
 // UseCouponRequest 使用优惠券请求
 type UseCouponRequest struct {
 	OrderAmount float64 `json:"order_amount" binding:"required,min=0"`
+	StackWith   []uint  `json:"stack_with" binding:"omitempty,dive,min=1"` // 同一订单中一并使用的其他用户优惠券ID
 }
 
 // DistributeCouponRequest 分发优惠券请求
@@ -42,6 +48,31 @@ type DistributeCouponRequest struct {
 	UserIDs []uint `json:"user_ids" binding:"required,min=1"`
 }
 
+// PromoCodeRequest 创建兑换码请求
+type PromoCodeRequest struct {
+	Code      string     `json:"code" binding:"required,min=4,max=32"`
+	MaxUses   int        `json:"max_uses" binding:"min=0"`
+	ExpiredAt *time.Time `json:"expired_at"`
+}
+
+// GeneratePromoCodesRequest 批量生成兑换码请求
+type GeneratePromoCodesRequest struct {
+	Count     int        `json:"count" binding:"required,min=1,max=10000"`
+	Prefix    string     `json:"prefix" binding:"max=16"`
+	Length    int        `json:"length" binding:"min=0,max=16"`
+	ExpiredAt *time.Time `json:"expired_at"`
+}
+
+// PromoCodeStatusRequest 兑换码状态请求
+type PromoCodeStatusRequest struct {
+	Status models.PromoCodeStatus `json:"status" binding:"min=0,max=1"`
+}
+
+// RedeemPromoCodeRequest 兑换码兑换请求
+type RedeemPromoCodeRequest struct {
+	Code string `json:"code" binding:"required,max=64"`
+}
+
 // CouponController 优惠券控制器
 type CouponController struct {
 	couponService *services.CouponService
@@ -124,6 +155,8 @@ func (cc *CouponController) Create(c *gin.Context) {
 		DateRange:       req.DateRange,
 		Status:          req.Status,
 		TotalCount:      req.TotalCount,
+		Stackable:       req.Stackable,
+		StackGroup:      strings.TrimSpace(req.StackGroup),
 	}
 
 	if err := cc.couponService.Create(coupon); err != nil {
@@ -178,6 +211,8 @@ func (cc *CouponController) Update(c *gin.Context) {
 	coupon.DateRange = req.DateRange
 	coupon.Status = req.Status
 	coupon.TotalCount = req.TotalCount
+	coupon.Stackable = req.Stackable
+	coupon.StackGroup = strings.TrimSpace(req.StackGroup)
 
 	if err := cc.couponService.Update(coupon); err != nil {
 		utils.InternalServerError(c, "更新优惠券失败")
@@ -314,7 +349,7 @@ func (cc *CouponController) UseCoupon(c *gin.Context) {
 		return
 	}
 
-	discountAmount, err := cc.couponService.UseCoupon(userID.(uint), uriReq.ID, req.OrderAmount)
+	discountAmount, err := cc.couponService.UseCoupon(userID.(uint), uriReq.ID, req.OrderAmount, req.StackWith)
 	if err != nil {
 		utils.BadRequest(c, err.Error())
 		return
@@ -356,6 +391,34 @@ func (cc *CouponController) GetAvailableCoupons(c *gin.Context) {
 	utils.Success(c, coupons)
 }
 
+// GetBestCombination 获取最优优惠券组合
+func (cc *CouponController) GetBestCombination(c *gin.Context) {
+	// 获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "请先登录")
+		return
+	}
+
+	orderAmount, err := strconv.ParseFloat(c.Query("order_amount"), 64)
+	if err != nil || orderAmount <= 0 {
+		utils.BadRequest(c, "订单金额必须大于0")
+		return
+	}
+
+	result, err := cc.couponService.GetBestCouponCombination(userID.(uint), orderAmount)
+	if err != nil {
+		if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
+		} else {
+			utils.InternalServerError(c, "计算最优优惠券组合失败")
+		}
+		return
+	}
+
+	utils.Success(c, result)
+}
+
 // GetStatistics 获取优惠券统计
 func (cc *CouponController) GetStatistics(c *gin.Context) {
 	stats, err := cc.couponService.GetStatistics()
@@ -365,4 +428,206 @@ func (cc *CouponController) GetStatistics(c *gin.Context) {
 	}
 
 	utils.Success(c, stats)
-}
\ No newline at end of file
+}
+// ListPromoCodes 获取优惠券兑换码列表
+func (cc *CouponController) ListPromoCodes(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var req types.FilterRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	promoCodes, total, err := cc.couponService.ListPromoCodes(uriReq.ID, &req, c.Query("batch_no"))
+	if err != nil {
+		utils.InternalServerError(c, "获取兑换码列表失败")
+		return
+	}
+
+	utils.PagedSuccess(c, promoCodes, total, req.GetPage(), req.GetSize())
+}
+
+// CreatePromoCode 创建通用兑换码
+func (cc *CouponController) CreatePromoCode(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var req PromoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	promoCode := &models.CouponPromoCode{
+		CouponID:  uriReq.ID,
+		Code:      req.Code,
+		Type:      models.PromoCodeTypeShared,
+		MaxUses:   req.MaxUses,
+		ExpiredAt: req.ExpiredAt,
+		CreatedBy: adminID,
+	}
+
+	if err := cc.couponService.CreatePromoCode(promoCode); err != nil {
+		if err.Error() == "优惠券不存在" {
+			utils.NotFound(c, "优惠券不存在")
+		} else if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
+		} else {
+			utils.InternalServerError(c, "创建兑换码失败")
+		}
+		return
+	}
+
+	utils.Created(c, promoCode)
+}
+
+// GeneratePromoCodes 批量生成唯一兑换码
+func (cc *CouponController) GeneratePromoCodes(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var req GeneratePromoCodesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	result, err := cc.couponService.GeneratePromoCodes(uriReq.ID, req.Count, req.Prefix, req.Length, req.ExpiredAt, adminID)
+	if err != nil {
+		if err.Error() == "优惠券不存在" {
+			utils.NotFound(c, "优惠券不存在")
+		} else if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
+		} else {
+			utils.InternalServerError(c, "生成兑换码失败")
+		}
+		return
+	}
+
+	utils.SuccessWithMessage(c, "兑换码生成成功", result)
+}
+
+// UpdatePromoCodeStatus 启用/停用兑换码
+func (cc *CouponController) UpdatePromoCodeStatus(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	codeID, err := strconv.ParseUint(c.Param("code_id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "无效的兑换码ID")
+		return
+	}
+
+	var req PromoCodeStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	if err := cc.couponService.UpdatePromoCodeStatus(uriReq.ID, uint(codeID), req.Status); err != nil {
+		if err.Error() == "兑换码不存在" {
+			utils.NotFound(c, "兑换码不存在")
+		} else {
+			utils.InternalServerError(c, "更新兑换码状态失败")
+		}
+		return
+	}
+
+	utils.SuccessWithMessage(c, "状态更新成功", nil)
+}
+
+// ExportPromoCodes 导出兑换码CSV
+func (cc *CouponController) ExportPromoCodes(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	data, err := cc.couponService.ExportPromoCodes(uriReq.ID, c.Query("batch_no"))
+	if err != nil {
+		if err.Error() == "优惠券不存在" {
+			utils.NotFound(c, "优惠券不存在")
+		} else {
+			utils.InternalServerError(c, "导出兑换码失败")
+		}
+		return
+	}
+
+	filename := fmt.Sprintf("promo_codes_%d_%s.csv", uriReq.ID, time.Now().Format("20060102150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Data(200, "text/csv; charset=utf-8", data)
+}
+
+// GetPromoCodeRedemptions 获取兑换码兑换记录
+func (cc *CouponController) GetPromoCodeRedemptions(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var req types.FilterRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	redemptions, total, err := cc.couponService.GetPromoCodeRedemptions(uriReq.ID, &req)
+	if err != nil {
+		if err.Error() == "优惠券不存在" {
+			utils.NotFound(c, "优惠券不存在")
+		} else {
+			utils.InternalServerError(c, "获取兑换记录失败")
+		}
+		return
+	}
+
+	utils.PagedSuccess(c, redemptions, total, req.GetPage(), req.GetSize())
+}
+
+// RedeemPromoCode 使用兑换码兑换优惠券
+func (cc *CouponController) RedeemPromoCode(c *gin.Context) {
+	var req RedeemPromoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	// 获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "请先登录")
+		return
+	}
+
+	userCoupon, err := cc.couponService.RedeemPromoCode(userID.(uint), req.Code, c.ClientIP())
+	if err != nil {
+		if se, ok := err.(*services.ServiceError); ok && se.Code == 429 {
+			utils.Error(c, 429, se.Message)
+		} else if ok {
+			utils.BadRequest(c, se.Message)
+		} else {
+			utils.InternalServerError(c, "兑换失败")
+		}
+		return
+	}
+
+	utils.SuccessWithMessage(c, "兑换成功", userCoupon)
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"strconv"
 
+	"backend/middleware"
 	"backend/models"
 	"backend/services"
 	"backend/types"
@@ -20,17 +21,23 @@ type CustomerRequest struct {
 	Address string                 `json:"address"`
 	Notes   string                 `json:"notes"`
 	Balance float64                `json:"balance" binding:"min=0"`
+	AgentAdminID *uint             `json:"agent_admin_id"`
+	Tags    string                 `json:"tags" binding:"max=500"`
 }
 
 // CustomerController 客户控制器
 type CustomerController struct {
 	customerService *services.CustomerService
+	exportService   *services.ExportService
+	importService   *services.ImportService
 }
 
 // NewCustomerController 创建客户控制器
 func NewCustomerController() *CustomerController {
 	return &CustomerController{
 		customerService: services.NewCustomerService(),
+		exportService:   services.NewExportService(),
+		importService:   services.NewImportService(),
 	}
 }
 
@@ -88,12 +95,14 @@ func (cc *CustomerController) Create(c *gin.Context) {
 		Status:  req.Status,
 		Address: req.Address,
 		Notes:   req.Notes,
+		Tags:    req.Tags,
 		Balance: req.Balance,
+		AgentAdminID: req.AgentAdminID,
 	}
 
 	if err := cc.customerService.Create(customer); err != nil {
-		if err.Error() == "邮箱已存在" {
-			utils.BadRequest(c, "邮箱已存在")
+		if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
 		} else {
 			utils.InternalServerError(c, "创建客户失败")
 		}
@@ -136,11 +145,15 @@ func (cc *CustomerController) Update(c *gin.Context) {
 	customer.Status = req.Status
 	customer.Address = req.Address
 	customer.Notes = req.Notes
+	customer.Tags = req.Tags
 	customer.Balance = req.Balance
+	if req.AgentAdminID != nil {
+		customer.AgentAdminID = req.AgentAdminID
+	}
 
 	if err := cc.customerService.Update(customer); err != nil {
-		if err.Error() == "邮箱已存在" {
-			utils.BadRequest(c, "邮箱已存在")
+		if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
 		} else {
 			utils.InternalServerError(c, "更新客户失败")
 		}
@@ -333,7 +346,7 @@ func (cc *CustomerController) GetStatistics(c *gin.Context) {
 	utils.Success(c, stats)
 }
 
-// Export 导出客户数据
+// Export 导出客户数据（CSV/XLSX）
 func (cc *CustomerController) Export(c *gin.Context) {
 	var req types.FilterRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
@@ -341,22 +354,39 @@ func (cc *CustomerController) Export(c *gin.Context) {
 		return
 	}
 
-	// 设置导出数量限制
-	req.Size = 10000
+	handleExport(c, cc.exportService, services.ExportResourceCustomers, &req)
+}
+
+// Import 批量导入客户（CSV/XLSX，后台任务执行）
+func (cc *CustomerController) Import(c *gin.Context) {
+	var req struct {
+		Mode         string `form:"mode" binding:"omitempty,oneof=create upsert"`
+		DryRun       bool   `form:"dry_run"`
+		AgentAdminID *uint  `form:"agent_admin_id"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.BadRequest(c, "请选择要导入的文件")
+		return
+	}
 
-	customers, _, err := cc.customerService.List(&req)
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	job, err := cc.importService.CreateCustomerImport(file, req.Mode, req.DryRun, req.AgentAdminID, adminID)
 	if err != nil {
-		utils.InternalServerError(c, "导出客户数据失败")
+		if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
+		} else {
+			utils.InternalServerError(c, "创建导入任务失败")
+		}
 		return
 	}
 
-	// 这里可以生成Excel或CSV文件
-	// 暂时返回JSON数据
-	utils.Success(c, map[string]interface{}{
-		"customers": customers,
-		"count":     len(customers),
-		"exported_at": "now",
-	})
+	utils.SuccessWithMessage(c, "导入任务已创建", job)
 }
 
 // BatchUpdateStatus 批量更新状态
@@ -429,7 +459,11 @@ func (cc *CustomerController) UpdateProfile(c *gin.Context) {
 	customer.Address = req.Address
 
 	if err := cc.customerService.Update(customer); err != nil {
-		utils.InternalServerError(c, "更新资料失败")
+		if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
+		} else {
+			utils.InternalServerError(c, "更新资料失败")
+		}
 		return
 	}
 
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"backend/middleware"
+	"backend/services"
+	"backend/types"
+	"backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportQuery 导出查询参数
+type ExportQuery struct {
+	Format  string `form:"format" binding:"omitempty,oneof=csv xlsx"`
+	Columns string `form:"columns"` // 逗号分隔的列名
+	Lang    string `form:"lang" binding:"omitempty,oneof=zh en"`
+	Async   bool   `form:"async"`
+}
+
+// ExportController 导出任务控制器
+type ExportController struct {
+	exportService *services.ExportService
+}
+
+// NewExportController 创建导出任务控制器
+func NewExportController() *ExportController {
+	return &ExportController{
+		exportService: services.NewExportService(),
+	}
+}
+
+// handleExport 处理导出请求：数据量小时直接流式输出文件，数据量大时转为异步任务
+func handleExport(c *gin.Context, exportService *services.ExportService, resource string, filter *types.FilterRequest) {
+	var query ExportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	req := &services.ExportRequest{
+		Resource: resource,
+		Format:   query.Format,
+		Lang:     query.Lang,
+		Async:    query.Async,
+		Filter:   *filter,
+	}
+	for _, column := range strings.Split(query.Columns, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			req.Columns = append(req.Columns, column)
+		}
+	}
+
+	if err := exportService.Validate(req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	async, total, err := exportService.ShouldRunAsync(req)
+	if err != nil {
+		utils.InternalServerError(c, "统计导出数据失败")
+		return
+	}
+
+	if async {
+		adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+		job, err := exportService.CreateJob(req, total, adminID)
+		if err != nil {
+			utils.InternalServerError(c, "创建导出任务失败")
+			return
+		}
+		utils.SuccessWithMessage(c, "导出数据较多，已转为后台任务", map[string]interface{}{
+			"async": true,
+			"job":   job,
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", exportService.FileName(req)))
+	c.Header("Content-Type", utils.ExportContentType(req.Format))
+	c.Status(200)
+	if _, err := exportService.Stream(req, c.Writer); err != nil {
+		// 响应头已发出，只能记录错误并中断输出
+		log.Printf("Export %s failed: %v", resource, err)
+		c.Abort()
+	}
+}
+
+// GetColumns 获取导出对象的可选列
+func (ec *ExportController) GetColumns(c *gin.Context) {
+	columns, err := ec.exportService.GetColumns(c.Param("resource"))
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.Success(c, columns)
+}
+
+// ListJobs 获取导出任务列表
+func (ec *ExportController) ListJobs(c *gin.Context) {
+	var req types.FilterRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	jobs, total, err := ec.exportService.ListJobs(&req, adminID)
+	if err != nil {
+		utils.InternalServerError(c, "获取导出任务列表失败")
+		return
+	}
+
+	utils.PagedSuccess(c, jobs, total, req.GetPage(), req.GetSize())
+}
+
+// GetJob 获取导出任务详情
+func (ec *ExportController) GetJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "无效的任务ID")
+		return
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	job, err := ec.exportService.GetJob(uint(id), adminID)
+	if err != nil {
+		if err.Error() == "导出任务不存在" {
+			utils.NotFound(c, err.Error())
+		} else {
+			utils.InternalServerError(c, "获取导出任务失败")
+		}
+		return
+	}
+
+	utils.Success(c, job)
+}
+
+// DownloadJob 下载导出文件
+func (ec *ExportController) DownloadJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "无效的任务ID")
+		return
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	job, err := ec.exportService.GetDownloadJob(uint(id), adminID)
+	if err != nil {
+		if err.Error() == "导出任务不存在" {
+			utils.NotFound(c, err.Error())
+		} else if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
+		} else {
+			utils.InternalServerError(c, "下载导出文件失败")
+		}
+		return
+	}
+
+	c.Header("Content-Type", utils.ExportContentType(job.Format))
+	c.FileAttachment(job.FilePath, job.FileName)
+}
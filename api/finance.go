@@ -27,12 +27,14 @@ type WithdrawRequest struct {
 // FinanceController 财务控制器
 type FinanceController struct {
 	financeService *services.FinanceService
+	exportService  *services.ExportService
 }
 
 // NewFinanceController 创建财务控制器
 func NewFinanceController() *FinanceController {
 	return &FinanceController{
 		financeService: services.NewFinanceService(),
+		exportService:  services.NewExportService(),
 	}
 }
 
@@ -263,7 +265,7 @@ func (fc *FinanceController) GetDashboardStats(c *gin.Context) {
 	utils.Success(c, stats)
 }
 
-// ExportTransactions 导出交易记录
+// ExportTransactions 导出交易记录（CSV/XLSX）
 func (fc *FinanceController) ExportTransactions(c *gin.Context) {
 	var req types.FilterRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
@@ -271,22 +273,7 @@ func (fc *FinanceController) ExportTransactions(c *gin.Context) {
 		return
 	}
 
-	// 设置导出数量限制
-	req.Size = 10000
-
-	transactions, _, err := fc.financeService.GetAllTransactions(&req)
-	if err != nil {
-		utils.InternalServerError(c, "导出交易记录失败")
-		return
-	}
-
-	// 这里可以生成Excel或CSV文件
-	// 暂时返回JSON数据
-	utils.Success(c, map[string]interface{}{
-		"transactions": transactions,
-		"count":        len(transactions),
-		"exported_at":  "now",
-	})
+	handleExport(c, fc.exportService, services.ExportResourceTransactions, &req)
 }
 
 // BatchProcessTransactions 批量处理交易
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"strconv"
+
+	"backend/middleware"
+	"backend/services"
+	"backend/types"
+	"backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ImportController 导入任务控制器
+type ImportController struct {
+	importService *services.ImportService
+}
+
+// NewImportController 创建导入任务控制器
+func NewImportController() *ImportController {
+	return &ImportController{
+		importService: services.NewImportService(),
+	}
+}
+
+// GetCustomerTemplate 下载客户导入模板
+func (ic *ImportController) GetCustomerTemplate(c *gin.Context) {
+	format := c.DefaultQuery("format", utils.ExportFormatCSV)
+	if !utils.IsValidExportFormat(format) {
+		utils.BadRequest(c, "模板格式只支持 csv 或 xlsx")
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=customer_import_template."+format)
+	c.Header("Content-Type", utils.ExportContentType(format))
+	c.Status(200)
+
+	writer, err := utils.NewExportWriter(format, c.Writer)
+	if err == nil {
+		err = writer.WriteRow(ic.importService.CustomerImportTemplate())
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		c.Abort()
+	}
+}
+
+// ListJobs 获取导入任务列表
+func (ic *ImportController) ListJobs(c *gin.Context) {
+	var req types.FilterRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	jobs, total, err := ic.importService.ListJobs(&req, adminID)
+	if err != nil {
+		utils.InternalServerError(c, "获取导入任务列表失败")
+		return
+	}
+
+	utils.PagedSuccess(c, jobs, total, req.GetPage(), req.GetSize())
+}
+
+// GetJob 获取导入任务详情
+func (ic *ImportController) GetJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "无效的任务ID")
+		return
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	job, err := ic.importService.GetJob(uint(id), adminID)
+	if err != nil {
+		if err.Error() == "导入任务不存在" {
+			utils.NotFound(c, err.Error())
+		} else {
+			utils.InternalServerError(c, "获取导入任务失败")
+		}
+		return
+	}
+
+	utils.Success(c, job)
+}
+
+// DownloadReport 下载导入错误报告
+func (ic *ImportController) DownloadReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "无效的任务ID")
+		return
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	job, err := ic.importService.GetReportJob(uint(id), adminID)
+	if err != nil {
+		if err.Error() == "导入任务不存在" {
+			utils.NotFound(c, err.Error())
+		} else if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
+		} else {
+			utils.InternalServerError(c, "下载错误报告失败")
+		}
+		return
+	}
+
+	c.Header("Content-Type", utils.ExportContentType(utils.ExportFormatCSV))
+	c.FileAttachment(job.ReportPath, "import_errors_"+job.JobNo+".csv")
+}
@@ -1,8 +1,10 @@
 package api
 
 import (
+	"encoding/json"
 	"strconv"
 
+	"backend/middleware"
 	"backend/models"
 	"backend/services"
 	"backend/types"
@@ -13,27 +15,93 @@ import (
 
 // ProductRequest 产品请求结构
 type ProductRequest struct {
-	Name          string             `json:"name" binding:"required,max=255"`
-	Type          string             `json:"type" binding:"max=100"`
-	Company       string             `json:"company" binding:"max=255"`
-	Description   string             `json:"description"`
-	Status        int                `json:"status" binding:"min=0,max=2"`
-	Logo          string             `json:"logo" binding:"max=500"`
-	Images        []string           `json:"images"`
-	GooglePayLink string             `json:"googlePayLink" binding:"omitempty,url,max=500"`
-	AppStoreLink  string             `json:"appStoreLink" binding:"omitempty,url,max=500"`
-	AppInfo       models.AppInfoList `json:"appInfo"`
+	Name          string              `json:"name" binding:"required,max=255"`
+	Type          string              `json:"type" binding:"max=100"`
+	Company       string              `json:"company" binding:"max=255"`
+	Description   string              `json:"description"`
+	Status        int                 `json:"status" binding:"min=0,max=2"`
+	Logo          string              `json:"logo" binding:"max=500"`
+	Images        []ProductImageInput `json:"images" binding:"omitempty,dive"`
+	GooglePayLink string              `json:"googlePayLink" binding:"omitempty,url,max=500"`
+	AppStoreLink  string              `json:"appStoreLink" binding:"omitempty,url,max=500"`
+	AppInfo       models.AppInfoList  `json:"appInfo"`
+}
+
+// ProductImageInput 产品图片，兼容旧版直接传 URL 字符串
+type ProductImageInput struct {
+	URL    string `json:"url" binding:"required,max=500"`
+	Alt    string `json:"alt" binding:"max=255"`
+	Width  int    `json:"width" binding:"min=0"`
+	Height int    `json:"height" binding:"min=0"`
+}
+
+// UnmarshalJSON 支持 "url" 与 {"url": ...} 两种写法
+func (in *ProductImageInput) UnmarshalJSON(data []byte) error {
+	var url string
+	if err := json.Unmarshal(data, &url); err == nil {
+		*in = ProductImageInput{URL: url}
+		return nil
+	}
+
+	type plain ProductImageInput
+	return json.Unmarshal(data, (*plain)(in))
+}
+
+// productImages 将请求中的图片转换为模型，排序值由服务层按顺序生成
+func productImages(inputs []ProductImageInput) []models.ProductImage {
+	images := make([]models.ProductImage, 0, len(inputs))
+	for _, input := range inputs {
+		images = append(images, models.ProductImage{
+			URL:    input.URL,
+			Alt:    input.Alt,
+			Width:  input.Width,
+			Height: input.Height,
+		})
+	}
+	return images
+}
+
+// ProductImageUpdateRequest 更新产品图片请求
+type ProductImageUpdateRequest struct {
+	Alt  *string `json:"alt" binding:"omitempty,max=255"`
+	Sort *int    `json:"sort" binding:"omitempty,min=0"`
+}
+
+// ProductImageOrderRequest 产品图片排序请求
+type ProductImageOrderRequest struct {
+	IDs []uint `json:"ids" binding:"required,min=1"`
+}
+
+// ProductImageURI 产品图片路径参数
+type ProductImageURI struct {
+	ID      uint `uri:"id" binding:"required,min=1"`
+	ImageID uint `uri:"image_id" binding:"required,min=1"`
+}
+
+// respondProductError 统一处理产品相关错误
+func respondProductError(c *gin.Context, err error, message string) {
+	if err.Error() == "产品不存在" || err.Error() == "图片不存在" {
+		utils.NotFound(c, err.Error())
+	} else if _, ok := err.(*services.ServiceError); ok {
+		utils.BadRequest(c, err.Error())
+	} else {
+		utils.InternalServerError(c, message)
+	}
 }
 
 // ProductController 产品控制器
 type ProductController struct {
-	productService *services.ProductService
+	productService  *services.ProductService
+	revisionService *services.ProductRevisionService
+	assetService    *services.AssetService
 }
 
 // NewProductController 创建产品控制器
 func NewProductController() *ProductController {
 	return &ProductController{
-		productService: services.NewProductService(),
+		productService:  services.NewProductService(),
+		revisionService: services.NewProductRevisionService(),
+		assetService:    services.NewAssetService(),
 	}
 }
 
@@ -95,20 +163,16 @@ func (pc *ProductController) Create(c *gin.Context) {
 	}
 
 	// 处理图片数组
-	if len(req.Images) > 0 {
-		if err := product.SetImages(req.Images); err != nil {
-			utils.InternalServerError(c, "图片数据处理失败")
-			return
-		}
-	}
+	product.Images = productImages(req.Images)
 
 	// 处理AppInfo
 	if len(req.AppInfo) > 0 {
 		product.AppInfo = req.AppInfo
 	}
 
-	if err := pc.productService.Create(product); err != nil {
-		utils.InternalServerError(c, "创建产品失败")
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	if err := pc.productService.Create(product, adminID); err != nil {
+		respondProductError(c, err, "创建产品失败")
 		return
 	}
 
@@ -150,21 +214,20 @@ func (pc *ProductController) Update(c *gin.Context) {
 	product.GooglePayLink = req.GooglePayLink
 	product.AppStoreLink = req.AppStoreLink
 
-	// 处理图片数组
-	if len(req.Images) > 0 {
-		if err := product.SetImages(req.Images); err != nil {
-			utils.InternalServerError(c, "图片数据处理失败")
-			return
-		}
-	}
-
 	// 处理AppInfo
 	if len(req.AppInfo) > 0 {
 		product.AppInfo = req.AppInfo
 	}
 
-	if err := pc.productService.Update(product); err != nil {
-		utils.InternalServerError(c, "更新产品失败")
+	// 传入 images 时（包括空数组）替换全部图片
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	if req.Images != nil {
+		err = pc.productService.UpdateWithImages(product, productImages(req.Images), adminID)
+	} else {
+		err = pc.productService.Update(product, adminID)
+	}
+	if err != nil {
+		respondProductError(c, err, "更新产品失败")
 		return
 	}
 
@@ -205,25 +268,49 @@ func (pc *ProductController) UpdateStatus(c *gin.Context) {
 		return
 	}
 
-	// 获取产品
-	product, err := pc.productService.GetByID(uriReq.ID)
+	// 更新状态
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	product, err := pc.productService.UpdateStatus(uriReq.ID, models.ProductStatus(bodyReq.Status), adminID)
+	if err != nil {
+		respondProductError(c, err, "更新状态失败")
+		return
+	}
+
+	utils.Success(c, product)
+}
+
+// GetTrackingKey 获取产品事件上报密钥
+func (pc *ProductController) GetTrackingKey(c *gin.Context) {
+	pc.respondTrackingKey(c, pc.productService.GetTrackingKey)
+}
+
+// RotateTrackingKey 重新生成产品事件上报密钥
+func (pc *ProductController) RotateTrackingKey(c *gin.Context) {
+	pc.respondTrackingKey(c, pc.productService.RotateTrackingKey)
+}
+
+// respondTrackingKey 返回产品事件上报密钥
+func (pc *ProductController) respondTrackingKey(c *gin.Context, fetch func(uint) (string, error)) {
+	var req types.IDRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	trackingKey, err := fetch(req.ID)
 	if err != nil {
 		if err.Error() == "产品不存在" {
 			utils.NotFound(c, "产品不存在")
 		} else {
-			utils.InternalServerError(c, "获取产品信息失败")
+			utils.InternalServerError(c, "获取上报密钥失败")
 		}
 		return
 	}
 
-	// 更新状态
-	product.Status = models.ProductStatus(bodyReq.Status)
-	if err := pc.productService.Update(product); err != nil {
-		utils.InternalServerError(c, "更新状态失败")
-		return
-	}
-
-	utils.Success(c, product)
+	utils.Success(c, gin.H{
+		"product_id":   req.ID,
+		"tracking_key": trackingKey,
+	})
 }
 
 // UploadLogo 上传产品Logo
@@ -254,21 +341,23 @@ func (pc *ProductController) UploadLogo(c *gin.Context) {
 	}
 
 	// 保存文件
-	uploadResp, err := utils.SaveUploadedFile(c, file, "products/logos")
+	uploadResp, err := utils.SaveUploadedImage(c, file, "products/logos")
 	if err != nil {
 		utils.BadRequest(c, "上传失败: "+err.Error())
 		return
 	}
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	pc.assetService.Register(uploadResp, file.Filename, adminID)
 
-	// 删除旧Logo文件
-	if product.Logo != "" {
-		utils.DeleteFile(product.Logo)
+	// 释放旧Logo文件
+	if product.Logo != "" && product.Logo != uploadResp.URL {
+		pc.assetService.ReleaseFile(product.Logo)
 	}
 
 	// 更新产品Logo字段
 	product.Logo = uploadResp.URL
-	if err := pc.productService.Update(product); err != nil {
-		utils.InternalServerError(c, "更新产品Logo失败")
+	if err := pc.productService.Update(product, adminID); err != nil {
+		respondProductError(c, err, "更新产品Logo失败")
 		return
 	}
 
@@ -314,27 +403,149 @@ func (pc *ProductController) UploadImages(c *gin.Context) {
 		utils.BadRequest(c, "上传失败: "+err.Error())
 		return
 	}
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	for i, resp := range uploadResponses {
+		pc.assetService.Register(resp, files[i].Filename, adminID)
+	}
 
-	// 获取现有图片列表
-	existingImages := product.GetImages()
-
-	// 添加新图片URL
+	// 追加到产品图片末尾，并记录图片尺寸
+	images := make([]models.ProductImage, 0, len(uploadResponses))
 	for _, resp := range uploadResponses {
-		existingImages = append(existingImages, resp.URL)
+		images = append(images, models.ProductImage{
+			URL:    resp.URL,
+			Width:  resp.Width,
+			Height: resp.Height,
+		})
 	}
 
-	// 更新产品图片字段
-	if err := product.SetImages(existingImages); err != nil {
-		utils.InternalServerError(c, "更新产品图片失败")
+	if _, err := pc.productService.AddImages(product.ID, images, adminID); err != nil {
+		respondProductError(c, err, "更新产品图片失败")
 		return
 	}
 
-	if err := pc.productService.Update(product); err != nil {
-		utils.InternalServerError(c, "更新产品图片失败")
+	utils.Success(c, uploadResponses)
+}
+
+// ListImages 获取产品图片列表
+func (pc *ProductController) ListImages(c *gin.Context) {
+	var req types.IDRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		utils.ValidateError(c, err)
 		return
 	}
 
-	utils.Success(c, uploadResponses)
+	images, err := pc.productService.ListImages(req.ID)
+	if err != nil {
+		respondProductError(c, err, "获取产品图片失败")
+		return
+	}
+
+	utils.Success(c, images)
+}
+
+// UpdateImage 更新产品图片的替代文本或排序
+func (pc *ProductController) UpdateImage(c *gin.Context) {
+	var uriReq ProductImageURI
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var req ProductImageUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	image, err := pc.productService.UpdateImage(uriReq.ID, uriReq.ImageID, req.Alt, req.Sort, adminID)
+	if err != nil {
+		respondProductError(c, err, "更新产品图片失败")
+		return
+	}
+
+	utils.Updated(c, image)
+}
+
+// DeleteImage 删除产品图片
+func (pc *ProductController) DeleteImage(c *gin.Context) {
+	var uriReq ProductImageURI
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	if err := pc.productService.DeleteImage(uriReq.ID, uriReq.ImageID, adminID); err != nil {
+		respondProductError(c, err, "删除产品图片失败")
+		return
+	}
+
+	utils.Deleted(c)
+}
+
+// ReorderImages 按给定ID顺序重新排列产品图片
+func (pc *ProductController) ReorderImages(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var req ProductImageOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	images, err := pc.productService.ReorderImages(uriReq.ID, req.IDs, adminID)
+	if err != nil {
+		respondProductError(c, err, "调整图片顺序失败")
+		return
+	}
+
+	utils.Success(c, images)
+}
+
+// ParseAppManifest 离线解析上传的 APK/IPA 清单并写入产品应用信息（安装包本身不保存）
+func (pc *ProductController) ParseAppManifest(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.BadRequest(c, "请选择要解析的安装包文件")
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		utils.InternalServerError(c, "读取安装包失败")
+		return
+	}
+	defer src.Close()
+
+	manifest, err := utils.ParseAppPackage(src, file.Size)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	product, err := pc.productService.ApplyAppManifest(uriReq.ID, manifest, adminID)
+	if err != nil {
+		respondProductError(c, err, "更新产品应用信息失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"manifest": manifest,
+		"product":  product,
+	})
 }
 
 // GetStatistics 获取产品统计
@@ -363,6 +574,8 @@ func (pc *ProductController) UploadFile(c *gin.Context) {
 		utils.BadRequest(c, "上传失败: "+err.Error())
 		return
 	}
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	pc.assetService.Register(uploadResp, file.Filename, adminID)
 
 	utils.Success(c, uploadResp)
 }
@@ -388,6 +601,10 @@ func (pc *ProductController) UploadFiles(c *gin.Context) {
 		utils.BadRequest(c, "上传失败: "+err.Error())
 		return
 	}
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	for i, resp := range uploadResponses {
+		pc.assetService.Register(resp, files[i].Filename, adminID)
+	}
 
 	utils.Success(c, uploadResponses)
 }
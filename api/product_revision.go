@@ -0,0 +1,111 @@
+package api
+
+import (
+	"backend/middleware"
+	"backend/types"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProductRevisionURI 产品版本路径参数
+type ProductRevisionURI struct {
+	ID      uint `uri:"id" binding:"required,min=1"`
+	Version int  `uri:"version" binding:"required,min=1"`
+}
+
+// ProductRevisionDiffQuery 版本对比参数，to 为空时与当前内容对比，from 为空时取 to 的上一版本
+type ProductRevisionDiffQuery struct {
+	From int `form:"from" binding:"omitempty,min=1"`
+	To   int `form:"to" binding:"omitempty,min=1"`
+}
+
+// respondRevisionError 统一处理产品版本相关错误
+func respondRevisionError(c *gin.Context, err error, message string) {
+	if err.Error() == "版本不存在" {
+		utils.NotFound(c, err.Error())
+		return
+	}
+	respondProductError(c, err, message)
+}
+
+// ListRevisions 获取产品版本列表
+func (pc *ProductController) ListRevisions(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var req types.PageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	revisions, total, err := pc.revisionService.List(uriReq.ID, &req)
+	if err != nil {
+		respondRevisionError(c, err, "获取产品版本失败")
+		return
+	}
+
+	utils.PagedSuccess(c, revisions, total, req.GetPage(), req.GetSize())
+}
+
+// GetRevision 获取产品指定版本的快照
+func (pc *ProductController) GetRevision(c *gin.Context) {
+	var uriReq ProductRevisionURI
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	revision, err := pc.revisionService.Get(uriReq.ID, uriReq.Version)
+	if err != nil {
+		respondRevisionError(c, err, "获取产品版本失败")
+		return
+	}
+
+	utils.Success(c, revision)
+}
+
+// DiffRevisions 对比产品的两个版本
+func (pc *ProductController) DiffRevisions(c *gin.Context) {
+	var uriReq types.IDRequest
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	var query ProductRevisionDiffQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	diff, err := pc.revisionService.Diff(uriReq.ID, query.From, query.To)
+	if err != nil {
+		respondRevisionError(c, err, "对比产品版本失败")
+		return
+	}
+
+	utils.Success(c, diff)
+}
+
+// RestoreRevision 将产品恢复到指定版本
+func (pc *ProductController) RestoreRevision(c *gin.Context) {
+	var uriReq ProductRevisionURI
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	product, err := pc.productService.Restore(uriReq.ID, uriReq.Version, adminID)
+	if err != nil {
+		respondRevisionError(c, err, "恢复产品版本失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "产品已恢复", product)
+}
@@ -1,21 +1,29 @@
 package api
 
 import (
+	"net/http"
+	"os"
 	"time"
 
+	"backend/configs"
 	"backend/database"
+	"backend/pkg/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
 var startTime = time.Now()
 
+// uploadURLExpiry 上传文件跳转地址的有效期
+const uploadURLExpiry = 15 * time.Minute
+
 // SetupRoutes 设置路由
 // 注意: 此函数已废弃，所有业务路由已迁移到 router 包
 // 保留此文件仅为了健康检查和静态文件服务
 func SetupRoutes(r *gin.Engine) {
 	// 静态文件服务
-	r.Static("/uploads", "./uploads")
+	r.GET("/uploads/*filepath", serveUpload)
+	r.HEAD("/uploads/*filepath", serveUpload)
 	r.Static("/static", "./static")
 
 	// 健康检查
@@ -27,6 +35,38 @@ func SetupRoutes(r *gin.Engine) {
 	// 所有 API 路由已迁移到 router.SetupRouter()
 }
 
+// serveUpload 访问上传文件：本地存储直接返回文件，对象存储跳转到限时签名地址
+// 切换到对象存储前上传的文件仍保存在本地目录，优先从本地读取
+func serveUpload(c *gin.Context) {
+	key, err := storage.CleanKey(c.Param("filepath"))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	local := storage.NewLocalStorage(configs.AppConfig.Upload.Path)
+	if fullPath, err := local.Path(key); err == nil {
+		if stat, err := os.Stat(fullPath); err == nil && !stat.IsDir() {
+			c.File(fullPath)
+			return
+		}
+	}
+
+	s := storage.Default()
+	if s.Provider() == storage.ProviderLocal {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	signedURL, err := s.SignedURL(key, uploadURLExpiry)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Redirect(http.StatusFound, signedURL)
+}
+
 // healthCheck 简单健康检查
 func healthCheck(c *gin.Context) {
 	c.JSON(200, gin.H{
@@ -1,8 +1,13 @@
 package api
 
 import (
+	"fmt"
+	"log"
+
+	"backend/middleware"
 	"backend/models"
 	"backend/services"
+	"backend/types"
 	"backend/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -12,15 +17,26 @@ type SystemConfigRequest struct {
 	Configs map[string]string `json:"configs" binding:"required"`
 }
 
-// BackupRequest 备份请求结构
+// ConfigVersionURI 配置历史版本路径参数
+type ConfigVersionURI struct {
+	Key     string `uri:"key" binding:"required"`
+	Version int    `uri:"version" binding:"required,min=1"`
+}
+
+// BackupRequest 备份请求结构（tables 为空时备份全部表）
 type BackupRequest struct {
 	Description string `json:"description" binding:"max=500"`
 	Tables      []string `json:"tables"`
 }
 
-// RestoreRequest 恢复请求结构
+// RestoreRequest 恢复请求结构，恢复会覆盖涉及的表，须显式确认
 type RestoreRequest struct {
-	BackupFile string `json:"backup_file" binding:"required"`
+	Confirm bool `json:"confirm"`
+}
+
+// BackupURI 备份文件路径参数
+type BackupURI struct {
+	Filename string `uri:"filename" binding:"required"`
 }
 
 // SystemController 系统控制器
@@ -54,7 +70,12 @@ func (sc *SystemController) UpdateConfigs(c *gin.Context) {
 		return
 	}
 
-	if err := sc.systemService.UpdateConfigs(req.Configs); err != nil {
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	if err := sc.systemService.UpdateConfigs(req.Configs, adminID); err != nil {
+		if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
+			return
+		}
 		utils.InternalServerError(c, "更新系统配置失败")
 		return
 	}
@@ -100,7 +121,12 @@ func (sc *SystemController) UpdateConfig(c *gin.Context) {
 		return
 	}
 
-	if err := sc.systemService.UpdateConfig(key, req.Value, req.Description); err != nil {
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	if err := sc.systemService.UpdateConfig(key, req.Value, req.Description, adminID); err != nil {
+		if _, ok := err.(*services.ServiceError); ok {
+			utils.BadRequest(c, err.Error())
+			return
+		}
 		utils.InternalServerError(c, "更新配置失败")
 		return
 	}
@@ -108,6 +134,45 @@ func (sc *SystemController) UpdateConfig(c *gin.Context) {
 	utils.SuccessWithMessage(c, "配置更新成功", nil)
 }
 
+// GetConfigDefinitions 获取配置项定义（类型、默认值、校验规则、是否敏感）
+func (sc *SystemController) GetConfigDefinitions(c *gin.Context) {
+	utils.Success(c, sc.systemService.GetConfigDefinitions())
+}
+
+// GetConfigHistory 获取配置的变更历史
+func (sc *SystemController) GetConfigHistory(c *gin.Context) {
+	var req types.PageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	histories, total, err := sc.systemService.ListConfigHistory(c.Param("key"), &req)
+	if err != nil {
+		respondBackupError(c, err, "获取配置历史失败")
+		return
+	}
+
+	utils.PagedSuccess(c, histories, total, req.GetPage(), req.GetSize())
+}
+
+// RestoreConfigVersion 将配置恢复到指定历史版本
+func (sc *SystemController) RestoreConfigVersion(c *gin.Context) {
+	var uriReq ConfigVersionURI
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	if err := sc.systemService.RestoreConfigVersion(uriReq.Key, uriReq.Version, adminID); err != nil {
+		respondBackupError(c, err, "恢复配置失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "配置已恢复", nil)
+}
+
 // GetStats 获取系统统计
 func (sc *SystemController) GetStats(c *gin.Context) {
 	stats, err := sc.systemService.GetSystemStats()
@@ -130,6 +195,15 @@ func (sc *SystemController) GetDashboard(c *gin.Context) {
 	utils.Success(c, dashboard)
 }
 
+// respondBackupError 统一处理备份相关错误
+func respondBackupError(c *gin.Context, err error, message string) {
+	if serviceErr, ok := err.(*services.ServiceError); ok {
+		utils.Error(c, serviceErr.Code, serviceErr.Message)
+	} else {
+		utils.InternalServerError(c, message+": "+err.Error())
+	}
+}
+
 // Backup 系统备份
 func (sc *SystemController) Backup(c *gin.Context) {
 	var req BackupRequest
@@ -138,29 +212,41 @@ func (sc *SystemController) Backup(c *gin.Context) {
 		return
 	}
 
-	backupInfo, err := sc.systemService.CreateBackup(req.Description, req.Tables)
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	backupInfo, err := sc.systemService.CreateBackup(req.Description, req.Tables, adminID)
 	if err != nil {
-		utils.InternalServerError(c, "系统备份失败: "+err.Error())
+		respondBackupError(c, err, "系统备份失败")
 		return
 	}
 
 	utils.SuccessWithMessage(c, "系统备份成功", backupInfo)
 }
 
-// Restore 系统恢复
+// Restore 从备份恢复，恢复前自动为涉及的表创建安全备份
 func (sc *SystemController) Restore(c *gin.Context) {
+	var uriReq BackupURI
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
 	var req RestoreRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.ValidateError(c, err)
 		return
 	}
+	if !req.Confirm {
+		utils.BadRequest(c, "恢复会覆盖备份中各表的当前数据，请确认后重试（confirm=true）")
+		return
+	}
 
-	if err := sc.systemService.RestoreFromBackup(req.BackupFile); err != nil {
-		utils.InternalServerError(c, "系统恢复失败: "+err.Error())
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	result, err := sc.systemService.RestoreFromBackup(uriReq.Filename, adminID)
+	if err != nil {
+		respondBackupError(c, err, "系统恢复失败")
 		return
 	}
 
-	utils.SuccessWithMessage(c, "系统恢复成功", nil)
+	utils.SuccessWithMessage(c, "系统恢复成功", result)
 }
 
 // GetBackups 获取备份列表
@@ -174,16 +260,34 @@ func (sc *SystemController) GetBackups(c *gin.Context) {
 	utils.Success(c, backups)
 }
 
+// DownloadBackup 下载备份文件，响应头 X-Checksum-SHA256 为文件校验和
+func (sc *SystemController) DownloadBackup(c *gin.Context) {
+	var uriReq BackupURI
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
+		return
+	}
+
+	info, path, err := sc.systemService.GetBackup(uriReq.Filename)
+	if err != nil {
+		respondBackupError(c, err, "下载备份失败")
+		return
+	}
+
+	c.Header("X-Checksum-SHA256", info.Checksum)
+	c.FileAttachment(path, info.Filename)
+}
+
 // DeleteBackup 删除备份
 func (sc *SystemController) DeleteBackup(c *gin.Context) {
-	filename := c.Param("filename")
-	if filename == "" {
-		utils.BadRequest(c, "备份文件名不能为空")
+	var uriReq BackupURI
+	if err := c.ShouldBindUri(&uriReq); err != nil {
+		utils.ValidateError(c, err)
 		return
 	}
 
-	if err := sc.systemService.DeleteBackup(filename); err != nil {
-		utils.InternalServerError(c, "删除备份失败: "+err.Error())
+	if err := sc.systemService.DeleteBackup(uriReq.Filename); err != nil {
+		respondBackupError(c, err, "删除备份失败")
 		return
 	}
 
@@ -223,54 +327,54 @@ func (sc *SystemController) UpdateSystemInfo(c *gin.Context) {
 		models.ConfigKeyContactPhone:      req.ContactPhone,
 	}
 
-	if err := sc.systemService.UpdateConfigs(configs); err != nil {
-		utils.InternalServerError(c, "更新系统信息失败")
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	if err := sc.systemService.UpdateConfigs(configs, adminID); err != nil {
+		respondBackupError(c, err, "更新系统信息失败")
 		return
 	}
 
 	utils.SuccessWithMessage(c, "系统信息更新成功", nil)
 }
 
-// GetMaintenanceMode 获取维护模式状态
+// GetMaintenanceMode 获取维护模式状态及配置
 func (sc *SystemController) GetMaintenanceMode(c *gin.Context) {
-	isEnabled, err := sc.systemService.IsMaintenanceModeEnabled()
+	status, err := sc.systemService.GetMaintenanceStatus()
 	if err != nil {
 		utils.InternalServerError(c, "获取维护模式状态失败")
 		return
 	}
 
 	utils.Success(c, map[string]interface{}{
-		"maintenance_mode": isEnabled,
+		"maintenance_mode": status.Active,
+		"status":           status,
 	})
 }
 
-// SetMaintenanceMode 设置维护模式
+// SetMaintenanceMode 设置维护模式（开关、提示、重试时间、白名单与计划时段，未传的字段保持不变）
 func (sc *SystemController) SetMaintenanceMode(c *gin.Context) {
-	var req struct {
-		Enabled bool `json:"enabled"`
-	}
+	var req services.MaintenanceSettings
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.ValidateError(c, err)
 		return
 	}
 
-	value := "false"
-	if req.Enabled {
-		value = "true"
-	}
-
-	if err := sc.systemService.UpdateConfig(models.ConfigKeyMaintenanceMode, value, "维护模式开关"); err != nil {
-		utils.InternalServerError(c, "设置维护模式失败")
+	adminID, _, _, _ := middleware.GetCurrentAdmin(c)
+	status, err := sc.systemService.UpdateMaintenance(&req, adminID)
+	if err != nil {
+		respondBackupError(c, err, "设置维护模式失败")
 		return
 	}
 
-	message := "维护模式已关闭"
-	if req.Enabled {
+	message := "维护模式设置已更新"
+	if req.Enabled != nil && *req.Enabled {
 		message = "维护模式已开启"
+	} else if req.Enabled != nil {
+		message = "维护模式已关闭"
 	}
 
 	utils.SuccessWithMessage(c, message, map[string]interface{}{
-		"maintenance_mode": req.Enabled,
+		"maintenance_mode": status.Active,
+		"status":           status,
 	})
 }
 
@@ -342,11 +446,22 @@ func (sc *SystemController) ResetSystem(c *gin.Context) {
 	utils.SuccessWithMessage(c, "系统重置成功", nil)
 }
 
-// ExportData 导出数据
+// GetDataTables 可导出的数据表及其结构版本
+func (sc *SystemController) GetDataTables(c *gin.Context) {
+	tables, err := sc.systemService.GetDataTables()
+	if err != nil {
+		utils.InternalServerError(c, "获取数据表失败")
+		return
+	}
+
+	utils.Success(c, tables)
+}
+
+// ExportData 导出数据表（jsonl 为单个文件，csv 为每表一个文件的 zip 压缩包），tables 为空时导出全部表
 func (sc *SystemController) ExportData(c *gin.Context) {
 	var req struct {
 		Tables []string `json:"tables"`
-		Format string   `json:"format" binding:"oneof=json csv sql"`
+		Format string   `json:"format" binding:"omitempty,oneof=jsonl csv"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.ValidateError(c, err)
@@ -354,35 +469,57 @@ func (sc *SystemController) ExportData(c *gin.Context) {
 	}
 
 	if req.Format == "" {
-		req.Format = "json"
+		req.Format = services.DataFormatJSONL
 	}
 
-	exportInfo, err := sc.systemService.ExportData(req.Tables, req.Format)
+	manifest, err := sc.systemService.PrepareDataExport(req.Tables, req.Format)
 	if err != nil {
-		utils.InternalServerError(c, "数据导出失败: "+err.Error())
+		respondBackupError(c, err, "数据导出失败")
 		return
 	}
 
-	utils.SuccessWithMessage(c, "数据导出成功", exportInfo)
+	contentType := "application/x-ndjson"
+	if req.Format == services.DataFormatCSV {
+		contentType = "application/zip"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", services.DataExportFileName(manifest, req.Format)))
+	c.Header("Content-Type", contentType)
+	c.Status(200)
+	if err := sc.systemService.ExportData(manifest, req.Format, c.Writer); err != nil {
+		// 响应头已发出，只能记录错误并中断输出
+		log.Printf("Data export failed: %v", err)
+		c.Abort()
+	}
 }
 
-// ImportData 导入数据
+// ImportData 导入 ExportData 生成的文件；mode 为主键或唯一键冲突时的处理方式（skip/override/fail，默认 skip）
 func (sc *SystemController) ImportData(c *gin.Context) {
 	var req struct {
-		FilePath string `json:"file_path" binding:"required"`
-		Format   string `json:"format" binding:"oneof=json csv sql"`
-		Override bool   `json:"override"`
+		Format string `form:"format" binding:"omitempty,oneof=jsonl csv"`
+		Mode   string `form:"mode" binding:"omitempty,oneof=skip override fail"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBind(&req); err != nil {
 		utils.ValidateError(c, err)
 		return
 	}
 
-	result, err := sc.systemService.ImportData(req.FilePath, req.Format, req.Override)
+	fileHeader, err := c.FormFile("file")
 	if err != nil {
-		utils.InternalServerError(c, "数据导入失败: "+err.Error())
+		utils.BadRequest(c, "请选择要导入的文件")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.InternalServerError(c, "读取导入文件失败")
+		return
+	}
+	defer file.Close()
+
+	result, err := sc.systemService.ImportData(file, fileHeader.Size, fileHeader.Filename, req.Format, req.Mode)
+	if err != nil {
+		respondBackupError(c, err, "数据导入失败")
 		return
 	}
 
-	utils.SuccessWithMessage(c, "数据导入成功", result)
-}
\ No newline at end of file
+	utils.SuccessWithMessage(c, "数据导入完成", result)
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	_ "time/tzdata" // 计划排期按时区计算，容器内可能没有系统时区数据
 
 	"backend/api"
 	"backend/configs"
@@ -13,6 +14,7 @@ import (
 	"backend/middleware"
 	"backend/models"
 	"backend/router"
+	"backend/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,6 +33,12 @@ func main() {
 	models.AutoMigrate()
 	models.CreateIndexes()
 	models.SeedDefaultData()
+	services.EncryptPlaintextSecrets()
+
+	// 按系统配置初始化上传文件存储、上传限制与维护模式
+	services.LoadStorage()
+	services.LoadUploadPolicy()
+	services.LoadMaintenance()
 
 	// 设置Gin模式
 	if configs.AppConfig.Server.Env == "production" {
@@ -40,6 +48,11 @@ func main() {
 	// 创建Gin引擎
 	r := gin.New()
 
+	// 只采信可信代理转发的客户端 IP，避免伪造 X-Forwarded-For 绕过 IP 白名单与限流
+	if err := r.SetTrustedProxies(configs.AppConfig.Server.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// 添加中间件
 	setupMiddleware(r)
 
@@ -47,6 +60,24 @@ func main() {
 	api.SetupRoutes(r)                 // 健康检查和静态文件路由
 	router.SetupRouter(r, database.DB) // 业务路由
 
+	// 启动计划排期调度
+	services.NewCampaignScheduler().Start()
+
+	// 启动计划预算监控
+	services.NewCampaignBudgetService().StartMonitor()
+
+	// 启动过期视频上传会话清理
+	services.NewCampaignVideoUploadService().StartCleanup()
+
+	// 启动无引用素材清理
+	services.NewAssetService().StartCleanup()
+
+	// 启动维护模式配置同步
+	services.StartMaintenanceSync()
+
+	// 启动系统配置缓存同步
+	services.StartConfigSync()
+
 	// 启动服务器
 	addr := fmt.Sprintf("%s:%d", configs.AppConfig.Server.Host, configs.AppConfig.Server.Port)
 	log.Printf("Server starting on %s", addr)
@@ -64,6 +95,9 @@ func main() {
 	<-quit
 	log.Println("Shutting down server...")
 
+	// 写入缓冲中的广告事件
+	services.FlushCampaignEvents()
+
 	// 关闭数据库连接
 	if err := database.CloseDB(); err != nil {
 		log.Printf("Error closing database: %v", err)
@@ -0,0 +1,37 @@
+// s3standin 本地 S3 兼容服务，用于在没有 MinIO/S3 的环境下联调对象存储
+//
+// 使用方式：
+//
+//	S3_STANDIN_ACCESS_KEY=dev S3_STANDIN_SECRET_KEY=devsecret go run ./cmd/s3standin
+//
+// 然后将系统配置 storage_provider=s3、storage_endpoint=http://127.0.0.1:9000、
+// storage_bucket=任意名称、storage_access_key/storage_secret_key 设置为上面的值
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"backend/pkg/storage"
+)
+
+func main() {
+	addr := getEnv("S3_STANDIN_ADDR", "127.0.0.1:9000")
+	root := getEnv("S3_STANDIN_ROOT", "s3data")
+	accessKey := getEnv("S3_STANDIN_ACCESS_KEY", "minioadmin")
+	secretKey := getEnv("S3_STANDIN_SECRET_KEY", "minioadmin")
+	region := getEnv("S3_STANDIN_REGION", "us-east-1")
+
+	log.Printf("S3 stand-in listening on %s, data dir %s", addr, root)
+	if err := http.ListenAndServe(addr, storage.NewS3StandIn(root, accessKey, secretKey, region)); err != nil {
+		log.Fatalf("S3 stand-in failed: %v", err)
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
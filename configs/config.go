@@ -15,6 +15,9 @@ type Config struct {
 	JWT      JWTConfig
 	Server   ServerConfig
 	Upload   UploadConfig
+	Export   ExportConfig
+	Backup   BackupConfig
+	Security SecurityConfig
 	CORS     CORSConfig
 }
 
@@ -39,14 +42,33 @@ type JWTConfig struct {
 }
 
 type ServerConfig struct {
-	Host string
-	Port int
-	Env  string
+	Host           string
+	Port           int
+	Env            string
+	TrustedProxies []string // 可信反向代理（IP 或 CIDR），只采信其转发的 X-Forwarded-For；为空时以连接地址作为客户端 IP
 }
 
 type UploadConfig struct {
-	Path        string
-	MaxFileSize int64
+	Path             string
+	TempPath         string // 断点续传未完成文件的暂存目录，不对外静态暴露
+	MaxFileSize      int64
+	OrphanGraceHours int // 素材不再被引用后保留的小时数，超过后由清理任务删除
+}
+
+type ExportConfig struct {
+	Path           string
+	RetentionHours int
+}
+
+type BackupConfig struct {
+	Path            string
+	KeepCount       int // 手动备份最多保留的份数，0 表示不限
+	SafetyKeepCount int // 恢复前自动备份最多保留的份数，0 表示不限
+	RetentionDays   int // 超过该天数的备份会被清理（每类至少保留最新一份），0 表示不限
+}
+
+type SecurityConfig struct {
+	ConfigSecretKey string // 敏感系统配置的加密密钥，为空时使用 JWT 密钥
 }
 
 type CORSConfig struct {
@@ -82,17 +104,33 @@ func LoadConfig() {
 			ExpireHours: getEnvAsInt("JWT_EXPIRE_HOURS", 24),
 		},
 		Server: ServerConfig{
-			Host: getEnv("SERVER_HOST", "0.0.0.0"),
-			Port: getEnvAsInt("SERVER_PORT", 8080),
-			Env:  getEnv("ENV", "development"),
+			Host:           getEnv("SERVER_HOST", "0.0.0.0"),
+			Port:           getEnvAsInt("SERVER_PORT", 8080),
+			Env:            getEnv("ENV", "development"),
+			TrustedProxies: getEnvAsList("TRUSTED_PROXIES"),
 		},
 		Upload: UploadConfig{
-			Path:        getEnv("UPLOAD_PATH", "uploads/"),
-			MaxFileSize: int64(getEnvAsInt("MAX_UPLOAD_SIZE", 10485760)), // 10MB
+			Path:             getEnv("UPLOAD_PATH", "uploads/"),
+			TempPath:         getEnv("UPLOAD_TEMP_PATH", "uploads_tmp/"),
+			MaxFileSize:      int64(getEnvAsInt("MAX_UPLOAD_SIZE", 10485760)), // 10MB
+			OrphanGraceHours: getEnvAsInt("ASSET_ORPHAN_GRACE_HOURS", 72),
+		},
+		Export: ExportConfig{
+			Path:           getEnv("EXPORT_PATH", "exports/"),
+			RetentionHours: getEnvAsInt("EXPORT_RETENTION_HOURS", 72),
+		},
+		Backup: BackupConfig{
+			Path:            getEnv("BACKUP_PATH", "backups/"),
+			KeepCount:       getEnvAsInt("BACKUP_KEEP_COUNT", 10),
+			SafetyKeepCount: getEnvAsInt("BACKUP_SAFETY_KEEP_COUNT", 5),
+			RetentionDays:   getEnvAsInt("BACKUP_RETENTION_DAYS", 30),
+		},
+		Security: SecurityConfig{
+			ConfigSecretKey: getEnv("CONFIG_SECRET_KEY", ""),
 		},
 		CORS: CORSConfig{
 			AllowedOrigins: strings.Split(getEnv("CORS_ALLOWED_ORIGINS", "*"), ","),
-			AllowedMethods: strings.Split(getEnv("CORS_ALLOWED_METHODS", "GET,POST,PUT,DELETE,OPTIONS"), ","),
+			AllowedMethods: strings.Split(getEnv("CORS_ALLOWED_METHODS", "GET,POST,PUT,PATCH,DELETE,HEAD,OPTIONS"), ","),
 			AllowedHeaders: strings.Split(getEnv("CORS_ALLOWED_HEADERS", "*"), ","),
 		},
 	}
@@ -111,4 +149,15 @@ func getEnvAsInt(key string, defaultValue int) int {
 		return value
 	}
 	return defaultValue
+}
+
+// getEnvAsList 读取逗号分隔的列表，忽略空项
+func getEnvAsList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(getEnv(key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
\ No newline at end of file
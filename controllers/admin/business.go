@@ -73,6 +73,61 @@ func (h *ProductHandler) GetStatistics(c *gin.Context) {
 	h.controller.GetStatistics(c)
 }
 
+// GetTrackingKey 获取事件上报密钥
+func (h *ProductHandler) GetTrackingKey(c *gin.Context) {
+	h.controller.GetTrackingKey(c)
+}
+
+// RotateTrackingKey 重新生成事件上报密钥
+func (h *ProductHandler) RotateTrackingKey(c *gin.Context) {
+	h.controller.RotateTrackingKey(c)
+}
+
+// ListImages 产品图片列表
+func (h *ProductHandler) ListImages(c *gin.Context) {
+	h.controller.ListImages(c)
+}
+
+// UpdateImage 更新产品图片
+func (h *ProductHandler) UpdateImage(c *gin.Context) {
+	h.controller.UpdateImage(c)
+}
+
+// DeleteImage 删除产品图片
+func (h *ProductHandler) DeleteImage(c *gin.Context) {
+	h.controller.DeleteImage(c)
+}
+
+// ReorderImages 调整产品图片顺序
+func (h *ProductHandler) ReorderImages(c *gin.Context) {
+	h.controller.ReorderImages(c)
+}
+
+// ParseAppManifest 解析安装包清单
+func (h *ProductHandler) ParseAppManifest(c *gin.Context) {
+	h.controller.ParseAppManifest(c)
+}
+
+// ListRevisions 产品版本列表
+func (h *ProductHandler) ListRevisions(c *gin.Context) {
+	h.controller.ListRevisions(c)
+}
+
+// GetRevision 产品版本详情
+func (h *ProductHandler) GetRevision(c *gin.Context) {
+	h.controller.GetRevision(c)
+}
+
+// DiffRevisions 对比产品版本
+func (h *ProductHandler) DiffRevisions(c *gin.Context) {
+	h.controller.DiffRevisions(c)
+}
+
+// RestoreRevision 恢复产品版本
+func (h *ProductHandler) RestoreRevision(c *gin.Context) {
+	h.controller.RestoreRevision(c)
+}
+
 // CampaignHandler 广告计划管理（包装旧的CampaignController）
 type CampaignHandler struct {
 	controller *api.CampaignController
@@ -120,6 +175,36 @@ func (h *CampaignHandler) UploadVideo(c *gin.Context) {
 	h.controller.UploadVideo(c)
 }
 
+// VideoUploadOptions 断点续传能力
+func (h *CampaignHandler) VideoUploadOptions(c *gin.Context) {
+	h.controller.VideoUploadOptions(c)
+}
+
+// CreateVideoUpload 创建断点续传
+func (h *CampaignHandler) CreateVideoUpload(c *gin.Context) {
+	h.controller.CreateVideoUpload(c)
+}
+
+// HeadVideoUpload 断点续传进度
+func (h *CampaignHandler) HeadVideoUpload(c *gin.Context) {
+	h.controller.HeadVideoUpload(c)
+}
+
+// GetVideoUpload 断点续传详情
+func (h *CampaignHandler) GetVideoUpload(c *gin.Context) {
+	h.controller.GetVideoUpload(c)
+}
+
+// PatchVideoUpload 续传数据
+func (h *CampaignHandler) PatchVideoUpload(c *gin.Context) {
+	h.controller.PatchVideoUpload(c)
+}
+
+// DeleteVideoUpload 取消断点续传
+func (h *CampaignHandler) DeleteVideoUpload(c *gin.Context) {
+	h.controller.DeleteVideoUpload(c)
+}
+
 // GetStatistics 获取统计
 func (h *CampaignHandler) GetStatistics(c *gin.Context) {
 	h.controller.GetStatistics(c)
@@ -140,11 +225,148 @@ func (h *CampaignHandler) Resume(c *gin.Context) {
 	h.controller.Resume(c)
 }
 
+// GetStatusLogs 状态变更记录
+func (h *CampaignHandler) GetStatusLogs(c *gin.Context) {
+	h.controller.GetStatusLogs(c)
+}
+
+// EvaluateTargeting 评估定向
+func (h *CampaignHandler) EvaluateTargeting(c *gin.Context) {
+	h.controller.EvaluateTargeting(c)
+}
+
+// GetBudget 预算状态
+func (h *CampaignHandler) GetBudget(c *gin.Context) {
+	h.controller.GetBudget(c)
+}
+
+// GetBudgetAlerts 预算提醒列表
+func (h *CampaignHandler) GetBudgetAlerts(c *gin.Context) {
+	h.controller.GetBudgetAlerts(c)
+}
+
+// ListCreatives 创意列表
+func (h *CampaignHandler) ListCreatives(c *gin.Context) {
+	h.controller.ListCreatives(c)
+}
+
+// CreateCreative 创建创意
+func (h *CampaignHandler) CreateCreative(c *gin.Context) {
+	h.controller.CreateCreative(c)
+}
+
+// UpdateCreative 更新创意
+func (h *CampaignHandler) UpdateCreative(c *gin.Context) {
+	h.controller.UpdateCreative(c)
+}
+
+// DeleteCreative 删除创意
+func (h *CampaignHandler) DeleteCreative(c *gin.Context) {
+	h.controller.DeleteCreative(c)
+}
+
+// CompareCreatives 创意对比
+func (h *CampaignHandler) CompareCreatives(c *gin.Context) {
+	h.controller.CompareCreatives(c)
+}
+
+// PromoteCreative 采用创意
+func (h *CampaignHandler) PromoteCreative(c *gin.Context) {
+	h.controller.PromoteCreative(c)
+}
+
+// Clone 复制计划
+func (h *CampaignHandler) Clone(c *gin.Context) {
+	h.controller.Clone(c)
+}
+
+// SubmitReview 提交审核
+func (h *CampaignHandler) SubmitReview(c *gin.Context) {
+	h.controller.SubmitReview(c)
+}
+
+// WithdrawReview 撤回审核
+func (h *CampaignHandler) WithdrawReview(c *gin.Context) {
+	h.controller.WithdrawReview(c)
+}
+
+// ApproveReview 审核通过
+func (h *CampaignHandler) ApproveReview(c *gin.Context) {
+	h.controller.ApproveReview(c)
+}
+
+// RejectReview 驳回审核
+func (h *CampaignHandler) RejectReview(c *gin.Context) {
+	h.controller.RejectReview(c)
+}
+
+// GetReviewDiff 审核变更对比
+func (h *CampaignHandler) GetReviewDiff(c *gin.Context) {
+	h.controller.GetReviewDiff(c)
+}
+
+// GetReviews 审核记录
+func (h *CampaignHandler) GetReviews(c *gin.Context) {
+	h.controller.GetReviews(c)
+}
+
+// GetPendingReviews 待审核计划
+func (h *CampaignHandler) GetPendingReviews(c *gin.Context) {
+	h.controller.GetPendingReviews(c)
+}
+
+// GetReviewers 可分配审核人
+func (h *CampaignHandler) GetReviewers(c *gin.Context) {
+	h.controller.GetReviewers(c)
+}
+
 // UploadFile 通用文件上传
 func (h *CampaignHandler) UploadFile(c *gin.Context) {
 	h.controller.UploadFile(c)
 }
 
+// CampaignTemplateHandler 计划模板管理
+type CampaignTemplateHandler struct {
+	controller *api.CampaignTemplateController
+}
+
+// NewCampaignTemplateHandler 创建计划模板管理handler
+func NewCampaignTemplateHandler() *CampaignTemplateHandler {
+	return &CampaignTemplateHandler{
+		controller: api.NewCampaignTemplateController(),
+	}
+}
+
+// List 模板列表
+func (h *CampaignTemplateHandler) List(c *gin.Context) {
+	h.controller.List(c)
+}
+
+// GetByID 模板详情
+func (h *CampaignTemplateHandler) GetByID(c *gin.Context) {
+	h.controller.GetByID(c)
+}
+
+// Create 将计划保存为模板
+func (h *CampaignTemplateHandler) Create(c *gin.Context) {
+	h.controller.Create(c)
+}
+
+// Update 更新模板
+func (h *CampaignTemplateHandler) Update(c *gin.Context) {
+	h.controller.Update(c)
+}
+
+// Delete 删除模板
+func (h *CampaignTemplateHandler) Delete(c *gin.Context) {
+	h.controller.Delete(c)
+}
+
+// Instantiate 按模板创建计划
+func (h *CampaignTemplateHandler) Instantiate(c *gin.Context) {
+	h.controller.Instantiate(c)
+}
+
 // CustomerHandler 客户管理（包装旧的CustomerController）
 type CustomerHandler struct {
 	controller *api.CustomerController
@@ -222,6 +444,11 @@ func (h *CustomerHandler) Export(c *gin.Context) {
 	h.controller.Export(c)
 }
 
+// Import 批量导入
+func (h *CustomerHandler) Import(c *gin.Context) {
+	h.controller.Import(c)
+}
+
 // BatchUpdateStatus 批量更新状态
 func (h *CustomerHandler) BatchUpdateStatus(c *gin.Context) {
 	h.controller.BatchUpdateStatus(c)
@@ -274,6 +501,36 @@ func (h *CouponHandler) GetStatistics(c *gin.Context) {
 	h.controller.GetStatistics(c)
 }
 
+// ListPromoCodes 兑换码列表
+func (h *CouponHandler) ListPromoCodes(c *gin.Context) {
+	h.controller.ListPromoCodes(c)
+}
+
+// CreatePromoCode 创建兑换码
+func (h *CouponHandler) CreatePromoCode(c *gin.Context) {
+	h.controller.CreatePromoCode(c)
+}
+
+// GeneratePromoCodes 批量生成兑换码
+func (h *CouponHandler) GeneratePromoCodes(c *gin.Context) {
+	h.controller.GeneratePromoCodes(c)
+}
+
+// UpdatePromoCodeStatus 更新兑换码状态
+func (h *CouponHandler) UpdatePromoCodeStatus(c *gin.Context) {
+	h.controller.UpdatePromoCodeStatus(c)
+}
+
+// ExportPromoCodes 导出兑换码
+func (h *CouponHandler) ExportPromoCodes(c *gin.Context) {
+	h.controller.ExportPromoCodes(c)
+}
+
+// GetPromoCodeRedemptions 兑换记录
+func (h *CouponHandler) GetPromoCodeRedemptions(c *gin.Context) {
+	h.controller.GetPromoCodeRedemptions(c)
+}
+
 // AuthCodeHandler 授权码管理（包装旧的AuthCodeController）
 type AuthCodeHandler struct {
 	controller *api.AuthCodeController
@@ -346,6 +603,21 @@ func (h *AuthCodeHandler) GetCodeByCode(c *gin.Context) {
 	h.controller.GetCodeByCode(c)
 }
 
+// ListBatches 授权码批次列表
+func (h *AuthCodeHandler) ListBatches(c *gin.Context) {
+	h.controller.ListBatches(c)
+}
+
+// GetBatch 授权码批次详情
+func (h *AuthCodeHandler) GetBatch(c *gin.Context) {
+	h.controller.GetBatch(c)
+}
+
+// GetBatchCodes 批次下的授权码
+func (h *AuthCodeHandler) GetBatchCodes(c *gin.Context) {
+	h.controller.GetBatchCodes(c)
+}
+
 // FinanceHandler 财务管理（包装旧的FinanceController）
 type FinanceHandler struct {
 	controller *api.FinanceController
@@ -494,6 +766,21 @@ func (h *SystemHandler) UpdateConfig(c *gin.Context) {
 	h.controller.UpdateConfig(c)
 }
 
+// GetConfigDefinitions 获取配置项定义
+func (h *SystemHandler) GetConfigDefinitions(c *gin.Context) {
+	h.controller.GetConfigDefinitions(c)
+}
+
+// GetConfigHistory 获取配置变更历史
+func (h *SystemHandler) GetConfigHistory(c *gin.Context) {
+	h.controller.GetConfigHistory(c)
+}
+
+// RestoreConfigVersion 恢复配置历史版本
+func (h *SystemHandler) RestoreConfigVersion(c *gin.Context) {
+	h.controller.RestoreConfigVersion(c)
+}
+
 // GetStats 获取统计
 func (h *SystemHandler) GetStats(c *gin.Context) {
 	h.controller.GetStats(c)
@@ -528,3 +815,139 @@ func (h *SystemHandler) SetMaintenanceMode(c *gin.Context) {
 func (h *SystemHandler) GetHealth(c *gin.Context) {
 	h.controller.GetHealth(c)
 }
+
+// GetBackups 备份列表
+func (h *SystemHandler) GetBackups(c *gin.Context) {
+	h.controller.GetBackups(c)
+}
+
+// CreateBackup 创建备份
+func (h *SystemHandler) CreateBackup(c *gin.Context) {
+	h.controller.Backup(c)
+}
+
+// DownloadBackup 下载备份
+func (h *SystemHandler) DownloadBackup(c *gin.Context) {
+	h.controller.DownloadBackup(c)
+}
+
+// DeleteBackup 删除备份
+func (h *SystemHandler) DeleteBackup(c *gin.Context) {
+	h.controller.DeleteBackup(c)
+}
+
+// RestoreBackup 从备份恢复
+func (h *SystemHandler) RestoreBackup(c *gin.Context) {
+	h.controller.Restore(c)
+}
+
+// GetDataTables 可导出的数据表
+func (h *SystemHandler) GetDataTables(c *gin.Context) {
+	h.controller.GetDataTables(c)
+}
+
+// ExportData 导出数据
+func (h *SystemHandler) ExportData(c *gin.Context) {
+	h.controller.ExportData(c)
+}
+
+// ImportData 导入数据
+func (h *SystemHandler) ImportData(c *gin.Context) {
+	h.controller.ImportData(c)
+}
+
+// AssetHandler 素材库管理
+type AssetHandler struct {
+	controller *api.AssetController
+}
+
+// NewAssetHandler 创建素材库handler
+func NewAssetHandler() *AssetHandler {
+	return &AssetHandler{
+		controller: api.NewAssetController(),
+	}
+}
+
+// List 素材列表
+func (h *AssetHandler) List(c *gin.Context) {
+	h.controller.List(c)
+}
+
+// GetByID 素材详情
+func (h *AssetHandler) GetByID(c *gin.Context) {
+	h.controller.GetByID(c)
+}
+
+// Delete 删除素材
+func (h *AssetHandler) Delete(c *gin.Context) {
+	h.controller.Delete(c)
+}
+
+// Cleanup 清理无引用素材
+func (h *AssetHandler) Cleanup(c *gin.Context) {
+	h.controller.Cleanup(c)
+}
+
+// ExportHandler 导出任务管理
+type ExportHandler struct {
+	controller *api.ExportController
+}
+
+// NewExportHandler 创建导出任务handler
+func NewExportHandler() *ExportHandler {
+	return &ExportHandler{
+		controller: api.NewExportController(),
+	}
+}
+
+// GetColumns 获取可导出列
+func (h *ExportHandler) GetColumns(c *gin.Context) {
+	h.controller.GetColumns(c)
+}
+
+// ListJobs 导出任务列表
+func (h *ExportHandler) ListJobs(c *gin.Context) {
+	h.controller.ListJobs(c)
+}
+
+// GetJob 导出任务详情
+func (h *ExportHandler) GetJob(c *gin.Context) {
+	h.controller.GetJob(c)
+}
+
+// DownloadJob 下载导出文件
+func (h *ExportHandler) DownloadJob(c *gin.Context) {
+	h.controller.DownloadJob(c)
+}
+
+// ImportHandler 导入任务管理
+type ImportHandler struct {
+	controller *api.ImportController
+}
+
+// NewImportHandler 创建导入任务handler
+func NewImportHandler() *ImportHandler {
+	return &ImportHandler{
+		controller: api.NewImportController(),
+	}
+}
+
+// GetCustomerTemplate 下载客户导入模板
+func (h *ImportHandler) GetCustomerTemplate(c *gin.Context) {
+	h.controller.GetCustomerTemplate(c)
+}
+
+// ListJobs 导入任务列表
+func (h *ImportHandler) ListJobs(c *gin.Context) {
+	h.controller.ListJobs(c)
+}
+
+// GetJob 导入任务详情
+func (h *ImportHandler) GetJob(c *gin.Context) {
+	h.controller.GetJob(c)
+}
+
+// DownloadReport 下载错误报告
+func (h *ImportHandler) DownloadReport(c *gin.Context) {
+	h.controller.DownloadReport(c)
+}
@@ -129,6 +129,16 @@ func (h *CouponHandler) GetAvailableCoupons(c *gin.Context) {
 	h.controller.GetAvailableCoupons(c)
 }
 
+// GetBestCombination 最优优惠券组合
+func (h *CouponHandler) GetBestCombination(c *gin.Context) {
+	h.controller.GetBestCombination(c)
+}
+
+// RedeemPromoCode 兑换码兑换优惠券
+func (h *CouponHandler) RedeemPromoCode(c *gin.Context) {
+	h.controller.RedeemPromoCode(c)
+}
+
 // AuthCodeHandler 客户端授权码
 type AuthCodeHandler struct {
 	controller *api.AuthCodeController
@@ -145,3 +155,37 @@ func NewAuthCodeHandler() *AuthCodeHandler {
 func (h *AuthCodeHandler) Verify(c *gin.Context) {
 	h.controller.Verify(c)
 }
+
+// EventHandler 广告事件上报
+type EventHandler struct {
+	controller *api.CampaignEventController
+}
+
+// NewEventHandler 创建事件上报handler
+func NewEventHandler() *EventHandler {
+	return &EventHandler{
+		controller: api.NewCampaignEventController(),
+	}
+}
+
+// Track 批量上报事件
+func (h *EventHandler) Track(c *gin.Context) {
+	h.controller.Track(c)
+}
+
+// AdHandler 客户端广告
+type AdHandler struct {
+	controller *api.AdController
+}
+
+// NewAdHandler 创建广告handler
+func NewAdHandler() *AdHandler {
+	return &AdHandler{
+		controller: api.NewAdController(),
+	}
+}
+
+// List 获取广告
+func (h *AdHandler) List(c *gin.Context) {
+	h.controller.List(c)
+}
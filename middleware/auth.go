@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"strings"
 
 	"backend/utils"
@@ -26,10 +27,8 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		tokenString := authHeader[7:] // 去掉"Bearer "
-
 		// 解析token
-		claims, err := utils.ParseToken(tokenString)
+		claims, err := bearerClaims(c)
 		if err != nil {
 			// 返回详细的错误信息便于调试
 			utils.Unauthorized(c, "无效的认证信息: "+err.Error())
@@ -44,6 +43,15 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// bearerClaims 解析请求头中的Bearer token
+func bearerClaims(c *gin.Context) (*utils.JWTClaims, error) {
+	authHeader := c.GetHeader("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return nil, errors.New("认证格式错误")
+	}
+	return utils.ParseToken(authHeader[7:]) // 去掉"Bearer "
+}
+
 // RequireRole 角色权限中间件
 func RequireRole(requiredRole int) gin.HandlerFunc {
 	return func(c *gin.Context) {
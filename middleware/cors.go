@@ -6,12 +6,19 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// tusExposeHeaders 断点续传（tus）客户端需要读取的响应头
+var tusExposeHeaders = []string{
+	"Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size",
+	"Tus-Checksum-Algorithm", "Upload-Offset", "Upload-Length", "Upload-Expires",
+}
+
 // CORSMiddleware 跨域中间件
 func CORSMiddleware() gin.HandlerFunc {
 	config := cors.Config{
 		AllowOrigins:     configs.AppConfig.CORS.AllowedOrigins,
 		AllowMethods:     configs.AppConfig.CORS.AllowedMethods,
 		AllowHeaders:     configs.AppConfig.CORS.AllowedHeaders,
+		ExposeHeaders:    tusExposeHeaders,
 		AllowCredentials: true,
 	}
 
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 素材分类
+const (
+	AssetCategoryImage    = "image"
+	AssetCategoryVideo    = "video"
+	AssetCategoryDocument = "document"
+	AssetCategoryOther    = "other"
+)
+
+// 素材引用方类型
+const (
+	AssetOwnerProduct          = "product"           // 产品 logo、图片
+	AssetOwnerCampaign         = "campaign"          // 计划主图、视频及其创意
+	AssetOwnerCampaignTemplate = "campaign_template" // 计划模板及模板创意
+)
+
+// Asset 素材，记录每个上传的文件；内容相同（哈希一致）的文件只保存一份
+type Asset struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Hash       string     `json:"hash" gorm:"type:varchar(64);not null;index;comment:内容SHA-256"`
+	URL        string     `json:"url" gorm:"type:varchar(500);not null;uniqueIndex;comment:访问地址"`
+	Filename   string     `json:"filename" gorm:"type:varchar(255);comment:原始文件名"`
+	MimeType   string     `json:"mime_type" gorm:"type:varchar(100);comment:按内容检测的类型"`
+	Category   string     `json:"category" gorm:"type:varchar(20);not null;index;comment:分类(image/video/document/other)"`
+	Size       int64      `json:"size" gorm:"not null;default:0;comment:文件大小(字节)"`
+	Width      int        `json:"width" gorm:"not null;default:0"`
+	Height     int        `json:"height" gorm:"not null;default:0"`
+	Thumbnail  string     `json:"thumbnail" gorm:"type:varchar(500);comment:缩略图地址"`
+	WebP       string     `json:"webp" gorm:"column:webp;type:varchar(500);comment:WebP变体地址"`
+	OwnerID    uint       `json:"owner_id" gorm:"index;comment:上传管理员ID"`
+	RefCount   int        `json:"ref_count" gorm:"not null;default:0;comment:引用数"`
+	OrphanedAt *time.Time `json:"orphaned_at" gorm:"index;comment:引用数归零的时间"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	References []AssetReference `json:"references,omitempty" gorm:"foreignKey:AssetID"`
+}
+
+func (Asset) TableName() string {
+	return "assets"
+}
+
+// AssetReference 素材引用，按引用方整体重建
+type AssetReference struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	AssetID   uint      `json:"asset_id" gorm:"not null;uniqueIndex:idx_asset_owner_field"`
+	OwnerType string    `json:"owner_type" gorm:"type:varchar(30);not null;uniqueIndex:idx_asset_owner_field;index:idx_owner"`
+	OwnerID   uint      `json:"owner_id" gorm:"not null;uniqueIndex:idx_asset_owner_field;index:idx_owner"`
+	Field     string    `json:"field" gorm:"type:varchar(50);not null;uniqueIndex:idx_asset_owner_field;comment:引用字段，如 logo、images、creatives.3.video"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (AssetReference) TableName() string {
+	return "asset_references"
+}
+
+// assetDocumentExtensions 归为文档的扩展名（docx 等按内容检测为 zip，需结合扩展名判断）
+var assetDocumentExtensions = map[string]bool{
+	".pdf": true, ".doc": true, ".docx": true, ".xls": true, ".xlsx": true,
+	".ppt": true, ".pptx": true, ".csv": true, ".txt": true,
+}
+
+// AssetCategoryOf 按内容类型与文件名确定素材分类
+func AssetCategoryOf(mimeType, filename string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return AssetCategoryImage
+	case strings.HasPrefix(mimeType, "video/"):
+		return AssetCategoryVideo
+	case assetDocumentExtensions[strings.ToLower(filepath.Ext(filename))]:
+		return AssetCategoryDocument
+	}
+	return AssetCategoryOther
+}
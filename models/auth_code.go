@@ -3,6 +3,7 @@ package models
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -17,15 +18,22 @@ const (
 )
 
 type AuthCode struct {
-	ID        uint           `json:"id" gorm:"primaryKey;autoIncrement"`
-	Code      string         `json:"code" gorm:"type:varchar(64);uniqueIndex;not null"`
-	Status    AuthCodeStatus `json:"status" gorm:"type:tinyint;not null;default:1"`
-	UsedBy    *uint          `json:"used_by" gorm:"index"`           // 使用者用户ID
-	UsedAt    *time.Time     `json:"used_at"`                       // 使用时间
-	ExpiredAt time.Time      `json:"expired_at"`                    // 过期时间
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID              uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	Code            string         `json:"code" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Status          AuthCodeStatus `json:"status" gorm:"type:tinyint;not null;default:1"`
+	UsedBy          *uint          `json:"used_by" gorm:"index"`                                 // 使用者用户ID
+	UsedAt          *time.Time     `json:"used_at"`                                              // 使用时间
+	ExpiredAt       time.Time      `json:"expired_at"`                                           // 过期时间
+	BatchID         *uint          `json:"batch_id" gorm:"index"`                                // 所属批次
+	MaxUses         int            `json:"max_uses" gorm:"type:int;not null;default:1"`          // 最大兑换次数，0 表示不限
+	PerCustomerUses int            `json:"per_customer_uses" gorm:"type:int;not null;default:1"` // 每个客户可兑换次数
+	UsedCount       int            `json:"used_count" gorm:"type:int;not null;default:0"`        // 已兑换次数
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// 关联
+	Batch *AuthCodeBatch `json:"batch,omitempty" gorm:"foreignKey:BatchID"`
 }
 
 func (AuthCode) TableName() string {
@@ -42,6 +50,14 @@ func GenerateAuthCode() string {
 	return hex.EncodeToString(bytes)
 }
 
+// GeneratePrefixedAuthCode 生成带前缀的授权码，格式为 前缀-32位十六进制
+func GeneratePrefixedAuthCode(prefix string) string {
+	if prefix == "" {
+		return GenerateAuthCode()
+	}
+	return strings.ToUpper(prefix) + "-" + GenerateAuthCode()
+}
+
 // BeforeCreate 在创建前生成授权码
 func (ac *AuthCode) BeforeCreate(tx *gorm.DB) error {
 	if ac.Code == "" {
@@ -59,13 +75,18 @@ func (ac *AuthCode) IsUsable() bool {
 	if ac.Status != AuthCodeStatusUnused {
 		return false
 	}
-	
+
+	// 检查兑换次数
+	if ac.MaxUses > 0 && ac.UsedCount >= ac.MaxUses {
+		return false
+	}
+
 	// 检查是否过期
 	now := time.Now()
 	if now.After(ac.ExpiredAt) {
 		return false
 	}
-	
+
 	return true
 }
 
@@ -77,6 +98,22 @@ func (ac *AuthCode) Use(userID uint) {
 	ac.UsedAt = &now
 }
 
+// IsMultiUse 是否为多次兑换的授权码
+func (ac *AuthCode) IsMultiUse() bool {
+	return ac.MaxUses == 0 || ac.MaxUses > 1
+}
+
+// GetRemainingUses 获取剩余兑换次数，不限次数时返回 -1
+func (ac *AuthCode) GetRemainingUses() int {
+	if ac.MaxUses == 0 {
+		return -1
+	}
+	if ac.UsedCount >= ac.MaxUses {
+		return 0
+	}
+	return ac.MaxUses - ac.UsedCount
+}
+
 // Expire 使授权码过期
 func (ac *AuthCode) Expire() {
 	ac.Status = AuthCodeStatusExpired
@@ -88,4 +125,24 @@ func (ac *AuthCode) GetRemainingTime() time.Duration {
 		return 0
 	}
 	return ac.ExpiredAt.Sub(time.Now())
-}
\ No newline at end of file
+}
+
+// AuthCodeRedemption 授权码兑换记录
+type AuthCodeRedemption struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	AuthCodeID    uint      `json:"auth_code_id" gorm:"not null;index:idx_auth_code_customer"`
+	CustomerID    uint      `json:"customer_id" gorm:"not null;index:idx_auth_code_customer;index"`
+	BatchID       *uint     `json:"batch_id" gorm:"index"`
+	BalanceCredit float64   `json:"balance_credit" gorm:"type:decimal(15,2);default:0"` // 发放的余额
+	TransactionID *uint     `json:"transaction_id"`                                     // 奖励交易ID
+	ClientIP      string    `json:"client_ip" gorm:"type:varchar(64)"`
+	CreatedAt     time.Time `json:"created_at" gorm:"index"` // 兑换时间
+
+	// 关联
+	AuthCode *AuthCode `json:"auth_code,omitempty" gorm:"foreignKey:AuthCodeID"`
+	Customer *Customer `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
+}
+
+func (AuthCodeRedemption) TableName() string {
+	return "auth_code_redemptions"
+}
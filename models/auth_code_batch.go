@@ -0,0 +1,101 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuthCodeEntitlement 授权码权益（兑换时发放给用户）
+type AuthCodeEntitlement struct {
+	BalanceCredit   float64 `json:"balance_credit,omitempty"`   // 赠送余额
+	CouponIDs       []uint  `json:"coupon_ids,omitempty"`       // 发放的优惠券
+	ProductIDs      []uint  `json:"product_ids,omitempty"`      // 开通访问权限的产品
+	ActivateAccount bool    `json:"activate_account,omitempty"` // 激活客户账户
+}
+
+// Value 实现 driver.Valuer 接口
+func (e AuthCodeEntitlement) Value() (driver.Value, error) {
+	return json.Marshal(e)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (e *AuthCodeEntitlement) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return nil
+	}
+
+	return json.Unmarshal(bytes, e)
+}
+
+// IsEmpty 检查是否没有任何权益
+func (e *AuthCodeEntitlement) IsEmpty() bool {
+	return e == nil || (e.BalanceCredit <= 0 && len(e.CouponIDs) == 0 && len(e.ProductIDs) == 0 && !e.ActivateAccount)
+}
+
+// AuthCodeBatch 授权码批次
+type AuthCodeBatch struct {
+	ID              uint                 `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name            string               `json:"name" gorm:"type:varchar(255);not null"`
+	Prefix          string               `json:"prefix" gorm:"type:varchar(16);index"`                 // 授权码前缀
+	Count           int                  `json:"count" gorm:"type:int;not null;default:0"`             // 生成数量
+	ValidDays       int                  `json:"valid_days" gorm:"type:int;default:7"`                 // 有效天数
+	ExpiredAt       time.Time            `json:"expired_at"`                                           // 过期时间
+	CreatedBy       uint                 `json:"created_by" gorm:"index"`                              // 创建管理员ID
+	Notes           string               `json:"notes" gorm:"type:text"`                               // 备注
+	MaxUses         int                  `json:"max_uses" gorm:"type:int;not null;default:1"`          // 每个授权码最大兑换次数，0 表示不限
+	PerCustomerUses int                  `json:"per_customer_uses" gorm:"type:int;not null;default:1"` // 每个客户对同一授权码的兑换次数
+	Entitlement     *AuthCodeEntitlement `json:"entitlement" gorm:"type:json"`                         // 兑换权益
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt       `json:"-" gorm:"index"`
+
+	// 统计（非数据库字段）
+	UsedCount int64 `json:"used_count" gorm:"-"`
+
+	// 关联
+	AuthCodes []AuthCode `json:"auth_codes,omitempty" gorm:"foreignKey:BatchID"`
+}
+
+func (AuthCodeBatch) TableName() string {
+	return "auth_code_batches"
+}
+
+// CustomerProductAccess 客户产品访问权限
+type CustomerProductAccess struct {
+	ID         uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	CustomerID uint           `json:"customer_id" gorm:"not null;uniqueIndex:idx_customer_product"`
+	ProductID  uint           `json:"product_id" gorm:"not null;uniqueIndex:idx_customer_product"`
+	AuthCodeID *uint          `json:"auth_code_id" gorm:"index"` // 来源授权码
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// 关联
+	Product *Product `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+}
+
+func (CustomerProductAccess) TableName() string {
+	return "customer_product_accesses"
+}
+
+// AuthCodeGrant 授权码兑换发放结果
+type AuthCodeGrant struct {
+	BalanceCredit    float64       `json:"balance_credit"`
+	TransactionID    *uint         `json:"transaction_id,omitempty"`
+	UserCoupons      []*UserCoupon `json:"user_coupons"`
+	ProductIDs       []uint        `json:"product_ids"`
+	AccountActivated bool          `json:"account_activated"`
+}
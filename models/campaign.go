@@ -11,6 +11,13 @@ import (
 
 type CampaignStatus int
 
+// 计费方式
+const (
+	BillingTypeCPM = "cpm" // 按千次展示计费
+	BillingTypeCPC = "cpc" // 按点击计费
+	BillingTypeCPA = "cpa" // 按转化计费
+)
+
 const (
 	CampaignStatusInactive CampaignStatus = 0
 	CampaignStatusActive   CampaignStatus = 1
@@ -83,23 +90,52 @@ func (cfl *CustomFieldList) Scan(value any) error {
 }
 
 type Campaign struct {
-	ID              uint            `json:"id" gorm:"primaryKey;autoIncrement"`
-	Name            string          `json:"name" gorm:"type:varchar(255);not null;comment:计划名称"`
-	CampaignNumber  string          `json:"campaign_number" gorm:"type:varchar(50);uniqueIndex;comment:计划编号"`
-	ProductID       uint            `json:"product_id" gorm:"not null;index;comment:关联产品ID"`
-	Description     string          `json:"description" gorm:"type:text;comment:计划简介"`
-	Status          CampaignStatus  `json:"status" gorm:"type:tinyint;not null;default:1;comment:状态"`
-	MainImage       string          `json:"main_image" gorm:"type:varchar(500);comment:主图URL"`
-	Video           string          `json:"video" gorm:"type:varchar(500);comment:视频URL"`
-	DeliveryContent CustomFieldList `json:"delivery_content" gorm:"type:json;comment:投放内容(自定义字段数组)"`
-	DeliveryRules   CustomFieldList `json:"delivery_rules" gorm:"type:json;comment:投放规则(自定义字段数组)"`
-	UserTargeting   CustomFieldList `json:"user_targeting" gorm:"type:json;comment:用户定向(自定义字段数组)"`
-	CreatedAt       time.Time       `json:"created_at"`
-	UpdatedAt       time.Time       `json:"updated_at"`
-	DeletedAt       gorm.DeletedAt  `json:"-" gorm:"index"`
+	ID                uint              `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name              string            `json:"name" gorm:"type:varchar(255);not null;comment:计划名称"`
+	CampaignNumber    string            `json:"campaign_number" gorm:"type:varchar(50);uniqueIndex;comment:计划编号"`
+	ProductID         uint              `json:"product_id" gorm:"not null;index;comment:关联产品ID"`
+	Description       string            `json:"description" gorm:"type:text;comment:计划简介"`
+	Status            CampaignStatus    `json:"status" gorm:"type:tinyint;not null;default:1;comment:状态"`
+	MainImage         string            `json:"main_image" gorm:"type:varchar(500);comment:主图URL"`
+	Video             string            `json:"video" gorm:"type:varchar(500);comment:视频URL"`
+	VideoMeta         CampaignVideoMeta `json:"video_meta" gorm:"type:json;comment:视频元数据(时长/分辨率)"`
+	DeliveryContent   CustomFieldList   `json:"delivery_content" gorm:"type:json;comment:投放内容(自定义字段数组)"`
+	DeliveryRules     CustomFieldList   `json:"delivery_rules" gorm:"type:json;comment:投放规则(自定义字段数组)"`
+	UserTargeting     CustomFieldList   `json:"user_targeting" gorm:"type:json;comment:用户定向(自定义字段数组)"`
+	Targeting         CampaignTargeting `json:"targeting" gorm:"type:json;comment:结构化定向"`
+	BillingType       string            `json:"billing_type" gorm:"type:varchar(10);not null;default:'cpc';comment:计费方式(cpm/cpc/cpa)"`
+	BidPrice          float64           `json:"bid_price" gorm:"type:decimal(12,4);not null;default:0;comment:出价"`
+	StartAt           *time.Time        `json:"start_at" gorm:"index;comment:开始投放时间"`
+	EndAt             *time.Time        `json:"end_at" gorm:"index;comment:结束投放时间"`
+	Timezone          string            `json:"timezone" gorm:"type:varchar(64);comment:排期时区"`
+	DayParts          DayPartList       `json:"day_parts" gorm:"type:json;comment:投放时段"`
+	ScheduledPause    bool              `json:"scheduled_pause" gorm:"not null;default:false;comment:是否因排期自动暂停"`
+	TotalBudget       float64           `json:"total_budget" gorm:"type:decimal(15,2);not null;default:0;comment:总预算(0为不限)"`
+	DailyBudget       float64           `json:"daily_budget" gorm:"type:decimal(15,2);not null;default:0;comment:日预算(0为不限)"`
+	Pacing            string            `json:"pacing" gorm:"type:varchar(20);not null;default:'standard';comment:消耗节奏(standard/accelerated)"`
+	BudgetPaused      string            `json:"budget_paused" gorm:"type:varchar(10);comment:因预算用尽暂停(daily/total)"`
+	Weight            int               `json:"weight" gorm:"not null;default:1;comment:轮播权重"`
+	FrequencyCap      int               `json:"frequency_cap" gorm:"not null;default:0;comment:每客户展示上限(0为不限)"`
+	FrequencyWindow   int               `json:"frequency_window" gorm:"not null;default:24;comment:频次统计周期(小时)"`
+	AutoPromote       bool              `json:"auto_promote" gorm:"not null;default:false;comment:是否自动采用胜出创意"`
+	PromoteMetric     string            `json:"promote_metric" gorm:"type:varchar(10);not null;default:'ctr';comment:创意对比指标(ctr/cvr)"`
+	PromoteMinSample  int               `json:"promote_min_sample" gorm:"not null;default:1000;comment:每个变体的最小样本数"`
+	PromoteConfidence float64           `json:"promote_confidence" gorm:"type:decimal(5,4);not null;default:0.95;comment:置信度"`
+	PromotedAt        *time.Time        `json:"promoted_at" gorm:"comment:采用胜出创意时间"`
+	ReviewStatus      string            `json:"review_status" gorm:"type:varchar(20);not null;default:'approved';index;comment:审核状态(draft/pending/approved/rejected)，存量计划视为已通过"`
+	ReviewerID        *uint             `json:"reviewer_id" gorm:"index;comment:当前审核人"`
+	SubmittedBy       *uint             `json:"submitted_by" gorm:"comment:提交审核人"`
+	RejectReason      string            `json:"reject_reason" gorm:"type:varchar(500);comment:驳回原因"`
+	ApprovedBy        *uint             `json:"approved_by" gorm:"comment:审核通过人"`
+	ApprovedAt        *time.Time        `json:"approved_at" gorm:"comment:审核通过时间"`
+	ApprovedSnapshot  CampaignSnapshot  `json:"-" gorm:"type:json;comment:审核通过时的内容快照"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt    `json:"-" gorm:"index"`
 
 	// 关联
-	Product *Product `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	Product   *Product           `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	Creatives []CampaignCreative `json:"creatives,omitempty" gorm:"foreignKey:CampaignID"`
 }
 
 func (Campaign) TableName() string {
@@ -120,3 +156,50 @@ func (c *Campaign) GetFieldValue(fields CustomFieldList, title string) string {
 	}
 	return ""
 }
+
+// EventCost 计算单个事件的计费金额
+func (c *Campaign) EventCost(eventType CampaignEventType) float64 {
+	switch {
+	case c.BillingType == BillingTypeCPM && eventType == CampaignEventImpression:
+		return c.BidPrice / 1000
+	case c.BillingType == BillingTypeCPA && eventType == CampaignEventConversion:
+		return c.BidPrice
+	case (c.BillingType == BillingTypeCPC || c.BillingType == "") && eventType == CampaignEventClick:
+		return c.BidPrice
+	default:
+		return 0
+	}
+}
+
+// CopyContent 复制计划的投放配置（不含编号、状态、统计与运行时字段）
+func (c *Campaign) CopyContent() *Campaign {
+	return &Campaign{
+		Name:              c.Name,
+		ProductID:         c.ProductID,
+		Description:       c.Description,
+		Status:            CampaignStatusInactive,
+		MainImage:         c.MainImage,
+		Video:             c.Video,
+		VideoMeta:         c.VideoMeta,
+		DeliveryContent:   append(CustomFieldList{}, c.DeliveryContent...),
+		DeliveryRules:     append(CustomFieldList{}, c.DeliveryRules...),
+		UserTargeting:     append(CustomFieldList{}, c.UserTargeting...),
+		Targeting:         c.Targeting,
+		BillingType:       c.BillingType,
+		BidPrice:          c.BidPrice,
+		StartAt:           c.StartAt,
+		EndAt:             c.EndAt,
+		Timezone:          c.Timezone,
+		DayParts:          append(DayPartList{}, c.DayParts...),
+		TotalBudget:       c.TotalBudget,
+		DailyBudget:       c.DailyBudget,
+		Pacing:            c.Pacing,
+		Weight:            c.Weight,
+		FrequencyCap:      c.FrequencyCap,
+		FrequencyWindow:   c.FrequencyWindow,
+		AutoPromote:       c.AutoPromote,
+		PromoteMetric:     c.PromoteMetric,
+		PromoteMinSample:  c.PromoteMinSample,
+		PromoteConfidence: c.PromoteConfidence,
+	}
+}
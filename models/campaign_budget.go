@@ -0,0 +1,63 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 消耗节奏
+const (
+	PacingStandard    = "standard"    // 匀速：日预算按时间均匀消耗
+	PacingAccelerated = "accelerated" // 加速：尽快消耗
+)
+
+// 预算类型
+const (
+	BudgetTypeDaily = "daily"
+	BudgetTypeTotal = "total"
+)
+
+// CampaignBudgetAlert 计划预算消耗提醒，同一周期同一阈值只提醒一次
+type CampaignBudgetAlert struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CampaignID uint      `json:"campaign_id" gorm:"not null;uniqueIndex:idx_budget_alert;comment:计划ID"`
+	BudgetType string    `json:"budget_type" gorm:"type:varchar(10);not null;uniqueIndex:idx_budget_alert;comment:预算类型(daily/total)"`
+	Period     string    `json:"period" gorm:"type:varchar(10);not null;uniqueIndex:idx_budget_alert;comment:周期(日预算为日期，总预算为total)"`
+	Threshold  int       `json:"threshold" gorm:"not null;uniqueIndex:idx_budget_alert;comment:阈值百分比"`
+	Budget     float64   `json:"budget" gorm:"type:decimal(15,2);not null"`
+	Spend      float64   `json:"spend" gorm:"type:decimal(15,4);not null"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// 关联
+	Campaign *Campaign `json:"campaign,omitempty" gorm:"foreignKey:CampaignID"`
+}
+
+func (CampaignBudgetAlert) TableName() string {
+	return "campaign_budget_alerts"
+}
+
+// ParseBudgetAlertThresholds 解析预算提醒阈值配置，如 "50,80,100"
+func ParseBudgetAlertThresholds(value string) ([]int, error) {
+	thresholds := []int{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		threshold, err := strconv.Atoi(item)
+		if err != nil || threshold <= 0 || threshold > 100 {
+			return nil, fmt.Errorf("预算提醒阈值应为1-100的整数，多个用逗号分隔")
+		}
+		thresholds = append(thresholds, threshold)
+	}
+	sort.Ints(thresholds)
+	return thresholds, nil
+}
+
+// HasBudget 是否设置了预算
+func (c *Campaign) HasBudget() bool {
+	return c.TotalBudget > 0 || c.DailyBudget > 0
+}
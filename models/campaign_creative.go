@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CreativeStatus int
+
+const (
+	CreativeStatusPaused CreativeStatus = 0 // 暂停
+	CreativeStatusActive CreativeStatus = 1 // 投放中
+)
+
+// 创意对比指标
+const (
+	CreativeMetricCTR = "ctr" // 点击率
+	CreativeMetricCVR = "cvr" // 转化率
+)
+
+// CampaignCreative 计划创意（A/B 变体）
+type CampaignCreative struct {
+	ID              uint            `json:"id" gorm:"primaryKey;autoIncrement"`
+	CampaignID      uint            `json:"campaign_id" gorm:"not null;index;comment:计划ID"`
+	Name            string          `json:"name" gorm:"type:varchar(100);not null;comment:变体名称"`
+	MainImage       string          `json:"main_image" gorm:"type:varchar(500);comment:主图URL"`
+	Video           string          `json:"video" gorm:"type:varchar(500);comment:视频URL"`
+	DeliveryContent CustomFieldList `json:"delivery_content" gorm:"type:json;comment:投放内容"`
+	Weight          int             `json:"weight" gorm:"not null;default:1;comment:流量权重"`
+	Status          CreativeStatus  `json:"status" gorm:"type:tinyint;not null;default:1;comment:状态"`
+	IsWinner        bool            `json:"is_winner" gorm:"not null;default:false;comment:是否为胜出变体"`
+	Impressions     int64           `json:"impressions" gorm:"not null;default:0"`
+	Clicks          int64           `json:"clicks" gorm:"not null;default:0"`
+	Conversions     int64           `json:"conversions" gorm:"not null;default:0"`
+	Spend           float64         `json:"spend" gorm:"type:decimal(15,4);not null;default:0"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt  `json:"-" gorm:"index"`
+}
+
+func (CampaignCreative) TableName() string {
+	return "campaign_creatives"
+}
+
+// IsActive 是否投放中
+func (cc *CampaignCreative) IsActive() bool {
+	return cc.Status == CreativeStatusActive
+}
+
+// Metrics 变体指标
+func (cc *CampaignCreative) Metrics() CampaignMetrics {
+	return CampaignMetrics{
+		Impressions: cc.Impressions,
+		Clicks:      cc.Clicks,
+		Conversions: cc.Conversions,
+		Spend:       cc.Spend,
+	}
+}
+
+// Trials 返回指定指标的样本数与成功数（CTR：展示/点击，CVR：点击/转化）
+func (cc *CampaignCreative) Trials(metric string) (int64, int64) {
+	if metric == CreativeMetricCVR {
+		return cc.Clicks, cc.Conversions
+	}
+	return cc.Impressions, cc.Clicks
+}
+
+// CopyContent 复制创意内容（计数清零，胜出标记不保留）
+func (cc *CampaignCreative) CopyContent() CampaignCreative {
+	return CampaignCreative{
+		Name:            cc.Name,
+		MainImage:       cc.MainImage,
+		Video:           cc.Video,
+		DeliveryContent: append(CustomFieldList{}, cc.DeliveryContent...),
+		Weight:          cc.Weight,
+		Status:          cc.Status,
+	}
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"time"
+)
+
+type CampaignEventType int
+
+const (
+	CampaignEventImpression CampaignEventType = 1 // 展示
+	CampaignEventClick      CampaignEventType = 2 // 点击
+	CampaignEventConversion CampaignEventType = 3 // 转化
+)
+
+// ParseCampaignEventType 解析事件类型名称
+func ParseCampaignEventType(name string) (CampaignEventType, bool) {
+	switch name {
+	case "impression":
+		return CampaignEventImpression, true
+	case "click":
+		return CampaignEventClick, true
+	case "conversion":
+		return CampaignEventConversion, true
+	default:
+		return 0, false
+	}
+}
+
+// CampaignEvent 广告计划事件明细
+type CampaignEvent struct {
+	ID         uint              `json:"id" gorm:"primaryKey;autoIncrement"`
+	EventID    string            `json:"event_id" gorm:"type:varchar(64);uniqueIndex:idx_campaign_events_product_event,priority:2;not null;comment:客户端事件ID(同一产品内去重)"`
+	CampaignID uint              `json:"campaign_id" gorm:"not null;index:idx_campaign_events_campaign_time;comment:计划ID"`
+	ProductID  uint              `json:"product_id" gorm:"not null;uniqueIndex:idx_campaign_events_product_event,priority:1;comment:产品ID"`
+	CreativeID *uint             `json:"creative_id" gorm:"index;comment:创意ID"`
+	Type       CampaignEventType `json:"type" gorm:"type:tinyint;not null;comment:事件类型"`
+	CustomerID *uint             `json:"customer_id" gorm:"index;comment:客户ID"`
+	DeviceID   string            `json:"device_id" gorm:"type:varchar(128);comment:设备ID"`
+	Cost       float64           `json:"cost" gorm:"type:decimal(12,4);not null;default:0;comment:计费金额"`
+	ClientIP   string            `json:"client_ip" gorm:"type:varchar(64)"`
+	OccurredAt time.Time         `json:"occurred_at" gorm:"not null;index:idx_campaign_events_campaign_time;comment:发生时间"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+func (CampaignEvent) TableName() string {
+	return "campaign_events"
+}
+
+// CampaignMetrics 广告计划指标
+type CampaignMetrics struct {
+	Impressions int64   `json:"impressions"`
+	Clicks      int64   `json:"clicks"`
+	Conversions int64   `json:"conversions"`
+	Spend       float64 `json:"spend"`
+}
+
+// CTR 点击率(%)
+func (m CampaignMetrics) CTR() float64 {
+	return ratio(float64(m.Clicks), float64(m.Impressions)) * 100
+}
+
+// CVR 转化率(%)
+func (m CampaignMetrics) CVR() float64 {
+	return ratio(float64(m.Conversions), float64(m.Clicks)) * 100
+}
+
+// CPC 平均点击成本
+func (m CampaignMetrics) CPC() float64 {
+	return ratio(m.Spend, float64(m.Clicks))
+}
+
+// CPA 平均转化成本
+func (m CampaignMetrics) CPA() float64 {
+	return ratio(m.Spend, float64(m.Conversions))
+}
+
+func ratio(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return a / b
+}
+
+// CampaignStatHourly 广告计划小时汇总
+type CampaignStatHourly struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CampaignID  uint      `json:"campaign_id" gorm:"not null;uniqueIndex:idx_campaign_hour"`
+	Hour        time.Time `json:"hour" gorm:"not null;uniqueIndex:idx_campaign_hour;comment:整点时间"`
+	Impressions int64     `json:"impressions" gorm:"not null;default:0"`
+	Clicks      int64     `json:"clicks" gorm:"not null;default:0"`
+	Conversions int64     `json:"conversions" gorm:"not null;default:0"`
+	Spend       float64   `json:"spend" gorm:"type:decimal(15,4);not null;default:0"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (CampaignStatHourly) TableName() string {
+	return "campaign_stats_hourly"
+}
+
+// CampaignStatDaily 广告计划日汇总
+type CampaignStatDaily struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CampaignID  uint      `json:"campaign_id" gorm:"not null;uniqueIndex:idx_campaign_date"`
+	Date        string    `json:"date" gorm:"type:char(10);not null;uniqueIndex:idx_campaign_date;comment:日期(YYYY-MM-DD)"`
+	Impressions int64     `json:"impressions" gorm:"not null;default:0"`
+	Clicks      int64     `json:"clicks" gorm:"not null;default:0"`
+	Conversions int64     `json:"conversions" gorm:"not null;default:0"`
+	Spend       float64   `json:"spend" gorm:"type:decimal(15,4);not null;default:0"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (CampaignStatDaily) TableName() string {
+	return "campaign_stats_daily"
+}
@@ -0,0 +1,268 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+// 计划审核状态
+const (
+	ReviewStatusDraft    = "draft"    // 草稿（未提交或审核后有修改）
+	ReviewStatusPending  = "pending"  // 待审核
+	ReviewStatusApproved = "approved" // 已通过
+	ReviewStatusRejected = "rejected" // 已驳回
+)
+
+// 审核操作
+const (
+	ReviewActionSubmit   = "submit"   // 提交审核
+	ReviewActionWithdraw = "withdraw" // 撤回
+	ReviewActionApprove  = "approve"  // 通过
+	ReviewActionReject   = "reject"   // 驳回
+	ReviewActionReset    = "reset"    // 审核后内容变更，退回草稿
+)
+
+// CampaignReviewPermission 审核人需要具备的权限代码
+const CampaignReviewPermission = "campaigns.edit"
+
+// CampaignSnapshot 计划审核内容快照（字段名 → JSON 值）
+type CampaignSnapshot map[string]json.RawMessage
+
+// Value 实现 driver.Valuer 接口
+func (s CampaignSnapshot) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (s *CampaignSnapshot) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("无法解析计划快照")
+	}
+	if len(data) == 0 || string(data) == "null" {
+		*s = nil
+		return nil
+	}
+	return json.Unmarshal(data, s)
+}
+
+// CampaignFieldChange 审核字段变更
+type CampaignFieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old"`
+	New   json.RawMessage `json:"new"`
+}
+
+// CampaignFieldChanges 字段变更列表
+type CampaignFieldChanges []CampaignFieldChange
+
+// Value 实现 driver.Valuer 接口
+func (c CampaignFieldChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return "[]", nil
+	}
+	return json.Marshal(c)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (c *CampaignFieldChanges) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*c = CampaignFieldChanges{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("无法解析变更记录")
+	}
+	if len(data) == 0 || string(data) == "null" {
+		*c = CampaignFieldChanges{}
+		return nil
+	}
+	return json.Unmarshal(data, c)
+}
+
+// reviewCreative 参与审核的创意内容（权重与投放状态不需要审核）
+type reviewCreative struct {
+	ID              uint            `json:"id"`
+	Name            string          `json:"name"`
+	MainImage       string          `json:"main_image"`
+	Video           string          `json:"video"`
+	DeliveryContent CustomFieldList `json:"delivery_content"`
+}
+
+// campaignReviewContent 需要审核的计划内容
+type campaignReviewContent struct {
+	Name            string            `json:"name"`
+	ProductID       uint              `json:"product_id"`
+	Description     string            `json:"description"`
+	MainImage       string            `json:"main_image"`
+	Video           string            `json:"video"`
+	DeliveryContent CustomFieldList   `json:"delivery_content"`
+	DeliveryRules   CustomFieldList   `json:"delivery_rules"`
+	UserTargeting   CustomFieldList   `json:"user_targeting"`
+	Targeting       CampaignTargeting `json:"targeting"`
+	BillingType     string            `json:"billing_type"`
+	BidPrice        float64           `json:"bid_price"`
+	StartAt         *time.Time        `json:"start_at"`
+	EndAt           *time.Time        `json:"end_at"`
+	Timezone        string            `json:"timezone"`
+	DayParts        DayPartList       `json:"day_parts"`
+	TotalBudget     float64           `json:"total_budget"`
+	DailyBudget     float64           `json:"daily_budget"`
+	Creatives       []reviewCreative  `json:"creatives"`
+}
+
+// ReviewSnapshot 生成计划当前需要审核的内容快照
+func (c *Campaign) ReviewSnapshot(creatives []*CampaignCreative) CampaignSnapshot {
+	content := campaignReviewContent{
+		Name:            c.Name,
+		ProductID:       c.ProductID,
+		Description:     c.Description,
+		MainImage:       c.MainImage,
+		Video:           c.Video,
+		DeliveryContent: nonNilFields(c.DeliveryContent),
+		DeliveryRules:   nonNilFields(c.DeliveryRules),
+		UserTargeting:   nonNilFields(c.UserTargeting),
+		Targeting:       c.Targeting,
+		BillingType:     c.BillingType,
+		BidPrice:        c.BidPrice,
+		StartAt:         snapshotTime(c.StartAt),
+		EndAt:           snapshotTime(c.EndAt),
+		Timezone:        c.Timezone,
+		DayParts:        c.DayParts,
+		TotalBudget:     c.TotalBudget,
+		DailyBudget:     c.DailyBudget,
+		Creatives:       make([]reviewCreative, 0, len(creatives)),
+	}
+	if content.DayParts == nil {
+		content.DayParts = DayPartList{}
+	}
+	for _, creative := range creatives {
+		content.Creatives = append(content.Creatives, reviewCreative{
+			ID:              creative.ID,
+			Name:            creative.Name,
+			MainImage:       creative.MainImage,
+			Video:           creative.Video,
+			DeliveryContent: nonNilFields(creative.DeliveryContent),
+		})
+	}
+
+	data, _ := json.Marshal(content)
+	snapshot := CampaignSnapshot{}
+	_ = json.Unmarshal(data, &snapshot)
+	return snapshot
+}
+
+// snapshotTime 统一为 UTC 秒级时间，避免时区与精度差异被识别为变更
+func snapshotTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	value := t.UTC().Truncate(time.Second)
+	return &value
+}
+
+// nonNilFields 空列表统一为 []，避免 null 与 [] 被识别为变更
+func nonNilFields(fields CustomFieldList) CustomFieldList {
+	if fields == nil {
+		return CustomFieldList{}
+	}
+	return fields
+}
+
+// DiffSnapshots 比较两个快照（计划或产品），返回按字段名排序的变更；old 为空时所有字段都视为新增
+func DiffSnapshots(old, current map[string]json.RawMessage) CampaignFieldChanges {
+	fields := make(map[string]bool)
+	for field := range old {
+		fields[field] = true
+	}
+	for field := range current {
+		fields[field] = true
+	}
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	changes := CampaignFieldChanges{}
+	for _, field := range names {
+		oldValue, newValue := old[field], current[field]
+		if jsonEqual(oldValue, newValue) {
+			continue
+		}
+		changes = append(changes, CampaignFieldChange{
+			Field: field,
+			Old:   nullIfEmpty(oldValue),
+			New:   nullIfEmpty(newValue),
+		})
+	}
+	return changes
+}
+
+// jsonEqual 比较两个 JSON 值是否等价（忽略空白差异）
+func jsonEqual(a, b json.RawMessage) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	ca, _ := json.Marshal(va)
+	cb, _ := json.Marshal(vb)
+	return bytes.Equal(ca, cb)
+}
+
+// nullIfEmpty 空值输出为 JSON null
+func nullIfEmpty(value json.RawMessage) json.RawMessage {
+	if len(value) == 0 {
+		return json.RawMessage("null")
+	}
+	return value
+}
+
+// IsApproved 是否已通过审核
+func (c *Campaign) IsApproved() bool {
+	return c.ReviewStatus == ReviewStatusApproved
+}
+
+// CampaignReview 计划审核记录
+type CampaignReview struct {
+	ID         uint                 `json:"id" gorm:"primaryKey;autoIncrement"`
+	CampaignID uint                 `json:"campaign_id" gorm:"not null;index;comment:计划ID"`
+	Action     string               `json:"action" gorm:"type:varchar(20);not null;comment:操作(submit/withdraw/approve/reject/reset)"`
+	FromStatus string               `json:"from_status" gorm:"type:varchar(20);comment:原审核状态"`
+	ToStatus   string               `json:"to_status" gorm:"type:varchar(20);comment:新审核状态"`
+	OperatorID *uint                `json:"operator_id" gorm:"index;comment:操作人(空为系统)"`
+	ReviewerID *uint                `json:"reviewer_id" gorm:"index;comment:审核人"`
+	Reason     string               `json:"reason" gorm:"type:varchar(500);comment:驳回原因/备注"`
+	Changes    CampaignFieldChanges `json:"changes" gorm:"type:json;comment:相对上次审核通过的变更"`
+	CreatedAt  time.Time            `json:"created_at"`
+
+	Operator *Admin `json:"operator,omitempty" gorm:"foreignKey:OperatorID"`
+	Reviewer *Admin `json:"reviewer,omitempty" gorm:"foreignKey:ReviewerID"`
+}
+
+func (CampaignReview) TableName() string {
+	return "campaign_reviews"
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DayPart 投放时段，Weekdays 为空表示每天，时间格式 HH:MM（结束时间可为 24:00）
+type DayPart struct {
+	Weekdays []int  `json:"weekdays"` // 0=周日 ... 6=周六
+	Start    string `json:"start"`
+	End      string `json:"end"`
+}
+
+// DayPartList 投放时段列表
+type DayPartList []DayPart
+
+// Value 实现 driver.Valuer 接口
+func (dpl DayPartList) Value() (driver.Value, error) {
+	if dpl == nil {
+		return json.Marshal([]DayPart{})
+	}
+	return json.Marshal([]DayPart(dpl))
+}
+
+// Scan 实现 sql.Scanner 接口
+func (dpl *DayPartList) Scan(value any) error {
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	}
+
+	var list []DayPart
+	if len(bytes) == 0 || json.Unmarshal(bytes, &list) != nil {
+		*dpl = DayPartList{}
+		return nil
+	}
+	*dpl = list
+	return nil
+}
+
+// parseClock 解析 HH:MM 为当天分钟数
+func parseClock(value string) (int, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hour, &minute); err != nil || len(value) != 5 {
+		return 0, fmt.Errorf("时间格式应为 HH:MM")
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("时间超出范围")
+	}
+	return hour*60 + minute, nil
+}
+
+// Validate 校验时段配置
+func (dp DayPart) Validate() error {
+	start, err := parseClock(dp.Start)
+	if err != nil {
+		return fmt.Errorf("开始%s", err.Error())
+	}
+	end, err := parseClock(dp.End)
+	if err != nil {
+		return fmt.Errorf("结束%s", err.Error())
+	}
+	if end <= start {
+		return fmt.Errorf("结束时间必须晚于开始时间，跨天请拆分为两个时段")
+	}
+	for _, day := range dp.Weekdays {
+		if day < 0 || day > 6 {
+			return fmt.Errorf("星期取值应为0-6")
+		}
+	}
+	return nil
+}
+
+// Contains 判断本地时间是否落在时段内
+func (dp DayPart) Contains(local time.Time) bool {
+	if len(dp.Weekdays) > 0 {
+		matched := false
+		for _, day := range dp.Weekdays {
+			if time.Weekday(day) == local.Weekday() {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	start, err1 := parseClock(dp.Start)
+	end, err2 := parseClock(dp.End)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	minute := local.Hour()*60 + local.Minute()
+	return minute >= start && minute < end
+}
+
+// Location 计划所在时区，未设置或无效时使用服务器时区
+func (c *Campaign) Location() *time.Location {
+	if c.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// HasSchedule 是否配置了排期
+func (c *Campaign) HasSchedule() bool {
+	return c.StartAt != nil || c.EndAt != nil || len(c.DayParts) > 0
+}
+
+// IsStarted 是否已到开始时间
+func (c *Campaign) IsStarted(now time.Time) bool {
+	return c.StartAt == nil || !now.Before(*c.StartAt)
+}
+
+// IsExpired 是否已过结束时间
+func (c *Campaign) IsExpired(now time.Time) bool {
+	return c.EndAt != nil && !now.Before(*c.EndAt)
+}
+
+// InDayParts 是否处于投放时段内（未配置时段视为全天投放）
+func (c *Campaign) InDayParts(now time.Time) bool {
+	if len(c.DayParts) == 0 {
+		return true
+	}
+	local := now.In(c.Location())
+	for _, part := range c.DayParts {
+		if part.Contains(local) {
+			return true
+		}
+	}
+	return false
+}
+
+// InSchedule 当前时间是否应当投放
+func (c *Campaign) InSchedule(now time.Time) bool {
+	return c.IsStarted(now) && !c.IsExpired(now) && c.InDayParts(now)
+}
+
+// 状态变更来源
+const (
+	CampaignStatusSourceScheduler = "scheduler" // 排期自动变更
+	CampaignStatusSourceReview    = "review"    // 审核后内容变更自动暂停
+)
+
+// CampaignStatusLog 计划状态变更记录
+type CampaignStatusLog struct {
+	ID         uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	CampaignID uint           `json:"campaign_id" gorm:"not null;index;comment:计划ID"`
+	FromStatus CampaignStatus `json:"from_status" gorm:"type:tinyint;not null;comment:变更前状态"`
+	ToStatus   CampaignStatus `json:"to_status" gorm:"type:tinyint;not null;comment:变更后状态"`
+	Source     string         `json:"source" gorm:"type:varchar(20);not null;comment:变更来源"`
+	Reason     string         `json:"reason" gorm:"type:varchar(255);comment:变更原因"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+func (CampaignStatusLog) TableName() string {
+	return "campaign_status_logs"
+}
@@ -0,0 +1,408 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 定向可选的设备类型
+var TargetingDevices = []string{"mobile", "tablet", "desktop", "tv"}
+
+// 定向可选的操作系统
+var TargetingOS = []string{"android", "ios", "harmonyos", "windows", "macos", "linux"}
+
+var (
+	countryCodePattern  = regexp.MustCompile(`^[A-Z]{2}$`)
+	languageTagPattern  = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+	legacyValueSplitter = regexp.MustCompile(`\s*[,，、;；|/]\s*`)
+)
+
+// CampaignTargeting 计划定向条件，各维度为空表示不限
+type CampaignTargeting struct {
+	Countries   []string `json:"countries,omitempty"`    // 国家（ISO 3166-1 两位代码，如 CN、US）
+	Regions     []string `json:"regions,omitempty"`      // 省份/州
+	Cities      []string `json:"cities,omitempty"`       // 城市
+	Devices     []string `json:"devices,omitempty"`      // 设备类型
+	OS          []string `json:"os,omitempty"`           // 操作系统
+	Languages   []string `json:"languages,omitempty"`    // 语言（如 zh、zh-cn、en）
+	AgeMin      *int     `json:"age_min,omitempty"`      // 最小年龄
+	AgeMax      *int     `json:"age_max,omitempty"`      // 最大年龄
+	Tags        []string `json:"tags,omitempty"`         // 客户需具备任一标签
+	ExcludeTags []string `json:"exclude_tags,omitempty"` // 具备任一标签的客户不投放
+	AgentIDs    []uint   `json:"agent_ids,omitempty"`    // 代理AdminID，包含其下级代理的客户
+}
+
+// Value 实现 driver.Valuer 接口
+func (t CampaignTargeting) Value() (driver.Value, error) {
+	return json.Marshal(t)
+}
+
+// Scan 实现 sql.Scanner 接口，兼容旧的标题/内容数组格式
+func (t *CampaignTargeting) Scan(value any) error {
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	}
+
+	*t = CampaignTargeting{}
+	trimmed := strings.TrimSpace(string(bytes))
+	if trimmed == "" || trimmed == "null" {
+		return nil
+	}
+
+	if strings.HasPrefix(trimmed, "[") {
+		var fields CustomFieldList
+		if err := fields.Scan(bytes); err == nil {
+			*t, _ = TargetingFromFields(fields)
+		}
+		return nil
+	}
+
+	json.Unmarshal(bytes, t)
+	return nil
+}
+
+// IsEmpty 是否未设置任何定向
+func (t *CampaignTargeting) IsEmpty() bool {
+	return len(t.Countries) == 0 && len(t.Regions) == 0 && len(t.Cities) == 0 &&
+		len(t.Devices) == 0 && len(t.OS) == 0 && len(t.Languages) == 0 &&
+		t.AgeMin == nil && t.AgeMax == nil && len(t.Tags) == 0 && len(t.ExcludeTags) == 0 &&
+		len(t.AgentIDs) == 0
+}
+
+// Normalize 统一大小写并去除空值与重复值
+func (t *CampaignTargeting) Normalize() {
+	t.Countries = normalizeValues(t.Countries, strings.ToUpper)
+	t.Regions = normalizeValues(t.Regions, nil)
+	t.Cities = normalizeValues(t.Cities, nil)
+	t.Devices = normalizeValues(t.Devices, strings.ToLower)
+	t.OS = normalizeValues(t.OS, strings.ToLower)
+	t.Languages = normalizeValues(t.Languages, func(s string) string {
+		return strings.ReplaceAll(strings.ToLower(s), "_", "-")
+	})
+	t.Tags = normalizeValues(t.Tags, nil)
+	t.ExcludeTags = normalizeValues(t.ExcludeTags, nil)
+
+	seen := make(map[uint]bool)
+	agentIDs := []uint{}
+	for _, id := range t.AgentIDs {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			agentIDs = append(agentIDs, id)
+		}
+	}
+	t.AgentIDs = agentIDs
+}
+
+// Validate 校验定向条件（调用前应先 Normalize）
+func (t *CampaignTargeting) Validate() error {
+	for _, country := range t.Countries {
+		if !countryCodePattern.MatchString(country) {
+			return fmt.Errorf("国家代码 %s 无效，应为两位字母代码", country)
+		}
+	}
+	for _, device := range t.Devices {
+		if !containsString(TargetingDevices, device) {
+			return fmt.Errorf("设备类型 %s 无效，可选值: %s", device, strings.Join(TargetingDevices, ", "))
+		}
+	}
+	for _, os := range t.OS {
+		if !containsString(TargetingOS, os) {
+			return fmt.Errorf("操作系统 %s 无效，可选值: %s", os, strings.Join(TargetingOS, ", "))
+		}
+	}
+	for _, language := range t.Languages {
+		if !languageTagPattern.MatchString(language) {
+			return fmt.Errorf("语言 %s 无效，应为 zh、en、zh-cn 等格式", language)
+		}
+	}
+	for _, age := range []*int{t.AgeMin, t.AgeMax} {
+		if age != nil && (*age < 0 || *age > 120) {
+			return fmt.Errorf("年龄应在0-120之间")
+		}
+	}
+	if t.AgeMin != nil && t.AgeMax != nil && *t.AgeMin > *t.AgeMax {
+		return fmt.Errorf("最小年龄不能大于最大年龄")
+	}
+	for _, tag := range append(append([]string{}, t.Tags...), t.ExcludeTags...) {
+		if len([]rune(tag)) > 50 {
+			return fmt.Errorf("标签 %s 过长", tag)
+		}
+	}
+	return nil
+}
+
+// TargetingContext 定向匹配的客户上下文
+type TargetingContext struct {
+	CustomerID   *uint    `json:"customer_id"`
+	Country      string   `json:"country"`
+	Region       string   `json:"region"`
+	City         string   `json:"city"`
+	Device       string   `json:"device"`
+	OS           string   `json:"os"`
+	Language     string   `json:"language"`
+	Age          *int     `json:"age"`
+	Tags         []string `json:"tags"`
+	AgentAdminID *uint    `json:"agent_admin_id"`
+
+	// AgentChain 客户归属代理及其所有上级代理的AdminID，由服务层填充
+	AgentChain []uint `json:"-"`
+}
+
+// Match 判断上下文是否满足定向条件，返回不满足的原因
+func (t *CampaignTargeting) Match(ctx *TargetingContext) (bool, []string) {
+	reasons := []string{}
+
+	if len(t.Countries) > 0 && !containsFold(t.Countries, ctx.Country) {
+		reasons = append(reasons, "国家不在定向范围内")
+	}
+	if len(t.Regions) > 0 && !containsFold(t.Regions, ctx.Region) {
+		reasons = append(reasons, "地区不在定向范围内")
+	}
+	if len(t.Cities) > 0 && !containsFold(t.Cities, ctx.City) {
+		reasons = append(reasons, "城市不在定向范围内")
+	}
+	if len(t.Devices) > 0 && !containsFold(t.Devices, ctx.Device) {
+		reasons = append(reasons, "设备类型不在定向范围内")
+	}
+	if len(t.OS) > 0 && !containsFold(t.OS, ctx.OS) {
+		reasons = append(reasons, "操作系统不在定向范围内")
+	}
+	if len(t.Languages) > 0 && !matchLanguage(t.Languages, ctx.Language) {
+		reasons = append(reasons, "语言不在定向范围内")
+	}
+	if t.AgeMin != nil || t.AgeMax != nil {
+		switch {
+		case ctx.Age == nil:
+			reasons = append(reasons, "缺少年龄信息")
+		case t.AgeMin != nil && *ctx.Age < *t.AgeMin, t.AgeMax != nil && *ctx.Age > *t.AgeMax:
+			reasons = append(reasons, "年龄不在定向范围内")
+		}
+	}
+	if len(t.Tags) > 0 && !intersectsFold(t.Tags, ctx.Tags) {
+		reasons = append(reasons, "客户不具备定向标签")
+	}
+	if len(t.ExcludeTags) > 0 && intersectsFold(t.ExcludeTags, ctx.Tags) {
+		reasons = append(reasons, "客户具备排除标签")
+	}
+	if len(t.AgentIDs) > 0 {
+		matched := false
+		for _, id := range ctx.AgentChain {
+			for _, target := range t.AgentIDs {
+				if id == target {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			reasons = append(reasons, "客户不属于定向代理")
+		}
+	}
+
+	return len(reasons) == 0, reasons
+}
+
+// legacyTargetingTitles 旧版自定义字段标题与定向维度的对应关系
+var legacyTargetingTitles = map[string]string{
+	"国家": "countries", "country": "countries", "countries": "countries",
+	"地区": "regions", "省份": "regions", "region": "regions", "regions": "regions",
+	"城市": "cities", "city": "cities", "cities": "cities",
+	"设备": "devices", "device": "devices", "devices": "devices",
+	"系统": "os", "操作系统": "os", "os": "os",
+	"语言": "languages", "language": "languages", "languages": "languages",
+	"年龄": "age", "age": "age",
+	"标签": "tags", "tag": "tags", "tags": "tags",
+}
+
+// legacyTargetingNames 旧版定向维度的名称，用于说明被忽略的字段
+var legacyTargetingNames = map[string]string{
+	"countries": "国家", "regions": "地区", "cities": "城市", "devices": "设备",
+	"os": "操作系统", "languages": "语言", "age": "年龄", "tags": "标签",
+}
+
+// TargetingFromFields 从旧版标题/内容字段解析定向条件，无法识别的字段忽略。
+// 旧版字段是未经校验的自由文本，只有能通过新版校验的维度才会生效：地区、城市没有统一取值无法可靠匹配，
+// 其他维度只要有一个取值不合法（如国家写作“中国”、设备写作“手机”）就整体忽略，避免收窄投放范围。
+// 返回被忽略的维度及原因
+func TargetingFromFields(fields CustomFieldList) (CampaignTargeting, []string) {
+	values := make(map[string][]string)
+	var dimensions []string
+	var ageContent string
+	for _, field := range fields {
+		dimension := legacyTargetingTitles[strings.ToLower(strings.TrimSpace(field.Title))]
+		if dimension == "" {
+			continue
+		}
+		if _, ok := values[dimension]; !ok {
+			dimensions = append(dimensions, dimension)
+			values[dimension] = []string{}
+		}
+		if dimension == "age" {
+			ageContent = field.Content
+			continue
+		}
+		values[dimension] = append(values[dimension], splitLegacyValues(field.Content)...)
+	}
+
+	var t CampaignTargeting
+	var skipped []string
+	for _, dimension := range dimensions {
+		var candidate CampaignTargeting
+		switch dimension {
+		case "countries":
+			candidate.Countries = values[dimension]
+		case "devices":
+			candidate.Devices = values[dimension]
+		case "os":
+			candidate.OS = values[dimension]
+		case "languages":
+			candidate.Languages = values[dimension]
+		case "tags":
+			candidate.Tags = values[dimension]
+		case "age":
+			candidate.AgeMin, candidate.AgeMax = parseLegacyAge(ageContent)
+			if candidate.AgeMin == nil && candidate.AgeMax == nil {
+				skipped = append(skipped, fmt.Sprintf("年龄（无法解析: %s）", ageContent))
+				continue
+			}
+		default:
+			skipped = append(skipped, legacyTargetingNames[dimension]+"（旧版自由文本无法可靠匹配）")
+			continue
+		}
+		candidate.Normalize()
+		if err := candidate.Validate(); err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s（%s）", legacyTargetingNames[dimension], err.Error()))
+			continue
+		}
+
+		t.Countries = append(t.Countries, candidate.Countries...)
+		t.Devices = append(t.Devices, candidate.Devices...)
+		t.OS = append(t.OS, candidate.OS...)
+		t.Languages = append(t.Languages, candidate.Languages...)
+		t.Tags = append(t.Tags, candidate.Tags...)
+		if dimension == "age" {
+			t.AgeMin, t.AgeMax = candidate.AgeMin, candidate.AgeMax
+		}
+	}
+	t.Normalize()
+	return t, skipped
+}
+
+// parseLegacyAge 解析 "18-35"、"18+"、"18以上" 形式的年龄范围
+func parseLegacyAge(content string) (*int, *int) {
+	content = strings.TrimSpace(content)
+	content = strings.NewReplacer("岁", "", "以上", "+", "~", "-", "至", "-").Replace(content)
+
+	if strings.HasSuffix(content, "+") {
+		if min, err := strconv.Atoi(strings.TrimSuffix(content, "+")); err == nil {
+			return &min, nil
+		}
+		return nil, nil
+	}
+
+	parts := strings.SplitN(content, "-", 2)
+	if len(parts) != 2 {
+		return nil, nil
+	}
+	min, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+	max, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err1 != nil || err2 != nil {
+		return nil, nil
+	}
+	return &min, &max
+}
+
+// splitLegacyValues 拆分旧版字段中的多个取值
+func splitLegacyValues(content string) []string {
+	values := []string{}
+	for _, value := range legacyValueSplitter.Split(content, -1) {
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// normalizeValues 去除空值与重复值，可选转换大小写
+func normalizeValues(values []string, transform func(string) string) []string {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if transform != nil {
+			value = transform(value)
+		}
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
+
+// containsString 判断切片中是否包含指定值
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold 忽略大小写判断切片中是否包含指定值
+func containsFold(values []string, target string) bool {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return false
+	}
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// intersectsFold 忽略大小写判断两个切片是否有交集
+func intersectsFold(values, targets []string) bool {
+	for _, target := range targets {
+		if containsFold(values, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLanguage 语言匹配：定向 zh 可匹配 zh-cn，定向 zh-cn 只匹配 zh-cn
+func matchLanguage(languages []string, language string) bool {
+	language = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(language)), "_", "-")
+	if language == "" {
+		return false
+	}
+	for _, target := range languages {
+		if language == target || strings.HasPrefix(language, target+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveTargeting 获取计划实际生效的定向：优先使用结构化定向，否则兼容解析旧版用户定向和投放规则；
+// 返回是否来自旧版字段，以及旧版字段中未生效的维度
+func (c *Campaign) EffectiveTargeting() (CampaignTargeting, bool, []string) {
+	if !c.Targeting.IsEmpty() {
+		return c.Targeting, false, nil
+	}
+	fields := append(append(CustomFieldList{}, c.UserTargeting...), c.DeliveryRules...)
+	targeting, skipped := TargetingFromFields(fields)
+	return targeting, true, skipped
+}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CampaignTemplateCreative 模板中保存的创意
+type CampaignTemplateCreative struct {
+	Name            string          `json:"name"`
+	MainImage       string          `json:"main_image"`
+	Video           string          `json:"video"`
+	DeliveryContent CustomFieldList `json:"delivery_content"`
+	Weight          int             `json:"weight"`
+	Status          CreativeStatus  `json:"status"`
+}
+
+// CampaignTemplateCreativeList 模板创意列表
+type CampaignTemplateCreativeList []CampaignTemplateCreative
+
+// Value 实现 driver.Valuer 接口
+func (l CampaignTemplateCreativeList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	return json.Marshal(l)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (l *CampaignTemplateCreativeList) Scan(value any) error {
+	var bytes []byte
+	switch v := value.(type) {
+	case nil:
+		*l = CampaignTemplateCreativeList{}
+		return nil
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return errors.New("无法解析模板创意")
+	}
+	if len(bytes) == 0 || string(bytes) == "null" {
+		*l = CampaignTemplateCreativeList{}
+		return nil
+	}
+	return json.Unmarshal(bytes, l)
+}
+
+// CampaignTemplate 计划模板，保存投放配置供其他产品复用（不含排期起止时间）
+type CampaignTemplate struct {
+	ID                uint                         `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name              string                       `json:"name" gorm:"type:varchar(100);not null;comment:模板名称"`
+	Remark            string                       `json:"remark" gorm:"type:varchar(500);comment:模板说明"`
+	SourceCampaignID  *uint                        `json:"source_campaign_id" gorm:"index;comment:来源计划ID"`
+	CreatedBy         uint                         `json:"created_by" gorm:"index;comment:创建人"`
+	Description       string                       `json:"description" gorm:"type:text;comment:计划简介"`
+	MainImage         string                       `json:"main_image" gorm:"type:varchar(500);comment:主图URL"`
+	Video             string                       `json:"video" gorm:"type:varchar(500);comment:视频URL"`
+	VideoMeta         CampaignVideoMeta            `json:"video_meta" gorm:"type:json;comment:视频元数据"`
+	DeliveryContent   CustomFieldList              `json:"delivery_content" gorm:"type:json;comment:投放内容"`
+	DeliveryRules     CustomFieldList              `json:"delivery_rules" gorm:"type:json;comment:投放规则"`
+	UserTargeting     CustomFieldList              `json:"user_targeting" gorm:"type:json;comment:用户定向"`
+	Targeting         CampaignTargeting            `json:"targeting" gorm:"type:json;comment:结构化定向"`
+	BillingType       string                       `json:"billing_type" gorm:"type:varchar(10);not null;default:'cpc';comment:计费方式"`
+	BidPrice          float64                      `json:"bid_price" gorm:"type:decimal(12,4);not null;default:0;comment:出价"`
+	Timezone          string                       `json:"timezone" gorm:"type:varchar(64);comment:排期时区"`
+	DayParts          DayPartList                  `json:"day_parts" gorm:"type:json;comment:投放时段"`
+	TotalBudget       float64                      `json:"total_budget" gorm:"type:decimal(15,2);not null;default:0;comment:总预算"`
+	DailyBudget       float64                      `json:"daily_budget" gorm:"type:decimal(15,2);not null;default:0;comment:日预算"`
+	Pacing            string                       `json:"pacing" gorm:"type:varchar(20);not null;default:'standard';comment:消耗节奏"`
+	Weight            int                          `json:"weight" gorm:"not null;default:1;comment:轮播权重"`
+	FrequencyCap      int                          `json:"frequency_cap" gorm:"not null;default:0;comment:每客户展示上限"`
+	FrequencyWindow   int                          `json:"frequency_window" gorm:"not null;default:24;comment:频次统计周期(小时)"`
+	AutoPromote       bool                         `json:"auto_promote" gorm:"not null;default:false;comment:是否自动采用胜出创意"`
+	PromoteMetric     string                       `json:"promote_metric" gorm:"type:varchar(10);not null;default:'ctr';comment:创意对比指标"`
+	PromoteMinSample  int                          `json:"promote_min_sample" gorm:"not null;default:1000;comment:每个变体的最小样本数"`
+	PromoteConfidence float64                      `json:"promote_confidence" gorm:"type:decimal(5,4);not null;default:0.95;comment:置信度"`
+	Creatives         CampaignTemplateCreativeList `json:"creatives" gorm:"type:json;comment:创意"`
+	CreatedAt         time.Time                    `json:"created_at"`
+	UpdatedAt         time.Time                    `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt               `json:"-" gorm:"index"`
+}
+
+func (CampaignTemplate) TableName() string {
+	return "campaign_templates"
+}
+
+// NewCampaignTemplate 根据计划及其创意生成模板
+func NewCampaignTemplate(campaign *Campaign, creatives []*CampaignCreative) *CampaignTemplate {
+	template := &CampaignTemplate{
+		Creatives: CampaignTemplateCreativeList{},
+	}
+	template.Fill(campaign, creatives)
+	return template
+}
+
+// Fill 用计划及其创意覆盖模板内容，名称与说明保持不变
+func (t *CampaignTemplate) Fill(campaign *Campaign, creatives []*CampaignCreative) {
+	sourceID := campaign.ID
+	t.SourceCampaignID = &sourceID
+	t.Description = campaign.Description
+	t.MainImage = campaign.MainImage
+	t.Video = campaign.Video
+	t.VideoMeta = campaign.VideoMeta
+	t.DeliveryContent = append(CustomFieldList{}, campaign.DeliveryContent...)
+	t.DeliveryRules = append(CustomFieldList{}, campaign.DeliveryRules...)
+	t.UserTargeting = append(CustomFieldList{}, campaign.UserTargeting...)
+	t.Targeting = campaign.Targeting
+	t.BillingType = campaign.BillingType
+	t.BidPrice = campaign.BidPrice
+	t.Timezone = campaign.Timezone
+	t.DayParts = append(DayPartList{}, campaign.DayParts...)
+	t.TotalBudget = campaign.TotalBudget
+	t.DailyBudget = campaign.DailyBudget
+	t.Pacing = campaign.Pacing
+	t.Weight = campaign.Weight
+	t.FrequencyCap = campaign.FrequencyCap
+	t.FrequencyWindow = campaign.FrequencyWindow
+	t.AutoPromote = campaign.AutoPromote
+	t.PromoteMetric = campaign.PromoteMetric
+	t.PromoteMinSample = campaign.PromoteMinSample
+	t.PromoteConfidence = campaign.PromoteConfidence
+
+	t.Creatives = make(CampaignTemplateCreativeList, 0, len(creatives))
+	for _, creative := range creatives {
+		t.Creatives = append(t.Creatives, CampaignTemplateCreative{
+			Name:            creative.Name,
+			MainImage:       creative.MainImage,
+			Video:           creative.Video,
+			DeliveryContent: append(CustomFieldList{}, creative.DeliveryContent...),
+			Weight:          creative.Weight,
+			Status:          creative.Status,
+		})
+	}
+}
+
+// NewCampaign 按模板为指定产品生成未激活的计划及其创意
+func (t *CampaignTemplate) NewCampaign(productID uint, name string) (*Campaign, []CampaignCreative) {
+	if name == "" {
+		name = t.Name
+	}
+	campaign := &Campaign{
+		Name:              name,
+		ProductID:         productID,
+		Description:       t.Description,
+		Status:            CampaignStatusInactive,
+		MainImage:         t.MainImage,
+		Video:             t.Video,
+		VideoMeta:         t.VideoMeta,
+		DeliveryContent:   append(CustomFieldList{}, t.DeliveryContent...),
+		DeliveryRules:     append(CustomFieldList{}, t.DeliveryRules...),
+		UserTargeting:     append(CustomFieldList{}, t.UserTargeting...),
+		Targeting:         t.Targeting,
+		BillingType:       t.BillingType,
+		BidPrice:          t.BidPrice,
+		Timezone:          t.Timezone,
+		DayParts:          append(DayPartList{}, t.DayParts...),
+		TotalBudget:       t.TotalBudget,
+		DailyBudget:       t.DailyBudget,
+		Pacing:            t.Pacing,
+		Weight:            t.Weight,
+		FrequencyCap:      t.FrequencyCap,
+		FrequencyWindow:   t.FrequencyWindow,
+		AutoPromote:       t.AutoPromote,
+		PromoteMetric:     t.PromoteMetric,
+		PromoteMinSample:  t.PromoteMinSample,
+		PromoteConfidence: t.PromoteConfidence,
+	}
+
+	creatives := make([]CampaignCreative, 0, len(t.Creatives))
+	for _, creative := range t.Creatives {
+		creatives = append(creatives, CampaignCreative{
+			Name:            creative.Name,
+			MainImage:       creative.MainImage,
+			Video:           creative.Video,
+			DeliveryContent: append(CustomFieldList{}, creative.DeliveryContent...),
+			Weight:          creative.Weight,
+			Status:          creative.Status,
+		})
+	}
+	return campaign, creatives
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// CampaignVideoMeta 计划视频元数据
+type CampaignVideoMeta struct {
+	Format   string  `json:"format,omitempty"`    // 容器格式：mp4/quicktime/webm/matroska/avi
+	MimeType string  `json:"mime_type,omitempty"` // 按文件内容检测的类型
+	Size     int64   `json:"size,omitempty"`      // 文件大小（字节）
+	Duration float64 `json:"duration,omitempty"`  // 时长（秒）
+	Width    int     `json:"width,omitempty"`     // 分辨率宽
+	Height   int     `json:"height,omitempty"`    // 分辨率高
+}
+
+// Value 实现 driver.Valuer 接口
+func (m CampaignVideoMeta) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (m *CampaignVideoMeta) Scan(value any) error {
+	var bytes []byte
+	switch v := value.(type) {
+	case nil:
+		*m = CampaignVideoMeta{}
+		return nil
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return errors.New("无法解析视频元数据")
+	}
+	if len(bytes) == 0 || string(bytes) == "null" {
+		*m = CampaignVideoMeta{}
+		return nil
+	}
+	return json.Unmarshal(bytes, m)
+}
+
+// 视频分片上传状态
+const (
+	VideoUploadStatusUploading = "uploading" // 上传中
+	VideoUploadStatusCompleted = "completed" // 已完成并写入计划
+	VideoUploadStatusFailed    = "failed"    // 校验或合并失败
+	VideoUploadStatusCancelled = "cancelled" // 已取消
+	VideoUploadStatusExpired   = "expired"   // 超时未完成
+)
+
+// CampaignVideoUpload 计划视频的断点续传会话（tus 协议），已接收的数据暂存在本地临时文件
+type CampaignVideoUpload struct {
+	ID          uint              `json:"-" gorm:"primaryKey;autoIncrement"`
+	UploadID    string            `json:"upload_id" gorm:"type:varchar(36);uniqueIndex;not null"`
+	CampaignID  uint              `json:"campaign_id" gorm:"not null;index"`
+	Filename    string            `json:"filename" gorm:"type:varchar(255);not null;comment:原始文件名"`
+	Length      int64             `json:"length" gorm:"not null;comment:文件总大小"`
+	Offset      int64             `json:"offset" gorm:"not null;default:0;comment:已接收字节数"`
+	Checksum    string            `json:"checksum" gorm:"type:varchar(200);comment:整个文件的校验值(算法 base64摘要)"`
+	Status      string            `json:"status" gorm:"type:varchar(20);not null;default:'uploading';index"`
+	Error       string            `json:"error,omitempty" gorm:"type:varchar(500)"`
+	URL         string            `json:"url,omitempty" gorm:"type:varchar(500);comment:合并后的视频地址"`
+	Meta        CampaignVideoMeta `json:"meta" gorm:"type:json;comment:视频元数据"`
+	CreatedBy   uint              `json:"created_by" gorm:"index"`
+	ExpiresAt   time.Time         `json:"expires_at" gorm:"index;comment:未完成会话的过期时间"`
+	CompletedAt *time.Time        `json:"completed_at"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+func (CampaignVideoUpload) TableName() string {
+	return "campaign_video_uploads"
+}
+
+// IsResumable 会话是否仍可继续上传
+func (u *CampaignVideoUpload) IsResumable(now time.Time) bool {
+	return u.Status == VideoUploadStatusUploading && now.Before(u.ExpiresAt)
+}
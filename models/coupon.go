@@ -3,6 +3,7 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"math"
 	"time"
 
 	"gorm.io/gorm"
@@ -75,6 +76,8 @@ type Coupon struct {
 	Status          CouponStatus   `json:"status" gorm:"type:tinyint;not null;default:1"`
 	TotalCount      int            `json:"total_count" gorm:"type:int;default:0"`               // 总发放数量，0表示无限制
 	UsedCount       int            `json:"used_count" gorm:"type:int;default:0"`                // 已使用数量
+	Stackable       bool           `json:"stackable" gorm:"type:boolean;default:false"`          // 是否可与其他优惠券叠加，否则为互斥券
+	StackGroup      string         `json:"stack_group" gorm:"type:varchar(64);default:''"`      // 叠加分组，同组优惠券不能同时使用
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
@@ -144,4 +147,70 @@ func (c *Coupon) CanUseForNewUser(isNewUser bool) bool {
 		return false
 	}
 	return true
-}
\ No newline at end of file
+}
+// CanStackWith 检查两张优惠券是否可以叠加使用
+func (c *Coupon) CanStackWith(other *Coupon) bool {
+	if !c.Stackable || !other.Stackable {
+		return false
+	}
+
+	// 同一叠加分组内的优惠券互斥
+	if c.StackGroup != "" && c.StackGroup == other.StackGroup {
+		return false
+	}
+
+	return true
+}
+
+// CalculateCombinationDiscount 计算优惠券组合的总优惠金额
+// 门槛按原始订单金额判断；先扣减固定金额类优惠券，再按剩余金额计算折扣券；
+// 增值券是额外赠送金额而不是减免，不计入优惠金额，因此也不会被选为最优组合
+// 返回 false 表示组合不合法（存在不可叠加的优惠券或有优惠券不满足使用门槛）
+func CalculateCombinationDiscount(orderAmount float64, coupons []*Coupon) (float64, bool) {
+	if len(coupons) == 0 {
+		return 0, true
+	}
+
+	for i := 0; i < len(coupons); i++ {
+		if orderAmount < coupons[i].MinAmount {
+			return 0, false
+		}
+		for j := i + 1; j < len(coupons); j++ {
+			if !coupons[i].CanStackWith(coupons[j]) {
+				return 0, false
+			}
+		}
+	}
+
+	ordered := make([]*Coupon, 0, len(coupons))
+	for _, coupon := range coupons {
+		if coupon.Type != CouponTypeDiscount && coupon.Type != CouponTypeValueAdded {
+			ordered = append(ordered, coupon)
+		}
+	}
+	for _, coupon := range coupons {
+		if coupon.Type == CouponTypeDiscount {
+			ordered = append(ordered, coupon)
+		}
+	}
+
+	remaining := orderAmount
+	for _, coupon := range ordered {
+		var discount float64
+		if coupon.Type == CouponTypeDiscount {
+			discount = remaining * coupon.DiscountPercent / 100
+		} else {
+			discount = coupon.GetDiscountAmount(orderAmount)
+		}
+
+		if coupon.MaxAmount > 0 && discount > coupon.MaxAmount {
+			discount = coupon.MaxAmount
+		}
+		if discount > remaining {
+			discount = remaining
+		}
+		remaining -= discount
+	}
+
+	return math.Round((orderAmount-remaining)*100) / 100, true
+}
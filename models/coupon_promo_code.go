@@ -0,0 +1,102 @@
+package models
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PromoCodeType int
+type PromoCodeStatus int
+
+const (
+	PromoCodeTypeShared PromoCodeType = 1 // 通用码（多人共用，受使用上限约束）
+	PromoCodeTypeUnique PromoCodeType = 2 // 唯一码（一码一人，批量生成）
+)
+
+const (
+	PromoCodeStatusDisabled PromoCodeStatus = 0
+	PromoCodeStatusActive   PromoCodeStatus = 1
+)
+
+// promoCodeCharset 生成兑换码使用的字符集（去掉了易混淆的 0/O/1/I）
+const promoCodeCharset = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// CouponPromoCode 优惠券兑换码
+type CouponPromoCode struct {
+	ID         uint            `json:"id" gorm:"primaryKey;autoIncrement"`
+	CouponID   uint            `json:"coupon_id" gorm:"not null;index"`
+	Code       string          `json:"code" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Type       PromoCodeType   `json:"type" gorm:"type:tinyint;not null;default:1"`
+	Status     PromoCodeStatus `json:"status" gorm:"type:tinyint;not null;default:1"`
+	BatchNo    string          `json:"batch_no" gorm:"type:varchar(64);index"` // 批量生成批次号
+	MaxUses    int             `json:"max_uses" gorm:"type:int;default:0"`     // 最大使用次数，0表示无限制
+	UsedCount  int             `json:"used_count" gorm:"type:int;default:0"`   // 已使用次数
+	RedeemedBy *uint           `json:"redeemed_by" gorm:"index"`               // 唯一码的兑换用户
+	RedeemedAt *time.Time      `json:"redeemed_at"`                            // 唯一码的兑换时间
+	ExpiredAt  *time.Time      `json:"expired_at"`                             // 兑换码过期时间，为空则跟随优惠券
+	CreatedBy  uint            `json:"created_by" gorm:"index"`                // 创建管理员ID
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt  `json:"-" gorm:"index"`
+
+	// 关联
+	Coupon *Coupon `json:"coupon,omitempty" gorm:"foreignKey:CouponID"`
+}
+
+func (CouponPromoCode) TableName() string {
+	return "coupon_promo_codes"
+}
+
+// NormalizePromoCode 规范化兑换码（去空格并转大写）
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// GeneratePromoCode 生成指定长度的随机兑换码
+func GeneratePromoCode(prefix string, length int) string {
+	max := big.NewInt(int64(len(promoCodeCharset)))
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			b[i] = promoCodeCharset[time.Now().UnixNano()%int64(len(promoCodeCharset))]
+			continue
+		}
+		b[i] = promoCodeCharset[n.Int64()]
+	}
+	return NormalizePromoCode(prefix) + string(b)
+}
+
+// IsUsable 检查兑换码是否可用
+func (pc *CouponPromoCode) IsUsable() bool {
+	if pc.Status != PromoCodeStatusActive {
+		return false
+	}
+
+	if pc.ExpiredAt != nil && time.Now().After(*pc.ExpiredAt) {
+		return false
+	}
+
+	switch pc.Type {
+	case PromoCodeTypeUnique:
+		return pc.RedeemedBy == nil
+	default:
+		return pc.MaxUses == 0 || pc.UsedCount < pc.MaxUses
+	}
+}
+
+// GetTypeString 获取兑换码类型字符串
+func (pc *CouponPromoCode) GetTypeString() string {
+	switch pc.Type {
+	case PromoCodeTypeShared:
+		return "通用码"
+	case PromoCodeTypeUnique:
+		return "唯一码"
+	default:
+		return "未知"
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -26,6 +27,8 @@ type Customer struct {
 	Notes     string         `json:"notes" gorm:"type:text"`          // 备注
 	Balance   float64        `json:"balance" gorm:"type:decimal(15,2);default:0"` // 账户余额
 	LastLoginAt *time.Time   `json:"last_login_at"`                   // 最后登录时间
+	AgentAdminID *uint       `json:"agent_admin_id" gorm:"index"`      // 归属代理的AdminID
+	Tags      string         `json:"tags" gorm:"type:varchar(500)"`   // 客户标签（逗号分隔）
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
@@ -39,6 +42,17 @@ func (Customer) TableName() string {
 	return "customers"
 }
 
+// GetTags 获取客户标签列表
+func (c *Customer) GetTags() []string {
+	tags := []string{}
+	for _, tag := range strings.Split(c.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
 // IsActive 检查客户是否激活
 func (c *Customer) IsActive() bool {
 	return c.Status == CustomerStatusActive
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+)
+
+type ExportJobStatus int
+
+const (
+	ExportJobStatusPending   ExportJobStatus = 0 // 等待中
+	ExportJobStatusRunning   ExportJobStatus = 1 // 导出中
+	ExportJobStatusCompleted ExportJobStatus = 2 // 已完成
+	ExportJobStatusFailed    ExportJobStatus = 3 // 失败
+)
+
+// ExportJob 异步导出任务
+type ExportJob struct {
+	ID            uint            `json:"id" gorm:"primaryKey;autoIncrement"`
+	JobNo         string          `json:"job_no" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Resource      string          `json:"resource" gorm:"type:varchar(50);not null;index"` // 导出对象：customers/transactions/auth_codes
+	Format        string          `json:"format" gorm:"type:varchar(10);not null"`         // csv/xlsx
+	Params        string          `json:"params" gorm:"type:text"`                         // 导出参数（JSON）
+	Status        ExportJobStatus `json:"status" gorm:"type:tinyint;not null;default:0;index"`
+	TotalRows     int64           `json:"total_rows" gorm:"default:0"`     // 预计行数
+	ProcessedRows int64           `json:"processed_rows" gorm:"default:0"` // 已导出行数
+	FileName      string          `json:"file_name" gorm:"type:varchar(255)"`
+	FilePath      string          `json:"-" gorm:"type:varchar(500)"`
+	FileSize      int64           `json:"file_size" gorm:"default:0"`
+	Error         string          `json:"error" gorm:"type:text"`
+	CreatedBy     uint            `json:"created_by" gorm:"index"` // 创建管理员ID
+	StartedAt     *time.Time      `json:"started_at"`
+	FinishedAt    *time.Time      `json:"finished_at"`
+	ExpiredAt     *time.Time      `json:"expired_at"` // 文件过期时间
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+func (ExportJob) TableName() string {
+	return "export_jobs"
+}
+
+// IsDownloadable 检查导出文件是否可下载
+func (ej *ExportJob) IsDownloadable() bool {
+	if ej.Status != ExportJobStatusCompleted || ej.FilePath == "" {
+		return false
+	}
+	return ej.ExpiredAt == nil || time.Now().Before(*ej.ExpiredAt)
+}
+
+// GetStatusString 获取状态字符串
+func (ej *ExportJob) GetStatusString() string {
+	switch ej.Status {
+	case ExportJobStatusPending:
+		return "等待中"
+	case ExportJobStatusRunning:
+		return "导出中"
+	case ExportJobStatusCompleted:
+		return "已完成"
+	case ExportJobStatusFailed:
+		return "失败"
+	default:
+		return "未知"
+	}
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ImportJobStatus int
+
+const (
+	ImportJobStatusPending   ImportJobStatus = 0 // 等待中
+	ImportJobStatusRunning   ImportJobStatus = 1 // 导入中
+	ImportJobStatusCompleted ImportJobStatus = 2 // 已完成
+	ImportJobStatusFailed    ImportJobStatus = 3 // 失败
+)
+
+// 导入模式
+const (
+	ImportModeCreate = "create" // 仅新增，邮箱已存在视为错误
+	ImportModeUpsert = "upsert" // 按邮箱新增或更新
+)
+
+// ImportJob 批量导入任务
+type ImportJob struct {
+	ID           uint            `json:"id" gorm:"primaryKey;autoIncrement"`
+	JobNo        string          `json:"job_no" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Resource     string          `json:"resource" gorm:"type:varchar(50);not null;index"` // 导入对象：customers
+	Mode         string          `json:"mode" gorm:"type:varchar(20);not null"`           // create/upsert
+	DryRun       bool            `json:"dry_run" gorm:"not null;default:false"`           // 仅校验不写入
+	AgentAdminID *uint           `json:"agent_admin_id"`                                  // 默认归属代理
+	Status       ImportJobStatus `json:"status" gorm:"type:tinyint;not null;default:0;index"`
+	FileName     string          `json:"file_name" gorm:"type:varchar(255)"` // 上传的原始文件名
+	FilePath     string          `json:"-" gorm:"type:varchar(500)"`
+	TotalRows    int64           `json:"total_rows" gorm:"default:0"` // 已处理行数
+	CreatedRows  int64           `json:"created_rows" gorm:"default:0"`
+	UpdatedRows  int64           `json:"updated_rows" gorm:"default:0"`
+	FailedRows   int64           `json:"failed_rows" gorm:"default:0"`
+	ReportPath   string          `json:"-" gorm:"type:varchar(500)"` // 错误报告文件
+	Error        string          `json:"error" gorm:"type:text"`
+	CreatedBy    uint            `json:"created_by" gorm:"index"` // 创建管理员ID
+	StartedAt    *time.Time      `json:"started_at"`
+	FinishedAt   *time.Time      `json:"finished_at"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+
+	// 非数据库字段
+	HasReport bool `json:"has_report" gorm:"-"`
+}
+
+func (ImportJob) TableName() string {
+	return "import_jobs"
+}
+
+// AfterFind 填充是否存在错误报告
+func (ij *ImportJob) AfterFind(tx *gorm.DB) error {
+	ij.HasReport = ij.ReportPath != ""
+	return nil
+}
+
+// GetStatusString 获取状态字符串
+func (ij *ImportJob) GetStatusString() string {
+	switch ij.Status {
+	case ImportJobStatusPending:
+		return "等待中"
+	case ImportJobStatusRunning:
+		return "导入中"
+	case ImportJobStatusCompleted:
+		return "已完成"
+	case ImportJobStatusFailed:
+		return "失败"
+	default:
+		return "未知"
+	}
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaintenanceMessage 维护期间返回给客户端的默认提示
+const DefaultMaintenanceMessage = "系统维护中，请稍后再试"
+
+// MaintenanceWindow 计划维护时段，时段内客户端接口自动进入维护模式
+type MaintenanceWindow struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Message string    `json:"message,omitempty"` // 为空时使用维护提示配置
+}
+
+// Contains 时刻是否在维护时段内
+func (w MaintenanceWindow) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// ParseMaintenanceWindows 解析计划维护时段配置（JSON 数组，时间为 RFC3339），按开始时间排序
+func ParseMaintenanceWindows(value string) ([]MaintenanceWindow, error) {
+	windows := []MaintenanceWindow{}
+	if strings.TrimSpace(value) == "" {
+		return windows, nil
+	}
+	if err := json.Unmarshal([]byte(value), &windows); err != nil {
+		return nil, fmt.Errorf("维护时段应为 JSON 数组，如 [{\"start\":\"2024-01-01T02:00:00+08:00\",\"end\":\"2024-01-01T04:00:00+08:00\"}]")
+	}
+	for _, window := range windows {
+		if window.Start.IsZero() || window.End.IsZero() || !window.End.After(window.Start) {
+			return nil, fmt.Errorf("维护时段的结束时间必须晚于开始时间")
+		}
+	}
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].Start.Before(windows[j].Start)
+	})
+	return windows, nil
+}
+
+// ParseMaintenanceAllowedIPs 解析维护期间放行的 IP 配置，逗号分隔，支持 CIDR，如 "10.0.0.1,192.168.1.0/24"
+func ParseMaintenanceAllowedIPs(value string) ([]*net.IPNet, error) {
+	networks := []*net.IPNet{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("无效的 IP 地址: %s", item)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("无效的 IP 网段: %s", item)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// ParseMaintenanceAllowedCustomers 解析维护期间放行的客户ID配置，逗号分隔
+func ParseMaintenanceAllowedCustomers(value string) ([]uint, error) {
+	ids := []uint{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(item, 10, 32)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("无效的客户ID: %s", item)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"log"
 
 	"backend/database"
@@ -17,13 +18,33 @@ func AllModels() []interface{} {
 		&AdminRoleAssoc{},
 		&RolePermission{},
 		&Product{},
+		&ProductImage{},
+		&ProductRevision{},
 		&Campaign{},
+		&CampaignEvent{},
+		&CampaignStatHourly{},
+		&CampaignStatDaily{},
+		&CampaignStatusLog{},
+		&CampaignBudgetAlert{},
+		&CampaignCreative{},
+		&CampaignTemplate{},
+		&CampaignReview{},
+		&CampaignVideoUpload{},
+		&Asset{},
+		&AssetReference{},
 		&Coupon{},
 		&UserCoupon{},
+		&CouponPromoCode{},
 		&AuthCode{},
+		&AuthCodeBatch{},
+		&AuthCodeRedemption{},
+		&CustomerProductAccess{},
 		&Transaction{},
 		&Customer{},
 		&SystemConfig{},
+		&SystemConfigHistory{},
+		&ExportJob{},
+		&ImportJob{},
 
 		// 代理商系统模型
 		&Agent{},
@@ -59,6 +80,12 @@ func AutoMigrate() {
 	// 执行额外的数据库迁移操作
 	CleanupOldAgentTables()
 	AddAgentInviteCode()
+	BackfillAuthCodeRedemptions()
+	BackfillProductTrackingKeys()
+	BackfillProductImages()
+	DropCampaignEventLegacyIndexes()
+	BackfillProductRevisions()
+	BackfillAllowedFileTypes()
 }
 
 // CleanupOldAgentTables 删除旧的代理商相关表
@@ -145,7 +172,190 @@ func AddAgentInviteCode() {
 			}
 		}
 	}
-} // CreateIndexes 创建额外的索引
+}
+
+// BackfillAuthCodeRedemptions 为已使用的单次授权码补充兑换记录
+func BackfillAuthCodeRedemptions() {
+	db := database.GetDB()
+
+	log.Println("Backfilling auth code redemptions...")
+
+	result := db.Exec(`
+		INSERT INTO auth_code_redemptions (auth_code_id, customer_id, batch_id, created_at)
+		SELECT id, used_by, batch_id, COALESCE(used_at, updated_at)
+		FROM auth_codes
+		WHERE status = ? AND used_by IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM auth_code_redemptions r WHERE r.auth_code_id = auth_codes.id)
+	`, AuthCodeStatusUsed)
+	if result.Error != nil {
+		log.Printf("Warning: Failed to backfill auth code redemptions: %v", result.Error)
+		return
+	}
+
+	if err := db.Exec(`
+		UPDATE auth_codes SET used_count = 1
+		WHERE status = ? AND used_by IS NOT NULL AND used_count = 0
+	`, AuthCodeStatusUsed).Error; err != nil {
+		log.Printf("Warning: Failed to update auth code used_count: %v", err)
+		return
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("✅ Backfilled %d auth code redemptions", result.RowsAffected)
+	}
+}
+
+// BackfillProductTrackingKeys 为已有产品生成事件上报签名密钥
+func BackfillProductTrackingKeys() {
+	db := database.GetDB()
+
+	var products []Product
+	if err := db.Select("id").Where("tracking_key IS NULL OR tracking_key = ''").Find(&products).Error; err != nil {
+		log.Printf("Warning: Failed to query products without tracking key: %v", err)
+		return
+	}
+
+	for _, product := range products {
+		if err := db.Model(&Product{}).Where("id = ?", product.ID).
+			Update("tracking_key", GenerateTrackingKey()).Error; err != nil {
+			log.Printf("Warning: Failed to set tracking key for product %d: %v", product.ID, err)
+		}
+	}
+
+	if len(products) > 0 {
+		log.Printf("✅ Generated tracking keys for %d products", len(products))
+	}
+}
+
+// BackfillProductImages 将 products.images 文本列中的 JSON 图片数组迁移到 product_images 表，
+// 每个产品的写入与清空旧数据在同一事务中完成，避免之后删光图片的产品在重启时被旧数据回填。
+// 旧列保留以便回滚到旧版本，确认无需回滚后再单独删除
+func BackfillProductImages() {
+	db := database.GetDB()
+
+	if !db.Migrator().HasColumn(&Product{}, "images") {
+		return
+	}
+
+	var rows []struct {
+		ID     uint
+		Images string
+	}
+	if err := db.Table("products").
+		Select("id, images").
+		Where("images IS NOT NULL AND images <> '' AND images <> '[]'").
+		Where("NOT EXISTS (SELECT 1 FROM product_images WHERE product_images.product_id = products.id)").
+		Scan(&rows).Error; err != nil {
+		log.Printf("Warning: Failed to query legacy product images: %v", err)
+		return
+	}
+
+	migrated, failed := 0, 0
+	for _, row := range rows {
+		var urls []string
+		if err := json.Unmarshal([]byte(row.Images), &urls); err != nil {
+			log.Printf("Warning: Failed to parse images of product %d: %v", row.ID, err)
+			failed++
+			continue
+		}
+
+		images := make([]ProductImage, 0, len(urls))
+		for _, url := range urls {
+			if url == "" {
+				continue
+			}
+			images = append(images, ProductImage{ProductID: row.ID, URL: url, Sort: len(images)})
+		}
+		if len(images) == 0 {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&images).Error; err != nil {
+				return err
+			}
+			return tx.Table("products").Where("id = ?", row.ID).Update("images", "").Error
+		})
+		if err != nil {
+			log.Printf("Warning: Failed to migrate images of product %d: %v", row.ID, err)
+			failed++
+			continue
+		}
+		migrated++
+	}
+
+	if migrated > 0 {
+		log.Printf("✅ Migrated images of %d products", migrated)
+	}
+	if failed > 0 {
+		log.Printf("Warning: %d products still have legacy images in products.images", failed)
+	}
+}
+
+// DropCampaignEventLegacyIndexes 删除事件表旧的索引：事件ID改为按产品去重，
+// 旧的全局唯一索引会让不同产品使用相同事件ID的事件被误判为重复；产品ID单列索引已被复合索引覆盖
+func DropCampaignEventLegacyIndexes() {
+	db := database.GetDB()
+
+	for _, index := range []string{"idx_campaign_events_event_id", "idx_campaign_events_product_id"} {
+		if !db.Migrator().HasIndex(&CampaignEvent{}, index) {
+			continue
+		}
+		if err := db.Migrator().DropIndex(&CampaignEvent{}, index); err != nil {
+			log.Printf("Warning: Failed to drop index %s: %v", index, err)
+		}
+	}
+}
+
+// BackfillProductRevisions 为尚无版本记录的产品生成基线版本
+func BackfillProductRevisions() {
+	db := database.GetDB()
+
+	var products []Product
+	if err := db.Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort ASC, id ASC")
+	}).Where("NOT EXISTS (SELECT 1 FROM product_revisions WHERE product_revisions.product_id = products.id)").
+		Find(&products).Error; err != nil {
+		log.Printf("Warning: Failed to query products without revisions: %v", err)
+		return
+	}
+
+	for _, product := range products {
+		snapshot := product.RevisionSnapshot()
+		revision := ProductRevision{
+			ProductID: product.ID,
+			Version:   1,
+			Action:    ProductRevisionBaseline,
+			Snapshot:  snapshot,
+			Changes:   DiffSnapshots(nil, snapshot),
+		}
+		if err := db.Create(&revision).Error; err != nil {
+			log.Printf("Warning: Failed to create baseline revision for product %d: %v", product.ID, err)
+		}
+	}
+
+	if len(products) > 0 {
+		log.Printf("✅ Created baseline revisions for %d products", len(products))
+	}
+}
+
+// BackfillAllowedFileTypes 上传改为按扩展名白名单校验后，将仍为旧默认值的配置更新为新默认值，
+// 避免此前可以上传的 WebP 图片与视频被拒绝
+func BackfillAllowedFileTypes() {
+	db := database.GetDB()
+
+	result := db.Model(&SystemConfig{}).
+		Where("`key` = ? AND value = ?", ConfigKeyAllowedFileTypes, LegacyAllowedFileTypes).
+		Update("value", DefaultAllowedFileTypes)
+	if result.Error != nil {
+		log.Printf("Warning: Failed to update allowed file types: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Println("✅ Updated allowed_file_types to the new default")
+	}
+}
+
+// CreateIndexes 创建额外的索引
 func CreateIndexes() {
 	db := database.GetDB()
 
@@ -198,7 +408,7 @@ func seedSystemConfigs(db *gorm.DB) {
 
 	for _, config := range configs {
 		var existing SystemConfig
-		if err := db.Where("key = ?", config.Key).First(&existing).Error; err != nil {
+		if err := db.Where("`key` = ?", config.Key).First(&existing).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
 				if err := db.Create(&config).Error; err != nil {
 					log.Printf("Failed to create system config %s: %v", config.Key, err)
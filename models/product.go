@@ -1,7 +1,9 @@
 package models
 
 import (
+	"crypto/rand"
 	"database/sql/driver"
+	"encoding/hex"
 	"encoding/json"
 	"time"
 
@@ -62,43 +64,59 @@ type Product struct {
 	Description   string         `json:"description" gorm:"type:text"`
 	Status        ProductStatus  `json:"status" gorm:"type:tinyint;not null;default:1"`
 	Logo          string         `json:"logo" gorm:"type:varchar(500)"`
-	Images        string         `json:"images" gorm:"type:text"` // JSON数组存储多张图片
 	GooglePayLink string         `json:"google_pay_link" gorm:"type:varchar(500)"`
 	AppStoreLink  string         `json:"app_store_link" gorm:"type:varchar(500)"`
+	PackageName   string         `json:"package_name" gorm:"type:varchar(255);index"` // Android 包名（商店链接或 APK 解析）
+	AppStoreID    string         `json:"app_store_id" gorm:"type:varchar(20)"`        // App Store 应用ID（商店链接解析）
+	BundleID      string         `json:"bundle_id" gorm:"type:varchar(255)"`          // iOS Bundle ID（IPA 解析）
 	AppInfo       AppInfoList    `json:"app_info" gorm:"type:json"`
+	TrackingKey   string         `json:"-" gorm:"type:varchar(64)"` // 事件上报签名密钥
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// 关联
-	Campaigns []Campaign `json:"campaigns,omitempty" gorm:"foreignKey:ProductID"`
+	Images    []ProductImage `json:"images" gorm:"foreignKey:ProductID"`
+	Campaigns []Campaign     `json:"campaigns,omitempty" gorm:"foreignKey:ProductID"`
 }
 
 func (Product) TableName() string {
 	return "products"
 }
 
-// GetImages 获取图片列表
-func (p *Product) GetImages() []string {
-	if p.Images == "" {
-		return []string{}
+// BeforeCreate 创建前钩子 - 生成事件上报签名密钥
+func (p *Product) BeforeCreate(tx *gorm.DB) error {
+	if p.TrackingKey == "" {
+		p.TrackingKey = GenerateTrackingKey()
 	}
+	return nil
+}
 
-	var images []string
-	if err := json.Unmarshal([]byte(p.Images), &images); err != nil {
-		return []string{}
+// GenerateTrackingKey 生成事件上报签名密钥
+func GenerateTrackingKey() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// GetImages 获取按顺序排列的图片URL列表
+func (p *Product) GetImages() []string {
+	images := make([]string, 0, len(p.Images))
+	for _, image := range p.Images {
+		images = append(images, image.URL)
 	}
 	return images
 }
 
-// SetImages 设置图片列表
-func (p *Product) SetImages(images []string) error {
-	data, err := json.Marshal(images)
-	if err != nil {
-		return err
+// SetAppInfo 设置应用信息项，已存在的 key 覆盖其值，新 key 追加到末尾
+func (p *Product) SetAppInfo(key, value string) {
+	for i := range p.AppInfo {
+		if p.AppInfo[i].Key == key {
+			p.AppInfo[i].Value = value
+			return
+		}
 	}
-	p.Images = string(data)
-	return nil
+	p.AppInfo = append(p.AppInfo, AppInfoItem{Key: key, Value: value})
 }
 
 // IsActive 检查产品是否激活
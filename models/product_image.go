@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// ProductImage 产品图片
+type ProductImage struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProductID uint      `json:"product_id" gorm:"not null;index;comment:产品ID"`
+	URL       string    `json:"url" gorm:"type:varchar(500);not null;comment:图片URL"`
+	Alt       string    `json:"alt" gorm:"type:varchar(255);comment:替代文本"`
+	Width     int       `json:"width" gorm:"not null;default:0;comment:宽度(像素)"`
+	Height    int       `json:"height" gorm:"not null;default:0;comment:高度(像素)"`
+	Sort      int       `json:"sort" gorm:"not null;default:0;index;comment:排序(从小到大)"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (ProductImage) TableName() string {
+	return "product_images"
+}
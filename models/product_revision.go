@@ -0,0 +1,166 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// 产品版本操作
+const (
+	ProductRevisionBaseline = "baseline" // 上线版本管理前已存在的数据
+	ProductRevisionCreate   = "create"   // 创建
+	ProductRevisionUpdate   = "update"   // 编辑
+	ProductRevisionStatus   = "status"   // 状态变更
+	ProductRevisionImages   = "images"   // 图片变更
+	ProductRevisionManifest = "manifest" // 安装包清单解析
+	ProductRevisionRestore  = "restore"  // 恢复到历史版本
+)
+
+// ProductSnapshot 产品内容快照（字段名 → JSON 值）
+type ProductSnapshot map[string]json.RawMessage
+
+// Value 实现 driver.Valuer 接口
+func (s ProductSnapshot) Value() (driver.Value, error) {
+	if s == nil {
+		return "{}", nil
+	}
+	return json.Marshal(s)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (s *ProductSnapshot) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*s = ProductSnapshot{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("无法解析产品快照")
+	}
+	if len(data) == 0 || string(data) == "null" {
+		*s = ProductSnapshot{}
+		return nil
+	}
+	return json.Unmarshal(data, s)
+}
+
+// revisionImage 快照中的产品图片
+type revisionImage struct {
+	URL    string `json:"url"`
+	Alt    string `json:"alt"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// productRevisionContent 纳入版本管理的产品内容
+type productRevisionContent struct {
+	Name          string          `json:"name"`
+	Type          string          `json:"type"`
+	Company       string          `json:"company"`
+	Description   string          `json:"description"`
+	Status        ProductStatus   `json:"status"`
+	Logo          string          `json:"logo"`
+	GooglePayLink string          `json:"google_pay_link"`
+	AppStoreLink  string          `json:"app_store_link"`
+	PackageName   string          `json:"package_name"`
+	AppStoreID    string          `json:"app_store_id"`
+	BundleID      string          `json:"bundle_id"`
+	AppInfo       AppInfoList     `json:"app_info"`
+	Images        []revisionImage `json:"images"`
+}
+
+// RevisionSnapshot 生成产品当前内容的快照，需要预先加载 Images
+func (p *Product) RevisionSnapshot() ProductSnapshot {
+	content := productRevisionContent{
+		Name:          p.Name,
+		Type:          p.Type,
+		Company:       p.Company,
+		Description:   p.Description,
+		Status:        p.Status,
+		Logo:          p.Logo,
+		GooglePayLink: p.GooglePayLink,
+		AppStoreLink:  p.AppStoreLink,
+		PackageName:   p.PackageName,
+		AppStoreID:    p.AppStoreID,
+		BundleID:      p.BundleID,
+		AppInfo:       p.AppInfo,
+		Images:        make([]revisionImage, 0, len(p.Images)),
+	}
+	if content.AppInfo == nil {
+		content.AppInfo = AppInfoList{}
+	}
+	for _, image := range p.Images {
+		content.Images = append(content.Images, revisionImage{
+			URL:    image.URL,
+			Alt:    image.Alt,
+			Width:  image.Width,
+			Height: image.Height,
+		})
+	}
+
+	data, _ := json.Marshal(content)
+	snapshot := ProductSnapshot{}
+	_ = json.Unmarshal(data, &snapshot)
+	return snapshot
+}
+
+// ApplyTo 将快照内容写回产品并返回快照中的图片；状态不随版本恢复，避免误上架或下架
+func (s ProductSnapshot) ApplyTo(product *Product) ([]ProductImage, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	var content productRevisionContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, err
+	}
+
+	product.Name = content.Name
+	product.Type = content.Type
+	product.Company = content.Company
+	product.Description = content.Description
+	product.Logo = content.Logo
+	product.GooglePayLink = content.GooglePayLink
+	product.AppStoreLink = content.AppStoreLink
+	product.PackageName = content.PackageName
+	product.AppStoreID = content.AppStoreID
+	product.BundleID = content.BundleID
+	product.AppInfo = content.AppInfo
+
+	images := make([]ProductImage, 0, len(content.Images))
+	for _, image := range content.Images {
+		images = append(images, ProductImage{
+			ProductID: product.ID,
+			URL:       image.URL,
+			Alt:       image.Alt,
+			Width:     image.Width,
+			Height:    image.Height,
+		})
+	}
+	return images, nil
+}
+
+// ProductRevision 产品版本（只追加，不修改）
+type ProductRevision struct {
+	ID        uint                 `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProductID uint                 `json:"product_id" gorm:"not null;uniqueIndex:idx_product_revision_version;comment:产品ID"`
+	Version   int                  `json:"version" gorm:"not null;uniqueIndex:idx_product_revision_version;comment:版本号(按产品递增)"`
+	Action    string               `json:"action" gorm:"type:varchar(20);not null;comment:操作(baseline/create/update/status/images/manifest/restore)"`
+	Snapshot  ProductSnapshot      `json:"snapshot,omitempty" gorm:"type:json;comment:产品内容快照"`
+	Changes   CampaignFieldChanges `json:"changes" gorm:"type:json;comment:相对上一版本的变更"`
+	Remark    string               `json:"remark" gorm:"type:varchar(255);comment:备注"`
+	AdminID   *uint                `json:"admin_id" gorm:"index;comment:操作人(空为系统)"`
+	CreatedAt time.Time            `json:"created_at"`
+
+	Admin *Admin `json:"admin,omitempty" gorm:"foreignKey:AdminID"`
+}
+
+func (ProductRevision) TableName() string {
+	return "product_revisions"
+}
@@ -30,6 +30,7 @@ const (
 	ConfigKeyRegistrationEnabled = "registration_enabled"
 	ConfigKeyMaxUploadSize     = "max_upload_size"
 	ConfigKeyAllowedFileTypes  = "allowed_file_types"
+	ConfigKeyMaxVideoUploadSize = "max_video_upload_size"
 	ConfigKeyDefaultUserRole   = "default_user_role"
 	ConfigKeyPasswordMinLength = "password_min_length"
 	ConfigKeySessionTimeout    = "session_timeout"
@@ -46,72 +47,38 @@ const (
 	ConfigKeyStorageEndpoint   = "storage_endpoint"
 	ConfigKeyStorageAccessKey  = "storage_access_key"
 	ConfigKeyStorageSecretKey  = "storage_secret_key"
+	ConfigKeyStorageBucket     = "storage_bucket"
+	ConfigKeyStorageRegion     = "storage_region"
+	ConfigKeyCouponMaxPerOrder = "coupon_max_per_order"
+	ConfigKeyExportAsyncThreshold = "export_async_threshold"
+	ConfigKeyBudgetAlertThresholds = "budget_alert_thresholds"
+
+	// 维护模式
+	ConfigKeyMaintenanceMessage          = "maintenance_message"
+	ConfigKeyMaintenanceRetryAfter       = "maintenance_retry_after"
+	ConfigKeyMaintenanceAllowedIPs       = "maintenance_allowed_ips"
+	ConfigKeyMaintenanceAllowedCustomers = "maintenance_allowed_customers"
+	ConfigKeyMaintenanceWindows          = "maintenance_windows"
+)
+
+// 允许上传的文件扩展名
+const (
+	DefaultAllowedFileTypes = "jpg,jpeg,png,gif,webp,mp4,mov,webm,avi,pdf,doc,docx,xls,xlsx,ppt,pptx"
+	// LegacyAllowedFileTypes 旧版默认值，不含图片 WebP 与视频格式
+	LegacyAllowedFileTypes = "jpg,jpeg,png,gif,pdf,doc,docx,xls,xlsx,ppt,pptx"
 )
 
-// GetDefaultConfigs 获取默认配置
+// GetDefaultConfigs 获取默认配置（由配置项定义生成）
 func GetDefaultConfigs() map[string]SystemConfig {
-	return map[string]SystemConfig{
-		ConfigKeySystemName: {
-			Key:         ConfigKeySystemName,
-			Value:       "广告平台",
-			Description: "系统名称",
-		},
-		ConfigKeySystemLogo: {
-			Key:         ConfigKeySystemLogo,
-			Value:       "/static/logo.png",
-			Description: "系统Logo",
-		},
-		ConfigKeySystemDescription: {
-			Key:         ConfigKeySystemDescription,
-			Value:       "专业的广告投放管理平台",
-			Description: "系统描述",
-		},
-		ConfigKeyContactEmail: {
-			Key:         ConfigKeyContactEmail,
-			Value:       "admin@example.com",
-			Description: "联系邮箱",
-		},
-		ConfigKeyContactPhone: {
-			Key:         ConfigKeyContactPhone,
-			Value:       "400-000-0000",
-			Description: "联系电话",
-		},
-		ConfigKeyMaintenanceMode: {
-			Key:         ConfigKeyMaintenanceMode,
-			Value:       "false",
-			Description: "维护模式开关",
-		},
-		ConfigKeyRegistrationEnabled: {
-			Key:         ConfigKeyRegistrationEnabled,
-			Value:       "true",
-			Description: "允许用户注册",
-		},
-		ConfigKeyMaxUploadSize: {
-			Key:         ConfigKeyMaxUploadSize,
-			Value:       "10485760", // 10MB
-			Description: "最大上传文件大小(字节)",
-		},
-		ConfigKeyAllowedFileTypes: {
-			Key:         ConfigKeyAllowedFileTypes,
-			Value:       "jpg,jpeg,png,gif,pdf,doc,docx,xls,xlsx,ppt,pptx",
-			Description: "允许上传的文件类型",
-		},
-		ConfigKeyDefaultUserRole: {
-			Key:         ConfigKeyDefaultUserRole,
-			Value:       "1",
-			Description: "默认用户角色",
-		},
-		ConfigKeyPasswordMinLength: {
-			Key:         ConfigKeyPasswordMinLength,
-			Value:       "6",
-			Description: "密码最小长度",
-		},
-		ConfigKeySessionTimeout: {
-			Key:         ConfigKeySessionTimeout,
-			Value:       "3600", // 1小时
-			Description: "会话超时时间(秒)",
-		},
+	configs := make(map[string]SystemConfig, len(configDefinitions))
+	for _, definition := range configDefinitions {
+		configs[definition.Key] = SystemConfig{
+			Key:         definition.Key,
+			Value:       definition.Default,
+			Description: definition.Description,
+		}
 	}
+	return configs
 }
 
 // IsEnabled 检查布尔值配置是否启用
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// SystemConfigHistory 系统配置变更历史，每个配置键的版本号从 1 递增
+// 敏感配置的新旧值均为密文
+type SystemConfigHistory struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Key       string    `json:"key" gorm:"type:varchar(100);not null;uniqueIndex:idx_config_key_version,priority:1"`
+	Version   int       `json:"version" gorm:"not null;uniqueIndex:idx_config_key_version,priority:2"`
+	OldValue  *string   `json:"old_value" gorm:"type:text"` // 为空表示该版本新建配置
+	NewValue  string    `json:"new_value" gorm:"type:text"`
+	ChangedBy uint      `json:"changed_by" gorm:"index"` // 操作管理员ID，0 表示系统
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (SystemConfigHistory) TableName() string {
+	return "system_config_histories"
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConfigType 系统配置值类型
+type ConfigType string
+
+const (
+	ConfigTypeString ConfigType = "string"
+	ConfigTypeInt    ConfigType = "int"
+	ConfigTypeBool   ConfigType = "bool"
+	ConfigTypeList   ConfigType = "list" // 逗号分隔
+	ConfigTypeJSON   ConfigType = "json"
+)
+
+// ConfigMaskedValue 敏感配置对外展示的掩码，提交该值表示保持原值不变
+const ConfigMaskedValue = "******"
+
+// ConfigDefinition 系统配置项定义
+type ConfigDefinition struct {
+	Key         string     `json:"key"`
+	Type        ConfigType `json:"type"`
+	Default     string     `json:"default"`
+	Description string     `json:"description"`
+	Sensitive   bool       `json:"sensitive"` // 加密存储，读取时掩码
+	Required    bool       `json:"required"`  // 不允许为空
+	Min         *int64     `json:"min,omitempty"`
+	Max         *int64     `json:"max,omitempty"`
+	MaxLength   int        `json:"max_length,omitempty"`
+
+	validate func(value string) error
+}
+
+// Validate 按类型与规则校验配置值
+func (d ConfigDefinition) Validate(value string) error {
+	if value == "" {
+		if d.Required || d.Type == ConfigTypeInt || d.Type == ConfigTypeBool {
+			return fmt.Errorf("%s不能为空", d.Description)
+		}
+		return nil
+	}
+	if d.MaxLength > 0 && len([]rune(value)) > d.MaxLength {
+		return fmt.Errorf("%s长度不能超过%d", d.Description, d.MaxLength)
+	}
+
+	switch d.Type {
+	case ConfigTypeInt:
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s应为整数", d.Description)
+		}
+		if d.Min != nil && number < *d.Min {
+			return fmt.Errorf("%s不能小于%d", d.Description, *d.Min)
+		}
+		if d.Max != nil && number > *d.Max {
+			return fmt.Errorf("%s不能大于%d", d.Description, *d.Max)
+		}
+	case ConfigTypeBool:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("%s只能是 true 或 false", d.Description)
+		}
+	case ConfigTypeList:
+		if d.Required && strings.Trim(value, ", ") == "" {
+			return fmt.Errorf("%s不能为空", d.Description)
+		}
+	case ConfigTypeJSON:
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("%s应为合法的 JSON", d.Description)
+		}
+	}
+
+	if d.validate != nil {
+		return d.validate(value)
+	}
+	return nil
+}
+
+// Mask 敏感配置返回掩码（未设置时为空），其余原样返回
+func (d ConfigDefinition) Mask(value string) string {
+	if d.Sensitive && value != "" {
+		return ConfigMaskedValue
+	}
+	return value
+}
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+// validateEmail 邮箱格式校验
+func validateEmail(value string) error {
+	if _, err := mail.ParseAddress(value); err != nil {
+		return fmt.Errorf("邮箱格式不正确: %s", value)
+	}
+	return nil
+}
+
+// validateHTTPURL http/https 地址校验
+func validateHTTPURL(value string) error {
+	if !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+		return fmt.Errorf("地址必须以 http:// 或 https:// 开头")
+	}
+	return nil
+}
+
+// configDefinitions 已登记的系统配置项，未登记的键不允许写入
+var configDefinitions = []ConfigDefinition{
+	// 基础信息
+	{Key: ConfigKeySystemName, Type: ConfigTypeString, Default: "广告平台", Description: "系统名称", Required: true, MaxLength: 255},
+	{Key: ConfigKeySystemLogo, Type: ConfigTypeString, Default: "/static/logo.png", Description: "系统Logo", MaxLength: 500},
+	{Key: ConfigKeySystemDescription, Type: ConfigTypeString, Default: "专业的广告投放管理平台", Description: "系统描述", MaxLength: 1000},
+	{Key: ConfigKeyContactEmail, Type: ConfigTypeString, Default: "admin@example.com", Description: "联系邮箱", MaxLength: 255, validate: validateEmail},
+	{Key: ConfigKeyContactPhone, Type: ConfigTypeString, Default: "400-000-0000", Description: "联系电话", MaxLength: 20},
+
+	// 维护模式
+	{Key: ConfigKeyMaintenanceMode, Type: ConfigTypeBool, Default: "false", Description: "维护模式开关"},
+	{Key: ConfigKeyMaintenanceMessage, Type: ConfigTypeString, Default: DefaultMaintenanceMessage, Description: "维护期间返回给客户端的提示", MaxLength: 500},
+	{Key: ConfigKeyMaintenanceRetryAfter, Type: ConfigTypeInt, Default: "600", Description: "维护期间建议客户端重试的间隔(秒)，计划维护时段内按时段结束时间计算", Min: int64Ptr(0)},
+	{Key: ConfigKeyMaintenanceAllowedIPs, Type: ConfigTypeList, Default: "", Description: "维护期间放行的IP（逗号分隔，支持CIDR）", validate: func(value string) error {
+		_, err := ParseMaintenanceAllowedIPs(value)
+		return err
+	}},
+	{Key: ConfigKeyMaintenanceAllowedCustomers, Type: ConfigTypeList, Default: "", Description: "维护期间放行的客户ID（逗号分隔）", validate: func(value string) error {
+		_, err := ParseMaintenanceAllowedCustomers(value)
+		return err
+	}},
+	{Key: ConfigKeyMaintenanceWindows, Type: ConfigTypeJSON, Default: "[]", Description: "计划维护时段（JSON数组，含 start、end 及可选 message）", validate: func(value string) error {
+		_, err := ParseMaintenanceWindows(value)
+		return err
+	}},
+
+	// 用户与安全
+	{Key: ConfigKeyRegistrationEnabled, Type: ConfigTypeBool, Default: "true", Description: "允许用户注册"},
+	{Key: ConfigKeyDefaultUserRole, Type: ConfigTypeInt, Default: "1", Description: "默认用户角色", Min: int64Ptr(0)},
+	{Key: ConfigKeyPasswordMinLength, Type: ConfigTypeInt, Default: "6", Description: "密码最小长度", Min: int64Ptr(1), Max: int64Ptr(128)},
+	{Key: ConfigKeySessionTimeout, Type: ConfigTypeInt, Default: "3600", Description: "会话超时时间(秒)", Min: int64Ptr(60)},
+
+	// 上传
+	{Key: ConfigKeyMaxUploadSize, Type: ConfigTypeInt, Default: "10485760", Description: "最大上传文件大小(字节)", Min: int64Ptr(1)},
+	{Key: ConfigKeyMaxVideoUploadSize, Type: ConfigTypeInt, Default: "1073741824", Description: "视频断点续传最大文件大小(字节)", Min: int64Ptr(1)},
+	{Key: ConfigKeyAllowedFileTypes, Type: ConfigTypeList, Default: DefaultAllowedFileTypes, Description: "允许上传的文件类型", Required: true},
+
+	// 业务
+	{Key: ConfigKeyCouponMaxPerOrder, Type: ConfigTypeInt, Default: "3", Description: "单笔订单最多可叠加使用的优惠券数量", Min: int64Ptr(1), Max: int64Ptr(5)},
+	{Key: ConfigKeyExportAsyncThreshold, Type: ConfigTypeInt, Default: "5000", Description: "导出行数超过该值时转为异步任务", Min: int64Ptr(1)},
+	{Key: ConfigKeyBudgetAlertThresholds, Type: ConfigTypeList, Default: "50,80,100", Description: "计划预算消耗提醒阈值（百分比，逗号分隔）", validate: func(value string) error {
+		_, err := ParseBudgetAlertThresholds(value)
+		return err
+	}},
+
+	// 邮件
+	{Key: ConfigKeyEmailSMTPHost, Type: ConfigTypeString, Default: "", Description: "SMTP 服务器", MaxLength: 255},
+	{Key: ConfigKeyEmailSMTPPort, Type: ConfigTypeInt, Default: "465", Description: "SMTP 端口", Min: int64Ptr(1), Max: int64Ptr(65535)},
+	{Key: ConfigKeyEmailSMTPUser, Type: ConfigTypeString, Default: "", Description: "SMTP 用户名", MaxLength: 255},
+	{Key: ConfigKeyEmailSMTPPassword, Type: ConfigTypeString, Default: "", Description: "SMTP 密码", Sensitive: true},
+
+	// 支付
+	{Key: ConfigKeyPaymentGateway, Type: ConfigTypeString, Default: "", Description: "支付网关", MaxLength: 100},
+	{Key: ConfigKeyPaymentPublicKey, Type: ConfigTypeString, Default: "", Description: "支付公钥"},
+	{Key: ConfigKeyPaymentPrivateKey, Type: ConfigTypeString, Default: "", Description: "支付私钥", Sensitive: true},
+
+	// 短信
+	{Key: ConfigKeySMSProvider, Type: ConfigTypeString, Default: "", Description: "短信服务商", MaxLength: 100},
+	{Key: ConfigKeySMSAPIKey, Type: ConfigTypeString, Default: "", Description: "短信 API Key", Sensitive: true},
+
+	// 存储
+	{Key: ConfigKeyStorageProvider, Type: ConfigTypeString, Default: "local", Description: "上传文件存储方式（local 本地磁盘 / s3 S3 兼容对象存储）", validate: func(value string) error {
+		if value != "local" && value != "s3" {
+			return fmt.Errorf("存储方式只能是 local 或 s3")
+		}
+		return nil
+	}},
+	{Key: ConfigKeyStorageEndpoint, Type: ConfigTypeString, Default: "", Description: "S3 服务地址，如 https://s3.amazonaws.com 或 http://127.0.0.1:9000", validate: validateHTTPURL},
+	{Key: ConfigKeyStorageBucket, Type: ConfigTypeString, Default: "", Description: "S3 存储桶", MaxLength: 255},
+	{Key: ConfigKeyStorageRegion, Type: ConfigTypeString, Default: "us-east-1", Description: "S3 区域", MaxLength: 100},
+	{Key: ConfigKeyStorageAccessKey, Type: ConfigTypeString, Default: "", Description: "S3 AccessKey", MaxLength: 255},
+	{Key: ConfigKeyStorageSecretKey, Type: ConfigTypeString, Default: "", Description: "S3 SecretKey", Sensitive: true},
+}
+
+var configRegistry = func() map[string]ConfigDefinition {
+	registry := make(map[string]ConfigDefinition, len(configDefinitions))
+	for _, definition := range configDefinitions {
+		registry[definition.Key] = definition
+	}
+	return registry
+}()
+
+// GetConfigDefinition 获取配置项定义，未登记的键返回 false
+func GetConfigDefinition(key string) (ConfigDefinition, bool) {
+	definition, ok := configRegistry[key]
+	return definition, ok
+}
+
+// GetConfigDefinitions 获取全部配置项定义（按键排序）
+func GetConfigDefinitions() []ConfigDefinition {
+	definitions := append([]ConfigDefinition{}, configDefinitions...)
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Key < definitions[j].Key
+	})
+	return definitions
+}
+
+// IsSensitiveConfig 配置是否为敏感配置
+func IsSensitiveConfig(key string) bool {
+	definition, ok := configRegistry[key]
+	return ok && definition.Sensitive
+}
@@ -15,16 +15,18 @@ const (
 )
 
 type UserCoupon struct {
-	ID        uint             `json:"id" gorm:"primaryKey;autoIncrement"`
-	UserID    uint             `json:"user_id" gorm:"not null;index"`
-	CouponID  uint             `json:"coupon_id" gorm:"not null;index"`
-	Status    UserCouponStatus `json:"status" gorm:"type:tinyint;not null;default:1"`
-	UsedAt    *time.Time       `json:"used_at"`
-	ExpiredAt time.Time        `json:"expired_at"`
-	CreatedAt time.Time        `json:"created_at"`
-	UpdatedAt time.Time        `json:"updated_at"`
-	DeletedAt gorm.DeletedAt   `json:"-" gorm:"index"`
-	
+	ID          uint             `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID      uint             `json:"user_id" gorm:"not null;index"`
+	CouponID    uint             `json:"coupon_id" gorm:"not null;index"`
+	Status      UserCouponStatus `json:"status" gorm:"type:tinyint;not null;default:1"`
+	UsedAt      *time.Time       `json:"used_at"`
+	ExpiredAt   time.Time        `json:"expired_at"`
+	PromoCodeID *uint            `json:"promo_code_id" gorm:"index"`               // 兑换来源的兑换码ID
+	PromoCode   string           `json:"promo_code" gorm:"type:varchar(64);index"` // 兑换来源的兑换码
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt   `json:"-" gorm:"index"`
+
 	// 关联
 	Coupon *Coupon `json:"coupon,omitempty" gorm:"foreignKey:CouponID"`
 }
@@ -38,13 +40,13 @@ func (uc *UserCoupon) IsUsable() bool {
 	if uc.Status != UserCouponStatusUnused {
 		return false
 	}
-	
+
 	// 检查是否过期
 	now := time.Now()
 	if now.After(uc.ExpiredAt) {
 		return false
 	}
-	
+
 	return true
 }
 
@@ -70,4 +72,4 @@ func (uc *UserCoupon) CalculateExpiredAt(coupon *Coupon) {
 		// 默认30天有效期
 		uc.ExpiredAt = time.Now().AddDate(0, 0, 30)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LocalStorage 本地磁盘存储，文件通过 /uploads 路由直接访问
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage 创建本地磁盘存储
+func NewLocalStorage(root string) *LocalStorage {
+	if root == "" {
+		root = "uploads"
+	}
+	return &LocalStorage{root: root}
+}
+
+// Provider 返回存储后端类型
+func (ls *LocalStorage) Provider() string {
+	return ProviderLocal
+}
+
+// Path 返回对象在磁盘上的路径
+func (ls *LocalStorage) Path(key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(ls.root, filepath.FromSlash(key)), nil
+}
+
+// Put 写入对象：先写临时文件再重命名，避免读到写了一半的文件
+func (ls *LocalStorage) Put(key string, r io.Reader, size int64, contentType string) error {
+	fullPath, err := ls.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fullPath)
+}
+
+// Get 读取对象
+func (ls *LocalStorage) Get(key string) (io.ReadCloser, *ObjectInfo, error) {
+	fullPath, err := ls.Path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if stat.IsDir() {
+		file.Close()
+		return nil, nil, ErrNotFound
+	}
+
+	cleaned, _ := CleanKey(key)
+	return file, &ObjectInfo{
+		Key:         cleaned,
+		Size:        stat.Size(),
+		ContentType: mime.TypeByExtension(filepath.Ext(fullPath)),
+		ModTime:     stat.ModTime(),
+	}, nil
+}
+
+// Delete 删除对象
+func (ls *LocalStorage) Delete(key string) error {
+	fullPath, err := ls.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// SignedURL 本地文件公开可读，直接返回访问地址
+func (ls *LocalStorage) SignedURL(key string, expires time.Duration) (string, error) {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return URL(cleaned), nil
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readObject 读取对象内容与元信息
+func readObject(t *testing.T, s Storage, key string) ([]byte, *ObjectInfo) {
+	t.Helper()
+	reader, info, err := s.Get(key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read %q: %v", key, err)
+	}
+	return data, info
+}
+
+func TestLocalStorageRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	s := NewLocalStorage(root)
+	key := "products/logos/hello.txt"
+	content := []byte("hello, storage")
+
+	if err := s.Put("/"+key, bytes.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	data, info := readObject(t, s, key)
+	if !bytes.Equal(data, content) {
+		t.Fatalf("Get content = %q, want %q", data, content)
+	}
+	if info.Key != key || info.Size != int64(len(content)) {
+		t.Fatalf("Get info = %+v, want key %q size %d", info, key, len(content))
+	}
+	if !strings.HasPrefix(info.ContentType, "text/plain") {
+		t.Fatalf("Get content type = %q, want text/plain", info.ContentType)
+	}
+
+	// 覆盖写入，且不残留临时文件
+	replaced := []byte("replaced")
+	if err := s.Put(key, bytes.NewReader(replaced), -1, ""); err != nil {
+		t.Fatalf("Put replace: %v", err)
+	}
+	if data, _ := readObject(t, s, key); !bytes.Equal(data, replaced) {
+		t.Fatalf("Get after replace = %q, want %q", data, replaced)
+	}
+	entries, err := os.ReadDir(filepath.Join(root, "products", "logos"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "hello.txt" {
+		t.Fatalf("unexpected files after Put: %v", entries)
+	}
+
+	url, err := s.SignedURL(key, 0)
+	if err != nil || url != URL(key) {
+		t.Fatalf("SignedURL = %q, %v; want %q", url, err, URL(key))
+	}
+	if got, ok := KeyFromURL(url); !ok || got != key {
+		t.Fatalf("KeyFromURL(%q) = %q, %v", url, got, ok)
+	}
+
+	if err := s.Delete(key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, _, err := s.Get(key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(key); err != nil {
+		t.Fatalf("Delete missing object: %v", err)
+	}
+}
+
+func TestLocalStorageRejectsInvalidKeys(t *testing.T) {
+	root := t.TempDir()
+	s := NewLocalStorage(filepath.Join(root, "uploads"))
+
+	for _, key := range []string{"", "/", "../escape.txt", "a/../../escape.txt", `..\escape.txt`} {
+		if err := s.Put(key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("Put(%q) succeeded, want error", key)
+		}
+		if _, _, err := s.Get(key); err == nil {
+			t.Errorf("Get(%q) succeeded, want error", key)
+		}
+		if _, err := s.SignedURL(key, 0); err == nil {
+			t.Errorf("SignedURL(%q) succeeded, want error", key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "escape.txt")); !os.IsNotExist(err) {
+		t.Fatalf("file written outside the storage root: %v", err)
+	}
+}
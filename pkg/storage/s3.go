@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Storage S3 兼容对象存储（路径风格寻址：endpoint/bucket/key），请求按 SigV4 签名
+type S3Storage struct {
+	endpoint *url.URL
+	bucket   string
+	signer   *sigV4Signer
+	client   *http.Client
+}
+
+// NewS3Storage 创建 S3 兼容存储
+func NewS3Storage(cfg Config) (*S3Storage, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3 存储需要配置服务地址与存储桶")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("S3 存储需要配置 AccessKey 与 SecretKey")
+	}
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("S3 服务地址无效: %s", cfg.Endpoint)
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &S3Storage{
+		endpoint: endpoint,
+		bucket:   cfg.Bucket,
+		signer: &sigV4Signer{
+			accessKey: cfg.AccessKey,
+			secretKey: cfg.SecretKey,
+			region:    region,
+		},
+		client: &http.Client{Timeout: 10 * time.Minute},
+	}, nil
+}
+
+// Provider 返回存储后端类型
+func (ss *S3Storage) Provider() string {
+	return ProviderS3
+}
+
+// objectURL 返回对象的请求地址
+func (ss *S3Storage) objectURL(key string) (*url.URL, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	u := *ss.endpoint
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + ss.bucket + "/" + key
+	u.RawPath = ""
+	u.RawQuery = ""
+	return &u, nil
+}
+
+// do 签名并发送请求
+func (ss *S3Storage) do(method, key string, body io.Reader, size int64, contentType string) (*http.Response, error) {
+	u, err := ss.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	payloadHash := emptyPayloadSHA256
+	if body != nil {
+		payloadHash = unsignedPayload
+		req.ContentLength = size
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	ss.signer.signRequest(req, payloadHash, time.Now())
+
+	return ss.client.Do(req)
+}
+
+// Put 上传对象
+func (ss *S3Storage) Put(key string, r io.Reader, size int64, contentType string) error {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	// S3 不接受普通分块传输，长度未知时先读入内存
+	if size < 0 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		r, size = bytes.NewReader(data), int64(len(data))
+	}
+	resp, err := ss.do(http.MethodPut, key, r, size, contentType)
+	if err != nil {
+		return fmt.Errorf("上传到对象存储失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return responseError("上传到对象存储失败", resp)
+	}
+	return nil
+}
+
+// Get 下载对象
+func (ss *S3Storage) Get(key string) (io.ReadCloser, *ObjectInfo, error) {
+	resp, err := ss.do(http.MethodGet, key, nil, 0, "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取对象存储失败: %v", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, nil, ErrNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, nil, responseError("读取对象存储失败", resp)
+	}
+
+	cleaned, _ := CleanKey(key)
+	info := &ObjectInfo{
+		Key:         cleaned,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil {
+		info.Size = size
+	}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = modTime
+	}
+	return resp.Body, info, nil
+}
+
+// Delete 删除对象（S3 对不存在的对象同样返回成功）
+func (ss *S3Storage) Delete(key string) error {
+	resp, err := ss.do(http.MethodDelete, key, nil, 0, "")
+	if err != nil {
+		return fmt.Errorf("删除对象存储文件失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		return responseError("删除对象存储文件失败", resp)
+	}
+	return nil
+}
+
+// SignedURL 生成预签名的 GET 地址，有效期最长 7 天
+func (ss *S3Storage) SignedURL(key string, expires time.Duration) (string, error) {
+	u, err := ss.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	if expires > 7*24*time.Hour {
+		expires = 7 * 24 * time.Hour
+	}
+	return ss.signer.presign(http.MethodGet, u, expires, time.Now()), nil
+}
+
+// responseError 读取 S3 错误响应中的 Code/Message
+func responseError(prefix string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	code := xmlValue(string(body), "Code")
+	message := xmlValue(string(body), "Message")
+	if code == "" {
+		return fmt.Errorf("%s: HTTP %d", prefix, resp.StatusCode)
+	}
+	return fmt.Errorf("%s: %s %s", prefix, code, message)
+}
+
+// xmlValue 取出 XML 中第一个指定标签的文本
+func xmlValue(body, tag string) string {
+	start := strings.Index(body, "<"+tag+">")
+	if start < 0 {
+		return ""
+	}
+	start += len(tag) + 2
+	end := strings.Index(body[start:], "</"+tag+">")
+	if end < 0 {
+		return ""
+	}
+	return body[start : start+end]
+}
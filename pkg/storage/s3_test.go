@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "test-access-key"
+	testSecretKey = "test-secret-key"
+	testBucket    = "media"
+)
+
+// newTestS3 启动本地 S3 兼容服务，返回指向它的 S3Storage
+func newTestS3(t *testing.T, secretKey string) (*S3Storage, *httptest.Server) {
+	t.Helper()
+	server := httptest.NewServer(NewS3StandIn(t.TempDir(), testAccessKey, testSecretKey, ""))
+	t.Cleanup(server.Close)
+
+	s, err := NewS3Storage(Config{
+		Provider:  ProviderS3,
+		Endpoint:  server.URL,
+		Bucket:    testBucket,
+		AccessKey: testAccessKey,
+		SecretKey: secretKey,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage: %v", err)
+	}
+	return s, server
+}
+
+func TestS3StorageRoundTrip(t *testing.T) {
+	s, _ := newTestS3(t, testSecretKey)
+
+	// 含空格、加号与中文的 key 验证签名路径编码
+	for _, key := range []string{"campaigns/videos/demo.txt", "素材/a b+c.txt"} {
+		content := []byte("object body for " + key)
+		if err := s.Put(key, bytes.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+		data, info := readObject(t, s, key)
+		if !bytes.Equal(data, content) {
+			t.Fatalf("Get(%q) content = %q, want %q", key, data, content)
+		}
+		if info.Key != key || info.Size != int64(len(content)) {
+			t.Fatalf("Get(%q) info = %+v", key, info)
+		}
+		if !strings.HasPrefix(info.ContentType, "text/plain") {
+			t.Fatalf("Get(%q) content type = %q, want text/plain", key, info.ContentType)
+		}
+		if info.ModTime.IsZero() {
+			t.Fatalf("Get(%q) missing modification time", key)
+		}
+
+		if err := s.Delete(key); err != nil {
+			t.Fatalf("Delete(%q): %v", key, err)
+		}
+		if _, _, err := s.Get(key); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Get(%q) after Delete error = %v, want ErrNotFound", key, err)
+		}
+	}
+
+	if err := s.Delete("missing/object.txt"); err != nil {
+		t.Fatalf("Delete missing object: %v", err)
+	}
+}
+
+func TestS3StoragePutUnknownSize(t *testing.T) {
+	s, _ := newTestS3(t, testSecretKey)
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+
+	if err := s.Put("logs/unknown-size.bin", io.MultiReader(bytes.NewReader(content)), -1, ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	data, info := readObject(t, s, "logs/unknown-size.bin")
+	if !bytes.Equal(data, content) || info.Size != int64(len(content)) {
+		t.Fatalf("Get returned %d bytes (size %d), want %d", len(data), info.Size, len(content))
+	}
+}
+
+func TestS3StorageSignedURL(t *testing.T) {
+	s, server := newTestS3(t, testSecretKey)
+	key := "products/images/photo.txt"
+	content := []byte("signed content")
+	if err := s.Put(key, bytes.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	signed, err := s.SignedURL(key, time.Hour)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	if !strings.HasPrefix(signed, server.URL+"/"+testBucket+"/"+key+"?") {
+		t.Fatalf("SignedURL = %q, want object address under %s", signed, server.URL)
+	}
+
+	resp, err := http.Get(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(data, content) {
+		t.Fatalf("GET signed URL = %d %q, want 200 %q", resp.StatusCode, data, content)
+	}
+
+	// 签名只对原对象有效
+	other := strings.Replace(signed, "photo.txt", "other.txt", 1)
+	resp, err = http.Get(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("GET tampered signed URL = %d, want 403", resp.StatusCode)
+	}
+
+	// 预签名地址不允许写入
+	req, _ := http.NewRequest(http.MethodPut, signed, strings.NewReader("overwrite"))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("PUT signed URL = %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestS3StorageRejectsWrongSecret(t *testing.T) {
+	s, server := newTestS3(t, "wrong-secret")
+
+	if err := s.Put("a.txt", strings.NewReader("x"), 1, ""); err == nil {
+		t.Fatal("Put with wrong secret succeeded")
+	}
+
+	// 未签名的请求同样被拒绝
+	resp, err := http.Get(server.URL + "/" + testBucket + "/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("unsigned GET = %d, want 403", resp.StatusCode)
+	}
+}
@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxClockSkew 请求时间与服务器时间允许的最大偏差
+const maxClockSkew = 15 * time.Minute
+
+// S3StandIn 本地 S3 兼容服务（类似 MinIO 的最小实现），用于在无外网环境下联调与测试 S3Storage
+// 支持路径风格的 PUT/GET/HEAD/DELETE 对象操作，校验 SigV4 请求头签名与预签名 URL
+type S3StandIn struct {
+	disk   *LocalStorage
+	signer *sigV4Signer
+}
+
+// NewS3StandIn 创建本地 S3 兼容服务，对象保存在 root/bucket/key
+func NewS3StandIn(root, accessKey, secretKey, region string) *S3StandIn {
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3StandIn{
+		disk: NewLocalStorage(root),
+		signer: &sigV4Signer{
+			accessKey: accessKey,
+			secretKey: secretKey,
+			region:    region,
+		},
+	}
+}
+
+// s3Error S3 错误响应
+type s3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+// writeError 输出 S3 格式的错误
+func (s *S3StandIn) writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(s3Error{Code: code, Message: message})
+}
+
+// ServeHTTP 处理对象请求
+func (s *S3StandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		s.writeError(w, http.StatusBadRequest, "InvalidRequest", "只支持路径风格的对象请求: /bucket/key")
+		return
+	}
+	key, err := CleanKey(parts[0] + "/" + parts[1])
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return
+	}
+
+	if err := s.authenticate(r); err != nil {
+		s.writeError(w, http.StatusForbidden, "SignatureDoesNotMatch", err.Error())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		s.put(w, r, key)
+	case http.MethodGet, http.MethodHead:
+		s.get(w, r, key)
+	case http.MethodDelete:
+		if err := s.disk.Delete(key); err != nil {
+			s.writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+// put 保存对象，负载已签名时校验 SHA256
+func (s *S3StandIn) put(w http.ResponseWriter, r *http.Request, key string) {
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	hasher := sha256.New()
+	body := io.TeeReader(r.Body, hasher)
+
+	tmpKey := key + ".uploading-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	if err := s.disk.Put(tmpKey, body, r.ContentLength, r.Header.Get("Content-Type")); err != nil {
+		s.writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	if payloadHash != "" && payloadHash != unsignedPayload && payloadHash != hex.EncodeToString(hasher.Sum(nil)) {
+		_ = s.disk.Delete(tmpKey)
+		s.writeError(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch", "请求体与 x-amz-content-sha256 不一致")
+		return
+	}
+
+	tmpPath, _ := s.disk.Path(tmpKey)
+	fullPath, _ := s.disk.Path(key)
+	if err := os.Rename(tmpPath, fullPath); err != nil {
+		_ = s.disk.Delete(tmpKey)
+		s.writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// get 读取对象，支持 Range 请求
+func (s *S3StandIn) get(w http.ResponseWriter, r *http.Request, key string) {
+	reader, info, err := s.disk.Get(key)
+	if errors.Is(err, ErrNotFound) {
+		s.writeError(w, http.StatusNotFound, "NoSuchKey", "对象不存在")
+		return
+	}
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	defer reader.Close()
+
+	if info.ContentType != "" {
+		w.Header().Set("Content-Type", info.ContentType)
+	}
+	http.ServeContent(w, r, key, info.ModTime, reader.(io.ReadSeeker))
+}
+
+// authenticate 校验请求头签名或预签名 URL
+func (s *S3StandIn) authenticate(r *http.Request) error {
+	query := r.URL.Query()
+	if query.Get("X-Amz-Signature") != "" {
+		return s.authenticatePresigned(r)
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, sigV4Algorithm+" ") {
+		return fmt.Errorf("缺少 SigV4 签名")
+	}
+	fields := make(map[string]string)
+	for _, part := range strings.Split(strings.TrimPrefix(auth, sigV4Algorithm+" "), ",") {
+		if name, value, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+			fields[name] = value
+		}
+	}
+
+	t, err := s.checkCredential(fields["Credential"], r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return err
+	}
+	if time.Since(t) > maxClockSkew || time.Until(t) > maxClockSkew {
+		return fmt.Errorf("请求时间偏差过大")
+	}
+
+	headers := make(map[string]string)
+	for _, name := range strings.Split(fields["SignedHeaders"], ";") {
+		if name == "host" {
+			headers[name] = r.Host
+		} else {
+			headers[name] = strings.Join(r.Header.Values(name), ",")
+		}
+	}
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		return fmt.Errorf("缺少 x-amz-content-sha256")
+	}
+
+	expected, _ := s.signer.signature(r.Method, r.URL.EscapedPath(), query, headers, payloadHash, t)
+	if !hmac.Equal([]byte(expected), []byte(fields["Signature"])) {
+		return fmt.Errorf("签名不匹配")
+	}
+	return nil
+}
+
+// authenticatePresigned 校验预签名 URL（只允许读取）
+func (s *S3StandIn) authenticatePresigned(r *http.Request) error {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return fmt.Errorf("预签名地址只允许读取")
+	}
+	query := r.URL.Query()
+	if query.Get("X-Amz-Algorithm") != sigV4Algorithm {
+		return fmt.Errorf("不支持的签名算法")
+	}
+	t, err := s.checkCredential(query.Get("X-Amz-Credential"), query.Get("X-Amz-Date"))
+	if err != nil {
+		return err
+	}
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || expires < 1 {
+		return fmt.Errorf("X-Amz-Expires 无效")
+	}
+	if time.Now().After(t.Add(time.Duration(expires) * time.Second)) {
+		return fmt.Errorf("预签名地址已过期")
+	}
+
+	signature := query.Get("X-Amz-Signature")
+	query.Del("X-Amz-Signature")
+	expected, _ := s.signer.signature(r.Method, r.URL.EscapedPath(), query, map[string]string{"host": r.Host}, unsignedPayload, t)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return fmt.Errorf("签名不匹配")
+	}
+	return nil
+}
+
+// checkCredential 校验凭证范围并解析请求时间
+func (s *S3StandIn) checkCredential(credential, amzDate string) (time.Time, error) {
+	t, err := time.Parse(sigV4DateFormat, amzDate)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("x-amz-date 无效")
+	}
+	if credential != s.signer.accessKey+"/"+s.signer.scope(t) {
+		return time.Time{}, fmt.Errorf("AccessKey 或凭证范围无效")
+	}
+	return t, nil
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 存储后端类型
+const (
+	ProviderLocal = "local" // 本地磁盘
+	ProviderS3    = "s3"    // S3 兼容对象存储（AWS S3、MinIO 等）
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("对象不存在")
+
+// ObjectInfo 对象元信息
+type ObjectInfo struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	ModTime     time.Time `json:"mod_time"`
+}
+
+// Storage 对象存储接口，key 为不以 / 开头的相对路径，如 products/logos/a.png
+type Storage interface {
+	// Provider 返回存储后端类型
+	Provider() string
+	// Put 写入对象，size 未知时传 -1
+	Put(key string, r io.Reader, size int64, contentType string) error
+	// Get 读取对象，调用方负责关闭返回的 ReadCloser
+	Get(key string) (io.ReadCloser, *ObjectInfo, error)
+	// Delete 删除对象，对象不存在时不报错
+	Delete(key string) error
+	// SignedURL 生成限时访问地址
+	SignedURL(key string, expires time.Duration) (string, error)
+}
+
+// Config 存储配置
+type Config struct {
+	Provider  string // local / s3
+	LocalRoot string // 本地存储根目录
+	Endpoint  string // S3 服务地址，如 https://s3.amazonaws.com 或 http://127.0.0.1:9000
+	Region    string // S3 区域，默认 us-east-1
+	Bucket    string // S3 存储桶
+	AccessKey string
+	SecretKey string
+}
+
+// New 根据配置创建存储后端
+func New(cfg Config) (Storage, error) {
+	switch cfg.Provider {
+	case "", ProviderLocal:
+		return NewLocalStorage(cfg.LocalRoot), nil
+	case ProviderS3:
+		return NewS3Storage(cfg)
+	default:
+		return nil, fmt.Errorf("不支持的存储类型: %s", cfg.Provider)
+	}
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultStorage Storage
+)
+
+// SetDefault 设置全局默认存储
+func SetDefault(s Storage) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultStorage = s
+}
+
+// Default 返回全局默认存储，未设置时使用 uploads/ 目录的本地存储
+func Default() Storage {
+	defaultMu.RLock()
+	s := defaultStorage
+	defaultMu.RUnlock()
+	if s != nil {
+		return s
+	}
+
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultStorage == nil {
+		defaultStorage = NewLocalStorage("uploads")
+	}
+	return defaultStorage
+}
+
+// CleanKey 规范化对象 key，拒绝空 key 与跳出根目录的路径
+func CleanKey(key string) (string, error) {
+	key = strings.TrimLeft(strings.ReplaceAll(key, "\\", "/"), "/")
+	if key == "" {
+		return "", fmt.Errorf("对象路径不能为空")
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("对象路径无效: %s", key)
+		}
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." {
+		return "", fmt.Errorf("对象路径无效: %s", key)
+	}
+	return cleaned, nil
+}
+
+// URLPrefix 上传文件对外访问地址前缀，地址与存储后端无关，由 /uploads 路由按后端读取或跳转
+const URLPrefix = "/uploads/"
+
+// URL 返回对象的对外访问地址
+func URL(key string) string {
+	return URLPrefix + strings.TrimLeft(key, "/")
+}
+
+// KeyFromURL 从对外访问地址中解析对象 key，非本系统上传的地址返回 false
+func KeyFromURL(url string) (string, bool) {
+	if !strings.HasPrefix(url, URLPrefix) {
+		return "", false
+	}
+	key, err := CleanKey(strings.TrimPrefix(url, URLPrefix))
+	if err != nil {
+		return "", false
+	}
+	return key, true
+}
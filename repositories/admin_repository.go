@@ -155,4 +155,31 @@ func (r *AdminRepository) AssignRoles(adminID uint, roleIDs []uint) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+// permissionQuery 关联角色与权限，筛选拥有指定权限代码的管理员
+func (r *AdminRepository) permissionQuery(code string) *gorm.DB {
+	return r.db.Model(&models.Admin{}).
+		Joins("JOIN admin_roles ON admin_roles.admin_id = admins.id").
+		Joins("JOIN roles ON roles.id = admin_roles.role_id AND roles.status = 1 AND roles.deleted_at IS NULL").
+		Joins("JOIN role_permissions ON role_permissions.role_id = roles.id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id AND permissions.status = 1 AND permissions.deleted_at IS NULL").
+		Where("permissions.code = ?", code)
+}
+
+// GetAdminsWithPermission 获取拥有指定权限代码的激活管理员
+func (r *AdminRepository) GetAdminsWithPermission(code string) ([]*models.Admin, error) {
+	var admins []*models.Admin
+	err := r.permissionQuery(code).
+		Distinct("admins.*").
+		Where("admins.status = ?", models.AdminStatusActive).
+		Order("admins.id ASC").
+		Find(&admins).Error
+	return admins, err
+}
+
+// HasPermission 检查管理员是否拥有指定权限代码
+func (r *AdminRepository) HasPermission(adminID uint, code string) (bool, error) {
+	var count int64
+	err := r.permissionQuery(code).Where("admins.id = ?", adminID).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"fmt"
+
+	"backend/database"
+	"backend/models"
+	"gorm.io/gorm"
+)
+
+// AgentRepository 代理商仓库
+type AgentRepository struct {
+	db *gorm.DB
+}
+
+// NewAgentRepository 创建代理商仓库
+func NewAgentRepository() *AgentRepository {
+	return &AgentRepository{
+		db: database.DB,
+	}
+}
+
+// GetByAdminID 根据管理员ID获取代理商
+func (ar *AgentRepository) GetByAdminID(adminID uint) (*models.Agent, error) {
+	var agent models.Agent
+	if err := ar.db.Where("admin_id = ?", adminID).First(&agent).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("代理商不存在")
+		}
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// GetByInviteCode 根据邀请码获取代理商
+func (ar *AgentRepository) GetByInviteCode(inviteCode string) (*models.Agent, error) {
+	var agent models.Agent
+	if err := ar.db.Where("invite_code = ?", inviteCode).First(&agent).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("代理商不存在")
+		}
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// GetAgentChain 获取代理及其所有上级代理的AdminID（由下至上）
+func (ar *AgentRepository) GetAgentChain(adminID uint) ([]uint, error) {
+	chain := []uint{adminID}
+	seen := map[uint]bool{adminID: true}
+
+	current := adminID
+	for {
+		var agent models.Agent
+		err := ar.db.Select("admin_id", "parent_admin_id").Where("admin_id = ?", current).First(&agent).Error
+		if err == gorm.ErrRecordNotFound {
+			return chain, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// 防止层级数据异常导致死循环
+		if agent.ParentAdminID == nil || seen[*agent.ParentAdminID] {
+			return chain, nil
+		}
+		current = *agent.ParentAdminID
+		seen[current] = true
+		chain = append(chain, current)
+	}
+}
@@ -0,0 +1,262 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/database"
+	"backend/models"
+	"backend/types"
+	"gorm.io/gorm"
+)
+
+// AssetRepository 素材仓库
+type AssetRepository struct {
+	db *gorm.DB
+}
+
+// NewAssetRepository 创建素材仓库
+func NewAssetRepository() *AssetRepository {
+	return &AssetRepository{
+		db: database.DB,
+	}
+}
+
+// Create 创建素材
+func (ar *AssetRepository) Create(asset *models.Asset) error {
+	return ar.db.Create(asset).Error
+}
+
+// GetByID 根据ID获取素材（含引用）
+func (ar *AssetRepository) GetByID(id uint) (*models.Asset, error) {
+	var asset models.Asset
+	if err := ar.db.Preload("References", func(db *gorm.DB) *gorm.DB {
+		return db.Order("owner_type, owner_id, field")
+	}).First(&asset, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("素材不存在")
+		}
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// FindByHash 按内容哈希查找素材，先上传的在前
+func (ar *AssetRepository) FindByHash(hash string) ([]*models.Asset, error) {
+	var assets []*models.Asset
+	err := ar.db.Where("hash = ?", hash).Order("id ASC").Find(&assets).Error
+	return assets, err
+}
+
+// FindByURLs 按访问地址批量查找素材（地址可以是原图或缩略图、WebP 变体）
+func (ar *AssetRepository) FindByURLs(urls []string) ([]*models.Asset, error) {
+	var assets []*models.Asset
+	if len(urls) == 0 {
+		return assets, nil
+	}
+	err := ar.db.Where("url IN ? OR thumbnail IN ? OR webp IN ?", urls, urls, urls).Find(&assets).Error
+	return assets, err
+}
+
+// List 获取素材列表；Search 匹配文件名、地址或哈希前缀，Category 为分类
+func (ar *AssetRepository) List(req *types.FilterRequest, mimeType string, ownerID *uint, referenced *bool) ([]*models.Asset, int64, error) {
+	var assets []*models.Asset
+	var total int64
+
+	query := ar.db.Model(&models.Asset{})
+
+	if req.Search != "" {
+		searchPattern := "%" + req.Search + "%"
+		query = query.Where("filename LIKE ? OR url LIKE ? OR hash LIKE ?", searchPattern, searchPattern, req.Search+"%")
+	}
+	if req.Category != "" {
+		query = query.Where("category = ?", req.Category)
+	}
+	if mimeType != "" {
+		query = query.Where("mime_type = ?", mimeType)
+	}
+	if ownerID != nil {
+		query = query.Where("owner_id = ?", *ownerID)
+	}
+	if referenced != nil {
+		if *referenced {
+			query = query.Where("ref_count > 0")
+		} else {
+			query = query.Where("ref_count = 0")
+		}
+	}
+	if req.StartDate != nil {
+		query = query.Where("created_at >= ?", req.StartDate)
+	}
+	if req.EndDate != nil {
+		query = query.Where("created_at <= ?", req.EndDate)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 排序字段白名单
+	sortField := "id"
+	switch req.GetSort() {
+	case "size", "created_at", "ref_count", "filename":
+		sortField = req.GetSort()
+	}
+	if err := query.Order(sortField + " " + req.GetOrder()).
+		Offset(req.GetOffset()).
+		Limit(req.GetSize()).
+		Find(&assets).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return assets, total, nil
+}
+
+// ListOrphans 获取在 before 之前就已无引用的素材
+func (ar *AssetRepository) ListOrphans(before time.Time, limit int) ([]*models.Asset, error) {
+	var assets []*models.Asset
+	err := ar.db.Where("ref_count = 0 AND orphaned_at IS NOT NULL AND orphaned_at <= ?", before).
+		Order("orphaned_at ASC").
+		Limit(limit).
+		Find(&assets).Error
+	return assets, err
+}
+
+// Touch 无引用的素材从 now 起重新计算宽限期（重复上传命中已有素材时调用）
+func (ar *AssetRepository) Touch(id uint, now time.Time) error {
+	return ar.db.Model(&models.Asset{}).
+		Where("id = ? AND ref_count = 0", id).
+		Update("orphaned_at", now).Error
+}
+
+// DeleteOrphan 素材仍无引用且在 before 之前就已无引用时删除，返回是否删除
+func (ar *AssetRepository) DeleteOrphan(id uint, before time.Time) (bool, error) {
+	result := ar.db.Where("id = ? AND ref_count = 0 AND orphaned_at IS NOT NULL AND orphaned_at <= ?", id, before).
+		Delete(&models.Asset{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Delete 删除素材及其引用
+func (ar *AssetRepository) Delete(id uint) error {
+	return ar.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("asset_id = ?", id).Delete(&models.AssetReference{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Asset{}, id).Error
+	})
+}
+
+// ReplaceReferences 重建某个引用方的全部引用，并重新统计受影响素材的引用数
+func (ar *AssetRepository) ReplaceReferences(ownerType string, ownerID uint, refs []models.AssetReference) error {
+	return ar.db.Transaction(func(tx *gorm.DB) error {
+		var affected []uint
+		if err := tx.Model(&models.AssetReference{}).
+			Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).
+			Distinct().
+			Pluck("asset_id", &affected).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).
+			Delete(&models.AssetReference{}).Error; err != nil {
+			return err
+		}
+		if len(refs) > 0 {
+			if err := tx.Create(&refs).Error; err != nil {
+				return err
+			}
+		}
+		for _, ref := range refs {
+			affected = append(affected, ref.AssetID)
+		}
+		if len(affected) == 0 {
+			return nil
+		}
+
+		// 引用数归零时记录时间，供清理任务计算宽限期；重新被引用时清除
+		counts := tx.Model(&models.AssetReference{}).
+			Select("COUNT(*)").
+			Where("asset_references.asset_id = assets.id")
+		if err := tx.Model(&models.Asset{}).
+			Where("id IN ?", affected).
+			Update("ref_count", counts).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Asset{}).
+			Where("id IN ? AND ref_count > 0", affected).
+			Update("orphaned_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Asset{}).
+			Where("id IN ? AND ref_count = 0 AND orphaned_at IS NULL", affected).
+			Update("orphaned_at", time.Now()).Error
+	})
+}
+
+// FindHolders 直接检查各业务表是否仍在使用这些地址，返回使用方；清理前兜底核对，
+// 避免引用表因导入、直接改库等途径未同步而误删文件
+func (ar *AssetRepository) FindHolders(urls []string) ([]models.AssetReference, error) {
+	var holders []models.AssetReference
+	if len(urls) == 0 {
+		return holders, nil
+	}
+
+	// 简介、应用信息、投放内容等文本与 JSON 字段中嵌入的地址按子串匹配
+	var productIDs []uint
+	conditions, args := containsAny([]string{"description", "app_info"}, urls)
+	if err := ar.db.Model(&models.Product{}).Where("logo IN ? OR "+conditions, append([]interface{}{urls}, args...)...).Pluck("id", &productIDs).Error; err != nil {
+		return nil, err
+	}
+	// 历史版本快照引用的文件在恢复版本时仍需使用
+	var revisionProductIDs []uint
+	conditions, args = containsAny([]string{"snapshot"}, urls)
+	if err := ar.db.Model(&models.ProductRevision{}).Where(conditions, args...).Distinct().Pluck("product_id", &revisionProductIDs).Error; err != nil {
+		return nil, err
+	}
+	productIDs = append(productIDs, revisionProductIDs...)
+	var imageProductIDs []uint
+	if err := ar.db.Model(&models.ProductImage{}).Where("url IN ?", urls).Distinct().Pluck("product_id", &imageProductIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range append(productIDs, imageProductIDs...) {
+		holders = append(holders, models.AssetReference{OwnerType: models.AssetOwnerProduct, OwnerID: id})
+	}
+
+	var campaignIDs []uint
+	conditions, args = containsAny([]string{"description", "delivery_content", "delivery_rules"}, urls)
+	if err := ar.db.Model(&models.Campaign{}).Where("main_image IN ? OR video IN ? OR "+conditions, append([]interface{}{urls, urls}, args...)...).Pluck("id", &campaignIDs).Error; err != nil {
+		return nil, err
+	}
+	var creativeCampaignIDs []uint
+	conditions, args = containsAny([]string{"delivery_content"}, urls)
+	if err := ar.db.Model(&models.CampaignCreative{}).Where("main_image IN ? OR video IN ? OR "+conditions, append([]interface{}{urls, urls}, args...)...).Distinct().Pluck("campaign_id", &creativeCampaignIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range append(campaignIDs, creativeCampaignIDs...) {
+		holders = append(holders, models.AssetReference{OwnerType: models.AssetOwnerCampaign, OwnerID: id})
+	}
+
+	// 模板创意以 JSON 保存，同样按字符串匹配
+	var templateIDs []uint
+	conditions, args = containsAny([]string{"description", "delivery_content", "delivery_rules", "creatives"}, urls)
+	if err := ar.db.Model(&models.CampaignTemplate{}).Where("main_image IN ? OR video IN ? OR "+conditions, append([]interface{}{urls, urls}, args...)...).Pluck("id", &templateIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range templateIDs {
+		holders = append(holders, models.AssetReference{OwnerType: models.AssetOwnerCampaignTemplate, OwnerID: id})
+	}
+	return holders, nil
+}
+
+// containsAny 生成"任一列包含任一地址"的查询条件；LIKE 通配符未转义，只会多匹配，不会漏掉引用
+func containsAny(columns []string, urls []string) (string, []interface{}) {
+	conditions := make([]string, 0, len(columns)*len(urls))
+	args := make([]interface{}, 0, len(columns)*len(urls))
+	for _, column := range columns {
+		for _, url := range urls {
+			conditions = append(conditions, column+" LIKE ?")
+			args = append(args, "%"+url+"%")
+		}
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
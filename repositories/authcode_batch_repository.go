@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"fmt"
+
+	"backend/database"
+	"backend/models"
+	"backend/types"
+	"gorm.io/gorm"
+)
+
+// AuthCodeBatchRepository 授权码批次仓库
+type AuthCodeBatchRepository struct {
+	db *gorm.DB
+}
+
+// NewAuthCodeBatchRepository 创建授权码批次仓库
+func NewAuthCodeBatchRepository() *AuthCodeBatchRepository {
+	return &AuthCodeBatchRepository{
+		db: database.DB,
+	}
+}
+
+// CreateWithCodes 在同一事务中创建批次及其授权码
+func (abr *AuthCodeBatchRepository) CreateWithCodes(batch *models.AuthCodeBatch, authCodes []*models.AuthCode) error {
+	return abr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("AuthCodes").Create(batch).Error; err != nil {
+			return err
+		}
+
+		for _, authCode := range authCodes {
+			authCode.BatchID = &batch.ID
+		}
+
+		return tx.CreateInBatches(authCodes, 500).Error
+	})
+}
+
+// List 获取批次列表
+func (abr *AuthCodeBatchRepository) List(req *types.FilterRequest) ([]*models.AuthCodeBatch, int64, error) {
+	var batches []*models.AuthCodeBatch
+	var total int64
+
+	query := abr.db.Model(&models.AuthCodeBatch{})
+
+	// 搜索条件
+	if req.Search != "" {
+		searchPattern := "%" + req.Search + "%"
+		query = query.Where("name LIKE ? OR prefix LIKE ? OR notes LIKE ?", searchPattern, searchPattern, searchPattern)
+	}
+
+	// 日期范围筛选
+	if req.StartDate != nil {
+		query = query.Where("created_at >= ?", req.StartDate)
+	}
+	if req.EndDate != nil {
+		query = query.Where("created_at <= ?", req.EndDate)
+	}
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 排序和分页
+	orderClause := fmt.Sprintf("%s %s", req.GetSort(), req.GetOrder())
+	if err := query.Order(orderClause).
+		Offset(req.GetOffset()).
+		Limit(req.GetSize()).
+		Find(&batches).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := abr.fillUsedCounts(batches); err != nil {
+		return nil, 0, err
+	}
+
+	return batches, total, nil
+}
+
+// GetByID 根据ID获取批次
+func (abr *AuthCodeBatchRepository) GetByID(id uint) (*models.AuthCodeBatch, error) {
+	var batch models.AuthCodeBatch
+	if err := abr.db.First(&batch, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("授权码批次不存在")
+		}
+		return nil, err
+	}
+
+	if err := abr.fillUsedCounts([]*models.AuthCodeBatch{&batch}); err != nil {
+		return nil, err
+	}
+
+	return &batch, nil
+}
+
+// fillUsedCounts 填充批次的已使用数量
+func (abr *AuthCodeBatchRepository) fillUsedCounts(batches []*models.AuthCodeBatch) error {
+	if len(batches) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(batches))
+	for i, batch := range batches {
+		ids[i] = batch.ID
+	}
+
+	var stats []struct {
+		BatchID uint
+		Count   int64
+	}
+	if err := abr.db.Model(&models.AuthCode{}).
+		Select("batch_id, COUNT(*) as count").
+		Where("batch_id IN ?", ids).
+		Where("status = ?", models.AuthCodeStatusUsed).
+		Group("batch_id").
+		Scan(&stats).Error; err != nil {
+		return err
+	}
+
+	counts := make(map[uint]int64, len(stats))
+	for _, stat := range stats {
+		counts[stat.BatchID] = stat.Count
+	}
+	for _, batch := range batches {
+		batch.UsedCount = counts[batch.ID]
+	}
+
+	return nil
+}
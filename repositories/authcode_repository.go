@@ -9,6 +9,7 @@ import (
 	"backend/models"
 	"backend/types"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // AuthCodeRepository 授权码仓库
@@ -28,6 +29,187 @@ func (acr *AuthCodeRepository) List(req *types.FilterRequest) ([]*models.AuthCod
 	var authCodes []*models.AuthCode
 	var total int64
 
+	query := acr.filterQuery(req)
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 排序和分页
+	orderClause := fmt.Sprintf("%s %s", req.GetSort(), req.GetOrder())
+	if err := query.Order(orderClause).
+		Offset(req.GetOffset()).
+		Limit(req.GetSize()).
+		Find(&authCodes).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return authCodes, total, nil
+}
+
+// GetByID 根据ID获取授权码
+func (acr *AuthCodeRepository) GetByID(id uint) (*models.AuthCode, error) {
+	var authCode models.AuthCode
+	if err := acr.db.First(&authCode, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("授权码不存在")
+		}
+		return nil, err
+	}
+	return &authCode, nil
+}
+
+// GetByCode 根据代码获取授权码
+func (acr *AuthCodeRepository) GetByCode(code string) (*models.AuthCode, error) {
+	var authCode models.AuthCode
+	if err := acr.db.Preload("Batch").Where("code = ?", code).First(&authCode).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("授权码不存在")
+		}
+		return nil, err
+	}
+	return &authCode, nil
+}
+
+// ExistsCodes 返回已存在的授权码集合
+func (acr *AuthCodeRepository) ExistsCodes(codes []string) (map[string]bool, error) {
+	var existing []string
+	if err := acr.db.Unscoped().Model(&models.AuthCode{}).
+		Where("code IN ?", codes).
+		Pluck("code", &existing).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]bool, len(existing))
+	for _, code := range existing {
+		result[code] = true
+	}
+	return result, nil
+}
+
+// ListByBatch 获取批次下的授权码列表
+func (acr *AuthCodeRepository) ListByBatch(batchID uint, req *types.FilterRequest) ([]*models.AuthCode, int64, error) {
+	var authCodes []*models.AuthCode
+	var total int64
+
+	query := acr.db.Model(&models.AuthCode{}).Where("batch_id = ?", batchID)
+
+	// 搜索条件
+	if req.Search != "" {
+		query = query.Where("code LIKE ?", "%"+req.Search+"%")
+	}
+
+	// 状态筛选
+	if req.Status != nil {
+		query = query.Where("status = ?", *req.Status)
+	}
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 排序和分页
+	orderClause := fmt.Sprintf("%s %s", req.GetSort(), req.GetOrder())
+	if err := query.Order(orderClause).
+		Offset(req.GetOffset()).
+		Limit(req.GetSize()).
+		Find(&authCodes).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return authCodes, total, nil
+}
+
+// Redeem 兑换授权码：条件扣减授权码次数，并在同一事务中发放权益、记录兑换历史
+func (acr *AuthCodeRepository) Redeem(authCode *models.AuthCode, userID uint, clientIP string) (*models.AuthCodeGrant, error) {
+	var grant *models.AuthCodeGrant
+	err := acr.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// 仅当授权码仍可用且未达兑换上限时更新，防止并发超额兑换
+		result := tx.Model(&models.AuthCode{}).
+			Where("id = ?", authCode.ID).
+			Where("status = ?", models.AuthCodeStatusUnused).
+			Where("expired_at > ?", now).
+			Where("max_uses = 0 OR used_count < max_uses").
+			Updates(map[string]interface{}{
+				"used_count": gorm.Expr("used_count + 1"),
+				"used_at":    now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("授权码已被使用或已失效")
+		}
+
+		// 上面的更新已锁定授权码行，同一授权码的兑换在此串行执行
+		var customerUses int64
+		if err := tx.Model(&models.AuthCodeRedemption{}).
+			Where("auth_code_id = ? AND customer_id = ?", authCode.ID, userID).
+			Count(&customerUses).Error; err != nil {
+			return err
+		}
+		perCustomerUses := authCode.PerCustomerUses
+		if perCustomerUses <= 0 {
+			perCustomerUses = 1
+		}
+		if customerUses >= int64(perCustomerUses) {
+			return fmt.Errorf("您已达到该授权码的兑换次数上限")
+		}
+
+		// 单次授权码记录使用者；次数用完后标记为已使用（max_uses 为 0 表示不限次数）
+		if authCode.MaxUses == 1 {
+			if err := tx.Model(&models.AuthCode{}).
+				Where("id = ?", authCode.ID).
+				Update("used_by", userID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.AuthCode{}).
+			Where("id = ? AND max_uses > 0 AND used_count >= max_uses", authCode.ID).
+			Update("status", models.AuthCodeStatusUsed).Error; err != nil {
+			return err
+		}
+
+		var err error
+		grant, err = acr.ApplyEntitlement(tx, authCode, userID)
+		if err != nil {
+			return err
+		}
+
+		redemption := &models.AuthCodeRedemption{
+			AuthCodeID:    authCode.ID,
+			CustomerID:    userID,
+			BatchID:       authCode.BatchID,
+			BalanceCredit: grant.BalanceCredit,
+			TransactionID: grant.TransactionID,
+			ClientIP:      clientIP,
+		}
+		if err := tx.Create(redemption).Error; err != nil {
+			return err
+		}
+
+		authCode.UsedCount++
+		authCode.UsedAt = &now
+		if authCode.MaxUses == 1 {
+			authCode.UsedBy = &userID
+		}
+		if authCode.MaxUses > 0 && authCode.UsedCount >= authCode.MaxUses {
+			authCode.Status = models.AuthCodeStatusUsed
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+// filterQuery 构建列表/导出共用的筛选条件
+func (acr *AuthCodeRepository) filterQuery(req *types.FilterRequest) *gorm.DB {
 	query := acr.db.Model(&models.AuthCode{})
 
 	// 搜索条件
@@ -49,45 +231,204 @@ func (acr *AuthCodeRepository) List(req *types.FilterRequest) ([]*models.AuthCod
 		query = query.Where("created_at <= ?", req.EndDate)
 	}
 
+	return query
+}
+
+// CountByFilter 按筛选条件统计数量
+func (acr *AuthCodeRepository) CountByFilter(req *types.FilterRequest) (int64, error) {
+	var total int64
+	err := acr.filterQuery(req).Count(&total).Error
+	return total, err
+}
+
+// Cursor 按筛选条件以数据库游标逐行遍历（用于导出）
+func (acr *AuthCodeRepository) Cursor(req *types.FilterRequest, fn func(authCode *models.AuthCode) error) error {
+	rows, err := acr.filterQuery(req).Order("id ASC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var authCode models.AuthCode
+		if err := acr.db.ScanRows(rows, &authCode); err != nil {
+			return err
+		}
+		if err := fn(&authCode); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ListRedemptions 获取授权码兑换记录
+func (acr *AuthCodeRepository) ListRedemptions(req *types.FilterRequest, authCodeID *uint) ([]*models.AuthCodeRedemption, int64, error) {
+	var redemptions []*models.AuthCodeRedemption
+	var total int64
+
+	query := acr.db.Model(&models.AuthCodeRedemption{})
+
+	if authCodeID != nil {
+		query = query.Where("auth_code_redemptions.auth_code_id = ?", *authCodeID)
+	}
+
+	// 搜索条件（授权码/客户名称/邮箱）
+	if req.Search != "" {
+		searchPattern := "%" + req.Search + "%"
+		query = query.Joins("JOIN auth_codes ON auth_codes.id = auth_code_redemptions.auth_code_id").
+			Joins("LEFT JOIN customers ON customers.id = auth_code_redemptions.customer_id").
+			Where("auth_codes.code LIKE ? OR customers.name LIKE ? OR customers.email LIKE ?",
+				searchPattern, searchPattern, searchPattern)
+	}
+
+	// 日期范围筛选
+	if req.StartDate != nil {
+		query = query.Where("auth_code_redemptions.created_at >= ?", req.StartDate)
+	}
+	if req.EndDate != nil {
+		query = query.Where("auth_code_redemptions.created_at <= ?", req.EndDate)
+	}
+
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	// 排序和分页
-	orderClause := fmt.Sprintf("%s %s", req.GetSort(), req.GetOrder())
-	if err := query.Order(orderClause).
+	if err := query.Preload("AuthCode").Preload("Customer").
+		Order("auth_code_redemptions.created_at DESC").
 		Offset(req.GetOffset()).
 		Limit(req.GetSize()).
-		Find(&authCodes).Error; err != nil {
+		Find(&redemptions).Error; err != nil {
 		return nil, 0, err
 	}
 
-	return authCodes, total, nil
+	return redemptions, total, nil
 }
 
-// GetByID 根据ID获取授权码
-func (acr *AuthCodeRepository) GetByID(id uint) (*models.AuthCode, error) {
-	var authCode models.AuthCode
-	if err := acr.db.First(&authCode, id).Error; err != nil {
+// ApplyEntitlement 为用户发放授权码所属批次的权益
+func (acr *AuthCodeRepository) ApplyEntitlement(tx *gorm.DB, authCode *models.AuthCode, userID uint) (*models.AuthCodeGrant, error) {
+	grant := &models.AuthCodeGrant{
+		UserCoupons: []*models.UserCoupon{},
+		ProductIDs:  []uint{},
+	}
+
+	if authCode.Batch == nil || authCode.Batch.Entitlement.IsEmpty() {
+		return grant, nil
+	}
+	entitlement := authCode.Batch.Entitlement
+
+	// 锁定客户记录，保证余额变更前后值准确
+	var customer models.Customer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&customer, userID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("授权码不存在")
+			return nil, fmt.Errorf("用户不存在")
 		}
 		return nil, err
 	}
-	return &authCode, nil
-}
 
-// GetByCode 根据代码获取授权码
-func (acr *AuthCodeRepository) GetByCode(code string) (*models.AuthCode, error) {
-	var authCode models.AuthCode
-	if err := acr.db.Where("code = ?", code).First(&authCode).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("授权码不存在")
+	// 激活账户：仅处理未激活账户，被禁用的账户不允许兑换
+	if entitlement.ActivateAccount {
+		if customer.IsBlocked() {
+			return nil, fmt.Errorf("账户已被禁用")
+		}
+		if customer.Status == models.CustomerStatusInactive {
+			if err := tx.Model(&models.Customer{}).
+				Where("id = ?", userID).
+				Update("status", models.CustomerStatusActive).Error; err != nil {
+				return nil, err
+			}
+			grant.AccountActivated = true
 		}
-		return nil, err
 	}
-	return &authCode, nil
+
+	// 赠送余额：记录奖励交易并增加客户余额
+	if entitlement.BalanceCredit > 0 {
+
+		now := time.Now()
+		transaction := &models.Transaction{
+			UserID:        userID,
+			Type:          models.TransactionTypeReward,
+			Amount:        entitlement.BalanceCredit,
+			Status:        models.TransactionStatusSuccess,
+			Description:   fmt.Sprintf("授权码兑换奖励（%s）", authCode.Code),
+			PaymentMethod: "auth_code",
+			BalanceBefore: customer.Balance,
+			BalanceAfter:  customer.Balance + entitlement.BalanceCredit,
+			ProcessedAt:   &now,
+		}
+		if err := tx.Create(transaction).Error; err != nil {
+			return nil, err
+		}
+
+		if err := tx.Model(&models.Customer{}).
+			Where("id = ?", userID).
+			Update("balance", gorm.Expr("balance + ?", entitlement.BalanceCredit)).Error; err != nil {
+			return nil, err
+		}
+
+		grant.BalanceCredit = entitlement.BalanceCredit
+		grant.TransactionID = &transaction.ID
+	}
+
+	// 发放优惠券：已持有的优惠券跳过，库存不足时失败
+	for _, couponID := range entitlement.CouponIDs {
+		var coupon models.Coupon
+		if err := tx.First(&coupon, couponID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, fmt.Errorf("优惠券不存在")
+			}
+			return nil, err
+		}
+
+		var exists int64
+		if err := tx.Model(&models.UserCoupon{}).
+			Where("user_id = ? AND coupon_id = ?", userID, couponID).
+			Count(&exists).Error; err != nil {
+			return nil, err
+		}
+		if exists > 0 {
+			continue
+		}
+
+		result := tx.Model(&models.Coupon{}).
+			Where("id = ?", couponID).
+			Where("(total_count = 0 OR used_count < total_count)").
+			Update("used_count", gorm.Expr("used_count + 1"))
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil, fmt.Errorf("优惠券已被领完")
+		}
+
+		userCoupon := &models.UserCoupon{
+			UserID:   userID,
+			CouponID: couponID,
+			Status:   models.UserCouponStatusUnused,
+		}
+		userCoupon.CalculateExpiredAt(&coupon)
+		if err := tx.Create(userCoupon).Error; err != nil {
+			return nil, err
+		}
+
+		grant.UserCoupons = append(grant.UserCoupons, userCoupon)
+	}
+
+	// 开通产品访问权限
+	for _, productID := range entitlement.ProductIDs {
+		access := models.CustomerProductAccess{
+			CustomerID: userID,
+			ProductID:  productID,
+			AuthCodeID: &authCode.ID,
+		}
+		if err := tx.Where("customer_id = ? AND product_id = ?", userID, productID).
+			FirstOrCreate(&access).Error; err != nil {
+			return nil, err
+		}
+		grant.ProductIDs = append(grant.ProductIDs, productID)
+	}
+
+	return grant, nil
 }
 
 // Create 创建授权码
@@ -180,20 +521,25 @@ func (acr *AuthCodeRepository) GetUsedByUser(userID uint, req *types.FilterReque
 	var authCodes []*models.AuthCode
 	var total int64
 
-	query := acr.db.Model(&models.AuthCode{}).Where("used_by = ?", userID)
-
-	// 搜索条件
-	if req.Search != "" {
-		searchPattern := "%" + req.Search + "%"
-		query = query.Where("code LIKE ?", searchPattern)
-	}
+	// 以兑换记录为准，兼容多次兑换的授权码
+	redemptions := acr.db.Model(&models.AuthCodeRedemption{}).
+		Select("auth_code_id").
+		Where("customer_id = ?", userID)
 
 	// 日期范围筛选
 	if req.StartDate != nil {
-		query = query.Where("used_at >= ?", req.StartDate)
+		redemptions = redemptions.Where("created_at >= ?", req.StartDate)
 	}
 	if req.EndDate != nil {
-		query = query.Where("used_at <= ?", req.EndDate)
+		redemptions = redemptions.Where("created_at <= ?", req.EndDate)
+	}
+
+	query := acr.db.Model(&models.AuthCode{}).Where("id IN (?)", redemptions)
+
+	// 搜索条件
+	if req.Search != "" {
+		searchPattern := "%" + req.Search + "%"
+		query = query.Where("code LIKE ?", searchPattern)
 	}
 
 	// 获取总数
@@ -233,28 +579,35 @@ func (acr *AuthCodeRepository) Search(keyword string, limit int) ([]*models.Auth
 // GetStatistics 获取授权码统计
 func (acr *AuthCodeRepository) GetStatistics() (*types.StatisticsResponse, error) {
 	var total int64
-	var used int64
-	var expired int64
+	var available int64
 
 	// 总数统计
 	if err := acr.db.Model(&models.AuthCode{}).Count(&total).Error; err != nil {
 		return nil, err
 	}
 
-	// 已使用数量
+	// 可用数量（未使用、未过期且仍有剩余兑换次数）
 	if err := acr.db.Model(&models.AuthCode{}).
-		Where("status = ?", models.AuthCodeStatusUsed).Count(&used).Error; err != nil {
+		Where("status = ?", models.AuthCodeStatusUnused).
+		Where("expired_at > NOW()").
+		Where("max_uses = 0 OR used_count < max_uses").Count(&available).Error; err != nil {
 		return nil, err
 	}
 
-	// 已过期数量
-	if err := acr.db.Model(&models.AuthCode{}).
-		Where("status = ? OR expired_at <= NOW()", models.AuthCodeStatusExpired).Count(&expired).Error; err != nil {
+	// 多次兑换码数量及部分兑换数量
+	var multiUse int64
+	var partiallyUsed int64
+	acr.db.Model(&models.AuthCode{}).Where("max_uses = 0 OR max_uses > 1").Count(&multiUse)
+	acr.db.Model(&models.AuthCode{}).
+		Where("max_uses = 0 OR max_uses > 1").
+		Where("used_count > 0 AND (max_uses = 0 OR used_count < max_uses)").Count(&partiallyUsed)
+
+	// 兑换总次数
+	var redemptionCount int64
+	if err := acr.db.Model(&models.AuthCodeRedemption{}).Count(&redemptionCount).Error; err != nil {
 		return nil, err
 	}
 
-	available := total - used - expired
-
 	// 按状态统计
 	var statusStats []struct {
 		Status models.AuthCodeStatus `json:"status"`
@@ -282,30 +635,31 @@ func (acr *AuthCodeRepository) GetStatistics() (*types.StatisticsResponse, error
 		}
 		categories[statusName] = stat.Count
 	}
+	categories["多次兑换码"] = multiUse
+	categories["部分兑换"] = partiallyUsed
+	categories["兑换总次数"] = redemptionCount
 
-	// 趋势数据（最近7天使用量）
+	// 趋势数据（最近7天兑换次数）
 	var trendData []types.TrendData
 	if err := acr.db.Raw(`
-		SELECT DATE(used_at) as date, COUNT(*) as value 
-		FROM auth_codes 
-		WHERE used_at >= DATE_SUB(NOW(), INTERVAL 7 DAY) 
-		AND status = ?
-		GROUP BY DATE(used_at) 
+		SELECT DATE(created_at) as date, COUNT(*) as value
+		FROM auth_code_redemptions
+		WHERE created_at >= DATE_SUB(NOW(), INTERVAL 7 DAY)
+		GROUP BY DATE(created_at)
 		ORDER BY date ASC
-	`, models.AuthCodeStatusUsed).Scan(&trendData).Error; err != nil {
+	`).Scan(&trendData).Error; err != nil {
 		return nil, err
 	}
 
-	// 计算使用率增长（与上周同期比较）
+	// 计算兑换次数增长（与上周同期比较）
 	var currentWeekUsed int64
 	var lastWeekUsed int64
-	
-	acr.db.Model(&models.AuthCode{}).
-		Where("used_at >= DATE_SUB(NOW(), INTERVAL 7 DAY)").
-		Where("status = ?", models.AuthCodeStatusUsed).Count(&currentWeekUsed)
-	acr.db.Model(&models.AuthCode{}).
-		Where("used_at >= DATE_SUB(NOW(), INTERVAL 14 DAY) AND used_at < DATE_SUB(NOW(), INTERVAL 7 DAY)").
-		Where("status = ?", models.AuthCodeStatusUsed).Count(&lastWeekUsed)
+
+	acr.db.Model(&models.AuthCodeRedemption{}).
+		Where("created_at >= DATE_SUB(NOW(), INTERVAL 7 DAY)").Count(&currentWeekUsed)
+	acr.db.Model(&models.AuthCodeRedemption{}).
+		Where("created_at >= DATE_SUB(NOW(), INTERVAL 14 DAY) AND created_at < DATE_SUB(NOW(), INTERVAL 7 DAY)").
+		Count(&lastWeekUsed)
 
 	var growth float64
 	if lastWeekUsed > 0 {
@@ -315,7 +669,7 @@ func (acr *AuthCodeRepository) GetStatistics() (*types.StatisticsResponse, error
 	return &types.StatisticsResponse{
 		Total:      total,
 		Active:     available,
-		Inactive:   used + expired,
+		Inactive:   total - available,
 		Growth:     growth,
 		TrendData:  trendData,
 		Categories: categories,
@@ -0,0 +1,552 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/database"
+	"backend/models"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// backupInsertBatch 恢复时每条 INSERT 写入的最大行数（同时受占位符上限约束）
+const backupInsertBatch = 500
+
+// backupMaxPlaceholders 单条语句的占位符上限（MySQL 为 65535）
+const backupMaxPlaceholders = 60000
+
+// BackupColumn 备份中的列定义
+type BackupColumn struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Binary bool   `json:"binary,omitempty"` // 二进制列，值以 []byte 返回，需按字节保存
+}
+
+// BackupRepository 逻辑备份仓库，按表流式读取与写回原始行数据
+type BackupRepository struct {
+	db *gorm.DB
+}
+
+// NewBackupRepository 创建备份仓库
+func NewBackupRepository() *BackupRepository {
+	return &BackupRepository{
+		db: database.DB,
+	}
+}
+
+// TableNames 所有模型对应的表（含多对多关联表），按 AllModels 顺序
+func (br *BackupRepository) TableNames() ([]string, error) {
+	var tables []string
+	seen := make(map[string]bool)
+	add := func(table string) {
+		if table != "" && !seen[table] {
+			seen[table] = true
+			tables = append(tables, table)
+		}
+	}
+
+	for _, model := range models.AllModels() {
+		stmt := &gorm.Statement{DB: br.db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		add(stmt.Schema.Table)
+		for _, rel := range stmt.Schema.Relationships.Relations {
+			if rel.JoinTable != nil {
+				add(rel.JoinTable.Table)
+			}
+		}
+	}
+	return tables, nil
+}
+
+// Dump 在同一个只读事务中依次读取各表，保证多张表之间数据一致；
+// 每张表先回调 begin 返回列定义，再逐行回调 row。时间列格式化为字符串，二进制列为 []byte，其余文本为 string
+func (br *BackupRepository) Dump(tables []string, begin func(table string, columns []BackupColumn) error, row func(values []interface{}) error) error {
+	return br.db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range tables {
+			if err := br.dumpTable(tx, table, begin, row); err != nil {
+				return fmt.Errorf("备份表 %s 失败: %w", table, err)
+			}
+		}
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+}
+
+// dumpTable 按主键顺序流式读取一张表
+func (br *BackupRepository) dumpTable(tx *gorm.DB, table string, begin func(table string, columns []BackupColumn) error, row func(values []interface{}) error) error {
+	query := tx.Table(table)
+	if order := br.orderClause(tx, table); order != "" {
+		query = query.Order(order)
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	columns := make([]BackupColumn, len(columnTypes))
+	for i, ct := range columnTypes {
+		columns[i] = BackupColumn{
+			Name:   ct.Name(),
+			Type:   strings.ToUpper(ct.DatabaseTypeName()),
+			Binary: isBinaryColumn(ct.DatabaseTypeName()),
+		}
+	}
+	if err := begin(table, columns); err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		out := make([]interface{}, len(columns))
+		for i, value := range values {
+			out[i] = normalizeBackupValue(value, columns[i])
+		}
+		if err := row(out); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// orderClause 有主键时按主键排序，使同一数据的备份内容稳定
+func (br *BackupRepository) orderClause(tx *gorm.DB, table string) string {
+	var keys []string
+	tx.Raw(`SELECT column_name FROM information_schema.key_column_usage
+		WHERE table_schema = DATABASE() AND table_name = ? AND constraint_name = 'PRIMARY'
+		ORDER BY ordinal_position`, table).Scan(&keys)
+	quoted := make([]string, len(keys))
+	for i, key := range keys {
+		quoted[i] = quoteIdent(key)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// isBinaryColumn 是否二进制类型的列
+func isBinaryColumn(typeName string) bool {
+	switch strings.ToUpper(typeName) {
+	case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "BIT", "GEOMETRY":
+		return true
+	}
+	return false
+}
+
+// normalizeBackupValue 把驱动返回的值转为可以原样写回的形式
+func normalizeBackupValue(value interface{}, column BackupColumn) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		if column.Binary {
+			return append([]byte(nil), v...)
+		}
+		return string(v)
+	case time.Time:
+		// 连接使用 parseTime + loc，按读取时的时区原样格式化即可还原为库中的字面值
+		if v.IsZero() {
+			if column.Type == "DATE" {
+				return "0000-00-00"
+			}
+			return "0000-00-00 00:00:00"
+		}
+		if column.Type == "DATE" {
+			return v.Format("2006-01-02")
+		}
+		return v.Format("2006-01-02 15:04:05.999999")
+	default:
+		return v
+	}
+}
+
+// quoteIdent 为表名、列名加反引号
+func quoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// BackupLoader 恢复写入器，在恢复事务内逐表清空并批量写回数据
+type BackupLoader struct {
+	tx      *gorm.DB
+	table   string
+	columns []string
+	batch   int
+	pending [][]interface{}
+	rows    map[string]int64
+}
+
+// Begin 开始写入一张表：校验列与当前表结构一致后清空该表
+func (bl *BackupLoader) Begin(table string, columns []string) error {
+	if err := bl.flush(); err != nil {
+		return err
+	}
+
+	existing, err := bl.tx.Migrator().ColumnTypes(table)
+	if err != nil {
+		return fmt.Errorf("读取表 %s 结构失败: %w", table, err)
+	}
+	if len(existing) == 0 {
+		return fmt.Errorf("表 %s 不存在", table)
+	}
+	known := make(map[string]bool, len(existing))
+	for _, ct := range existing {
+		known[ct.Name()] = true
+	}
+	for _, column := range columns {
+		if !known[column] {
+			return fmt.Errorf("表 %s 缺少列 %s，备份与当前表结构不一致", table, column)
+		}
+	}
+
+	// DELETE 而非 TRUNCATE：TRUNCATE 会隐式提交事务，失败时无法回滚
+	if err := bl.tx.Exec("DELETE FROM " + quoteIdent(table)).Error; err != nil {
+		return err
+	}
+
+	bl.table = table
+	bl.columns = columns
+	bl.batch = backupInsertBatch
+	if len(columns) > 0 && backupMaxPlaceholders/len(columns) < bl.batch {
+		bl.batch = backupMaxPlaceholders / len(columns)
+	}
+	if _, ok := bl.rows[table]; !ok {
+		bl.rows[table] = 0
+	}
+	return nil
+}
+
+// Insert 写入一行，值的顺序与 Begin 传入的列一致
+func (bl *BackupLoader) Insert(values []interface{}) error {
+	if bl.table == "" {
+		return fmt.Errorf("写入数据前未指定表")
+	}
+	if len(values) != len(bl.columns) {
+		return fmt.Errorf("表 %s 的数据列数不一致：期望 %d，实际 %d", bl.table, len(bl.columns), len(values))
+	}
+	bl.pending = append(bl.pending, values)
+	if len(bl.pending) >= bl.batch {
+		return bl.flush()
+	}
+	return nil
+}
+
+// Rows 各表已写入的行数
+func (bl *BackupLoader) Rows() map[string]int64 {
+	return bl.rows
+}
+
+// flush 以多行 INSERT 写入缓存的数据
+func (bl *BackupLoader) flush() error {
+	if len(bl.pending) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(bl.pending)*len(bl.columns))
+	for _, values := range bl.pending {
+		args = append(args, values...)
+	}
+	if err := bl.tx.Exec(insertSQL(bl.table, bl.columns, len(bl.pending), nil), args...).Error; err != nil {
+		return fmt.Errorf("写入表 %s 失败: %w", bl.table, err)
+	}
+
+	bl.rows[bl.table] += int64(len(bl.pending))
+	bl.pending = bl.pending[:0]
+	return nil
+}
+
+// insertSQL 生成多行 INSERT 语句；updateColumns 非空时主键或唯一键冲突改为更新这些列
+func insertSQL(table string, columns []string, rowCount int, updateColumns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdent(column)
+	}
+	placeholder := "(" + strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",") + ")"
+
+	var sqlBuilder strings.Builder
+	sqlBuilder.WriteString("INSERT INTO " + quoteIdent(table) + " (" + strings.Join(quoted, ", ") + ") VALUES ")
+	for i := 0; i < rowCount; i++ {
+		if i > 0 {
+			sqlBuilder.WriteString(", ")
+		}
+		sqlBuilder.WriteString(placeholder)
+	}
+	if len(updateColumns) > 0 {
+		assignments := make([]string, len(updateColumns))
+		for i, column := range updateColumns {
+			assignments[i] = quoteIdent(column) + " = VALUES(" + quoteIdent(column) + ")"
+		}
+		sqlBuilder.WriteString(" ON DUPLICATE KEY UPDATE " + strings.Join(assignments, ", "))
+	}
+	return sqlBuilder.String()
+}
+
+// Restore 在一个事务中写回备份数据，load 返回错误时整体回滚
+func (br *BackupRepository) Restore(load func(loader *BackupLoader) error) (map[string]int64, error) {
+	var rows map[string]int64
+	err := br.withoutForeignKeyChecks(func(tx *gorm.DB) error {
+		loader := &BackupLoader{tx: tx, rows: make(map[string]int64)}
+		if err := load(loader); err != nil {
+			return err
+		}
+		if err := loader.flush(); err != nil {
+			return err
+		}
+		rows = loader.Rows()
+		return nil
+	})
+	return rows, err
+}
+
+// withoutForeignKeyChecks 在同一连接上关闭外键检查后执行事务（各表的写入顺序不保证满足外键依赖），
+// 连接归还连接池前恢复外键检查
+func (br *BackupRepository) withoutForeignKeyChecks(fn func(tx *gorm.DB) error) error {
+	return br.db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SET FOREIGN_KEY_CHECKS = 0").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("SET FOREIGN_KEY_CHECKS = 1")
+
+		return conn.Transaction(fn)
+	})
+}
+
+// 导入时主键或唯一键冲突的处理方式
+const (
+	ImportConflictSkip     = "skip"     // 保留已有数据，跳过冲突行
+	ImportConflictOverride = "override" // 用导入的数据覆盖已有数据（主键保持不变）
+	ImportConflictFail     = "fail"     // 出现冲突即中止并回滚整个导入
+)
+
+// importErrorSamples 每张表最多记录的失败行
+const importErrorSamples = 20
+
+// MySQL 错误码
+const (
+	mysqlDuplicateEntry  = 1062 // 主键或唯一键冲突
+	mysqlLockWaitTimeout = 1205
+	mysqlDeadlock        = 1213
+)
+
+// TableColumn 表的列定义
+type TableColumn struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"` // 完整列类型，如 varchar(255)、bigint unsigned
+	PrimaryKey bool   `json:"primary_key,omitempty"`
+	Binary     bool   `json:"binary,omitempty"`
+}
+
+// TableSchema 读取表的当前列定义（按列顺序）
+func (br *BackupRepository) TableSchema(table string) ([]TableColumn, error) {
+	columnTypes, err := br.db.Migrator().ColumnTypes(table)
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]TableColumn, len(columnTypes))
+	for i, ct := range columnTypes {
+		columnType, _ := ct.ColumnType()
+		primaryKey, _ := ct.PrimaryKey()
+		columns[i] = TableColumn{
+			Name:       ct.Name(),
+			Type:       strings.ToLower(columnType),
+			PrimaryKey: primaryKey,
+			Binary:     isBinaryColumn(ct.DatabaseTypeName()),
+		}
+	}
+	return columns, nil
+}
+
+// ImportRowError 导入失败的行
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// ImportConflictError 冲突处理方式为 fail 时遇到的冲突
+type ImportConflictError struct {
+	Table string
+	Line  int
+	Err   error
+}
+
+func (e *ImportConflictError) Error() string {
+	return fmt.Sprintf("表 %s 第 %d 行与已有数据冲突: %v", e.Table, e.Line, e.Err)
+}
+
+// ImportTableResult 单表导入结果
+type ImportTableResult struct {
+	Table     string           `json:"table"`
+	Imported  int64            `json:"imported"`
+	Skipped   int64            `json:"skipped"`
+	Errors    int64            `json:"errors"`
+	ErrorRows []ImportRowError `json:"error_rows,omitempty"` // 最多记录前 20 条
+}
+
+// DataImporter 数据导入写入器：在导入事务内按批写入，整批失败时逐行重试以区分冲突与错误行
+type DataImporter struct {
+	tx            *gorm.DB
+	mode          string
+	table         string
+	columns       []string
+	updateColumns []string
+	batch         int
+	pending       [][]interface{}
+	lines         []int
+	result        *ImportTableResult
+	results       []*ImportTableResult
+}
+
+// Begin 开始导入一张表；覆盖模式下冲突时更新除主键外的列
+func (di *DataImporter) Begin(table string, columns []string, primaryKeys []string) error {
+	if err := di.flush(); err != nil {
+		return err
+	}
+
+	di.table = table
+	di.columns = columns
+	di.updateColumns = nil
+	if di.mode == ImportConflictOverride {
+		isKey := make(map[string]bool, len(primaryKeys))
+		for _, key := range primaryKeys {
+			isKey[key] = true
+		}
+		for _, column := range columns {
+			if !isKey[column] {
+				di.updateColumns = append(di.updateColumns, column)
+			}
+		}
+	}
+	di.batch = backupInsertBatch
+	if len(columns) > 0 && backupMaxPlaceholders/len(columns) < di.batch {
+		di.batch = backupMaxPlaceholders / len(columns)
+	}
+	di.result = nil
+	for _, result := range di.results {
+		if result.Table == table {
+			di.result = result
+		}
+	}
+	if di.result == nil {
+		di.result = &ImportTableResult{Table: table}
+		di.results = append(di.results, di.result)
+	}
+	return nil
+}
+
+// Insert 写入一行，line 为该行在导入文件中的行号
+func (di *DataImporter) Insert(line int, values []interface{}) error {
+	if di.result == nil {
+		return fmt.Errorf("写入数据前未指定表")
+	}
+	di.pending = append(di.pending, values)
+	di.lines = append(di.lines, line)
+	if len(di.pending) >= di.batch {
+		return di.flush()
+	}
+	return nil
+}
+
+// Fail 记录一行无法解析的数据
+func (di *DataImporter) Fail(line int, message string) {
+	if di.result == nil {
+		return
+	}
+	di.result.Errors++
+	if len(di.result.ErrorRows) < importErrorSamples {
+		di.result.ErrorRows = append(di.result.ErrorRows, ImportRowError{Line: line, Message: message})
+	}
+}
+
+// Results 各表的导入结果
+func (di *DataImporter) Results() []*ImportTableResult {
+	return di.results
+}
+
+// flush 写入缓存的数据；多行 INSERT 是单条语句，失败时整批不生效，改为逐行写入
+func (di *DataImporter) flush() error {
+	if len(di.pending) == 0 {
+		return nil
+	}
+	defer func() {
+		di.pending = di.pending[:0]
+		di.lines = di.lines[:0]
+	}()
+
+	args := make([]interface{}, 0, len(di.pending)*len(di.columns))
+	for _, values := range di.pending {
+		args = append(args, values...)
+	}
+	err := di.tx.Exec(insertSQL(di.table, di.columns, len(di.pending), di.updateColumns), args...).Error
+	if err == nil {
+		di.result.Imported += int64(len(di.pending))
+		return nil
+	}
+	if _, ok := rowError(err); !ok {
+		return fmt.Errorf("写入表 %s 失败: %w", di.table, err)
+	}
+
+	rowSQL := insertSQL(di.table, di.columns, 1, di.updateColumns)
+	for i, values := range di.pending {
+		err := di.tx.Exec(rowSQL, values...).Error
+		if err == nil {
+			di.result.Imported++
+			continue
+		}
+
+		mysqlErr, ok := rowError(err)
+		if !ok {
+			return fmt.Errorf("写入表 %s 失败: %w", di.table, err)
+		}
+		if mysqlErr.Number == mysqlDuplicateEntry {
+			switch di.mode {
+			case ImportConflictFail:
+				return &ImportConflictError{Table: di.table, Line: di.lines[i], Err: err}
+			case ImportConflictSkip:
+				di.result.Skipped++
+				continue
+			}
+		}
+		di.Fail(di.lines[i], err.Error())
+	}
+	return nil
+}
+
+// rowError 是否只影响当前语句的数据错误；死锁、锁等待超时会回滚整个事务，连接错误无法继续，均不属于此类
+func rowError(err error) (*mysqldriver.MySQLError, bool) {
+	var mysqlErr *mysqldriver.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number == mysqlDeadlock || mysqlErr.Number == mysqlLockWaitTimeout {
+		return nil, false
+	}
+	return mysqlErr, true
+}
+
+// Import 在一个事务中导入数据，load 返回错误时整体回滚
+func (br *BackupRepository) Import(mode string, load func(importer *DataImporter) error) ([]*ImportTableResult, error) {
+	var results []*ImportTableResult
+	err := br.withoutForeignKeyChecks(func(tx *gorm.DB) error {
+		importer := &DataImporter{tx: tx, mode: mode}
+		if err := load(importer); err != nil {
+			return err
+		}
+		if err := importer.flush(); err != nil {
+			return err
+		}
+		results = importer.Results()
+		return nil
+	})
+	return results, err
+}
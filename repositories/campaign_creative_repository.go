@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"backend/database"
+	"backend/models"
+	"gorm.io/gorm"
+)
+
+// CampaignCreativeRepository 计划创意仓库
+type CampaignCreativeRepository struct {
+	db *gorm.DB
+}
+
+// NewCampaignCreativeRepository 创建计划创意仓库
+func NewCampaignCreativeRepository() *CampaignCreativeRepository {
+	return &CampaignCreativeRepository{
+		db: database.DB,
+	}
+}
+
+// ListByCampaign 获取计划的全部创意
+func (ccr *CampaignCreativeRepository) ListByCampaign(campaignID uint) ([]*models.CampaignCreative, error) {
+	var creatives []*models.CampaignCreative
+	err := ccr.db.Where("campaign_id = ?", campaignID).Order("id ASC").Find(&creatives).Error
+	return creatives, err
+}
+
+// GetByID 根据ID获取创意
+func (ccr *CampaignCreativeRepository) GetByID(id uint) (*models.CampaignCreative, error) {
+	var creative models.CampaignCreative
+	if err := ccr.db.First(&creative, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("创意不存在")
+		}
+		return nil, err
+	}
+	return &creative, nil
+}
+
+// Create 创建创意
+func (ccr *CampaignCreativeRepository) Create(creative *models.CampaignCreative) error {
+	return ccr.db.Create(creative).Error
+}
+
+// Update 更新创意
+func (ccr *CampaignCreativeRepository) Update(creative *models.CampaignCreative) error {
+	return ccr.db.Save(creative).Error
+}
+
+// Delete 删除创意
+func (ccr *CampaignCreativeRepository) Delete(id uint) error {
+	return ccr.db.Delete(&models.CampaignCreative{}, id).Error
+}
+
+// RefreshCounters 根据事件明细重新计算创意的展示/点击/转化计数
+func (ccr *CampaignCreativeRepository) RefreshCounters(creativeIDs []uint) error {
+	if len(creativeIDs) == 0 {
+		return nil
+	}
+
+	var rows []struct {
+		CreativeID uint
+		models.CampaignMetrics
+	}
+	if err := ccr.db.Model(&models.CampaignEvent{}).
+		Select("creative_id, "+metricsSelect).
+		Where("creative_id IN ?", creativeIDs).
+		Group("creative_id").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := ccr.db.Model(&models.CampaignCreative{}).
+			Where("id = ?", row.CreativeID).
+			Updates(map[string]interface{}{
+				"impressions": row.Impressions,
+				"clicks":      row.Clicks,
+				"conversions": row.Conversions,
+				"spend":       row.Spend,
+			}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Promote 采用胜出创意：其余创意暂停，并将胜出创意内容同步到计划
+func (ccr *CampaignCreativeRepository) Promote(winner *models.CampaignCreative) error {
+	return ccr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.CampaignCreative{}).
+			Where("campaign_id = ? AND id <> ?", winner.CampaignID, winner.ID).
+			Updates(map[string]interface{}{
+				"status":    models.CreativeStatusPaused,
+				"is_winner": false,
+			}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.CampaignCreative{}).
+			Where("id = ?", winner.ID).
+			Updates(map[string]interface{}{
+				"status":    models.CreativeStatusActive,
+				"is_winner": true,
+			}).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(&models.Campaign{}).
+			Where("id = ?", winner.CampaignID).
+			Updates(map[string]interface{}{
+				"main_image":       winner.MainImage,
+				"video":            winner.Video,
+				"delivery_content": winner.DeliveryContent,
+				"promoted_at":      &now,
+			}).Error
+	})
+}
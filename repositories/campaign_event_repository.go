@@ -0,0 +1,151 @@
+package repositories
+
+import (
+	"time"
+
+	"backend/database"
+	"backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CampaignEventRepository 广告计划事件仓库
+type CampaignEventRepository struct {
+	db *gorm.DB
+}
+
+// NewCampaignEventRepository 创建广告计划事件仓库
+func NewCampaignEventRepository() *CampaignEventRepository {
+	return &CampaignEventRepository{
+		db: database.DB,
+	}
+}
+
+// metricsSelect 按事件类型聚合的查询字段
+const metricsSelect = `
+	COALESCE(SUM(CASE WHEN type = 1 THEN 1 ELSE 0 END), 0) AS impressions,
+	COALESCE(SUM(CASE WHEN type = 2 THEN 1 ELSE 0 END), 0) AS clicks,
+	COALESCE(SUM(CASE WHEN type = 3 THEN 1 ELSE 0 END), 0) AS conversions,
+	COALESCE(SUM(cost), 0) AS spend`
+
+// rollupSelect 汇总表的聚合字段
+const rollupSelect = `
+	COALESCE(SUM(impressions), 0) AS impressions,
+	COALESCE(SUM(clicks), 0) AS clicks,
+	COALESCE(SUM(conversions), 0) AS conversions,
+	COALESCE(SUM(spend), 0) AS spend`
+
+// InsertEvents 批量写入事件，重复的事件ID忽略
+func (cer *CampaignEventRepository) InsertEvents(events []*models.CampaignEvent) error {
+	return cer.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(events, 500).Error
+}
+
+// RefreshHourly 根据事件明细重新计算计划某小时的汇总
+func (cer *CampaignEventRepository) RefreshHourly(campaignID uint, hour time.Time) error {
+	var metrics models.CampaignMetrics
+	if err := cer.db.Model(&models.CampaignEvent{}).
+		Select(metricsSelect).
+		Where("campaign_id = ? AND occurred_at >= ? AND occurred_at < ?", campaignID, hour, hour.Add(time.Hour)).
+		Scan(&metrics).Error; err != nil {
+		return err
+	}
+
+	stat := &models.CampaignStatHourly{
+		CampaignID:  campaignID,
+		Hour:        hour,
+		Impressions: metrics.Impressions,
+		Clicks:      metrics.Clicks,
+		Conversions: metrics.Conversions,
+		Spend:       metrics.Spend,
+	}
+	return cer.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "campaign_id"}, {Name: "hour"}},
+		DoUpdates: clause.AssignmentColumns([]string{"impressions", "clicks", "conversions", "spend", "updated_at"}),
+	}).Create(stat).Error
+}
+
+// RefreshDaily 根据小时汇总重新计算计划某天的汇总
+func (cer *CampaignEventRepository) RefreshDaily(campaignID uint, day time.Time) error {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+
+	var metrics models.CampaignMetrics
+	if err := cer.db.Model(&models.CampaignStatHourly{}).
+		Select(rollupSelect).
+		Where("campaign_id = ? AND hour >= ? AND hour < ?", campaignID, start, start.AddDate(0, 0, 1)).
+		Scan(&metrics).Error; err != nil {
+		return err
+	}
+
+	stat := &models.CampaignStatDaily{
+		CampaignID:  campaignID,
+		Date:        start.Format("2006-01-02"),
+		Impressions: metrics.Impressions,
+		Clicks:      metrics.Clicks,
+		Conversions: metrics.Conversions,
+		Spend:       metrics.Spend,
+	}
+	return cer.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "campaign_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"impressions", "clicks", "conversions", "spend", "updated_at"}),
+	}).Create(stat).Error
+}
+
+// dailyRange 按日期范围筛选日汇总
+func dailyRange(query *gorm.DB, startDate, endDate *time.Time) *gorm.DB {
+	if startDate != nil {
+		query = query.Where("date >= ?", startDate.Format("2006-01-02"))
+	}
+	if endDate != nil {
+		query = query.Where("date <= ?", endDate.Format("2006-01-02"))
+	}
+	return query
+}
+
+// GetMetrics 获取计划在日期范围内的汇总指标
+func (cer *CampaignEventRepository) GetMetrics(campaignID uint, startDate, endDate *time.Time) (models.CampaignMetrics, error) {
+	var metrics models.CampaignMetrics
+	query := cer.db.Model(&models.CampaignStatDaily{}).Select(rollupSelect).Where("campaign_id = ?", campaignID)
+	err := dailyRange(query, startDate, endDate).Scan(&metrics).Error
+	return metrics, err
+}
+
+// GetMetricsByCampaigns 批量获取多个计划的汇总指标
+func (cer *CampaignEventRepository) GetMetricsByCampaigns(campaignIDs []uint, startDate, endDate *time.Time) (map[uint]models.CampaignMetrics, error) {
+	result := make(map[uint]models.CampaignMetrics, len(campaignIDs))
+	if len(campaignIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		CampaignID uint
+		models.CampaignMetrics
+	}
+	query := cer.db.Model(&models.CampaignStatDaily{}).
+		Select("campaign_id, "+rollupSelect).
+		Where("campaign_id IN ?", campaignIDs)
+	if err := dailyRange(query, startDate, endDate).Group("campaign_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		result[row.CampaignID] = row.CampaignMetrics
+	}
+	return result, nil
+}
+
+// GetDailyStats 获取计划的日汇总
+func (cer *CampaignEventRepository) GetDailyStats(campaignID uint, startDate, endDate *time.Time) ([]*models.CampaignStatDaily, error) {
+	var stats []*models.CampaignStatDaily
+	query := cer.db.Where("campaign_id = ?", campaignID)
+	err := dailyRange(query, startDate, endDate).Order("date ASC").Find(&stats).Error
+	return stats, err
+}
+
+// GetHourlyStats 获取计划的小时汇总
+func (cer *CampaignEventRepository) GetHourlyStats(campaignID uint, start, end time.Time) ([]*models.CampaignStatHourly, error) {
+	var stats []*models.CampaignStatHourly
+	err := cer.db.Where("campaign_id = ? AND hour >= ? AND hour < ?", campaignID, start, end).
+		Order("hour ASC").
+		Find(&stats).Error
+	return stats, err
+}
@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"backend/database"
 	"backend/models"
 	"backend/types"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CampaignRepository 计划仓库
@@ -89,7 +91,48 @@ func (cr *CampaignRepository) GetByID(id uint) (*models.Campaign, error) {
 
 // Create 创建计划
 func (cr *CampaignRepository) Create(campaign *models.Campaign) error {
-	return cr.db.Create(campaign).Error
+	inactive := campaign.Status == models.CampaignStatusInactive
+	if err := cr.db.Create(campaign).Error; err != nil {
+		return err
+	}
+	// status 列有默认值，零值不会写入，需要单独更新
+	if inactive {
+		return cr.db.Model(campaign).Update("status", models.CampaignStatusInactive).Error
+	}
+	return nil
+}
+
+// CreateWithCreatives 在同一事务中创建未激活的计划及其创意
+func (cr *CampaignRepository) CreateWithCreatives(campaign *models.Campaign, creatives []models.CampaignCreative) error {
+	return cr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(campaign).Error; err != nil {
+			return err
+		}
+		// status 列有默认值，零值不会写入，需要单独更新
+		if err := tx.Model(campaign).Update("status", models.CampaignStatusInactive).Error; err != nil {
+			return err
+		}
+		campaign.Status = models.CampaignStatusInactive
+
+		for i := range creatives {
+			creative := &creatives[i]
+			creative.ID = 0
+			creative.CampaignID = campaign.ID
+			// 同上，暂停状态的创意需要在创建后更新
+			paused := creative.Status == models.CreativeStatusPaused
+			if err := tx.Create(creative).Error; err != nil {
+				return err
+			}
+			if paused {
+				if err := tx.Model(creative).Update("status", models.CreativeStatusPaused).Error; err != nil {
+					return err
+				}
+				creative.Status = models.CreativeStatusPaused
+			}
+		}
+		campaign.Creatives = creatives
+		return nil
+	})
 }
 
 // Update 更新计划
@@ -251,10 +294,11 @@ func (cr *CampaignRepository) GetRunningCampaigns() ([]*models.Campaign, error)
 	var campaigns []*models.Campaign
 
 	// 查询活动状态且在有效期内的计划
+	now := time.Now()
 	if err := cr.db.Preload("Product").
 		Where("status = ?", models.CampaignStatusActive).
-		Where("JSON_EXTRACT(delivery_rules, '$.start_date') <= NOW()").
-		Where("JSON_EXTRACT(delivery_rules, '$.end_date') >= NOW()").
+		Where("start_at IS NULL OR start_at <= ?", now).
+		Where("end_at IS NULL OR end_at > ?", now).
 		Find(&campaigns).Error; err != nil {
 		return nil, err
 	}
@@ -267,8 +311,8 @@ func (cr *CampaignRepository) GetCampaignsByDateRange(startDate, endDate string)
 	var campaigns []*models.Campaign
 
 	if err := cr.db.Preload("Product").
-		Where("JSON_EXTRACT(delivery_rules, '$.start_date') >= ?", startDate).
-		Where("JSON_EXTRACT(delivery_rules, '$.end_date') <= ?", endDate).
+		Where("start_at >= ?", startDate).
+		Where("end_at <= ?", endDate).
 		Find(&campaigns).Error; err != nil {
 		return nil, err
 	}
@@ -282,10 +326,209 @@ func (cr *CampaignRepository) GetExpiredCampaigns() ([]*models.Campaign, error)
 
 	if err := cr.db.Preload("Product").
 		Where("status IN ?", []models.CampaignStatus{models.CampaignStatusActive, models.CampaignStatusPaused}).
-		Where("JSON_EXTRACT(delivery_rules, '$.end_date') < NOW()").
+		Where("end_at < ?", time.Now()).
 		Find(&campaigns).Error; err != nil {
 		return nil, err
 	}
 
 	return campaigns, nil
 }
+
+// GetServableCampaigns 获取投放中且产品启用的计划
+func (cr *CampaignRepository) GetServableCampaigns() ([]*models.Campaign, error) {
+	var campaigns []*models.Campaign
+
+	err := cr.db.Preload("Product").
+		Preload("Creatives", "status = ?", models.CreativeStatusActive).
+		Joins("JOIN products ON products.id = campaigns.product_id AND products.deleted_at IS NULL").
+		Where("campaigns.status = ? AND products.status = ?", models.CampaignStatusActive, models.ProductStatusActive).
+		Find(&campaigns).Error
+	return campaigns, err
+}
+
+// GetScheduledCampaigns 获取配置了排期且未结束的计划
+func (cr *CampaignRepository) GetScheduledCampaigns() ([]*models.Campaign, error) {
+	var campaigns []*models.Campaign
+
+	err := cr.db.Where("status <> ?", models.CampaignStatusEnded).
+		Where("start_at IS NOT NULL OR end_at IS NOT NULL OR JSON_LENGTH(day_parts) > 0").
+		Find(&campaigns).Error
+	return campaigns, err
+}
+
+// TransitionStatus 在状态未被并发修改的前提下变更计划状态，返回是否更新成功
+func (cr *CampaignRepository) TransitionStatus(id uint, from, to models.CampaignStatus, scheduledPause bool) (bool, error) {
+	result := cr.db.Model(&models.Campaign{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(map[string]interface{}{
+			"status":          to,
+			"scheduled_pause": scheduledPause,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CreateStatusLog 记录计划状态变更
+func (cr *CampaignRepository) CreateStatusLog(statusLog *models.CampaignStatusLog) error {
+	return cr.db.Create(statusLog).Error
+}
+
+// ListStatusLogs 获取计划状态变更记录
+func (cr *CampaignRepository) ListStatusLogs(campaignID uint, req *types.PageRequest) ([]*models.CampaignStatusLog, int64, error) {
+	var logs []*models.CampaignStatusLog
+	var total int64
+
+	query := cr.db.Model(&models.CampaignStatusLog{}).Where("campaign_id = ?", campaignID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (req.GetPage() - 1) * req.GetSize()
+	if err := query.Order("id DESC").Offset(offset).Limit(req.GetSize()).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
+
+// TransitionReview 仅当计划处于 from 审核状态时更新审核字段，返回是否更新成功
+func (cr *CampaignRepository) TransitionReview(id uint, from []string, fields map[string]interface{}) (bool, error) {
+	result := cr.db.Model(&models.Campaign{}).
+		Where("id = ? AND review_status IN ?", id, from).
+		Updates(fields)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CreateReview 记录审核操作
+func (cr *CampaignRepository) CreateReview(review *models.CampaignReview) error {
+	return cr.db.Create(review).Error
+}
+
+// ListReviews 获取计划审核记录
+func (cr *CampaignRepository) ListReviews(campaignID uint, req *types.PageRequest) ([]*models.CampaignReview, int64, error) {
+	var reviews []*models.CampaignReview
+	var total int64
+
+	query := cr.db.Model(&models.CampaignReview{}).Where("campaign_id = ?", campaignID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Preload("Operator").Preload("Reviewer").
+		Order("id DESC").
+		Offset(req.GetOffset()).
+		Limit(req.GetSize()).
+		Find(&reviews).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return reviews, total, nil
+}
+
+// ListPendingReviews 获取待审核的计划，reviewerID 不为空时只返回分配给该审核人的计划
+func (cr *CampaignRepository) ListPendingReviews(req *types.PageRequest, reviewerID *uint) ([]*models.Campaign, int64, error) {
+	var campaigns []*models.Campaign
+	var total int64
+
+	query := cr.db.Model(&models.Campaign{}).Where("review_status = ?", models.ReviewStatusPending)
+	if reviewerID != nil {
+		query = query.Where("reviewer_id = ?", *reviewerID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Preload("Product").
+		Order("updated_at ASC").
+		Offset(req.GetOffset()).
+		Limit(req.GetSize()).
+		Find(&campaigns).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return campaigns, total, nil
+}
+
+// CountPendingByReviewer 统计各审核人待审核的计划数
+func (cr *CampaignRepository) CountPendingByReviewer(reviewerIDs []uint) (map[uint]int64, error) {
+	var rows []struct {
+		ReviewerID uint
+		Total      int64
+	}
+	counts := make(map[uint]int64)
+	if len(reviewerIDs) == 0 {
+		return counts, nil
+	}
+	if err := cr.db.Model(&models.Campaign{}).
+		Select("reviewer_id, COUNT(*) AS total").
+		Where("review_status = ? AND reviewer_id IN ?", models.ReviewStatusPending, reviewerIDs).
+		Group("reviewer_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.ReviewerID] = row.Total
+	}
+	return counts, nil
+}
+
+// GetSpend 获取计划消耗（事件计费累计），day 为空时统计全部；
+// day 不为空时统计其所在时区的当天，按小时汇总累计（日汇总按服务器时区分日）
+func (cr *CampaignRepository) GetSpend(campaignID uint, day *time.Time) (float64, error) {
+	var spend float64
+	query := cr.db.Model(&models.CampaignStatDaily{}).
+		Select("COALESCE(SUM(spend), 0)").
+		Where("campaign_id = ?", campaignID)
+	if day != nil {
+		start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+		query = cr.db.Model(&models.CampaignStatHourly{}).
+			Select("COALESCE(SUM(spend), 0)").
+			Where("campaign_id = ? AND hour >= ? AND hour < ?", campaignID, start, start.AddDate(0, 0, 1))
+	}
+	if err := query.Scan(&spend).Error; err != nil {
+		return 0, err
+	}
+	return spend, nil
+}
+
+// GetBudgetedCampaigns 获取需要检查预算的计划（投放中，或因日预算暂停）
+func (cr *CampaignRepository) GetBudgetedCampaigns() ([]*models.Campaign, error) {
+	var campaigns []*models.Campaign
+
+	err := cr.db.Where("total_budget > 0 OR daily_budget > 0").
+		Where("status = ? OR (status = ? AND budget_paused = ?)",
+			models.CampaignStatusActive, models.CampaignStatusPaused, models.BudgetTypeDaily).
+		Find(&campaigns).Error
+	return campaigns, err
+}
+
+// UpdateFields 更新计划指定字段
+func (cr *CampaignRepository) UpdateFields(id uint, fields map[string]interface{}) error {
+	return cr.db.Model(&models.Campaign{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// CreateBudgetAlert 记录预算提醒，已提醒过返回false
+func (cr *CampaignRepository) CreateBudgetAlert(alert *models.CampaignBudgetAlert) (bool, error) {
+	result := cr.db.Clauses(clause.OnConflict{DoNothing: true}).Create(alert)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ListBudgetAlerts 获取预算提醒列表
+func (cr *CampaignRepository) ListBudgetAlerts(req *types.PageRequest, campaignID *uint) ([]*models.CampaignBudgetAlert, int64, error) {
+	var alerts []*models.CampaignBudgetAlert
+	var total int64
+
+	query := cr.db.Model(&models.CampaignBudgetAlert{})
+	if campaignID != nil {
+		query = query.Where("campaign_id = ?", *campaignID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (req.GetPage() - 1) * req.GetSize()
+	if err := query.Preload("Campaign").Order("id DESC").Offset(offset).Limit(req.GetSize()).Find(&alerts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return alerts, total, nil
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"fmt"
+
+	"backend/database"
+	"backend/models"
+	"backend/types"
+
+	"gorm.io/gorm"
+)
+
+// CampaignTemplateRepository 计划模板仓库
+type CampaignTemplateRepository struct {
+	db *gorm.DB
+}
+
+// NewCampaignTemplateRepository 创建计划模板仓库
+func NewCampaignTemplateRepository() *CampaignTemplateRepository {
+	return &CampaignTemplateRepository{
+		db: database.DB,
+	}
+}
+
+// List 获取模板列表
+func (ctr *CampaignTemplateRepository) List(req *types.PageRequest) ([]*models.CampaignTemplate, int64, error) {
+	var templates []*models.CampaignTemplate
+	var total int64
+
+	query := ctr.db.Model(&models.CampaignTemplate{})
+	if req.Search != "" {
+		searchPattern := "%" + req.Search + "%"
+		query = query.Where("name LIKE ? OR remark LIKE ?", searchPattern, searchPattern)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("id DESC").
+		Offset(req.GetOffset()).
+		Limit(req.GetSize()).
+		Find(&templates).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return templates, total, nil
+}
+
+// GetByID 根据ID获取模板
+func (ctr *CampaignTemplateRepository) GetByID(id uint) (*models.CampaignTemplate, error) {
+	var template models.CampaignTemplate
+	if err := ctr.db.First(&template, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("模板不存在")
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// Create 创建模板
+func (ctr *CampaignTemplateRepository) Create(template *models.CampaignTemplate) error {
+	return ctr.db.Create(template).Error
+}
+
+// Update 更新模板
+func (ctr *CampaignTemplateRepository) Update(template *models.CampaignTemplate) error {
+	return ctr.db.Save(template).Error
+}
+
+// Delete 删除模板
+func (ctr *CampaignTemplateRepository) Delete(id uint) error {
+	return ctr.db.Delete(&models.CampaignTemplate{}, id).Error
+}
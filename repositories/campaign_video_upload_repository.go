@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"backend/database"
+	"backend/models"
+	"gorm.io/gorm"
+)
+
+// CampaignVideoUploadRepository 计划视频断点续传会话仓库
+type CampaignVideoUploadRepository struct {
+	db *gorm.DB
+}
+
+// NewCampaignVideoUploadRepository 创建计划视频断点续传会话仓库
+func NewCampaignVideoUploadRepository() *CampaignVideoUploadRepository {
+	return &CampaignVideoUploadRepository{
+		db: database.DB,
+	}
+}
+
+// Create 创建上传会话
+func (cvur *CampaignVideoUploadRepository) Create(upload *models.CampaignVideoUpload) error {
+	return cvur.db.Create(upload).Error
+}
+
+// GetByUploadID 获取计划下的上传会话
+func (cvur *CampaignVideoUploadRepository) GetByUploadID(campaignID uint, uploadID string) (*models.CampaignVideoUpload, error) {
+	var upload models.CampaignVideoUpload
+	if err := cvur.db.Where("campaign_id = ? AND upload_id = ?", campaignID, uploadID).First(&upload).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("上传会话不存在")
+		}
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// AdvanceOffset 在偏移量未被其他请求修改的前提下更新已接收字节数，返回是否更新成功
+func (cvur *CampaignVideoUploadRepository) AdvanceOffset(id uint, from, to int64) (bool, error) {
+	result := cvur.db.Model(&models.CampaignVideoUpload{}).
+		Where("id = ? AND `offset` = ? AND status = ?", id, from, models.VideoUploadStatusUploading).
+		Update("offset", to)
+	return result.RowsAffected == 1, result.Error
+}
+
+// Save 保存上传会话
+func (cvur *CampaignVideoUploadRepository) Save(upload *models.CampaignVideoUpload) error {
+	return cvur.db.Save(upload).Error
+}
+
+// ListExpired 获取已过期但仍处于上传中的会话
+func (cvur *CampaignVideoUploadRepository) ListExpired(now time.Time, limit int) ([]*models.CampaignVideoUpload, error) {
+	var uploads []*models.CampaignVideoUpload
+	err := cvur.db.Where("status = ? AND expires_at < ?", models.VideoUploadStatusUploading, now).
+		Order("id ASC").Limit(limit).Find(&uploads).Error
+	return uploads, err
+}
+
+// MarkExpired 将会话标记为已过期
+func (cvur *CampaignVideoUploadRepository) MarkExpired(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return cvur.db.Model(&models.CampaignVideoUpload{}).
+		Where("id IN ? AND status = ?", ids, models.VideoUploadStatusUploading).
+		Update("status", models.VideoUploadStatusExpired).Error
+}
@@ -0,0 +1,257 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"backend/database"
+	"backend/models"
+	"backend/types"
+	"gorm.io/gorm"
+)
+
+// PromoCodeRedemption 兑换码兑换记录
+type PromoCodeRedemption struct {
+	UserCouponID uint                    `json:"user_coupon_id"`
+	UserID       uint                    `json:"user_id"`
+	CustomerName string                  `json:"customer_name"`
+	Email        string                  `json:"email"`
+	PromoCodeID  uint                    `json:"promo_code_id"`
+	PromoCode    string                  `json:"promo_code"`
+	Status       models.UserCouponStatus `json:"status"`
+	RedeemedAt   time.Time               `json:"redeemed_at"`
+	UsedAt       *time.Time              `json:"used_at"`
+}
+
+// CouponPromoCodeRepository 优惠券兑换码仓库
+type CouponPromoCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewCouponPromoCodeRepository 创建优惠券兑换码仓库
+func NewCouponPromoCodeRepository() *CouponPromoCodeRepository {
+	return &CouponPromoCodeRepository{
+		db: database.DB,
+	}
+}
+
+// Create 创建兑换码
+func (pr *CouponPromoCodeRepository) Create(promoCode *models.CouponPromoCode) error {
+	return pr.db.Create(promoCode).Error
+}
+
+// BatchCreate 批量创建兑换码
+func (pr *CouponPromoCodeRepository) BatchCreate(promoCodes []*models.CouponPromoCode) error {
+	return pr.db.CreateInBatches(promoCodes, 500).Error
+}
+
+// GetByID 根据ID获取兑换码
+func (pr *CouponPromoCodeRepository) GetByID(id uint) (*models.CouponPromoCode, error) {
+	var promoCode models.CouponPromoCode
+	if err := pr.db.First(&promoCode, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("兑换码不存在")
+		}
+		return nil, err
+	}
+	return &promoCode, nil
+}
+
+// GetByCode 根据兑换码获取
+func (pr *CouponPromoCodeRepository) GetByCode(code string) (*models.CouponPromoCode, error) {
+	var promoCode models.CouponPromoCode
+	if err := pr.db.Preload("Coupon").Where("code = ?", code).First(&promoCode).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("兑换码不存在")
+		}
+		return nil, err
+	}
+	return &promoCode, nil
+}
+
+// ExistsCodes 返回已存在的兑换码集合
+func (pr *CouponPromoCodeRepository) ExistsCodes(codes []string) (map[string]bool, error) {
+	var existing []string
+	if err := pr.db.Unscoped().Model(&models.CouponPromoCode{}).
+		Where("code IN ?", codes).
+		Pluck("code", &existing).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]bool, len(existing))
+	for _, code := range existing {
+		result[code] = true
+	}
+	return result, nil
+}
+
+// ListByCoupon 获取优惠券下的兑换码列表
+func (pr *CouponPromoCodeRepository) ListByCoupon(couponID uint, req *types.FilterRequest, batchNo string) ([]*models.CouponPromoCode, int64, error) {
+	var promoCodes []*models.CouponPromoCode
+	var total int64
+
+	query := pr.db.Model(&models.CouponPromoCode{}).Where("coupon_id = ?", couponID)
+
+	// 搜索条件
+	if req.Search != "" {
+		query = query.Where("code LIKE ?", "%"+models.NormalizePromoCode(req.Search)+"%")
+	}
+
+	// 状态筛选
+	if req.Status != nil {
+		query = query.Where("status = ?", *req.Status)
+	}
+
+	// 类型筛选
+	if req.Category != "" {
+		query = query.Where("type = ?", req.Category)
+	}
+
+	// 批次筛选
+	if batchNo != "" {
+		query = query.Where("batch_no = ?", batchNo)
+	}
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 排序和分页
+	orderClause := fmt.Sprintf("%s %s", req.GetSort(), req.GetOrder())
+	if err := query.Order(orderClause).
+		Offset(req.GetOffset()).
+		Limit(req.GetSize()).
+		Find(&promoCodes).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return promoCodes, total, nil
+}
+
+// FindInBatchesByCoupon 分批遍历优惠券下的兑换码（用于导出）
+func (pr *CouponPromoCodeRepository) FindInBatchesByCoupon(couponID uint, batchNo string, fn func(promoCodes []*models.CouponPromoCode) error) error {
+	query := pr.db.Model(&models.CouponPromoCode{}).Where("coupon_id = ?", couponID)
+	if batchNo != "" {
+		query = query.Where("batch_no = ?", batchNo)
+	}
+
+	var promoCodes []*models.CouponPromoCode
+	return query.Order("id ASC").FindInBatches(&promoCodes, 1000, func(tx *gorm.DB, batch int) error {
+		return fn(promoCodes)
+	}).Error
+}
+
+// UpdateStatus 更新兑换码状态
+func (pr *CouponPromoCodeRepository) UpdateStatus(id uint, status models.PromoCodeStatus) error {
+	result := pr.db.Model(&models.CouponPromoCode{}).Where("id = ?", id).Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("兑换码不存在")
+	}
+	return nil
+}
+
+// Redeem 兑换码兑换（在同一事务中扣减兑换码次数、扣减优惠券库存并发放用户优惠券）
+func (pr *CouponPromoCodeRepository) Redeem(promoCode *models.CouponPromoCode, userCoupon *models.UserCoupon) error {
+	return pr.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// 条件更新兑换码使用次数，防止并发超发
+		query := tx.Model(&models.CouponPromoCode{}).
+			Where("id = ?", promoCode.ID).
+			Where("status = ?", models.PromoCodeStatusActive).
+			Where("(expired_at IS NULL OR expired_at > ?)", now)
+
+		updates := map[string]interface{}{
+			"used_count": gorm.Expr("used_count + 1"),
+		}
+		if promoCode.Type == models.PromoCodeTypeUnique {
+			query = query.Where("redeemed_by IS NULL")
+			updates["redeemed_by"] = userCoupon.UserID
+			updates["redeemed_at"] = now
+		} else {
+			query = query.Where("(max_uses = 0 OR used_count < max_uses)")
+		}
+
+		result := query.Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("兑换码已被使用或已失效")
+		}
+
+		// 同一用户同一优惠券只能持有一张
+		var exists int64
+		if err := tx.Model(&models.UserCoupon{}).
+			Where("user_id = ? AND coupon_id = ?", userCoupon.UserID, userCoupon.CouponID).
+			Count(&exists).Error; err != nil {
+			return err
+		}
+		if exists > 0 {
+			return fmt.Errorf("您已经领取过此优惠券")
+		}
+
+		// 条件扣减优惠券库存
+		result = tx.Model(&models.Coupon{}).
+			Where("id = ?", userCoupon.CouponID).
+			Where("(total_count = 0 OR used_count < total_count)").
+			Update("used_count", gorm.Expr("used_count + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("优惠券已被领完")
+		}
+
+		userCoupon.PromoCodeID = &promoCode.ID
+		userCoupon.PromoCode = promoCode.Code
+		return tx.Create(userCoupon).Error
+	})
+}
+
+// GetRedemptions 获取优惠券通过兑换码发放的记录
+func (pr *CouponPromoCodeRepository) GetRedemptions(couponID uint, req *types.FilterRequest) ([]*PromoCodeRedemption, int64, error) {
+	var redemptions []*PromoCodeRedemption
+	var total int64
+
+	query := pr.db.Table("user_coupons").
+		Joins("LEFT JOIN customers ON customers.id = user_coupons.user_id").
+		Where("user_coupons.coupon_id = ?", couponID).
+		Where("user_coupons.promo_code_id IS NOT NULL").
+		Where("user_coupons.deleted_at IS NULL")
+
+	// 搜索条件（兑换码/客户名称/邮箱）
+	if req.Search != "" {
+		searchPattern := "%" + req.Search + "%"
+		query = query.Where("user_coupons.promo_code LIKE ? OR customers.name LIKE ? OR customers.email LIKE ?",
+			searchPattern, searchPattern, searchPattern)
+	}
+
+	// 日期范围筛选
+	if req.StartDate != nil {
+		query = query.Where("user_coupons.created_at >= ?", req.StartDate)
+	}
+	if req.EndDate != nil {
+		query = query.Where("user_coupons.created_at <= ?", req.EndDate)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Select(`user_coupons.id AS user_coupon_id, user_coupons.user_id, customers.name AS customer_name, customers.email,
+		user_coupons.promo_code_id, user_coupons.promo_code, user_coupons.status,
+		user_coupons.created_at AS redeemed_at, user_coupons.used_at`).
+		Order("user_coupons.created_at DESC").
+		Offset(req.GetOffset()).
+		Limit(req.GetSize()).
+		Scan(&redemptions).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return redemptions, total, nil
+}
@@ -27,6 +27,27 @@ func (cr *CustomerRepository) List(req *types.FilterRequest) ([]*models.Customer
 	var customers []*models.Customer
 	var total int64
 
+	query := cr.filterQuery(req)
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 排序和分页
+	orderClause := fmt.Sprintf("%s %s", req.GetSort(), req.GetOrder())
+	if err := query.Order(orderClause).
+		Offset(req.GetOffset()).
+		Limit(req.GetSize()).
+		Find(&customers).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return customers, total, nil
+}
+
+// filterQuery 构建列表/导出共用的筛选条件
+func (cr *CustomerRepository) filterQuery(req *types.FilterRequest) *gorm.DB {
 	query := cr.db.Model(&models.Customer{})
 
 	// 搜索条件
@@ -49,21 +70,34 @@ func (cr *CustomerRepository) List(req *types.FilterRequest) ([]*models.Customer
 		query = query.Where("created_at <= ?", req.EndDate)
 	}
 
-	// 获取总数
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
+	return query
+}
 
-	// 排序和分页
-	orderClause := fmt.Sprintf("%s %s", req.GetSort(), req.GetOrder())
-	if err := query.Order(orderClause).
-		Offset(req.GetOffset()).
-		Limit(req.GetSize()).
-		Find(&customers).Error; err != nil {
-		return nil, 0, err
+// CountByFilter 按筛选条件统计数量
+func (cr *CustomerRepository) CountByFilter(req *types.FilterRequest) (int64, error) {
+	var total int64
+	err := cr.filterQuery(req).Count(&total).Error
+	return total, err
+}
+
+// Cursor 按筛选条件以数据库游标逐行遍历（用于导出）
+func (cr *CustomerRepository) Cursor(req *types.FilterRequest, fn func(customer *models.Customer) error) error {
+	rows, err := cr.filterQuery(req).Order("id ASC").Rows()
+	if err != nil {
+		return err
 	}
+	defer rows.Close()
 
-	return customers, total, nil
+	for rows.Next() {
+		var customer models.Customer
+		if err := cr.db.ScanRows(rows, &customer); err != nil {
+			return err
+		}
+		if err := fn(&customer); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
 
 // GetByID 根据ID获取客户
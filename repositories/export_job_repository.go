@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"backend/database"
+	"backend/models"
+	"backend/types"
+	"gorm.io/gorm"
+)
+
+// ExportJobRepository 导出任务仓库
+type ExportJobRepository struct {
+	db *gorm.DB
+}
+
+// NewExportJobRepository 创建导出任务仓库
+func NewExportJobRepository() *ExportJobRepository {
+	return &ExportJobRepository{
+		db: database.DB,
+	}
+}
+
+// Create 创建导出任务
+func (ejr *ExportJobRepository) Create(job *models.ExportJob) error {
+	return ejr.db.Create(job).Error
+}
+
+// GetByID 根据ID获取导出任务
+func (ejr *ExportJobRepository) GetByID(id uint) (*models.ExportJob, error) {
+	var job models.ExportJob
+	if err := ejr.db.First(&job, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("导出任务不存在")
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// List 获取导出任务列表
+func (ejr *ExportJobRepository) List(req *types.FilterRequest, createdBy uint) ([]*models.ExportJob, int64, error) {
+	var jobs []*models.ExportJob
+	var total int64
+
+	query := ejr.db.Model(&models.ExportJob{})
+
+	// 只看自己创建的任务
+	if createdBy > 0 {
+		query = query.Where("created_by = ?", createdBy)
+	}
+
+	// 状态筛选
+	if req.Status != nil {
+		query = query.Where("status = ?", *req.Status)
+	}
+
+	// 导出对象筛选
+	if req.Category != "" {
+		query = query.Where("resource = ?", req.Category)
+	}
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("id DESC").
+		Offset(req.GetOffset()).
+		Limit(req.GetSize()).
+		Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return jobs, total, nil
+}
+
+// UpdateFields 更新导出任务字段
+func (ejr *ExportJobRepository) UpdateFields(id uint, fields map[string]interface{}) error {
+	return ejr.db.Model(&models.ExportJob{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// GetExpired 获取文件已过期的导出任务
+func (ejr *ExportJobRepository) GetExpired(before time.Time) ([]*models.ExportJob, error) {
+	var jobs []*models.ExportJob
+	err := ejr.db.Where("expired_at IS NOT NULL AND expired_at < ? AND file_path <> ''", before).
+		Find(&jobs).Error
+	return jobs, err
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"fmt"
+
+	"backend/database"
+	"backend/models"
+	"backend/types"
+	"gorm.io/gorm"
+)
+
+// ImportJobRepository 导入任务仓库
+type ImportJobRepository struct {
+	db *gorm.DB
+}
+
+// NewImportJobRepository 创建导入任务仓库
+func NewImportJobRepository() *ImportJobRepository {
+	return &ImportJobRepository{
+		db: database.DB,
+	}
+}
+
+// Create 创建导入任务
+func (ijr *ImportJobRepository) Create(job *models.ImportJob) error {
+	return ijr.db.Create(job).Error
+}
+
+// GetByID 根据ID获取导入任务
+func (ijr *ImportJobRepository) GetByID(id uint) (*models.ImportJob, error) {
+	var job models.ImportJob
+	if err := ijr.db.First(&job, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("导入任务不存在")
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// List 获取导入任务列表
+func (ijr *ImportJobRepository) List(req *types.FilterRequest, createdBy uint) ([]*models.ImportJob, int64, error) {
+	var jobs []*models.ImportJob
+	var total int64
+
+	query := ijr.db.Model(&models.ImportJob{})
+
+	// 只看自己创建的任务
+	if createdBy > 0 {
+		query = query.Where("created_by = ?", createdBy)
+	}
+
+	// 状态筛选
+	if req.Status != nil {
+		query = query.Where("status = ?", *req.Status)
+	}
+
+	// 导入对象筛选
+	if req.Category != "" {
+		query = query.Where("resource = ?", req.Category)
+	}
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("id DESC").
+		Offset(req.GetOffset()).
+		Limit(req.GetSize()).
+		Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return jobs, total, nil
+}
+
+// UpdateFields 更新导入任务字段
+func (ijr *ImportJobRepository) UpdateFields(id uint, fields map[string]interface{}) error {
+	return ijr.db.Model(&models.ImportJob{}).Where("id = ?", id).Updates(fields).Error
+}
@@ -9,6 +9,7 @@ import (
 	"backend/types"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ProductRepository 产品仓库
@@ -62,7 +63,8 @@ func (pr *ProductRepository) List(req *types.FilterRequest) ([]*models.Product,
 
 	// 排序和分页
 	orderClause := fmt.Sprintf("%s %s", req.GetSort(), req.GetOrder())
-	if err := query.Order(orderClause).
+	if err := query.Preload("Images", orderedImages).
+		Order(orderClause).
 		Offset(req.GetOffset()).
 		Limit(req.GetSize()).
 		Find(&products).Error; err != nil {
@@ -75,7 +77,7 @@ func (pr *ProductRepository) List(req *types.FilterRequest) ([]*models.Product,
 // GetByID 根据ID获取产品
 func (pr *ProductRepository) GetByID(id uint) (*models.Product, error) {
 	var product models.Product
-	if err := pr.db.First(&product, id).Error; err != nil {
+	if err := pr.db.Preload("Images", orderedImages).First(&product, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("产品不存在")
 		}
@@ -89,9 +91,14 @@ func (pr *ProductRepository) Create(product *models.Product) error {
 	return pr.db.Create(product).Error
 }
 
-// Update 更新产品
+// Update 更新产品（不级联保存图片等关联）
 func (pr *ProductRepository) Update(product *models.Product) error {
-	return pr.db.Save(product).Error
+	return pr.db.Omit(clause.Associations).Save(product).Error
+}
+
+// UpdateTrackingKey 更新产品事件上报密钥
+func (pr *ProductRepository) UpdateTrackingKey(id uint, trackingKey string) error {
+	return pr.db.Model(&models.Product{}).Where("id = ?", id).Update("tracking_key", trackingKey).Error
 }
 
 // Delete 删除产品
@@ -251,3 +258,102 @@ func (pr *ProductRepository) GetStatistics() (*types.StatisticsResponse, error)
 		Categories: categories,
 	}, nil
 }
+
+// orderedImages 产品图片按排序值、ID 升序加载
+func orderedImages(db *gorm.DB) *gorm.DB {
+	return db.Order("sort ASC, id ASC")
+}
+
+// ListImages 获取产品图片列表
+func (pr *ProductRepository) ListImages(productID uint) ([]models.ProductImage, error) {
+	var images []models.ProductImage
+	if err := orderedImages(pr.db.Where("product_id = ?", productID)).Find(&images).Error; err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// GetImage 获取产品的指定图片
+func (pr *ProductRepository) GetImage(productID, imageID uint) (*models.ProductImage, error) {
+	var image models.ProductImage
+	if err := pr.db.Where("id = ? AND product_id = ?", imageID, productID).First(&image).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("图片不存在")
+		}
+		return nil, err
+	}
+	return &image, nil
+}
+
+// CreateImages 批量添加产品图片
+func (pr *ProductRepository) CreateImages(images []models.ProductImage) error {
+	if len(images) == 0 {
+		return nil
+	}
+	return pr.db.Create(&images).Error
+}
+
+// UpdateImage 更新产品图片
+func (pr *ProductRepository) UpdateImage(image *models.ProductImage) error {
+	return pr.db.Save(image).Error
+}
+
+// DeleteImage 删除产品图片
+func (pr *ProductRepository) DeleteImage(productID, imageID uint) error {
+	result := pr.db.Where("id = ? AND product_id = ?", imageID, productID).Delete(&models.ProductImage{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("图片不存在")
+	}
+	return nil
+}
+
+// MaxImageSort 获取产品图片的最大排序值，没有图片时返回 -1
+func (pr *ProductRepository) MaxImageSort(productID uint) (int, error) {
+	var maxSort *int
+	if err := pr.db.Model(&models.ProductImage{}).
+		Where("product_id = ?", productID).
+		Select("MAX(sort)").
+		Scan(&maxSort).Error; err != nil {
+		return 0, err
+	}
+	if maxSort == nil {
+		return -1, nil
+	}
+	return *maxSort, nil
+}
+
+// UpdateWithImages 在一个事务中更新产品，images 不为 nil 时同时替换全部图片
+func (pr *ProductRepository) UpdateWithImages(product *models.Product, images []models.ProductImage) error {
+	return pr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(product).Error; err != nil {
+			return err
+		}
+		if images == nil {
+			return nil
+		}
+		if err := tx.Where("product_id = ?", product.ID).Delete(&models.ProductImage{}).Error; err != nil {
+			return err
+		}
+		if len(images) == 0 {
+			return nil
+		}
+		return tx.Create(&images).Error
+	})
+}
+
+// UpdateImageSorts 按给定 ID 顺序重写产品图片的排序值
+func (pr *ProductRepository) UpdateImageSorts(productID uint, ids []uint) error {
+	return pr.db.Transaction(func(tx *gorm.DB) error {
+		for sort, id := range ids {
+			if err := tx.Model(&models.ProductImage{}).
+				Where("id = ? AND product_id = ?", id, productID).
+				Update("sort", sort).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"fmt"
+
+	"backend/database"
+	"backend/models"
+	"backend/types"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProductRevisionRepository 产品版本仓库
+type ProductRevisionRepository struct {
+	db *gorm.DB
+}
+
+// NewProductRevisionRepository 创建产品版本仓库
+func NewProductRevisionRepository() *ProductRevisionRepository {
+	return &ProductRevisionRepository{
+		db: database.DB,
+	}
+}
+
+// List 获取产品的版本列表（按版本号倒序，不含快照内容）
+func (prr *ProductRevisionRepository) List(productID uint, req *types.PageRequest) ([]*models.ProductRevision, int64, error) {
+	var revisions []*models.ProductRevision
+	var total int64
+
+	query := prr.db.Model(&models.ProductRevision{}).Where("product_id = ?", productID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Omit("snapshot").
+		Preload("Admin").
+		Order("version DESC").
+		Offset(req.GetOffset()).
+		Limit(req.GetSize()).
+		Find(&revisions).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return revisions, total, nil
+}
+
+// GetByVersion 获取产品的指定版本
+func (prr *ProductRevisionRepository) GetByVersion(productID uint, version int) (*models.ProductRevision, error) {
+	var revision models.ProductRevision
+	if err := prr.db.Preload("Admin").
+		Where("product_id = ? AND version = ?", productID, version).
+		First(&revision).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("版本不存在")
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// Latest 获取产品的最新版本，没有版本时返回 nil
+func (prr *ProductRevisionRepository) Latest(productID uint) (*models.ProductRevision, error) {
+	var revision models.ProductRevision
+	if err := prr.db.Where("product_id = ?", productID).
+		Order("version DESC").
+		First(&revision).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// ListSnapshots 获取产品全部版本的快照（按版本号升序），用于核对素材引用
+func (prr *ProductRevisionRepository) ListSnapshots(productID uint) ([]*models.ProductRevision, error) {
+	var revisions []*models.ProductRevision
+	err := prr.db.Select("id", "product_id", "version", "snapshot").
+		Where("product_id = ?", productID).
+		Order("version ASC").
+		Find(&revisions).Error
+	return revisions, err
+}
+
+// Create 追加产品版本，版本号在事务中按产品递增分配；
+// 先锁定产品行，使同一产品的并发保存依次分配版本号，避免唯一索引冲突导致版本丢失
+func (prr *ProductRevisionRepository) Create(revision *models.ProductRevision) error {
+	return prr.db.Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			First(&product, revision.ProductID).Error; err != nil {
+			return err
+		}
+
+		var maxVersion int
+		if err := tx.Model(&models.ProductRevision{}).
+			Where("product_id = ?", revision.ProductID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&maxVersion).Error; err != nil {
+			return err
+		}
+		revision.Version = maxVersion + 1
+		return tx.Create(revision).Error
+	})
+}
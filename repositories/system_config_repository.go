@@ -2,10 +2,13 @@ package repositories
 
 import (
 	"fmt"
+	"sort"
 
 	"backend/database"
 	"backend/models"
+	"backend/types"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // SystemConfigRepository 系统配置仓库
@@ -38,7 +41,7 @@ func (scr *SystemConfigRepository) GetAllConfigs() (map[string]*models.SystemCon
 // GetByKey 根据键获取配置
 func (scr *SystemConfigRepository) GetByKey(key string) (*models.SystemConfig, error) {
 	var config models.SystemConfig
-	if err := scr.db.Where("key = ?", key).First(&config).Error; err != nil {
+	if err := scr.db.Where("`key` = ?", key).First(&config).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("配置不存在")
 		}
@@ -60,7 +63,7 @@ func (scr *SystemConfigRepository) Update(config *models.SystemConfig) error {
 // CreateOrUpdate 创建或更新配置
 func (scr *SystemConfigRepository) CreateOrUpdate(config *models.SystemConfig) error {
 	var existing models.SystemConfig
-	if err := scr.db.Where("key = ?", config.Key).First(&existing).Error; err != nil {
+	if err := scr.db.Where("`key` = ?", config.Key).First(&existing).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			// 不存在则创建
 			return scr.db.Create(config).Error
@@ -76,7 +79,7 @@ func (scr *SystemConfigRepository) CreateOrUpdate(config *models.SystemConfig) e
 
 // Delete 删除配置
 func (scr *SystemConfigRepository) Delete(key string) error {
-	result := scr.db.Where("key = ?", key).Delete(&models.SystemConfig{})
+	result := scr.db.Where("`key` = ?", key).Delete(&models.SystemConfig{})
 	if result.Error != nil {
 		return result.Error
 	}
@@ -86,44 +89,103 @@ func (scr *SystemConfigRepository) Delete(key string) error {
 	return nil
 }
 
-// UpdateConfigs 批量更新配置
-func (scr *SystemConfigRepository) UpdateConfigs(configs map[string]string) error {
-	tx := scr.db.Begin()
+// UpdateConfigs 批量更新配置，值有变化的配置按键追加一条变更历史
+func (scr *SystemConfigRepository) UpdateConfigs(configs map[string]string, changedBy uint) error {
+	// 固定加锁顺序，避免并发更新时死锁
+	keys := make([]string, 0, len(configs))
+	for key := range configs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 
-	for key, value := range configs {
-		var config models.SystemConfig
-		if err := tx.Where("key = ?", key).First(&config).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
+	return scr.db.Transaction(func(tx *gorm.DB) error {
+		for _, key := range keys {
+			value := configs[key]
+			history := models.SystemConfigHistory{Key: key, NewValue: value, ChangedBy: changedBy}
+
+			var config models.SystemConfig
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("`key` = ?", key).First(&config).Error
+			switch {
+			case err == gorm.ErrRecordNotFound:
 				// 不存在则创建
-				config = models.SystemConfig{
-					Key:   key,
-					Value: value,
+				config = models.SystemConfig{Key: key, Value: value}
+				if definition, ok := models.GetConfigDefinition(key); ok {
+					config.Description = definition.Description
 				}
 				if err := tx.Create(&config).Error; err != nil {
-					tx.Rollback()
 					return err
 				}
-			} else {
-				tx.Rollback()
+			case err != nil:
 				return err
+			case config.Value == value:
+				continue
+			default:
+				// 存在则更新
+				oldValue := config.Value
+				history.OldValue = &oldValue
+				config.Value = value
+				if err := tx.Save(&config).Error; err != nil {
+					return err
+				}
 			}
-		} else {
-			// 存在则更新
-			config.Value = value
-			if err := tx.Save(&config).Error; err != nil {
-				tx.Rollback()
+
+			var maxVersion int
+			if err := tx.Model(&models.SystemConfigHistory{}).
+				Where("`key` = ?", key).
+				Select("COALESCE(MAX(version), 0)").
+				Scan(&maxVersion).Error; err != nil {
+				return err
+			}
+			history.Version = maxVersion + 1
+			if err := tx.Create(&history).Error; err != nil {
 				return err
 			}
 		}
+		return nil
+	})
+}
+
+// UpdateDescription 更新配置说明
+func (scr *SystemConfigRepository) UpdateDescription(key, description string) error {
+	return scr.db.Model(&models.SystemConfig{}).Where("`key` = ?", key).Update("description", description).Error
+}
+
+// ListHistory 获取配置的变更历史（按版本号倒序）
+func (scr *SystemConfigRepository) ListHistory(key string, req *types.PageRequest) ([]*models.SystemConfigHistory, int64, error) {
+	var histories []*models.SystemConfigHistory
+	var total int64
+
+	query := scr.db.Model(&models.SystemConfigHistory{}).Where("`key` = ?", key)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
 
-	return tx.Commit().Error
+	if err := query.Order("version DESC").
+		Offset(req.GetOffset()).
+		Limit(req.GetSize()).
+		Find(&histories).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return histories, total, nil
+}
+
+// GetHistoryVersion 获取配置的指定历史版本
+func (scr *SystemConfigRepository) GetHistoryVersion(key string, version int) (*models.SystemConfigHistory, error) {
+	var history models.SystemConfigHistory
+	if err := scr.db.Where("`key` = ? AND version = ?", key, version).First(&history).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("版本不存在")
+		}
+		return nil, err
+	}
+	return &history, nil
 }
 
 // GetByKeys 根据键列表获取配置
 func (scr *SystemConfigRepository) GetByKeys(keys []string) (map[string]*models.SystemConfig, error) {
 	var configs []*models.SystemConfig
-	if err := scr.db.Where("key IN ?", keys).Find(&configs).Error; err != nil {
+	if err := scr.db.Where("`key` IN ?", keys).Find(&configs).Error; err != nil {
 		return nil, err
 	}
 
@@ -138,7 +200,7 @@ func (scr *SystemConfigRepository) GetByKeys(keys []string) (map[string]*models.
 // GetConfigsByPrefix 根据前缀获取配置
 func (scr *SystemConfigRepository) GetConfigsByPrefix(prefix string) ([]*models.SystemConfig, error) {
 	var configs []*models.SystemConfig
-	if err := scr.db.Where("key LIKE ?", prefix+"%").Find(&configs).Error; err != nil {
+	if err := scr.db.Where("`key` LIKE ?", prefix+"%").Find(&configs).Error; err != nil {
 		return nil, err
 	}
 	return configs, nil
@@ -184,7 +246,7 @@ func (scr *SystemConfigRepository) GetSystemStats() (map[string]interface{}, err
 	for prefix, typeName := range configTypes {
 		var typeCount int64
 		scr.db.Model(&models.SystemConfig{}).
-			Where("key LIKE ?", prefix+"%").Count(&typeCount)
+			Where("`key` LIKE ?", prefix+"%").Count(&typeCount)
 		typeStats = append(typeStats, struct {
 			Type  string `json:"type"`
 			Count int64  `json:"count"`
@@ -202,7 +264,7 @@ func (scr *SystemConfigRepository) GetSystemStats() (map[string]interface{}, err
 
 // BatchDelete 批量删除配置
 func (scr *SystemConfigRepository) BatchDelete(keys []string) error {
-	return scr.db.Where("key IN ?", keys).Delete(&models.SystemConfig{}).Error
+	return scr.db.Where("`key` IN ?", keys).Delete(&models.SystemConfig{}).Error
 }
 
 // ResetToDefaults 重置为默认配置
@@ -244,7 +306,7 @@ func (scr *SystemConfigRepository) ImportConfigs(configs map[string]string, over
 
 	for key, value := range configs {
 		var existing models.SystemConfig
-		err := tx.Where("key = ?", key).First(&existing).Error
+		err := tx.Where("`key` = ?", key).First(&existing).Error
 		
 		if err != nil {
 			if err == gorm.ErrRecordNotFound {
@@ -276,23 +338,11 @@ func (scr *SystemConfigRepository) ImportConfigs(configs map[string]string, over
 	return tx.Commit().Error
 }
 
-// ValidateConfig 验证配置值
+// ValidateConfig 验证配置值，未登记的配置键不允许写入
 func (scr *SystemConfigRepository) ValidateConfig(key, value string) error {
-	// 根据配置键验证值的有效性
-	switch key {
-	case models.ConfigKeyMaintenanceMode, models.ConfigKeyRegistrationEnabled:
-		if value != "true" && value != "false" {
-			return fmt.Errorf("布尔值配置只能是 true 或 false")
-		}
-	case models.ConfigKeyMaxUploadSize, models.ConfigKeyPasswordMinLength, models.ConfigKeySessionTimeout:
-		if value == "" {
-			return fmt.Errorf("数值配置不能为空")
-		}
-	case models.ConfigKeyContactEmail:
-		// 简单的邮箱格式验证
-		if value != "" && len(value) > 0 {
-			// 这里可以添加更严格的邮箱验证
-		}
+	definition, ok := models.GetConfigDefinition(key)
+	if !ok {
+		return fmt.Errorf("未知的配置项: %s", key)
 	}
-	return nil
-}
\ No newline at end of file
+	return definition.Validate(value)
+}
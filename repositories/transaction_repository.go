@@ -27,6 +27,27 @@ func (tr *TransactionRepository) List(req *types.FilterRequest) ([]*models.Trans
 	var transactions []*models.Transaction
 	var total int64
 
+	query := tr.filterQuery(req)
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 排序和分页
+	orderClause := fmt.Sprintf("%s %s", req.GetSort(), req.GetOrder())
+	if err := query.Order(orderClause).
+		Offset(req.GetOffset()).
+		Limit(req.GetSize()).
+		Find(&transactions).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return transactions, total, nil
+}
+
+// filterQuery 构建列表/导出共用的筛选条件
+func (tr *TransactionRepository) filterQuery(req *types.FilterRequest) *gorm.DB {
 	query := tr.db.Model(&models.Transaction{})
 
 	// 搜索条件
@@ -54,21 +75,34 @@ func (tr *TransactionRepository) List(req *types.FilterRequest) ([]*models.Trans
 		query = query.Where("created_at <= ?", req.EndDate)
 	}
 
-	// 获取总数
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
+	return query
+}
 
-	// 排序和分页
-	orderClause := fmt.Sprintf("%s %s", req.GetSort(), req.GetOrder())
-	if err := query.Order(orderClause).
-		Offset(req.GetOffset()).
-		Limit(req.GetSize()).
-		Find(&transactions).Error; err != nil {
-		return nil, 0, err
+// CountByFilter 按筛选条件统计数量
+func (tr *TransactionRepository) CountByFilter(req *types.FilterRequest) (int64, error) {
+	var total int64
+	err := tr.filterQuery(req).Count(&total).Error
+	return total, err
+}
+
+// Cursor 按筛选条件以数据库游标逐行遍历（用于导出）
+func (tr *TransactionRepository) Cursor(req *types.FilterRequest, fn func(transaction *models.Transaction) error) error {
+	rows, err := tr.filterQuery(req).Order("id ASC").Rows()
+	if err != nil {
+		return err
 	}
+	defer rows.Close()
 
-	return transactions, total, nil
+	for rows.Next() {
+		var transaction models.Transaction
+		if err := tr.db.ScanRows(rows, &transaction); err != nil {
+			return err
+		}
+		if err := fn(&transaction); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
 
 // GetByID 根据ID获取交易
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"fmt"
+	"time"
 
 	"backend/database"
 	"backend/models"
@@ -123,6 +124,27 @@ func (ucr *UserCouponRepository) GetAvailableByUserID(userID uint) ([]*models.Us
 	return userCoupons, nil
 }
 
+// GetUsableByUserID 获取用户在指定订单金额下可用的优惠券（单次JOIN查询）
+func (ucr *UserCouponRepository) GetUsableByUserID(userID uint, orderAmount float64) ([]*models.UserCoupon, error) {
+	var userCoupons []*models.UserCoupon
+
+	query := ucr.db.Joins("Coupon").
+		Where("user_coupons.user_id = ?", userID).
+		Where("user_coupons.status = ?", models.UserCouponStatusUnused).
+		Where("user_coupons.expired_at > NOW()").
+		Where("`Coupon`.`status` = ?", models.CouponStatusActive)
+
+	// 检查订单金额是否满足使用条件
+	if orderAmount > 0 {
+		query = query.Where("`Coupon`.`min_amount` <= ?", orderAmount)
+	}
+
+	if err := query.Order("user_coupons.expired_at ASC").Find(&userCoupons).Error; err != nil {
+		return nil, err
+	}
+	return userCoupons, nil
+}
+
 // GetUsedByUserID 获取用户已使用的优惠券
 func (ucr *UserCouponRepository) GetUsedByUserID(userID uint) ([]*models.UserCoupon, error) {
 	var userCoupons []*models.UserCoupon
@@ -147,6 +169,25 @@ func (ucr *UserCouponRepository) GetExpiredByUserID(userID uint) ([]*models.User
 	return userCoupons, nil
 }
 
+// MarkUsed 在一个事务中核销同一用户的多张优惠券，任一张已被使用或不属于该用户时全部不生效
+func (ucr *UserCouponRepository) MarkUsed(userID uint, ids []uint) error {
+	return ucr.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.UserCoupon{}).
+			Where("id IN ? AND user_id = ? AND status = ?", ids, userID, models.UserCouponStatusUnused).
+			Updates(map[string]interface{}{
+				"status":  models.UserCouponStatusUsed,
+				"used_at": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(ids)) {
+			return fmt.Errorf("优惠券已被使用")
+		}
+		return nil
+	})
+}
+
 // BatchExpire 批量过期优惠券
 func (ucr *UserCouponRepository) BatchExpire(ids []uint) error {
 	return ucr.db.Model(&models.UserCoupon{}).
@@ -12,19 +12,23 @@ import (
 // Handlers 所有handler的集合
 type Handlers struct {
 	// Admin handlers
-	AdminAgent      *admin.AgentHandler
-	AdminRole       *admin.RoleHandler
-	AdminDashboard  *admin.DashboardHandler
-	AdminAuth       *admin.AuthHandler
-	AdminProduct    *admin.ProductHandler
-	AdminCampaign   *admin.CampaignHandler
-	AdminCustomer   *admin.CustomerHandler
-	AdminCoupon     *admin.CouponHandler
-	AdminAuthCode   *admin.AuthCodeHandler
-	AdminFinance    *admin.FinanceHandler
-	AdminPermission *admin.PermissionHandler
-	AdminStatistics *admin.StatisticsHandler
-	AdminSystem     *admin.SystemHandler
+	AdminAgent            *admin.AgentHandler
+	AdminRole             *admin.RoleHandler
+	AdminDashboard        *admin.DashboardHandler
+	AdminAuth             *admin.AuthHandler
+	AdminProduct          *admin.ProductHandler
+	AdminCampaign         *admin.CampaignHandler
+	AdminCampaignTemplate *admin.CampaignTemplateHandler
+	AdminCustomer         *admin.CustomerHandler
+	AdminCoupon           *admin.CouponHandler
+	AdminAuthCode         *admin.AuthCodeHandler
+	AdminFinance          *admin.FinanceHandler
+	AdminPermission       *admin.PermissionHandler
+	AdminStatistics       *admin.StatisticsHandler
+	AdminSystem           *admin.SystemHandler
+	AdminExport           *admin.ExportHandler
+	AdminImport           *admin.ImportHandler
+	AdminAsset            *admin.AssetHandler
 
 	// Client handlers
 	ClientAuth     *client.AuthHandler
@@ -32,25 +36,31 @@ type Handlers struct {
 	ClientFinance  *client.FinanceHandler
 	ClientCoupon   *client.CouponHandler
 	ClientAuthCode *client.AuthCodeHandler
+	ClientEvent    *client.EventHandler
+	ClientAd       *client.AdHandler
 }
 
 // NewHandlers 创建所有handlers
 func NewHandlers(db *gorm.DB) *Handlers {
 	return &Handlers{
 		// Admin handlers
-		AdminAgent:      admin.NewAgentHandler(db),
-		AdminRole:       admin.NewRoleHandler(db),
-		AdminDashboard:  admin.NewDashboardHandler(),
-		AdminAuth:       admin.NewAuthHandler(),
-		AdminProduct:    admin.NewProductHandler(),
-		AdminCampaign:   admin.NewCampaignHandler(),
-		AdminCustomer:   admin.NewCustomerHandler(),
-		AdminCoupon:     admin.NewCouponHandler(),
-		AdminAuthCode:   admin.NewAuthCodeHandler(),
-		AdminFinance:    admin.NewFinanceHandler(),
-		AdminPermission: admin.NewPermissionHandler(),
-		AdminStatistics: admin.NewStatisticsHandler(),
-		AdminSystem:     admin.NewSystemHandler(),
+		AdminAgent:            admin.NewAgentHandler(db),
+		AdminRole:             admin.NewRoleHandler(db),
+		AdminDashboard:        admin.NewDashboardHandler(),
+		AdminAuth:             admin.NewAuthHandler(),
+		AdminProduct:          admin.NewProductHandler(),
+		AdminCampaign:         admin.NewCampaignHandler(),
+		AdminCampaignTemplate: admin.NewCampaignTemplateHandler(),
+		AdminCustomer:         admin.NewCustomerHandler(),
+		AdminCoupon:           admin.NewCouponHandler(),
+		AdminAuthCode:         admin.NewAuthCodeHandler(),
+		AdminFinance:          admin.NewFinanceHandler(),
+		AdminPermission:       admin.NewPermissionHandler(),
+		AdminStatistics:       admin.NewStatisticsHandler(),
+		AdminSystem:           admin.NewSystemHandler(),
+		AdminExport:           admin.NewExportHandler(),
+		AdminImport:           admin.NewImportHandler(),
+		AdminAsset:            admin.NewAssetHandler(),
 
 		// Client handlers
 		ClientAuth:     client.NewAuthHandler(),
@@ -58,6 +68,8 @@ func NewHandlers(db *gorm.DB) *Handlers {
 		ClientFinance:  client.NewFinanceHandler(),
 		ClientCoupon:   client.NewCouponHandler(),
 		ClientAuthCode: client.NewAuthCodeHandler(),
+		ClientEvent:    client.NewEventHandler(),
+		ClientAd:       client.NewAdHandler(),
 	}
 }
 
@@ -142,34 +154,81 @@ func SetupAdminRoutes(api *gin.RouterGroup, h *Handlers) {
 			// 产品管理
 			products := protected.Group("/products")
 			{
-				products.GET("", h.AdminProduct.List)                            // 列表
-				products.GET("/:id", h.AdminProduct.GetByID)                     // 详情
-				products.POST("", h.AdminProduct.Create)                         // 创建
-				products.PUT("/:id", h.AdminProduct.Update)                      // 更新
-				products.PATCH("/:id/status", h.AdminProduct.UpdateStatus)       // 更新状态
-				products.DELETE("/:id", h.AdminProduct.Delete)                   // 删除
-				products.POST("/:id/upload-logo", h.AdminProduct.UploadLogo)     // 上传Logo（需要ID）
-				products.POST("/:id/upload-images", h.AdminProduct.UploadImages) // 上传图片（需要ID）
-				products.POST("/upload", h.AdminProduct.UploadFile)              // 通用单文件上传
-				products.POST("/upload-multiple", h.AdminProduct.UploadFiles)    // 通用多文件上传
-				products.GET("/statistics", h.AdminProduct.GetStatistics)        // 统计
+				products.GET("", h.AdminProduct.List)                                            // 列表
+				products.GET("/:id", h.AdminProduct.GetByID)                                     // 详情
+				products.POST("", h.AdminProduct.Create)                                         // 创建
+				products.PUT("/:id", h.AdminProduct.Update)                                      // 更新
+				products.PATCH("/:id/status", h.AdminProduct.UpdateStatus)                       // 更新状态
+				products.DELETE("/:id", h.AdminProduct.Delete)                                   // 删除
+				products.POST("/:id/upload-logo", h.AdminProduct.UploadLogo)                     // 上传Logo（需要ID）
+				products.POST("/:id/upload-images", h.AdminProduct.UploadImages)                 // 上传图片（需要ID）
+				products.POST("/upload", h.AdminProduct.UploadFile)                              // 通用单文件上传
+				products.POST("/upload-multiple", h.AdminProduct.UploadFiles)                    // 通用多文件上传
+				products.GET("/statistics", h.AdminProduct.GetStatistics)                        // 统计
+				products.GET("/:id/tracking-key", h.AdminProduct.GetTrackingKey)                 // 事件上报密钥
+				products.POST("/:id/tracking-key/rotate", h.AdminProduct.RotateTrackingKey)      // 重新生成上报密钥
+				products.GET("/:id/images", h.AdminProduct.ListImages)                           // 图片列表
+				products.PUT("/:id/images/order", h.AdminProduct.ReorderImages)                  // 调整图片顺序
+				products.PUT("/:id/images/:image_id", h.AdminProduct.UpdateImage)                // 更新图片
+				products.DELETE("/:id/images/:image_id", h.AdminProduct.DeleteImage)             // 删除图片
+				products.POST("/:id/app-manifest", h.AdminProduct.ParseAppManifest)              // 解析APK/IPA清单
+				products.GET("/:id/revisions", h.AdminProduct.ListRevisions)                     // 版本历史
+				products.GET("/:id/revisions/diff", h.AdminProduct.DiffRevisions)                // 版本对比
+				products.GET("/:id/revisions/:version", h.AdminProduct.GetRevision)              // 版本详情
+				products.POST("/:id/revisions/:version/restore", h.AdminProduct.RestoreRevision) // 恢复到指定版本
 			}
 
 			// 广告计划管理
 			campaigns := protected.Group("/campaigns")
 			{
-				campaigns.GET("", h.AdminCampaign.List)                              // 列表
-				campaigns.GET("/:id", h.AdminCampaign.GetByID)                       // 详情
-				campaigns.POST("", h.AdminCampaign.Create)                           // 创建
-				campaigns.PUT("/:id", h.AdminCampaign.Update)                        // 更新
-				campaigns.DELETE("/:id", h.AdminCampaign.Delete)                     // 删除
-				campaigns.POST("/upload", h.AdminCampaign.UploadFile)                // 通用文件上传
-				campaigns.POST("/:id/upload-image", h.AdminCampaign.UploadMainImage) // 上传主图
-				campaigns.POST("/:id/upload-video", h.AdminCampaign.UploadVideo)     // 上传视频
-				campaigns.GET("/:id/stats", h.AdminCampaign.GetStatistics)           // 统计
-				campaigns.PUT("/:id/status", h.AdminCampaign.UpdateStatus)           // 更新状态
-				campaigns.POST("/:id/pause", h.AdminCampaign.Pause)                  // 暂停
-				campaigns.POST("/:id/resume", h.AdminCampaign.Resume)                // 恢复
+				campaigns.GET("", h.AdminCampaign.List)                                                // 列表
+				campaigns.GET("/:id", h.AdminCampaign.GetByID)                                         // 详情
+				campaigns.POST("", h.AdminCampaign.Create)                                             // 创建
+				campaigns.PUT("/:id", h.AdminCampaign.Update)                                          // 更新
+				campaigns.DELETE("/:id", h.AdminCampaign.Delete)                                       // 删除
+				campaigns.POST("/upload", h.AdminCampaign.UploadFile)                                  // 通用文件上传
+				campaigns.POST("/:id/upload-image", h.AdminCampaign.UploadMainImage)                   // 上传主图
+				campaigns.POST("/:id/upload-video", h.AdminCampaign.UploadVideo)                       // 上传视频
+				campaigns.OPTIONS("/:id/video-uploads", h.AdminCampaign.VideoUploadOptions)            // 断点续传能力
+				campaigns.POST("/:id/video-uploads", h.AdminCampaign.CreateVideoUpload)                // 断点续传创建
+				campaigns.HEAD("/:id/video-uploads/:upload_id", h.AdminCampaign.HeadVideoUpload)       // 断点续传进度
+				campaigns.GET("/:id/video-uploads/:upload_id", h.AdminCampaign.GetVideoUpload)         // 断点续传详情
+				campaigns.PATCH("/:id/video-uploads/:upload_id", h.AdminCampaign.PatchVideoUpload)     // 续传数据
+				campaigns.DELETE("/:id/video-uploads/:upload_id", h.AdminCampaign.DeleteVideoUpload)   // 取消断点续传
+				campaigns.GET("/:id/stats", h.AdminCampaign.GetStatistics)                             // 统计
+				campaigns.PUT("/:id/status", h.AdminCampaign.UpdateStatus)                             // 更新状态
+				campaigns.POST("/:id/pause", h.AdminCampaign.Pause)                                    // 暂停
+				campaigns.POST("/:id/resume", h.AdminCampaign.Resume)                                  // 恢复
+				campaigns.GET("/:id/status-logs", h.AdminCampaign.GetStatusLogs)                       // 状态变更记录
+				campaigns.GET("/:id/budget", h.AdminCampaign.GetBudget)                                // 预算状态
+				campaigns.GET("/budget-alerts", h.AdminCampaign.GetBudgetAlerts)                       // 预算提醒
+				campaigns.POST("/:id/targeting/evaluate", h.AdminCampaign.EvaluateTargeting)           // 评估定向
+				campaigns.GET("/:id/creatives", h.AdminCampaign.ListCreatives)                         // 创意列表
+				campaigns.POST("/:id/creatives", h.AdminCampaign.CreateCreative)                       // 创建创意
+				campaigns.GET("/:id/creatives/compare", h.AdminCampaign.CompareCreatives)              // 创意对比与显著性
+				campaigns.PUT("/:id/creatives/:creative_id", h.AdminCampaign.UpdateCreative)           // 更新创意
+				campaigns.DELETE("/:id/creatives/:creative_id", h.AdminCampaign.DeleteCreative)        // 删除创意
+				campaigns.POST("/:id/creatives/:creative_id/promote", h.AdminCampaign.PromoteCreative) // 采用创意
+				campaigns.POST("/:id/clone", h.AdminCampaign.Clone)                                    // 复制计划
+				campaigns.GET("/reviews/pending", h.AdminCampaign.GetPendingReviews)                   // 待审核计划
+				campaigns.GET("/reviewers", h.AdminCampaign.GetReviewers)                              // 可分配审核人
+				campaigns.POST("/:id/review/submit", h.AdminCampaign.SubmitReview)                     // 提交审核
+				campaigns.POST("/:id/review/withdraw", h.AdminCampaign.WithdrawReview)                 // 撤回审核
+				campaigns.POST("/:id/review/approve", h.AdminCampaign.ApproveReview)                   // 审核通过
+				campaigns.POST("/:id/review/reject", h.AdminCampaign.RejectReview)                     // 驳回
+				campaigns.GET("/:id/review/diff", h.AdminCampaign.GetReviewDiff)                       // 相对上次审核通过的变更
+				campaigns.GET("/:id/reviews", h.AdminCampaign.GetReviews)                              // 审核记录
+			}
+
+			// 计划模板
+			campaignTemplates := protected.Group("/campaign-templates")
+			{
+				campaignTemplates.GET("", h.AdminCampaignTemplate.List)                         // 列表
+				campaignTemplates.GET("/:id", h.AdminCampaignTemplate.GetByID)                  // 详情
+				campaignTemplates.POST("", h.AdminCampaignTemplate.Create)                      // 从计划保存模板
+				campaignTemplates.PUT("/:id", h.AdminCampaignTemplate.Update)                   // 更新
+				campaignTemplates.DELETE("/:id", h.AdminCampaignTemplate.Delete)                // 删除
+				campaignTemplates.POST("/:id/instantiate", h.AdminCampaignTemplate.Instantiate) // 按模板创建计划
 			}
 
 			// 客户管理
@@ -188,6 +247,7 @@ func SetupAdminRoutes(api *gin.RouterGroup, h *Handlers) {
 				customers.PUT("/:id/balance", h.AdminCustomer.UpdateBalance)        // 更新余额
 				customers.GET("/statistics", h.AdminCustomer.GetStatistics)         // 统计
 				customers.GET("/export", h.AdminCustomer.Export)                    // 导出
+				customers.POST("/import", h.AdminCustomer.Import)                   // 批量导入
 				customers.POST("/batch-status", h.AdminCustomer.BatchUpdateStatus)  // 批量更新状态
 			}
 
@@ -201,23 +261,34 @@ func SetupAdminRoutes(api *gin.RouterGroup, h *Handlers) {
 				coupons.DELETE("/:id", h.AdminCoupon.Delete)              // 删除
 				coupons.POST("/:id/distribute", h.AdminCoupon.Distribute) // 分发
 				coupons.GET("/statistics", h.AdminCoupon.GetStatistics)   // 统计
+
+				// 兑换码
+				coupons.GET("/:id/promo-codes", h.AdminCoupon.ListPromoCodes)                        // 兑换码列表
+				coupons.POST("/:id/promo-codes", h.AdminCoupon.CreatePromoCode)                      // 创建通用兑换码
+				coupons.POST("/:id/promo-codes/generate", h.AdminCoupon.GeneratePromoCodes)          // 批量生成唯一兑换码
+				coupons.GET("/:id/promo-codes/export", h.AdminCoupon.ExportPromoCodes)               // 导出兑换码CSV
+				coupons.GET("/:id/promo-codes/redemptions", h.AdminCoupon.GetPromoCodeRedemptions)   // 兑换记录
+				coupons.PUT("/:id/promo-codes/:code_id/status", h.AdminCoupon.UpdatePromoCodeStatus) // 启用/停用兑换码
 			}
 
 			// 授权码管理
 			authcodes := protected.Group("/authcodes")
 			{
-				authcodes.GET("", h.AdminAuthCode.List)                          // 列表
-				authcodes.GET("/:id", h.AdminAuthCode.GetByID)                   // 详情
-				authcodes.POST("/generate", h.AdminAuthCode.Generate)            // 生成
-				authcodes.POST("/verify", h.AdminAuthCode.Verify)                // 验证
-				authcodes.PUT("/:id/revoke", h.AdminAuthCode.Revoke)             // 撤销
-				authcodes.POST("/batch-revoke", h.AdminAuthCode.BatchRevoke)     // 批量撤销
-				authcodes.GET("/export", h.AdminAuthCode.Export)                 // 导出
-				authcodes.GET("/statistics", h.AdminAuthCode.GetStatistics)      // 统计
-				authcodes.GET("/usage-history", h.AdminAuthCode.GetUsageHistory) // 使用历史
-				authcodes.GET("/expired", h.AdminAuthCode.GetExpiredCodes)       // 过期授权码
-				authcodes.POST("/clean-expired", h.AdminAuthCode.CleanExpired)   // 清理过期
-				authcodes.GET("/code/:code", h.AdminAuthCode.GetCodeByCode)      // 按code查询
+				authcodes.GET("", h.AdminAuthCode.List)                            // 列表
+				authcodes.GET("/:id", h.AdminAuthCode.GetByID)                     // 详情
+				authcodes.POST("/generate", h.AdminAuthCode.Generate)              // 生成
+				authcodes.POST("/verify", h.AdminAuthCode.Verify)                  // 验证
+				authcodes.PUT("/:id/revoke", h.AdminAuthCode.Revoke)               // 撤销
+				authcodes.POST("/batch-revoke", h.AdminAuthCode.BatchRevoke)       // 批量撤销
+				authcodes.GET("/export", h.AdminAuthCode.Export)                   // 导出
+				authcodes.GET("/statistics", h.AdminAuthCode.GetStatistics)        // 统计
+				authcodes.GET("/usage-history", h.AdminAuthCode.GetUsageHistory)   // 使用历史
+				authcodes.GET("/expired", h.AdminAuthCode.GetExpiredCodes)         // 过期授权码
+				authcodes.POST("/clean-expired", h.AdminAuthCode.CleanExpired)     // 清理过期
+				authcodes.GET("/code/:code", h.AdminAuthCode.GetCodeByCode)        // 按code查询
+				authcodes.GET("/batches", h.AdminAuthCode.ListBatches)             // 批次列表
+				authcodes.GET("/batches/:id", h.AdminAuthCode.GetBatch)            // 批次详情
+				authcodes.GET("/batches/:id/codes", h.AdminAuthCode.GetBatchCodes) // 批次授权码
 			}
 
 			// 财务管理
@@ -251,20 +322,59 @@ func SetupAdminRoutes(api *gin.RouterGroup, h *Handlers) {
 				statistics.GET("/products", h.AdminStatistics.GetProducts) // 产品统计
 			}
 
-			// 系统管理
+			// 系统管理；备份与数据导入导出涉及全部数据（含管理员密码哈希），仅限超级管理员操作
+			superAdmin := middleware.RequireAdminRole(1)
 			system := protected.Group("/system")
 			{
-				system.GET("/configs", h.AdminSystem.GetConfigs)              // 获取配置
-				system.PUT("/configs", h.AdminSystem.UpdateConfigs)           // 更新配置
-				system.GET("/config/:key", h.AdminSystem.GetConfig)           // 获取单个配置
-				system.PUT("/config/:key", h.AdminSystem.UpdateConfig)        // 更新单个配置
-				system.GET("/stats", h.AdminSystem.GetStats)                  // 统计
-				system.GET("/dashboard", h.AdminSystem.GetDashboard)          // 仪表盘
-				system.GET("/info", h.AdminSystem.GetSystemInfo)              // 系统信息
-				system.PUT("/info", h.AdminSystem.UpdateSystemInfo)           // 更新系统信息
-				system.GET("/maintenance", h.AdminSystem.GetMaintenanceMode)  // 维护模式
-				system.POST("/maintenance", h.AdminSystem.SetMaintenanceMode) // 设置维护模式
-				system.GET("/health", h.AdminSystem.GetHealth)                // 健康检查
+				system.GET("/configs", h.AdminSystem.GetConfigs)                                         // 获取配置
+				system.PUT("/configs", h.AdminSystem.UpdateConfigs)                                      // 更新配置
+				system.GET("/config/:key", h.AdminSystem.GetConfig)                                      // 获取单个配置
+				system.PUT("/config/:key", h.AdminSystem.UpdateConfig)                                   // 更新单个配置
+				system.GET("/configs/definitions", h.AdminSystem.GetConfigDefinitions)                   // 配置项定义
+				system.GET("/config/:key/history", h.AdminSystem.GetConfigHistory)                       // 配置变更历史
+				system.POST("/config/:key/history/:version/restore", h.AdminSystem.RestoreConfigVersion) // 恢复配置历史版本
+				system.GET("/stats", h.AdminSystem.GetStats)                                             // 统计
+				system.GET("/dashboard", h.AdminSystem.GetDashboard)                                     // 仪表盘
+				system.GET("/info", h.AdminSystem.GetSystemInfo)                                         // 系统信息
+				system.PUT("/info", h.AdminSystem.UpdateSystemInfo)                                      // 更新系统信息
+				system.GET("/maintenance", h.AdminSystem.GetMaintenanceMode)                             // 维护模式
+				system.POST("/maintenance", h.AdminSystem.SetMaintenanceMode)                            // 设置维护模式
+				system.GET("/health", h.AdminSystem.GetHealth)                                           // 健康检查
+				system.GET("/backups", h.AdminSystem.GetBackups)                                         // 备份列表
+				system.POST("/backups", superAdmin, h.AdminSystem.CreateBackup)                          // 创建备份
+				system.GET("/backups/:filename/download", superAdmin, h.AdminSystem.DownloadBackup)      // 下载备份
+				system.DELETE("/backups/:filename", superAdmin, h.AdminSystem.DeleteBackup)              // 删除备份
+				system.POST("/backups/:filename/restore", superAdmin, h.AdminSystem.RestoreBackup)       // 从备份恢复（会先自动备份）
+				system.GET("/data/tables", superAdmin, h.AdminSystem.GetDataTables)                      // 可导出的数据表及结构版本
+				system.POST("/data/export", superAdmin, h.AdminSystem.ExportData)                        // 导出数据（jsonl/csv）
+				system.POST("/data/import", superAdmin, h.AdminSystem.ImportData)                        // 导入数据（skip/override/fail）
+			}
+
+			// 素材库
+			assets := protected.Group("/assets")
+			{
+				assets.GET("", h.AdminAsset.List)             // 列表与搜索
+				assets.GET("/:id", h.AdminAsset.GetByID)      // 详情（含引用）
+				assets.DELETE("/:id", h.AdminAsset.Delete)    // 删除无引用素材
+				assets.POST("/cleanup", h.AdminAsset.Cleanup) // 立即清理过期无引用素材
+			}
+
+			// 导出任务
+			exports := protected.Group("/exports")
+			{
+				exports.GET("", h.AdminExport.ListJobs)                     // 任务列表
+				exports.GET("/columns/:resource", h.AdminExport.GetColumns) // 可导出列
+				exports.GET("/:id", h.AdminExport.GetJob)                   // 任务详情
+				exports.GET("/:id/download", h.AdminExport.DownloadJob)     // 下载文件
+			}
+
+			// 导入任务
+			imports := protected.Group("/imports")
+			{
+				imports.GET("", h.AdminImport.ListJobs)                                // 任务列表
+				imports.GET("/templates/customers", h.AdminImport.GetCustomerTemplate) // 客户导入模板
+				imports.GET("/:id", h.AdminImport.GetJob)                              // 任务详情
+				imports.GET("/:id/report", h.AdminImport.DownloadReport)               // 下载错误报告
 			}
 		}
 	}
@@ -282,9 +392,12 @@ func SetupClientRoutes(api *gin.RouterGroup, h *Handlers) {
 			auth.POST("/login", h.ClientAuth.Login) // 客户登录
 		}
 
+		// 广告事件上报（按产品签名校验）
+		cli.POST("/events", middleware.MaintenanceMiddleware(), h.ClientEvent.Track) // 批量上报展示/点击/转化
+
 		// 受保护路由（需要认证）
 		protected := cli.Group("")
-		protected.Use(middleware.AuthMiddleware())
+		protected.Use(middleware.MaintenanceMiddleware(), middleware.AuthMiddleware()) // 维护模式拦截（登录不受限，便于白名单客户登录）
 		{
 			// 认证相关
 			authProtected := protected.Group("/auth")
@@ -318,6 +431,8 @@ func SetupClientRoutes(api *gin.RouterGroup, h *Handlers) {
 				coupons.POST("/claim", h.ClientCoupon.ClaimCoupon)            // 领取优惠券
 				coupons.POST("/:id/use", h.ClientCoupon.UseCoupon)            // 使用优惠券
 				coupons.GET("/available", h.ClientCoupon.GetAvailableCoupons) // 可用优惠券
+				coupons.POST("/redeem", h.ClientCoupon.RedeemPromoCode)       // 兑换码兑换
+				coupons.GET("/best", h.ClientCoupon.GetBestCombination)       // 最优优惠券组合
 			}
 
 			// 授权码验证
@@ -325,6 +440,9 @@ func SetupClientRoutes(api *gin.RouterGroup, h *Handlers) {
 			{
 				authcodes.POST("/verify", h.ClientAuthCode.Verify) // 验证授权码
 			}
+
+			// 广告
+			protected.GET("/ads", h.ClientAd.List) // 获取可投放的广告
 		}
 	}
 }
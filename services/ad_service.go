@@ -0,0 +1,417 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/database"
+	"backend/models"
+	"backend/repositories"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// adCandidateTTL 候选计划缓存时间
+	adCandidateTTL = 30 * time.Second
+	// adProfileTTL 客户定向信息缓存时间
+	adProfileTTL = time.Minute
+	// AdMaxLimit 单次请求最多返回的广告数
+	AdMaxLimit = 10
+)
+
+// AdItem 返回给客户端的广告
+type AdItem struct {
+	CampaignID      uint                   `json:"campaign_id"`
+	CampaignNumber  string                 `json:"campaign_number"`
+	CreativeID      *uint                  `json:"creative_id"`
+	Name            string                 `json:"name"`
+	Description     string                 `json:"description"`
+	MainImage       string                 `json:"main_image"`
+	Video           string                 `json:"video"`
+	DeliveryContent models.CustomFieldList `json:"delivery_content"`
+	ProductID       uint                   `json:"product_id"`
+	ProductName     string                 `json:"product_name"`
+	ProductLogo     string                 `json:"product_logo"`
+	GooglePayLink   string                 `json:"google_pay_link"`
+	AppStoreLink    string                 `json:"app_store_link"`
+}
+
+// adCandidate 缓存的候选计划
+type adCandidate struct {
+	campaign  *models.Campaign
+	targeting models.CampaignTargeting
+	throttled bool
+}
+
+// adProfile 缓存的客户定向信息
+type adProfile struct {
+	tags       []string
+	agentChain []uint
+	expiresAt  time.Time
+}
+
+// adCache 广告投放缓存（进程内）
+type adCache struct {
+	mu          sync.RWMutex
+	candidates  []*adCandidate
+	refreshedAt time.Time
+	refreshMu   sync.Mutex
+
+	// 已记录过旧版定向告警的计划及其更新时间（受 refreshMu 保护），计划修改后重新记录
+	legacyLogged map[uint]time.Time
+
+	profileMu sync.Mutex
+	profiles  map[uint]*adProfile
+
+	// Redis 不可用时的频次计数
+	freqMu sync.Mutex
+	freq   map[string]*adFrequency
+}
+
+// adFrequency 进程内频次计数
+type adFrequency struct {
+	count     int
+	expiresAt time.Time
+}
+
+var sharedAdCache = &adCache{
+	legacyLogged: make(map[uint]time.Time),
+	profiles:     make(map[uint]*adProfile),
+	freq:         make(map[string]*adFrequency),
+}
+
+// InvalidateAdCache 计划或产品变更后清除候选计划缓存
+func InvalidateAdCache() {
+	sharedAdCache.mu.Lock()
+	sharedAdCache.refreshedAt = time.Time{}
+	sharedAdCache.mu.Unlock()
+}
+
+// AdService 广告投放服务
+type AdService struct {
+	campaignService *CampaignService
+	budgetService   *CampaignBudgetService
+	campaignRepo    *repositories.CampaignRepository
+	cache           *adCache
+}
+
+// NewAdService 创建广告投放服务
+func NewAdService() *AdService {
+	return &AdService{
+		campaignService: NewCampaignService(),
+		budgetService:   NewCampaignBudgetService(),
+		campaignRepo:    repositories.NewCampaignRepository(),
+		cache:           sharedAdCache,
+	}
+}
+
+// Serve 为客户选择广告：投放中且在排期内、预算未用尽、命中定向、未超频次，按权重轮播；
+// 返回广告不占用频次，频次由客户端上报的展示事件计数（见 RecordAdImpression）
+func (as *AdService) Serve(customerID uint, ctx *models.TargetingContext, productID *uint, limit int) ([]*AdItem, error) {
+	if limit <= 0 {
+		limit = 1
+	}
+	if limit > AdMaxLimit {
+		limit = AdMaxLimit
+	}
+
+	candidates, err := as.getCandidates()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.CustomerID = &customerID
+	if err := as.fillProfile(customerID, ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	eligible := make([]*adCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		campaign := candidate.campaign
+		if candidate.throttled || !campaign.InSchedule(now) {
+			continue
+		}
+		if productID != nil && campaign.ProductID != *productID {
+			continue
+		}
+		if matched, _ := candidate.targeting.Match(ctx); !matched {
+			continue
+		}
+		eligible = append(eligible, candidate)
+	}
+
+	items := []*AdItem{}
+	for len(items) < limit && len(eligible) > 0 {
+		index := pickWeighted(eligible)
+		candidate := eligible[index]
+		eligible = append(eligible[:index], eligible[index+1:]...)
+
+		if !as.cache.underFrequencyCap(candidate.campaign, customerID) {
+			continue
+		}
+		items = append(items, newAdItem(candidate.campaign))
+	}
+
+	return items, nil
+}
+
+// getCandidates 获取候选计划，缓存过期时重新加载
+func (as *AdService) getCandidates() ([]*adCandidate, error) {
+	as.cache.mu.RLock()
+	if time.Since(as.cache.refreshedAt) < adCandidateTTL {
+		candidates := as.cache.candidates
+		as.cache.mu.RUnlock()
+		return candidates, nil
+	}
+	as.cache.mu.RUnlock()
+
+	// 同一时间只有一个请求刷新缓存
+	as.cache.refreshMu.Lock()
+	defer as.cache.refreshMu.Unlock()
+
+	as.cache.mu.RLock()
+	if time.Since(as.cache.refreshedAt) < adCandidateTTL {
+		candidates := as.cache.candidates
+		as.cache.mu.RUnlock()
+		return candidates, nil
+	}
+	as.cache.mu.RUnlock()
+
+	campaigns, err := as.campaignRepo.GetServableCampaigns()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	candidates := make([]*adCandidate, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		targeting, legacy, skipped := campaign.EffectiveTargeting()
+		if legacy && len(skipped) > 0 && !as.cache.legacyLogged[campaign.ID].Equal(campaign.UpdatedAt) {
+			as.cache.legacyLogged[campaign.ID] = campaign.UpdatedAt
+			log.Printf("计划 %d 的旧版定向字段未生效，按不限处理: %s", campaign.ID, strings.Join(skipped, "；"))
+		}
+		candidates = append(candidates, &adCandidate{
+			campaign:  campaign,
+			targeting: targeting,
+			throttled: as.budgetService.IsThrottled(campaign, now),
+		})
+	}
+
+	as.cache.mu.Lock()
+	as.cache.candidates = candidates
+	as.cache.refreshedAt = now
+	as.cache.mu.Unlock()
+
+	return candidates, nil
+}
+
+// fillProfile 补全客户标签与代理层级（带缓存）
+func (as *AdService) fillProfile(customerID uint, ctx *models.TargetingContext) error {
+	as.cache.profileMu.Lock()
+	profile, ok := as.cache.profiles[customerID]
+	as.cache.profileMu.Unlock()
+
+	if !ok || time.Now().After(profile.expiresAt) {
+		profileCtx := &models.TargetingContext{CustomerID: &customerID}
+		if err := as.campaignService.PrepareTargetingContext(profileCtx); err != nil {
+			return err
+		}
+		profile = &adProfile{
+			tags:       profileCtx.Tags,
+			agentChain: profileCtx.AgentChain,
+			expiresAt:  time.Now().Add(adProfileTTL),
+		}
+
+		as.cache.profileMu.Lock()
+		// 清理过期的缓存，避免无限增长
+		if len(as.cache.profiles) > 10000 {
+			now := time.Now()
+			for id, p := range as.cache.profiles {
+				if now.After(p.expiresAt) {
+					delete(as.cache.profiles, id)
+				}
+			}
+		}
+		as.cache.profiles[customerID] = profile
+		as.cache.profileMu.Unlock()
+	}
+
+	ctx.Tags = append(ctx.Tags, profile.tags...)
+	ctx.AgentChain = profile.agentChain
+	return nil
+}
+
+// adFrequencyKey 客户在计划下的展示频次计数键
+func adFrequencyKey(campaignID, customerID uint) string {
+	return fmt.Sprintf("ad_freq:%d:%d", campaignID, customerID)
+}
+
+// adFrequencyWindow 计划的频次统计周期，未设置时为 24 小时
+func adFrequencyWindow(campaign *models.Campaign) time.Duration {
+	window := time.Duration(campaign.FrequencyWindow) * time.Hour
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+	return window
+}
+
+// underFrequencyCap 客户在统计周期内已上报的展示次数是否未达上限（只读，不占用次数）
+func (c *adCache) underFrequencyCap(campaign *models.Campaign, customerID uint) bool {
+	if campaign.FrequencyCap <= 0 {
+		return true
+	}
+	key := adFrequencyKey(campaign.ID, customerID)
+
+	if redisClient := database.GetRedis(); redisClient != nil {
+		count, err := redisClient.Get(context.Background(), key).Int64()
+		if err == nil || err == redis.Nil {
+			return count < int64(campaign.FrequencyCap)
+		}
+		log.Printf("Redis frequency counter failed, falling back to memory: %v", err)
+	}
+
+	c.freqMu.Lock()
+	defer c.freqMu.Unlock()
+
+	entry, ok := c.freq[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return true
+	}
+	return entry.count < campaign.FrequencyCap
+}
+
+// addFrequency 客户的展示频次加一
+func (c *adCache) addFrequency(campaign *models.Campaign, customerID uint) {
+	window := adFrequencyWindow(campaign)
+	key := adFrequencyKey(campaign.ID, customerID)
+
+	if redisClient := database.GetRedis(); redisClient != nil {
+		ctx := context.Background()
+		count, err := redisClient.Incr(ctx, key).Result()
+		if err == nil {
+			if count == 1 {
+				redisClient.Expire(ctx, key, window)
+			}
+			return
+		}
+		log.Printf("Redis frequency counter failed, falling back to memory: %v", err)
+	}
+
+	c.freqMu.Lock()
+	defer c.freqMu.Unlock()
+
+	now := time.Now()
+	entry, ok := c.freq[key]
+	if !ok || now.After(entry.expiresAt) {
+		if len(c.freq) > 100000 {
+			for k, e := range c.freq {
+				if now.After(e.expiresAt) {
+					delete(c.freq, k)
+				}
+			}
+		}
+		entry = &adFrequency{expiresAt: now.Add(window)}
+		c.freq[key] = entry
+	}
+	entry.count++
+}
+
+// RecordAdImpression 客户的展示事件入库时计入计划的展示频次；
+// 频次只按上报的展示计数，客户端重试或预取广告不会消耗次数
+func RecordAdImpression(campaign *models.Campaign, customerID uint) {
+	if campaign.FrequencyCap <= 0 {
+		return
+	}
+	sharedAdCache.addFrequency(campaign, customerID)
+}
+
+// pickWeighted 按权重随机选择候选计划，返回下标
+func pickWeighted(candidates []*adCandidate) int {
+	total := 0
+	for _, candidate := range candidates {
+		total += adWeight(candidate.campaign)
+	}
+
+	n := rand.Intn(total)
+	for i, candidate := range candidates {
+		n -= adWeight(candidate.campaign)
+		if n < 0 {
+			return i
+		}
+	}
+	return len(candidates) - 1
+}
+
+// adWeight 计划轮播权重，未设置时为1
+func adWeight(campaign *models.Campaign) int {
+	if campaign.Weight <= 0 {
+		return 1
+	}
+	return campaign.Weight
+}
+
+// pickCreative 按流量权重选择投放中的创意，没有创意时返回nil
+func pickCreative(creatives []models.CampaignCreative) *models.CampaignCreative {
+	total := 0
+	for i := range creatives {
+		if creatives[i].IsActive() && creatives[i].Weight > 0 {
+			total += creatives[i].Weight
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	n := rand.Intn(total)
+	for i := range creatives {
+		if !creatives[i].IsActive() || creatives[i].Weight <= 0 {
+			continue
+		}
+		n -= creatives[i].Weight
+		if n < 0 {
+			return &creatives[i]
+		}
+	}
+	return nil
+}
+
+// newAdItem 组装广告返回数据
+func newAdItem(campaign *models.Campaign) *AdItem {
+	item := &AdItem{
+		CampaignID:      campaign.ID,
+		CampaignNumber:  campaign.CampaignNumber,
+		Name:            campaign.Name,
+		Description:     campaign.Description,
+		MainImage:       campaign.MainImage,
+		Video:           campaign.Video,
+		DeliveryContent: campaign.DeliveryContent,
+		ProductID:       campaign.ProductID,
+	}
+	if creative := pickCreative(campaign.Creatives); creative != nil {
+		creativeID := creative.ID
+		item.CreativeID = &creativeID
+		if creative.MainImage != "" {
+			item.MainImage = creative.MainImage
+		}
+		if creative.Video != "" {
+			item.Video = creative.Video
+		}
+		if len(creative.DeliveryContent) > 0 {
+			item.DeliveryContent = creative.DeliveryContent
+		}
+	}
+	if campaign.Product != nil {
+		item.ProductName = campaign.Product.Name
+		item.ProductLogo = campaign.Product.Logo
+		item.GooglePayLink = campaign.Product.GooglePayLink
+		item.AppStoreLink = campaign.Product.AppStoreLink
+	}
+	return item
+}
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"time"
+
+	"backend/configs"
+	"backend/models"
+	"backend/pkg/storage"
+	"backend/repositories"
+	"backend/types"
+	"backend/utils"
+)
+
+const (
+	defaultAssetOrphanGrace = 72 * time.Hour
+	assetSweepPeriod        = time.Hour
+	assetSweepBatch         = 100
+)
+
+// embeddedUploadURL 简介、投放内容等文本与 JSON 字段中嵌入的上传文件地址
+var embeddedUploadURL = regexp.MustCompile(regexp.QuoteMeta(storage.URLPrefix) + `[^\s"'<>()?#\\]+`)
+
+// AssetService 素材库服务：登记上传文件、按内容去重、维护业务引用并清理无引用的文件
+type AssetService struct {
+	assetRepo    *repositories.AssetRepository
+	productRepo  *repositories.ProductRepository
+	campaignRepo *repositories.CampaignRepository
+	creativeRepo *repositories.CampaignCreativeRepository
+	templateRepo *repositories.CampaignTemplateRepository
+	revisionRepo *repositories.ProductRevisionRepository
+}
+
+// NewAssetService 创建素材库服务
+func NewAssetService() *AssetService {
+	return &AssetService{
+		assetRepo:    repositories.NewAssetRepository(),
+		productRepo:  repositories.NewProductRepository(),
+		campaignRepo: repositories.NewCampaignRepository(),
+		creativeRepo: repositories.NewCampaignCreativeRepository(),
+		templateRepo: repositories.NewCampaignTemplateRepository(),
+		revisionRepo: repositories.NewProductRevisionRepository(),
+	}
+}
+
+// AssetOrphanGrace 素材无引用后保留的时长
+func AssetOrphanGrace() time.Duration {
+	if hours := configs.AppConfig.Upload.OrphanGraceHours; hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultAssetOrphanGrace
+}
+
+// syncAssetReferences 业务数据保存后重建其素材引用；失败只记录日志，清理前会再次核对实际使用情况
+func syncAssetReferences(ownerType string, ownerID uint) {
+	if ownerID == 0 {
+		return
+	}
+	if err := NewAssetService().SyncOwner(ownerType, ownerID); err != nil {
+		log.Printf("Failed to sync asset references for %s %d: %v", ownerType, ownerID, err)
+	}
+}
+
+// Register 登记刚上传的文件。已有内容相同的素材时删除本次写入的文件，改为返回已有素材的地址；
+// 已有素材缺少本次生成的缩略图或 WebP 变体时不复用，另行登记
+func (as *AssetService) Register(resp *types.UploadResponse, filename string, ownerID uint) {
+	if resp == nil || resp.Hash == "" {
+		return
+	}
+
+	candidates, err := as.assetRepo.FindByHash(resp.Hash)
+	if err != nil {
+		log.Printf("Failed to look up asset %s: %v", resp.Hash, err)
+		return
+	}
+	for _, existing := range candidates {
+		if existing.URL == resp.URL ||
+			(resp.Thumbnail != "" && existing.Thumbnail == "") ||
+			(resp.WebP != "" && existing.WebP == "") {
+			continue
+		}
+		if err := as.assetRepo.Touch(existing.ID, time.Now()); err != nil {
+			log.Printf("Failed to touch asset %d: %v", existing.ID, err)
+			continue
+		}
+		if err := utils.DeleteFile(resp.URL); err != nil {
+			log.Printf("Failed to delete duplicate upload %s: %v", resp.URL, err)
+		}
+		resp.URL = existing.URL
+		resp.Filename = path.Base(existing.URL)
+		resp.Size = existing.Size
+		resp.MimeType = existing.MimeType
+		resp.Width = existing.Width
+		resp.Height = existing.Height
+		resp.Thumbnail = existing.Thumbnail
+		resp.WebP = existing.WebP
+		resp.AssetID = existing.ID
+		return
+	}
+
+	// 新素材在被业务数据引用前同样按宽限期计时，上传后未使用的文件会被清理
+	now := time.Now()
+	asset := &models.Asset{
+		Hash:       resp.Hash,
+		URL:        resp.URL,
+		Filename:   path.Base(filename),
+		MimeType:   resp.MimeType,
+		Category:   models.AssetCategoryOf(resp.MimeType, filename),
+		Size:       resp.Size,
+		Width:      resp.Width,
+		Height:     resp.Height,
+		Thumbnail:  resp.Thumbnail,
+		WebP:       resp.WebP,
+		OwnerID:    ownerID,
+		OrphanedAt: &now,
+	}
+	if err := as.assetRepo.Create(asset); err != nil {
+		log.Printf("Failed to register asset %s: %v", resp.URL, err)
+		return
+	}
+	resp.AssetID = asset.ID
+}
+
+// ReleaseFile 替换下来的旧文件：已登记的素材由引用同步与清理任务处理（可能仍被其他数据使用），
+// 未登记的历史文件直接删除
+func (as *AssetService) ReleaseFile(url string) {
+	if url == "" {
+		return
+	}
+	assets, err := as.assetRepo.FindByURLs([]string{url})
+	if err != nil {
+		log.Printf("Failed to look up asset %s: %v", url, err)
+		return
+	}
+	if len(assets) == 0 {
+		utils.DeleteFile(url)
+	}
+}
+
+// List 素材库列表
+func (as *AssetService) List(req *types.FilterRequest, mimeType string, ownerID *uint, referenced *bool) ([]*models.Asset, int64, error) {
+	return as.assetRepo.List(req, mimeType, ownerID, referenced)
+}
+
+// GetByID 素材详情（含引用）
+func (as *AssetService) GetByID(id uint) (*models.Asset, error) {
+	return as.assetRepo.GetByID(id)
+}
+
+// Delete 删除未被引用的素材及其文件
+func (as *AssetService) Delete(id uint) error {
+	asset, err := as.assetRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	inUse, err := as.inUse(asset)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return &ServiceError{Code: 400, Message: "素材仍被引用，无法删除"}
+	}
+
+	if err := as.assetRepo.Delete(asset.ID); err != nil {
+		return err
+	}
+	if err := utils.DeleteFile(asset.URL); err != nil {
+		log.Printf("Failed to delete asset file %s: %v", asset.URL, err)
+	}
+	return nil
+}
+
+// inUse 引用数为 0 时再直接核对业务表，发现漏记的引用会同步补齐
+func (as *AssetService) inUse(asset *models.Asset) (bool, error) {
+	if asset.RefCount > 0 {
+		return true, nil
+	}
+	holders, err := as.assetRepo.FindHolders(assetURLs(asset))
+	if err != nil {
+		return false, err
+	}
+	for _, holder := range holders {
+		if err := as.SyncOwner(holder.OwnerType, holder.OwnerID); err != nil {
+			return false, err
+		}
+	}
+	return len(holders) > 0, nil
+}
+
+// assetURLs 素材的原图与变体地址
+func assetURLs(asset *models.Asset) []string {
+	urls := []string{asset.URL}
+	if asset.Thumbnail != "" {
+		urls = append(urls, asset.Thumbnail)
+	}
+	if asset.WebP != "" {
+		urls = append(urls, asset.WebP)
+	}
+	return urls
+}
+
+// SyncOwner 按业务数据当前内容重建其素材引用；数据已删除时清空引用
+func (as *AssetService) SyncOwner(ownerType string, ownerID uint) error {
+	fields, err := as.ownerFields(ownerType, ownerID)
+	if err != nil {
+		return err
+	}
+
+	var urls []string
+	for _, field := range fields {
+		urls = append(urls, field.url)
+	}
+	assets, err := as.assetRepo.FindByURLs(urls)
+	if err != nil {
+		return err
+	}
+	byURL := make(map[string]uint, len(assets)*3)
+	for _, asset := range assets {
+		for _, url := range assetURLs(asset) {
+			byURL[url] = asset.ID
+		}
+	}
+
+	refs := make([]models.AssetReference, 0, len(fields))
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		assetID, ok := byURL[field.url]
+		key := fmt.Sprintf("%d/%s", assetID, field.name)
+		if !ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		refs = append(refs, models.AssetReference{
+			AssetID:   assetID,
+			OwnerType: ownerType,
+			OwnerID:   ownerID,
+			Field:     field.name,
+		})
+	}
+	return as.assetRepo.ReplaceReferences(ownerType, ownerID, refs)
+}
+
+// assetField 业务数据中引用文件的字段
+type assetField struct {
+	name string
+	url  string
+}
+
+// ownerFields 读取业务数据中引用文件的字段，数据不存在时返回空
+func (as *AssetService) ownerFields(ownerType string, ownerID uint) ([]assetField, error) {
+	var fields []assetField
+	add := func(name, url string) {
+		if url != "" {
+			fields = append(fields, assetField{name: name, url: url})
+		}
+	}
+	// addEmbedded 登记文本或 JSON 字段中嵌入的地址
+	addEmbedded := func(name string, value interface{}) {
+		text, ok := value.(string)
+		if !ok {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return
+			}
+			text = string(data)
+		}
+		for _, url := range embeddedUploadURL.FindAllString(text, -1) {
+			add(name, url)
+		}
+	}
+
+	switch ownerType {
+	case models.AssetOwnerProduct:
+		// 历史版本快照中的文件在恢复版本时仍需使用，产品删除后同样保留
+		revisions, err := as.revisionRepo.ListSnapshots(ownerID)
+		if err != nil {
+			return nil, err
+		}
+		for _, revision := range revisions {
+			addEmbedded(fmt.Sprintf("revisions.%d", revision.Version), revision.Snapshot)
+		}
+
+		product, err := as.productRepo.GetByID(ownerID)
+		if err != nil {
+			if err.Error() == "产品不存在" {
+				return fields, nil
+			}
+			return nil, err
+		}
+		add("logo", product.Logo)
+		for _, image := range product.Images {
+			add("images", image.URL)
+		}
+		addEmbedded("description", product.Description)
+		addEmbedded("app_info", product.AppInfo)
+	case models.AssetOwnerCampaign:
+		campaign, err := as.campaignRepo.GetByID(ownerID)
+		if err != nil {
+			if err.Error() == "计划不存在" {
+				return nil, nil
+			}
+			return nil, err
+		}
+		add("main_image", campaign.MainImage)
+		add("video", campaign.Video)
+		addEmbedded("description", campaign.Description)
+		addEmbedded("delivery_content", campaign.DeliveryContent)
+		addEmbedded("delivery_rules", campaign.DeliveryRules)
+		creatives, err := as.creativeRepo.ListByCampaign(ownerID)
+		if err != nil {
+			return nil, err
+		}
+		for _, creative := range creatives {
+			add(fmt.Sprintf("creatives.%d.main_image", creative.ID), creative.MainImage)
+			add(fmt.Sprintf("creatives.%d.video", creative.ID), creative.Video)
+			addEmbedded(fmt.Sprintf("creatives.%d.delivery_content", creative.ID), creative.DeliveryContent)
+		}
+	case models.AssetOwnerCampaignTemplate:
+		template, err := as.templateRepo.GetByID(ownerID)
+		if err != nil {
+			if err.Error() == "模板不存在" {
+				return nil, nil
+			}
+			return nil, err
+		}
+		add("main_image", template.MainImage)
+		add("video", template.Video)
+		addEmbedded("description", template.Description)
+		addEmbedded("delivery_content", template.DeliveryContent)
+		addEmbedded("delivery_rules", template.DeliveryRules)
+		for i, creative := range template.Creatives {
+			add(fmt.Sprintf("creatives.%d.main_image", i), creative.MainImage)
+			add(fmt.Sprintf("creatives.%d.video", i), creative.Video)
+			addEmbedded(fmt.Sprintf("creatives.%d.delivery_content", i), creative.DeliveryContent)
+		}
+	default:
+		return nil, fmt.Errorf("未知的素材引用方类型: %s", ownerType)
+	}
+	return fields, nil
+}
+
+// CleanupOrphans 删除无引用超过宽限期的素材及其文件，返回删除数量
+func (as *AssetService) CleanupOrphans(now time.Time) (int, error) {
+	cutoff := now.Add(-AssetOrphanGrace())
+	total := 0
+	for {
+		assets, err := as.assetRepo.ListOrphans(cutoff, assetSweepBatch)
+		if err != nil {
+			return total, err
+		}
+		if len(assets) == 0 {
+			return total, nil
+		}
+
+		for _, asset := range assets {
+			inUse, err := as.inUse(asset)
+			if err != nil {
+				return total, err
+			}
+			if inUse {
+				// 引用已补齐；若仍未计入则重新计时，避免本轮反复处理
+				if err := as.assetRepo.Touch(asset.ID, now); err != nil {
+					return total, err
+				}
+				continue
+			}
+
+			deleted, err := as.assetRepo.DeleteOrphan(asset.ID, cutoff)
+			if err != nil {
+				return total, err
+			}
+			if !deleted {
+				continue
+			}
+			if err := utils.DeleteFile(asset.URL); err != nil {
+				log.Printf("Failed to delete orphaned asset file %s: %v", asset.URL, err)
+			}
+			total++
+		}
+	}
+}
+
+// StartCleanup 在后台定时清理无引用的素材
+func (as *AssetService) StartCleanup() {
+	go func() {
+		ticker := time.NewTicker(assetSweepPeriod)
+		defer ticker.Stop()
+
+		for {
+			if n, err := as.CleanupOrphans(time.Now()); err != nil {
+				log.Printf("Asset cleanup failed: %v", err)
+			} else if n > 0 {
+				log.Printf("Cleaned up %d orphaned assets", n)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -2,7 +2,9 @@ package services
 
 import (
 	"encoding/hex"
+	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"backend/models"
@@ -10,15 +12,27 @@ import (
 	"backend/types"
 )
 
+// authCodePattern 授权码格式：可选的 前缀- 加32位十六进制
+var authCodePattern = regexp.MustCompile(`^(?:[A-Za-z0-9]{1,8}-)?[0-9a-fA-F]{32}$`)
+
+// authCodePrefixPattern 授权码前缀格式
+var authCodePrefixPattern = regexp.MustCompile(`^[A-Z0-9]{1,8}$`)
+
 // AuthCodeService 授权码服务
 type AuthCodeService struct {
-	authCodeRepo *repositories.AuthCodeRepository
+	authCodeRepo      *repositories.AuthCodeRepository
+	authCodeBatchRepo *repositories.AuthCodeBatchRepository
+	couponRepo        *repositories.CouponRepository
+	productRepo       *repositories.ProductRepository
 }
 
 // NewAuthCodeService 创建授权码服务
 func NewAuthCodeService() *AuthCodeService {
 	return &AuthCodeService{
-		authCodeRepo: repositories.NewAuthCodeRepository(),
+		authCodeRepo:      repositories.NewAuthCodeRepository(),
+		authCodeBatchRepo: repositories.NewAuthCodeBatchRepository(),
+		couponRepo:        repositories.NewCouponRepository(),
+		productRepo:       repositories.NewProductRepository(),
 	}
 }
 
@@ -39,50 +53,177 @@ func (acs *AuthCodeService) GetByCode(code string) (*models.AuthCode, error) {
 
 // GenerateBatch 批量生成授权码
 func (acs *AuthCodeService) GenerateBatch(count int, validDays int) ([]*models.AuthCode, error) {
-	if count <= 0 || count > 1000 {
+	batch := &models.AuthCodeBatch{
+		Name:      fmt.Sprintf("批量生成 %s", time.Now().Format("2006-01-02 15:04:05")),
+		Count:     count,
+		ValidDays: validDays,
+		MaxUses:   1,
+	}
+	return acs.CreateBatch(batch)
+}
+
+// CreateBatch 创建授权码批次并生成授权码
+func (acs *AuthCodeService) CreateBatch(batch *models.AuthCodeBatch) ([]*models.AuthCode, error) {
+	if batch.Count <= 0 || batch.Count > 1000 {
 		return nil, &ServiceError{
 			Code:    400,
 			Message: "生成数量必须在1-1000之间",
 		}
 	}
 
-	if validDays <= 0 || validDays > 365 {
+	if batch.ValidDays <= 0 || batch.ValidDays > 365 {
 		return nil, &ServiceError{
 			Code:    400,
 			Message: "有效天数必须在1-365之间",
 		}
 	}
 
-	var authCodes []*models.AuthCode
-	expireTime := time.Now().AddDate(0, 0, validDays)
+	// max_uses 为 0 表示不限兑换次数
+	if batch.MaxUses < 0 || batch.MaxUses > 100000 {
+		return nil, &ServiceError{
+			Code:    400,
+			Message: "每个授权码的最大兑换次数必须在0-100000之间（0 表示不限）",
+		}
+	}
 
-	for i := 0; i < count; i++ {
-		authCode := &models.AuthCode{
-			Status:    models.AuthCodeStatusUnused,
-			ExpiredAt: expireTime,
+	if batch.PerCustomerUses == 0 {
+		batch.PerCustomerUses = 1
+	}
+	if batch.PerCustomerUses < 1 || (batch.MaxUses > 0 && batch.PerCustomerUses > batch.MaxUses) {
+		return nil, &ServiceError{
+			Code:    400,
+			Message: "每个客户的兑换次数必须在1到最大兑换次数之间",
 		}
+	}
 
-		// 确保授权码唯一
-		for {
-			authCode.Code = models.GenerateAuthCode()
-			existing, _ := acs.authCodeRepo.GetByCode(authCode.Code)
-			if existing == nil {
-				break
-			}
+	batch.Name = strings.TrimSpace(batch.Name)
+	if batch.Name == "" {
+		return nil, &ServiceError{
+			Code:    400,
+			Message: "批次名称不能为空",
+		}
+	}
+
+	batch.Prefix = strings.ToUpper(strings.TrimSpace(batch.Prefix))
+	if batch.Prefix != "" && !authCodePrefixPattern.MatchString(batch.Prefix) {
+		return nil, &ServiceError{
+			Code:    400,
+			Message: "前缀只能包含字母和数字，且长度不能超过8位",
+		}
+	}
+
+	if err := acs.validateEntitlement(batch.Entitlement); err != nil {
+		return nil, err
+	}
+
+	batch.ExpiredAt = time.Now().AddDate(0, 0, batch.ValidDays)
+
+	// 生成不重复的授权码
+	codes := make(map[string]bool, batch.Count)
+	for attempt := 0; len(codes) < batch.Count && attempt < 5; attempt++ {
+		var candidates []string
+		for len(codes)+len(candidates) < batch.Count {
+			candidates = append(candidates, models.GeneratePrefixedAuthCode(batch.Prefix))
 		}
 
-		if err := acs.authCodeRepo.Create(authCode); err != nil {
+		existing, err := acs.authCodeRepo.ExistsCodes(candidates)
+		if err != nil {
 			return nil, err
 		}
+		for _, code := range candidates {
+			if !existing[code] {
+				codes[code] = true
+			}
+		}
+	}
 
-		authCodes = append(authCodes, authCode)
+	// 多次重试后仍无法生成足够的不重复授权码（前缀下可用空间不足），不创建不完整的批次
+	if len(codes) < batch.Count {
+		return nil, &ServiceError{
+			Code:    409,
+			Message: fmt.Sprintf("只生成了%d个不重复的授权码，少于请求的%d个，请更换前缀或减少数量", len(codes), batch.Count),
+		}
+	}
+
+	authCodes := make([]*models.AuthCode, 0, batch.Count)
+	for code := range codes {
+		authCodes = append(authCodes, &models.AuthCode{
+			Code:            code,
+			Status:          models.AuthCodeStatusUnused,
+			ExpiredAt:       batch.ExpiredAt,
+			MaxUses:         batch.MaxUses,
+			PerCustomerUses: batch.PerCustomerUses,
+		})
+	}
+
+	if err := acs.authCodeBatchRepo.CreateWithCodes(batch, authCodes); err != nil {
+		return nil, err
 	}
 
 	return authCodes, nil
 }
 
+// ListBatches 获取授权码批次列表
+func (acs *AuthCodeService) ListBatches(req *types.FilterRequest) ([]*models.AuthCodeBatch, int64, error) {
+	return acs.authCodeBatchRepo.List(req)
+}
+
+// GetBatch 获取授权码批次详情
+func (acs *AuthCodeService) GetBatch(id uint) (*models.AuthCodeBatch, error) {
+	return acs.authCodeBatchRepo.GetByID(id)
+}
+
+// GetBatchCodes 获取批次下的授权码
+func (acs *AuthCodeService) GetBatchCodes(batchID uint, req *types.FilterRequest) ([]*models.AuthCode, int64, error) {
+	if _, err := acs.authCodeBatchRepo.GetByID(batchID); err != nil {
+		return nil, 0, err
+	}
+	return acs.authCodeRepo.ListByBatch(batchID, req)
+}
+
+// validateEntitlement 验证授权码权益配置
+func (acs *AuthCodeService) validateEntitlement(entitlement *models.AuthCodeEntitlement) error {
+	if entitlement == nil {
+		return nil
+	}
+
+	if entitlement.BalanceCredit < 0 {
+		return &ServiceError{
+			Code:    400,
+			Message: "赠送余额不能小于0",
+		}
+	}
+
+	for _, couponID := range entitlement.CouponIDs {
+		coupon, err := acs.couponRepo.GetByID(couponID)
+		if err != nil {
+			return &ServiceError{
+				Code:    400,
+				Message: fmt.Sprintf("优惠券 %d 不存在", couponID),
+			}
+		}
+		if !coupon.IsActive() {
+			return &ServiceError{
+				Code:    400,
+				Message: fmt.Sprintf("优惠券 %d 未激活", couponID),
+			}
+		}
+	}
+
+	for _, productID := range entitlement.ProductIDs {
+		if _, err := acs.productRepo.GetByID(productID); err != nil {
+			return &ServiceError{
+				Code:    400,
+				Message: fmt.Sprintf("产品 %d 不存在", productID),
+			}
+		}
+	}
+
+	return nil
+}
+
 // VerifyCode 验证授权码
-func (acs *AuthCodeService) VerifyCode(code string, userID uint) (map[string]interface{}, error) {
+func (acs *AuthCodeService) VerifyCode(code string, userID uint, clientIP string) (map[string]interface{}, error) {
 	if !acs.ValidateCodeFormat(code) {
 		return nil, &ServiceError{
 			Code:    400,
@@ -99,7 +240,7 @@ func (acs *AuthCodeService) VerifyCode(code string, userID uint) (map[string]int
 	}
 
 	if !authCode.IsUsable() {
-		if authCode.Status == models.AuthCodeStatusUsed {
+		if authCode.Status == models.AuthCodeStatusUsed || authCode.GetRemainingUses() == 0 {
 			return nil, &ServiceError{
 				Code:    400,
 				Message: "授权码已被使用",
@@ -117,25 +258,42 @@ func (acs *AuthCodeService) VerifyCode(code string, userID uint) (map[string]int
 		}
 	}
 
-	// 如果提供了用户ID，则标记为已使用
+	// 如果提供了用户ID，则标记为已使用并发放权益
+	var grant *models.AuthCodeGrant
 	if userID > 0 {
-		authCode.Use(userID)
-		if err := acs.authCodeRepo.Update(authCode); err != nil {
+		grant, err = acs.authCodeRepo.Redeem(authCode, userID, clientIP)
+		if err != nil {
+			switch err.Error() {
+			case "授权码已被使用或已失效", "您已达到该授权码的兑换次数上限", "用户不存在", "账户已被禁用", "优惠券不存在", "优惠券已被领完":
+				return nil, &ServiceError{
+					Code:    400,
+					Message: err.Error(),
+				}
+			}
 			return nil, err
 		}
 	}
 
 	result := map[string]interface{}{
-		"code":          authCode.Code,
-		"status":        authCode.Status,
-		"expired_at":    authCode.ExpiredAt,
+		"code":           authCode.Code,
+		"status":         authCode.Status,
+		"expired_at":     authCode.ExpiredAt,
 		"remaining_time": authCode.GetRemainingTime().String(),
-		"is_used":       authCode.Status == models.AuthCodeStatusUsed,
+		"is_used":        authCode.Status == models.AuthCodeStatusUsed,
+		"max_uses":       authCode.MaxUses,
+		"used_count":     authCode.UsedCount,
+		"remaining_uses": authCode.GetRemainingUses(),
+	}
+
+	if authCode.Batch != nil {
+		result["batch_id"] = authCode.Batch.ID
+		result["batch_name"] = authCode.Batch.Name
 	}
 
 	if userID > 0 {
 		result["used_by"] = userID
 		result["used_at"] = authCode.UsedAt
+		result["granted"] = grant
 	}
 
 	return result, nil
@@ -219,6 +377,25 @@ func (acs *AuthCodeService) GetStatistics() (*types.StatisticsResponse, error) {
 	return acs.authCodeRepo.GetStatistics()
 }
 
+// GetUsageHistory 获取授权码兑换历史
+func (acs *AuthCodeService) GetUsageHistory(req *types.FilterRequest, authCodeID *uint) ([]*models.AuthCodeRedemption, int64, error) {
+	return acs.authCodeRepo.ListRedemptions(req, authCodeID)
+}
+
+// authCodeStatusText 授权码状态文本
+func authCodeStatusText(ac *models.AuthCode) string {
+	switch {
+	case ac.Status == models.AuthCodeStatusExpired || time.Now().After(ac.ExpiredAt):
+		return "已过期"
+	case ac.Status == models.AuthCodeStatusUsed || ac.GetRemainingUses() == 0:
+		return "已使用"
+	case ac.UsedCount > 0:
+		return "部分兑换"
+	default:
+		return "未使用"
+	}
+}
+
 // GetUsedByUser 获取用户使用的授权码
 func (acs *AuthCodeService) GetUsedByUser(userID uint, req *types.FilterRequest) ([]*models.AuthCode, int64, error) {
 	return acs.authCodeRepo.GetUsedByUser(userID, req)
@@ -226,18 +403,15 @@ func (acs *AuthCodeService) GetUsedByUser(userID uint, req *types.FilterRequest)
 
 // ValidateCodeFormat 验证授权码格式
 func (acs *AuthCodeService) ValidateCodeFormat(code string) bool {
-	// 授权码应该是32位十六进制字符串
-	if len(code) != 32 {
-		return false
-	}
-
-	// 检查是否为有效的十六进制字符串
-	matched, _ := regexp.MatchString("^[0-9a-fA-F]{32}$", code)
-	if !matched {
+	// 授权码为32位十六进制字符串，批次设置前缀时格式为 前缀-32位十六进制
+	if !authCodePattern.MatchString(code) {
 		return false
 	}
 
 	// 尝试解码以验证有效性
+	if idx := strings.LastIndex(code, "-"); idx >= 0 {
+		code = code[idx+1:]
+	}
 	_, err := hex.DecodeString(code)
 	return err == nil
 }
@@ -259,6 +433,9 @@ func (acs *AuthCodeService) CheckAvailability(code string) (bool, map[string]int
 
 	info := map[string]interface{}{
 		"status":         authCode.Status,
+		"max_uses":       authCode.MaxUses,
+		"used_count":     authCode.UsedCount,
+		"remaining_uses": authCode.GetRemainingUses(),
 		"expired_at":     authCode.ExpiredAt,
 		"remaining_time": authCode.GetRemainingTime().String(),
 		"created_at":     authCode.CreatedAt,
@@ -269,7 +446,7 @@ func (acs *AuthCodeService) CheckAvailability(code string) (bool, map[string]int
 	}
 
 	// 设置不可用的原因
-	if authCode.Status == models.AuthCodeStatusUsed {
+	if authCode.Status == models.AuthCodeStatusUsed || authCode.GetRemainingUses() == 0 {
 		info["reason"] = "授权码已被使用"
 		info["used_by"] = authCode.UsedBy
 		info["used_at"] = authCode.UsedAt
@@ -336,5 +513,28 @@ func (acs *AuthCodeService) BatchGenerate(config map[string]interface{}) ([]*mod
 		}
 	}
 
-	return acs.GenerateBatch(count, validDays)
-}
\ No newline at end of file
+	name, _ := config["name"].(string)
+	if name == "" {
+		name = fmt.Sprintf("批量生成 %s", time.Now().Format("2006-01-02 15:04:05"))
+	}
+	notes, _ := config["notes"].(string)
+	createdBy, _ := config["created_by"].(uint)
+	entitlement, _ := config["entitlement"].(*models.AuthCodeEntitlement)
+	maxUses, ok := config["max_uses"].(int)
+	if !ok {
+		maxUses = 1 // 默认单次兑换
+	}
+	perCustomerUses, _ := config["per_customer_uses"].(int)
+
+	return acs.CreateBatch(&models.AuthCodeBatch{
+		Name:            name,
+		Prefix:          prefix,
+		Count:           count,
+		ValidDays:       validDays,
+		CreatedBy:       createdBy,
+		Notes:           notes,
+		Entitlement:     entitlement,
+		MaxUses:         maxUses,
+		PerCustomerUses: perCustomerUses,
+	})
+}
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"backend/models"
+	"backend/repositories"
+	"backend/types"
+)
+
+const (
+	// campaignBudgetCheckInterval 预算检查间隔
+	campaignBudgetCheckInterval = time.Minute
+	// pacingTolerance 匀速投放允许超前的比例（占日预算）
+	pacingTolerance = 0.05
+)
+
+// defaultBudgetAlertThresholds 默认预算提醒阈值（百分比）
+var defaultBudgetAlertThresholds = []int{50, 80, 100}
+
+// CampaignBudgetStatus 计划预算状态
+type CampaignBudgetStatus struct {
+	CampaignID     uint     `json:"campaign_id"`
+	TotalBudget    float64  `json:"total_budget"`
+	DailyBudget    float64  `json:"daily_budget"`
+	TotalSpend     float64  `json:"total_spend"`
+	TodaySpend     float64  `json:"today_spend"`
+	TotalRemaining *float64 `json:"total_remaining"` // 未设置总预算时为null
+	DailyRemaining *float64 `json:"daily_remaining"` // 未设置日预算时为null
+	Pacing         string   `json:"pacing"`
+	PacingTarget   *float64 `json:"pacing_target"` // 匀速投放时当前应消耗的金额
+	Throttled      bool     `json:"throttled"`     // 是否应暂缓投放
+	Exhausted      string   `json:"exhausted"`     // 已用尽的预算类型(daily/total)
+	BudgetPaused   string   `json:"budget_paused"`
+}
+
+// CampaignBudgetService 计划预算服务
+type CampaignBudgetService struct {
+	campaignService *CampaignService
+	campaignRepo    *repositories.CampaignRepository
+}
+
+// NewCampaignBudgetService 创建计划预算服务
+func NewCampaignBudgetService() *CampaignBudgetService {
+	return &CampaignBudgetService{
+		campaignService: NewCampaignService(),
+		campaignRepo:    repositories.NewCampaignRepository(),
+	}
+}
+
+// GetStatus 获取计划预算状态
+func (cbs *CampaignBudgetService) GetStatus(id uint) (*CampaignBudgetStatus, error) {
+	campaign, err := cbs.campaignRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return cbs.status(campaign, time.Now())
+}
+
+// IsThrottled 计划当前是否应暂缓投放（预算用尽或匀速投放超前）
+func (cbs *CampaignBudgetService) IsThrottled(campaign *models.Campaign, now time.Time) bool {
+	if !campaign.HasBudget() {
+		return false
+	}
+	status, err := cbs.status(campaign, now)
+	if err != nil {
+		return false
+	}
+	return status.Throttled
+}
+
+// status 计算计划预算状态，日预算与匀速投放按计划时区的自然日计算
+func (cbs *CampaignBudgetService) status(campaign *models.Campaign, now time.Time) (*CampaignBudgetStatus, error) {
+	now = now.In(campaign.Location())
+	totalSpend, err := cbs.campaignRepo.GetSpend(campaign.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+	todaySpend, err := cbs.campaignRepo.GetSpend(campaign.ID, &now)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &CampaignBudgetStatus{
+		CampaignID:   campaign.ID,
+		TotalBudget:  campaign.TotalBudget,
+		DailyBudget:  campaign.DailyBudget,
+		TotalSpend:   roundStat(totalSpend),
+		TodaySpend:   roundStat(todaySpend),
+		Pacing:       campaign.Pacing,
+		BudgetPaused: campaign.BudgetPaused,
+	}
+	if status.Pacing == "" {
+		status.Pacing = models.PacingStandard
+	}
+
+	if campaign.TotalBudget > 0 {
+		remaining := roundStat(campaign.TotalBudget - totalSpend)
+		status.TotalRemaining = &remaining
+		if totalSpend >= campaign.TotalBudget {
+			status.Exhausted = models.BudgetTypeTotal
+		}
+	}
+
+	if campaign.DailyBudget > 0 {
+		remaining := roundStat(campaign.DailyBudget - todaySpend)
+		status.DailyRemaining = &remaining
+		if status.Exhausted == "" && todaySpend >= campaign.DailyBudget {
+			status.Exhausted = models.BudgetTypeDaily
+		}
+
+		// 匀速投放：按当天已过去的时间比例计算应消耗金额
+		if status.Pacing == models.PacingStandard {
+			dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+			elapsed := now.Sub(dayStart).Seconds() / dayStart.AddDate(0, 0, 1).Sub(dayStart).Seconds()
+			target := roundStat(campaign.DailyBudget * elapsed)
+			status.PacingTarget = &target
+			if todaySpend >= target+campaign.DailyBudget*pacingTolerance {
+				status.Throttled = true
+			}
+		}
+	}
+
+	if status.Exhausted != "" {
+		status.Throttled = true
+	}
+
+	return status, nil
+}
+
+// exhaustedBudget 返回计划已用尽的预算类型，未用尽返回空字符串
+func (cs *CampaignService) exhaustedBudget(campaign *models.Campaign, now time.Time) string {
+	if campaign.TotalBudget > 0 {
+		if spend, err := cs.campaignRepo.GetSpend(campaign.ID, nil); err == nil && spend >= campaign.TotalBudget {
+			return models.BudgetTypeTotal
+		}
+	}
+	if campaign.DailyBudget > 0 {
+		today := now.In(campaign.Location())
+		if spend, err := cs.campaignRepo.GetSpend(campaign.ID, &today); err == nil && spend >= campaign.DailyBudget {
+			return models.BudgetTypeDaily
+		}
+	}
+	return ""
+}
+
+// CheckCampaign 检查计划预算：发送阈值提醒，预算用尽时暂停，次日恢复因日预算暂停的计划
+func (cbs *CampaignBudgetService) CheckCampaign(campaign *models.Campaign, now time.Time) error {
+	if !campaign.HasBudget() {
+		return nil
+	}
+
+	status, err := cbs.status(campaign, now)
+	if err != nil {
+		return err
+	}
+
+	cbs.sendAlerts(campaign, status, now)
+
+	switch campaign.Status {
+	case models.CampaignStatusActive:
+		if status.Exhausted == "" {
+			return nil
+		}
+		if err := cbs.campaignService.PauseCampaign(campaign.ID); err != nil {
+			return err
+		}
+		log.Printf("Campaign %d paused: %s budget exhausted", campaign.ID, status.Exhausted)
+		return cbs.campaignRepo.UpdateFields(campaign.ID, map[string]interface{}{
+			"budget_paused": status.Exhausted,
+		})
+
+	case models.CampaignStatusPaused:
+		if campaign.BudgetPaused != models.BudgetTypeDaily {
+			return nil
+		}
+		switch {
+		case status.Exhausted == models.BudgetTypeTotal:
+			return cbs.campaignRepo.UpdateFields(campaign.ID, map[string]interface{}{
+				"budget_paused": models.BudgetTypeTotal,
+			})
+		case status.Exhausted == "" && campaign.HasSchedule() && !campaign.InSchedule(now):
+			// 不在排期内，交给排期调度器在投放时段恢复
+			return cbs.campaignRepo.UpdateFields(campaign.ID, map[string]interface{}{
+				"budget_paused":   "",
+				"scheduled_pause": true,
+			})
+		case status.Exhausted == "":
+			log.Printf("Campaign %d resumed: daily budget reset", campaign.ID)
+			return cbs.campaignService.ResumeCampaign(campaign.ID)
+		}
+	}
+
+	return nil
+}
+
+// sendAlerts 记录达到阈值的预算提醒
+func (cbs *CampaignBudgetService) sendAlerts(campaign *models.Campaign, status *CampaignBudgetStatus, now time.Time) {
+	thresholds := cbs.getAlertThresholds()
+
+	check := func(budgetType, period string, budget, spend float64) {
+		if budget <= 0 {
+			return
+		}
+		percent := spend / budget * 100
+		for _, threshold := range thresholds {
+			if percent < float64(threshold) {
+				break
+			}
+			created, err := cbs.campaignRepo.CreateBudgetAlert(&models.CampaignBudgetAlert{
+				CampaignID: campaign.ID,
+				BudgetType: budgetType,
+				Period:     period,
+				Threshold:  threshold,
+				Budget:     budget,
+				Spend:      spend,
+			})
+			if err != nil {
+				log.Printf("Failed to record budget alert for campaign %d: %v", campaign.ID, err)
+				continue
+			}
+			if created {
+				log.Printf("Campaign %d (%s) %s budget reached %d%%: %.2f / %.2f",
+					campaign.ID, campaign.Name, budgetType, threshold, spend, budget)
+			}
+		}
+	}
+
+	check(models.BudgetTypeTotal, models.BudgetTypeTotal, campaign.TotalBudget, status.TotalSpend)
+	check(models.BudgetTypeDaily, now.In(campaign.Location()).Format("2006-01-02"), campaign.DailyBudget, status.TodaySpend)
+}
+
+// getAlertThresholds 获取预算提醒阈值配置
+func (cbs *CampaignBudgetService) getAlertThresholds() []int {
+	thresholds, err := models.ParseBudgetAlertThresholds(ConfigValue(models.ConfigKeyBudgetAlertThresholds))
+	if err != nil || len(thresholds) == 0 {
+		return defaultBudgetAlertThresholds
+	}
+	return thresholds
+}
+
+// CheckCampaigns 检查指定计划的预算
+func (cbs *CampaignBudgetService) CheckCampaigns(ids []uint) {
+	now := time.Now()
+	for _, id := range ids {
+		campaign, err := cbs.campaignRepo.GetByID(id)
+		if err != nil {
+			continue
+		}
+		if err := cbs.CheckCampaign(campaign, now); err != nil {
+			log.Printf("Failed to check budget for campaign %d: %v", id, err)
+		}
+	}
+}
+
+// RunOnce 检查所有设置了预算的计划
+func (cbs *CampaignBudgetService) RunOnce(now time.Time) error {
+	campaigns, err := cbs.campaignRepo.GetBudgetedCampaigns()
+	if err != nil {
+		return err
+	}
+
+	for _, campaign := range campaigns {
+		if err := cbs.CheckCampaign(campaign, now); err != nil {
+			log.Printf("Failed to check budget for campaign %d: %v", campaign.ID, err)
+		}
+	}
+	return nil
+}
+
+// StartMonitor 在后台定时检查计划预算
+func (cbs *CampaignBudgetService) StartMonitor() {
+	go func() {
+		ticker := time.NewTicker(campaignBudgetCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						log.Printf("campaign budget monitor panic: %v", r)
+					}
+				}()
+				if err := cbs.RunOnce(time.Now()); err != nil {
+					log.Printf("Campaign budget monitor failed: %v", err)
+				}
+			}()
+			<-ticker.C
+		}
+	}()
+}
+
+// ListAlerts 获取预算提醒列表
+func (cbs *CampaignBudgetService) ListAlerts(req *types.PageRequest, campaignID *uint) ([]*models.CampaignBudgetAlert, int64, error) {
+	return cbs.campaignRepo.ListBudgetAlerts(req, campaignID)
+}
@@ -0,0 +1,406 @@
+package services
+
+import (
+	"log"
+	"math"
+
+	"backend/models"
+	"backend/repositories"
+)
+
+const (
+	defaultPromoteMinSample  = 1000
+	defaultPromoteConfidence = 0.95
+)
+
+// CreativeVariantStat 创意变体对比数据
+type CreativeVariantStat struct {
+	CreativeID  uint                  `json:"creative_id"`
+	Name        string                `json:"name"`
+	Status      models.CreativeStatus `json:"status"`
+	Weight      int                   `json:"weight"`
+	IsWinner    bool                  `json:"is_winner"`
+	Impressions int64                 `json:"impressions"`
+	Clicks      int64                 `json:"clicks"`
+	Conversions int64                 `json:"conversions"`
+	Spend       float64               `json:"spend"`
+	CTR         float64               `json:"ctr"`
+	CVR         float64               `json:"cvr"`
+	Samples     int64                 `json:"samples"` // 对比指标的样本数
+	Rate        float64               `json:"rate"`    // 对比指标的转化率(%)
+	Lift        float64               `json:"lift"`    // 相对领先变体的提升(%)
+	ZScore      float64               `json:"z_score"`
+	PValue      float64               `json:"p_value"`
+	Significant bool                  `json:"significant"` // 与领先变体的差异是否显著
+}
+
+// CreativeComparison 创意变体对比结果
+type CreativeComparison struct {
+	CampaignID uint                   `json:"campaign_id"`
+	Metric     string                 `json:"metric"`
+	Confidence float64                `json:"confidence"`
+	MinSample  int                    `json:"min_sample"`
+	LeaderID   *uint                  `json:"leader_id"` // 当前指标最高的变体
+	WinnerID   *uint                  `json:"winner_id"` // 样本充足且显著优于其他所有变体时的胜出变体
+	Ready      bool                   `json:"ready"`     // 所有投放中的变体是否都达到最小样本数
+	Variants   []*CreativeVariantStat `json:"variants"`
+}
+
+// CampaignCreativeService 计划创意服务
+type CampaignCreativeService struct {
+	campaignRepo  *repositories.CampaignRepository
+	creativeRepo  *repositories.CampaignCreativeRepository
+	reviewService *CampaignReviewService
+}
+
+// NewCampaignCreativeService 创建计划创意服务
+func NewCampaignCreativeService() *CampaignCreativeService {
+	return &CampaignCreativeService{
+		campaignRepo:  repositories.NewCampaignRepository(),
+		creativeRepo:  repositories.NewCampaignCreativeRepository(),
+		reviewService: NewCampaignReviewService(),
+	}
+}
+
+// List 获取计划的创意列表
+func (ccs *CampaignCreativeService) List(campaignID uint) ([]*models.CampaignCreative, error) {
+	if _, err := ccs.campaignRepo.GetByID(campaignID); err != nil {
+		return nil, err
+	}
+	return ccs.creativeRepo.ListByCampaign(campaignID)
+}
+
+// GetByID 获取计划下的创意
+func (ccs *CampaignCreativeService) GetByID(campaignID, creativeID uint) (*models.CampaignCreative, error) {
+	creative, err := ccs.creativeRepo.GetByID(creativeID)
+	if err != nil {
+		return nil, err
+	}
+	if creative.CampaignID != campaignID {
+		return nil, &ServiceError{Code: 404, Message: "创意不存在"}
+	}
+	return creative, nil
+}
+
+// Create 创建创意
+func (ccs *CampaignCreativeService) Create(creative *models.CampaignCreative) error {
+	defer InvalidateAdCache()
+
+	campaign, err := ccs.campaignRepo.GetByID(creative.CampaignID)
+	if err != nil {
+		return err
+	}
+	if err := ccs.validate(creative); err != nil {
+		return err
+	}
+	before, err := ccs.reviewService.Snapshot(campaign)
+	if err != nil {
+		return err
+	}
+	// status 列有默认值，暂停状态需要在创建后更新
+	paused := creative.Status == models.CreativeStatusPaused
+	if err := ccs.creativeRepo.Create(creative); err != nil {
+		return err
+	}
+	if paused {
+		creative.Status = models.CreativeStatusPaused
+		if err := ccs.creativeRepo.Update(creative); err != nil {
+			return err
+		}
+	}
+	return ccs.afterContentChange(campaign, before)
+}
+
+// Update 更新创意
+func (ccs *CampaignCreativeService) Update(creative *models.CampaignCreative) error {
+	defer InvalidateAdCache()
+
+	if err := ccs.validate(creative); err != nil {
+		return err
+	}
+	campaign, err := ccs.campaignRepo.GetByID(creative.CampaignID)
+	if err != nil {
+		return err
+	}
+	before, err := ccs.reviewService.Snapshot(campaign)
+	if err != nil {
+		return err
+	}
+	if err := ccs.creativeRepo.Update(creative); err != nil {
+		return err
+	}
+	return ccs.afterContentChange(campaign, before)
+}
+
+// Delete 删除创意
+func (ccs *CampaignCreativeService) Delete(campaignID, creativeID uint) error {
+	defer InvalidateAdCache()
+
+	if _, err := ccs.GetByID(campaignID, creativeID); err != nil {
+		return err
+	}
+	campaign, err := ccs.campaignRepo.GetByID(campaignID)
+	if err != nil {
+		return err
+	}
+	before, err := ccs.reviewService.Snapshot(campaign)
+	if err != nil {
+		return err
+	}
+	if err := ccs.creativeRepo.Delete(creativeID); err != nil {
+		return err
+	}
+	return ccs.afterContentChange(campaign, before)
+}
+
+// afterContentChange 创意内容变更后同步素材引用，审核中或已通过的计划退回草稿
+func (ccs *CampaignCreativeService) afterContentChange(campaign *models.Campaign, before models.CampaignSnapshot) error {
+	syncAssetReferences(models.AssetOwnerCampaign, campaign.ID)
+
+	after, err := ccs.reviewService.Snapshot(campaign)
+	if err != nil {
+		return err
+	}
+	if !ccs.reviewService.HandleContentChange(campaign, before, after) {
+		return nil
+	}
+	return ccs.campaignRepo.UpdateFields(campaign.ID, map[string]interface{}{
+		"review_status":   campaign.ReviewStatus,
+		"reviewer_id":     nil,
+		"status":          campaign.Status,
+		"scheduled_pause": campaign.ScheduledPause,
+	})
+}
+
+// promote 采用创意；计划在采用前与审核快照一致时，同步更新快照，已审核的创意被采用无需重新审核
+func (ccs *CampaignCreativeService) promote(creative *models.CampaignCreative) error {
+	campaign, err := ccs.campaignRepo.GetByID(creative.CampaignID)
+	if err != nil {
+		return err
+	}
+	before, err := ccs.reviewService.Snapshot(campaign)
+	if err != nil {
+		return err
+	}
+	if err := ccs.creativeRepo.Promote(creative); err != nil {
+		return err
+	}
+	syncAssetReferences(models.AssetOwnerCampaign, campaign.ID)
+
+	if !campaign.IsApproved() || campaign.ApprovedSnapshot == nil || len(models.DiffSnapshots(campaign.ApprovedSnapshot, before)) > 0 {
+		return nil
+	}
+	campaign, err = ccs.campaignRepo.GetByID(creative.CampaignID)
+	if err != nil {
+		return err
+	}
+	after, err := ccs.reviewService.Snapshot(campaign)
+	if err != nil {
+		return err
+	}
+	return ccs.campaignRepo.UpdateFields(campaign.ID, map[string]interface{}{
+		"approved_snapshot": after,
+	})
+}
+
+// validate 校验创意内容
+func (ccs *CampaignCreativeService) validate(creative *models.CampaignCreative) error {
+	if creative.Name == "" {
+		return &ServiceError{Code: 400, Message: "创意名称不能为空"}
+	}
+	if creative.MainImage == "" && creative.Video == "" {
+		return &ServiceError{Code: 400, Message: "创意至少需要主图或视频"}
+	}
+	if creative.Weight < 0 {
+		return &ServiceError{Code: 400, Message: "流量权重不能为负数"}
+	}
+	return nil
+}
+
+// Compare 对比计划的创意变体，metric 为空时使用计划设置的对比指标
+func (ccs *CampaignCreativeService) Compare(campaignID uint, metric string) (*CreativeComparison, error) {
+	campaign, err := ccs.campaignRepo.GetByID(campaignID)
+	if err != nil {
+		return nil, err
+	}
+	creatives, err := ccs.creativeRepo.ListByCampaign(campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	if metric == "" {
+		metric = campaign.PromoteMetric
+	}
+	if metric != models.CreativeMetricCVR {
+		metric = models.CreativeMetricCTR
+	}
+	return compareCreatives(campaign, creatives, metric), nil
+}
+
+// compareCreatives 以指标最高的投放中变体为基准，对其余变体做双比例 z 检验
+func compareCreatives(campaign *models.Campaign, creatives []*models.CampaignCreative, metric string) *CreativeComparison {
+	confidence := campaign.PromoteConfidence
+	if confidence <= 0 || confidence >= 1 {
+		confidence = defaultPromoteConfidence
+	}
+	minSample := campaign.PromoteMinSample
+	if minSample <= 0 {
+		minSample = defaultPromoteMinSample
+	}
+
+	result := &CreativeComparison{
+		CampaignID: campaign.ID,
+		Metric:     metric,
+		Confidence: confidence,
+		MinSample:  minSample,
+		Variants:   make([]*CreativeVariantStat, 0, len(creatives)),
+	}
+
+	var leader *CreativeVariantStat
+	var leaderSuccess int64
+	active := 0
+	result.Ready = true
+	for _, creative := range creatives {
+		metrics := creative.Metrics()
+		samples, success := creative.Trials(metric)
+		stat := &CreativeVariantStat{
+			CreativeID:  creative.ID,
+			Name:        creative.Name,
+			Status:      creative.Status,
+			Weight:      creative.Weight,
+			IsWinner:    creative.IsWinner,
+			Impressions: creative.Impressions,
+			Clicks:      creative.Clicks,
+			Conversions: creative.Conversions,
+			Spend:       roundStat(creative.Spend),
+			CTR:         roundStat(metrics.CTR()),
+			CVR:         roundStat(metrics.CVR()),
+			Samples:     samples,
+		}
+		if samples > 0 {
+			stat.Rate = float64(success) / float64(samples) * 100
+		}
+		result.Variants = append(result.Variants, stat)
+
+		if !creative.IsActive() {
+			continue
+		}
+		active++
+		if samples < int64(minSample) {
+			result.Ready = false
+		}
+		if samples > 0 && (leader == nil || stat.Rate > leader.Rate) {
+			leader = stat
+			leaderSuccess = success
+		}
+	}
+
+	if leader == nil || active < 2 {
+		result.Ready = false
+		for _, stat := range result.Variants {
+			stat.Rate = roundStat(stat.Rate)
+		}
+		return result
+	}
+
+	leaderID := leader.CreativeID
+	result.LeaderID = &leaderID
+	leader.PValue = 1
+	beatsAll := true
+	for i, stat := range result.Variants {
+		if stat == leader || creatives[i].Status != models.CreativeStatusActive {
+			continue
+		}
+		_, success := creatives[i].Trials(metric)
+		stat.ZScore, stat.PValue = twoProportionZTest(leaderSuccess, leader.Samples, success, stat.Samples)
+		stat.Significant = stat.PValue < 1-confidence && leader.Rate > stat.Rate
+		if leader.Rate > 0 {
+			stat.Lift = roundStat((stat.Rate - leader.Rate) / leader.Rate * 100)
+		}
+		if !stat.Significant {
+			beatsAll = false
+		}
+		stat.ZScore = math.Round(stat.ZScore*1000) / 1000
+		stat.PValue = math.Round(stat.PValue*10000) / 10000
+	}
+	for _, stat := range result.Variants {
+		stat.Rate = roundStat(stat.Rate)
+	}
+
+	if result.Ready && beatsAll {
+		result.WinnerID = &leaderID
+	}
+	return result
+}
+
+// twoProportionZTest 双比例 z 检验，返回 z 值与双侧 p 值
+func twoProportionZTest(success1, n1, success2, n2 int64) (float64, float64) {
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+	p1 := float64(success1) / float64(n1)
+	p2 := float64(success2) / float64(n2)
+	pooled := float64(success1+success2) / float64(n1+n2)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		return 0, 1
+	}
+	z := (p1 - p2) / se
+	return z, math.Erfc(math.Abs(z) / math.Sqrt2)
+}
+
+// Promote 手动采用指定创意
+func (ccs *CampaignCreativeService) Promote(campaignID, creativeID uint) error {
+	defer InvalidateAdCache()
+
+	creative, err := ccs.GetByID(campaignID, creativeID)
+	if err != nil {
+		return err
+	}
+	return ccs.promote(creative)
+}
+
+// RefreshCounters 刷新创意计数
+func (ccs *CampaignCreativeService) RefreshCounters(creativeIDs []uint) error {
+	return ccs.creativeRepo.RefreshCounters(creativeIDs)
+}
+
+// CheckAutoPromote 对开启自动采用的计划，样本充足且结果显著时采用胜出创意
+func (ccs *CampaignCreativeService) CheckAutoPromote(campaignIDs []uint) {
+	promoted := false
+	for _, campaignID := range campaignIDs {
+		campaign, err := ccs.campaignRepo.GetByID(campaignID)
+		if err != nil || !campaign.AutoPromote || campaign.PromotedAt != nil {
+			continue
+		}
+		creatives, err := ccs.creativeRepo.ListByCampaign(campaignID)
+		if err != nil || len(creatives) < 2 {
+			continue
+		}
+
+		metric := campaign.PromoteMetric
+		if metric != models.CreativeMetricCVR {
+			metric = models.CreativeMetricCTR
+		}
+		comparison := compareCreatives(campaign, creatives, metric)
+		if comparison.WinnerID == nil {
+			continue
+		}
+
+		for _, creative := range creatives {
+			if creative.ID != *comparison.WinnerID {
+				continue
+			}
+			if err := ccs.promote(creative); err != nil {
+				log.Printf("Failed to promote creative %d for campaign %d: %v", creative.ID, campaignID, err)
+				break
+			}
+			log.Printf("Campaign %d promoted creative %d (%s) as winner by %s", campaignID, creative.ID, creative.Name, metric)
+			promoted = true
+		}
+	}
+
+	if promoted {
+		InvalidateAdCache()
+	}
+}
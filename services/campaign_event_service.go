@@ -0,0 +1,368 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"backend/models"
+	"backend/repositories"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// CampaignEventMaxBatch 单次上报的最大事件数
+	CampaignEventMaxBatch = 500
+	// campaignEventSignWindow 签名时间戳允许的偏差
+	campaignEventSignWindow = 5 * time.Minute
+	// campaignEventMaxAge 允许补报的最长时间
+	campaignEventMaxAge = 7 * 24 * time.Hour
+	// campaignEventFlushSize 缓冲区达到该数量立即写入
+	campaignEventFlushSize = 500
+	// campaignEventFlushInterval 缓冲区定时写入间隔
+	campaignEventFlushInterval = 2 * time.Second
+	// campaignEventBufferLimit 写入失败时缓冲区保留的最大事件数
+	campaignEventBufferLimit = 50000
+)
+
+// CampaignEventInput 客户端上报的事件
+type CampaignEventInput struct {
+	EventID    string     `json:"event_id"`
+	CampaignID uint       `json:"campaign_id"`
+	CreativeID *uint      `json:"creative_id"`
+	Type       string     `json:"type"`
+	CustomerID *uint      `json:"customer_id"`
+	DeviceID   string     `json:"device_id"`
+	OccurredAt *time.Time `json:"occurred_at"`
+}
+
+// CampaignEventReject 被拒绝的事件
+type CampaignEventReject struct {
+	Index   int    `json:"index"`
+	EventID string `json:"event_id"`
+	Reason  string `json:"reason"`
+}
+
+// CampaignEventResult 上报结果
+type CampaignEventResult struct {
+	Accepted int                   `json:"accepted"`
+	Rejected []CampaignEventReject `json:"rejected"`
+}
+
+// CampaignEventService 广告计划事件服务
+type CampaignEventService struct {
+	productRepo  *repositories.ProductRepository
+	campaignRepo *repositories.CampaignRepository
+	creativeRepo *repositories.CampaignCreativeRepository
+	buffer       *campaignEventBuffer
+}
+
+// NewCampaignEventService 创建广告计划事件服务
+func NewCampaignEventService() *CampaignEventService {
+	return &CampaignEventService{
+		productRepo:  repositories.NewProductRepository(),
+		campaignRepo: repositories.NewCampaignRepository(),
+		creativeRepo: repositories.NewCampaignCreativeRepository(),
+		buffer:       getCampaignEventBuffer(),
+	}
+}
+
+// SignCampaignEvents 计算事件上报签名：hex(HMAC-SHA256(tracking_key, timestamp + "." + body))
+func SignCampaignEvents(trackingKey, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(trackingKey))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature 校验产品签名，返回对应产品
+func (ces *CampaignEventService) VerifySignature(productID uint, timestamp, signature string, body []byte) (*models.Product, error) {
+	if productID == 0 || timestamp == "" || signature == "" {
+		return nil, &ServiceError{Code: 401, Message: "缺少签名信息"}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, &ServiceError{Code: 401, Message: "时间戳格式错误"}
+	}
+	skew := time.Since(time.Unix(unix, 0))
+	if skew > campaignEventSignWindow || skew < -campaignEventSignWindow {
+		return nil, &ServiceError{Code: 401, Message: "签名已过期"}
+	}
+
+	product, err := ces.productRepo.GetByID(productID)
+	if err != nil || product.TrackingKey == "" {
+		return nil, &ServiceError{Code: 401, Message: "签名无效"}
+	}
+
+	expected := SignCampaignEvents(product.TrackingKey, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, &ServiceError{Code: 401, Message: "签名无效"}
+	}
+
+	return product, nil
+}
+
+// Ingest 校验并缓冲写入一批事件
+func (ces *CampaignEventService) Ingest(product *models.Product, inputs []CampaignEventInput, clientIP string) (*CampaignEventResult, error) {
+	if len(inputs) == 0 {
+		return nil, &ServiceError{Code: 400, Message: "事件列表不能为空"}
+	}
+	if len(inputs) > CampaignEventMaxBatch {
+		return nil, &ServiceError{Code: 400, Message: fmt.Sprintf("单次最多上报%d条事件", CampaignEventMaxBatch)}
+	}
+
+	result := &CampaignEventResult{Rejected: []CampaignEventReject{}}
+	campaigns := make(map[uint]*models.Campaign)
+	creatives := make(map[uint]*models.CampaignCreative)
+	events := make([]*models.CampaignEvent, 0, len(inputs))
+	now := time.Now()
+
+	reject := func(index int, input CampaignEventInput, reason string) {
+		result.Rejected = append(result.Rejected, CampaignEventReject{
+			Index:   index,
+			EventID: input.EventID,
+			Reason:  reason,
+		})
+	}
+
+	for i, input := range inputs {
+		eventType, ok := models.ParseCampaignEventType(input.Type)
+		if !ok {
+			reject(i, input, "事件类型无效")
+			continue
+		}
+		if len(input.EventID) > 64 || len(input.DeviceID) > 128 {
+			reject(i, input, "事件ID或设备ID过长")
+			continue
+		}
+
+		campaign, cached := campaigns[input.CampaignID]
+		if !cached {
+			campaign, _ = ces.campaignRepo.GetByID(input.CampaignID)
+			campaigns[input.CampaignID] = campaign
+		}
+		if campaign == nil || campaign.ProductID != product.ID {
+			reject(i, input, "计划不存在")
+			continue
+		}
+		// 转化可能在计划暂停后回传，展示和点击只接受投放中的计划
+		if eventType != models.CampaignEventConversion && !campaign.IsActive() {
+			reject(i, input, "计划未在投放中")
+			continue
+		}
+
+		if input.CreativeID != nil {
+			creative, cached := creatives[*input.CreativeID]
+			if !cached {
+				creative, _ = ces.creativeRepo.GetByID(*input.CreativeID)
+				creatives[*input.CreativeID] = creative
+			}
+			if creative == nil || creative.CampaignID != campaign.ID {
+				reject(i, input, "创意不存在")
+				continue
+			}
+		}
+
+		occurredAt := now
+		if input.OccurredAt != nil {
+			occurredAt = *input.OccurredAt
+		}
+		if occurredAt.After(now.Add(campaignEventSignWindow)) || occurredAt.Before(now.Add(-campaignEventMaxAge)) {
+			reject(i, input, "事件时间超出允许范围")
+			continue
+		}
+
+		eventID := input.EventID
+		if eventID == "" {
+			eventID = uuid.New().String()
+		}
+
+		events = append(events, &models.CampaignEvent{
+			EventID:    eventID,
+			CampaignID: campaign.ID,
+			ProductID:  product.ID,
+			CreativeID: input.CreativeID,
+			Type:       eventType,
+			CustomerID: input.CustomerID,
+			DeviceID:   input.DeviceID,
+			Cost:       campaign.EventCost(eventType),
+			ClientIP:   clientIP,
+			OccurredAt: occurredAt,
+		})
+		// 展示频次按客户实际看到的展示计数
+		if eventType == models.CampaignEventImpression && input.CustomerID != nil {
+			RecordAdImpression(campaign, *input.CustomerID)
+		}
+	}
+
+	ces.buffer.add(events)
+	result.Accepted = len(events)
+	return result, nil
+}
+
+// FlushCampaignEvents 立即写入缓冲区中的事件，服务关闭时调用
+func FlushCampaignEvents() {
+	if campaignEventBufferInstance != nil {
+		campaignEventBufferInstance.flush()
+	}
+}
+
+// campaignEventBuffer 事件写入缓冲区，批量落库并刷新汇总表
+type campaignEventBuffer struct {
+	mu      sync.Mutex
+	flushMu sync.Mutex
+	events  []*models.CampaignEvent
+	repo    *repositories.CampaignEventRepository
+	notify  chan struct{}
+}
+
+var (
+	campaignEventBufferInstance *campaignEventBuffer
+	campaignEventBufferOnce     sync.Once
+)
+
+// getCampaignEventBuffer 获取全局事件缓冲区
+func getCampaignEventBuffer() *campaignEventBuffer {
+	campaignEventBufferOnce.Do(func() {
+		campaignEventBufferInstance = &campaignEventBuffer{
+			repo:   repositories.NewCampaignEventRepository(),
+			notify: make(chan struct{}, 1),
+		}
+		go campaignEventBufferInstance.run()
+	})
+	return campaignEventBufferInstance
+}
+
+// add 加入缓冲区，达到阈值时通知写入
+func (b *campaignEventBuffer) add(events []*models.CampaignEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	b.events = append(b.events, events...)
+	full := len(b.events) >= campaignEventFlushSize
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run 定时或达到阈值时写入
+func (b *campaignEventBuffer) run() {
+	ticker := time.NewTicker(campaignEventFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.notify:
+		}
+		b.flush()
+	}
+}
+
+// flush 写入事件并刷新涉及的小时/日汇总
+func (b *campaignEventBuffer) flush() {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("campaign event flush panic: %v", r)
+		}
+	}()
+
+	b.mu.Lock()
+	events := b.events
+	b.events = nil
+	b.mu.Unlock()
+
+	if len(events) == 0 {
+		return
+	}
+
+	if err := b.repo.InsertEvents(events); err != nil {
+		log.Printf("Failed to write campaign events: %v", err)
+		b.requeue(events)
+		return
+	}
+
+	type hourKey struct {
+		campaignID uint
+		hour       time.Time
+	}
+	type dayKey struct {
+		campaignID uint
+		day        string
+	}
+	hours := make(map[hourKey]bool)
+	days := make(map[dayKey]time.Time)
+	creativeIDs := make([]uint, 0)
+	seenCreatives := make(map[uint]bool)
+	for _, event := range events {
+		if event.CreativeID != nil && !seenCreatives[*event.CreativeID] {
+			seenCreatives[*event.CreativeID] = true
+			creativeIDs = append(creativeIDs, *event.CreativeID)
+		}
+		occurred := event.OccurredAt.In(time.Local)
+		// 按本地时间取整点，Truncate 按绝对时间取整，在 +05:30 等非整小时时区会错位
+		hour := time.Date(occurred.Year(), occurred.Month(), occurred.Day(), occurred.Hour(), 0, 0, 0, time.Local)
+		hours[hourKey{event.CampaignID, hour}] = true
+		days[dayKey{event.CampaignID, occurred.Format("2006-01-02")}] = occurred
+	}
+
+	for key := range hours {
+		if err := b.repo.RefreshHourly(key.campaignID, key.hour); err != nil {
+			log.Printf("Failed to refresh hourly stats for campaign %d: %v", key.campaignID, err)
+		}
+	}
+	campaignIDs := make([]uint, 0, len(days))
+	seen := make(map[uint]bool)
+	for key, day := range days {
+		if err := b.repo.RefreshDaily(key.campaignID, day); err != nil {
+			log.Printf("Failed to refresh daily stats for campaign %d: %v", key.campaignID, err)
+		}
+		if !seen[key.campaignID] {
+			seen[key.campaignID] = true
+			campaignIDs = append(campaignIDs, key.campaignID)
+		}
+	}
+
+	// 消耗变化后立即检查预算
+	NewCampaignBudgetService().CheckCampaigns(campaignIDs)
+
+	// 刷新创意计数，样本充足时自动采用胜出创意
+	if len(creativeIDs) > 0 {
+		creativeService := NewCampaignCreativeService()
+		if err := creativeService.RefreshCounters(creativeIDs); err != nil {
+			log.Printf("Failed to refresh creative counters: %v", err)
+		} else {
+			creativeService.CheckAutoPromote(campaignIDs)
+		}
+	}
+}
+
+// requeue 写入失败的事件放回缓冲区，超过上限的部分丢弃
+func (b *campaignEventBuffer) requeue(events []*models.CampaignEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.events = append(events, b.events...)
+	if dropped := len(b.events) - campaignEventBufferLimit; dropped > 0 {
+		log.Printf("Campaign event buffer full, dropped %d events", dropped)
+		b.events = b.events[dropped:]
+	}
+}
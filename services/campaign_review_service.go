@@ -0,0 +1,386 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"backend/models"
+	"backend/repositories"
+	"backend/types"
+)
+
+// CampaignReviewer 可分配的审核人
+type CampaignReviewer struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Account  string `json:"account"`
+	Pending  int64  `json:"pending"` // 待审核数量
+}
+
+// CampaignReviewDiff 计划相对上次审核通过的变更
+type CampaignReviewDiff struct {
+	CampaignID   uint                        `json:"campaign_id"`
+	ReviewStatus string                      `json:"review_status"`
+	ApprovedAt   *time.Time                  `json:"approved_at"`
+	ApprovedBy   *uint                       `json:"approved_by"`
+	HasBaseline  bool                        `json:"has_baseline"` // 是否有审核通过的快照，没有时所有内容都视为变更
+	Changes      models.CampaignFieldChanges `json:"changes"`
+}
+
+// CampaignReviewService 计划审核服务
+type CampaignReviewService struct {
+	campaignRepo *repositories.CampaignRepository
+	creativeRepo *repositories.CampaignCreativeRepository
+	adminRepo    *repositories.AdminRepository
+}
+
+// NewCampaignReviewService 创建计划审核服务
+func NewCampaignReviewService() *CampaignReviewService {
+	return &CampaignReviewService{
+		campaignRepo: repositories.NewCampaignRepository(),
+		creativeRepo: repositories.NewCampaignCreativeRepository(),
+		adminRepo:    repositories.NewAdminRepository(),
+	}
+}
+
+// Snapshot 生成计划当前的审核内容快照（含创意）
+func (crs *CampaignReviewService) Snapshot(campaign *models.Campaign) (models.CampaignSnapshot, error) {
+	creatives, err := crs.creativeRepo.ListByCampaign(campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+	return campaign.ReviewSnapshot(creatives), nil
+}
+
+// Reviewers 获取拥有审核权限的管理员及其待审核数量
+func (crs *CampaignReviewService) Reviewers() ([]*CampaignReviewer, error) {
+	admins, err := crs.adminRepo.GetAdminsWithPermission(models.CampaignReviewPermission)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(admins))
+	for _, admin := range admins {
+		ids = append(ids, admin.ID)
+	}
+	counts, err := crs.campaignRepo.CountPendingByReviewer(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	reviewers := make([]*CampaignReviewer, 0, len(admins))
+	for _, admin := range admins {
+		reviewers = append(reviewers, &CampaignReviewer{
+			ID:       admin.ID,
+			Username: admin.Username,
+			Account:  admin.Account,
+			Pending:  counts[admin.ID],
+		})
+	}
+	return reviewers, nil
+}
+
+// assignReviewer 分配审核人：指定时校验权限，否则选择待审核最少的审核人，尽量避开提交人
+func (crs *CampaignReviewService) assignReviewer(submitterID uint, reviewerID *uint) (uint, error) {
+	reviewers, err := crs.Reviewers()
+	if err != nil {
+		return 0, err
+	}
+
+	if reviewerID != nil {
+		for _, reviewer := range reviewers {
+			if reviewer.ID == *reviewerID {
+				return reviewer.ID, nil
+			}
+		}
+		return 0, &ServiceError{Code: 400, Message: "指定的审核人没有审核权限(" + models.CampaignReviewPermission + ")"}
+	}
+
+	var selected *CampaignReviewer
+	for _, reviewer := range reviewers {
+		if reviewer.ID == submitterID && len(reviewers) > 1 {
+			continue
+		}
+		if selected == nil || reviewer.Pending < selected.Pending {
+			selected = reviewer
+		}
+	}
+	if selected == nil {
+		return 0, &ServiceError{Code: 400, Message: "没有可分配的审核人，请先为管理员分配 " + models.CampaignReviewPermission + " 权限"}
+	}
+	return selected.ID, nil
+}
+
+// canReview 检查管理员能否审核该计划：超级管理员，或被分配且拥有审核权限的管理员（不能审核自己提交的计划）
+func (crs *CampaignReviewService) canReview(campaign *models.Campaign, operatorID uint, role int) error {
+	if role == int(models.AdminRoleSuperAdmin) {
+		return nil
+	}
+
+	ok, err := crs.adminRepo.HasPermission(operatorID, models.CampaignReviewPermission)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &ServiceError{Code: 403, Message: "没有审核权限"}
+	}
+	if campaign.ReviewerID == nil || *campaign.ReviewerID != operatorID {
+		return &ServiceError{Code: 403, Message: "该计划未分配给当前管理员审核"}
+	}
+	if campaign.SubmittedBy != nil && *campaign.SubmittedBy == operatorID {
+		return &ServiceError{Code: 403, Message: "不能审核自己提交的计划"}
+	}
+	return nil
+}
+
+// Submit 提交审核
+func (crs *CampaignReviewService) Submit(campaignID, submitterID uint, reviewerID *uint, remark string) (*models.Campaign, error) {
+	campaign, err := crs.campaignRepo.GetByID(campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.ReviewStatus != models.ReviewStatusDraft && campaign.ReviewStatus != models.ReviewStatusRejected {
+		return nil, &ServiceError{Code: 400, Message: "只有草稿或被驳回的计划可以提交审核"}
+	}
+
+	assigned, err := crs.assignReviewer(submitterID, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := crs.Snapshot(campaign)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := crs.campaignRepo.TransitionReview(campaign.ID,
+		[]string{models.ReviewStatusDraft, models.ReviewStatusRejected},
+		map[string]interface{}{
+			"review_status": models.ReviewStatusPending,
+			"reviewer_id":   assigned,
+			"submitted_by":  submitterID,
+			"reject_reason": "",
+		})
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, &ServiceError{Code: 400, Message: "计划审核状态已变更，请刷新后重试"}
+	}
+
+	crs.record(&models.CampaignReview{
+		CampaignID: campaign.ID,
+		Action:     models.ReviewActionSubmit,
+		FromStatus: campaign.ReviewStatus,
+		ToStatus:   models.ReviewStatusPending,
+		OperatorID: &submitterID,
+		ReviewerID: &assigned,
+		Reason:     remark,
+		Changes:    models.DiffSnapshots(campaign.ApprovedSnapshot, snapshot),
+	})
+	return crs.campaignRepo.GetByID(campaign.ID)
+}
+
+// Withdraw 撤回审核
+func (crs *CampaignReviewService) Withdraw(campaignID, operatorID uint) (*models.Campaign, error) {
+	campaign, err := crs.campaignRepo.GetByID(campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.ReviewStatus != models.ReviewStatusPending {
+		return nil, &ServiceError{Code: 400, Message: "计划不在审核中"}
+	}
+
+	updated, err := crs.campaignRepo.TransitionReview(campaign.ID,
+		[]string{models.ReviewStatusPending},
+		map[string]interface{}{
+			"review_status": models.ReviewStatusDraft,
+			"reviewer_id":   nil,
+		})
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, &ServiceError{Code: 400, Message: "计划审核状态已变更，请刷新后重试"}
+	}
+
+	crs.record(&models.CampaignReview{
+		CampaignID: campaign.ID,
+		Action:     models.ReviewActionWithdraw,
+		FromStatus: models.ReviewStatusPending,
+		ToStatus:   models.ReviewStatusDraft,
+		OperatorID: &operatorID,
+		ReviewerID: campaign.ReviewerID,
+	})
+	return crs.campaignRepo.GetByID(campaign.ID)
+}
+
+// Approve 审核通过，保存当前内容快照作为之后变更对比的基准
+func (crs *CampaignReviewService) Approve(campaignID, operatorID uint, role int, remark string) (*models.Campaign, error) {
+	campaign, err := crs.campaignRepo.GetByID(campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.ReviewStatus != models.ReviewStatusPending {
+		return nil, &ServiceError{Code: 400, Message: "计划不在审核中"}
+	}
+	if err := crs.canReview(campaign, operatorID, role); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := crs.Snapshot(campaign)
+	if err != nil {
+		return nil, err
+	}
+	changes := models.DiffSnapshots(campaign.ApprovedSnapshot, snapshot)
+
+	now := time.Now()
+	updated, err := crs.campaignRepo.TransitionReview(campaign.ID,
+		[]string{models.ReviewStatusPending},
+		map[string]interface{}{
+			"review_status":     models.ReviewStatusApproved,
+			"approved_by":       operatorID,
+			"approved_at":       now,
+			"approved_snapshot": snapshot,
+			"reject_reason":     "",
+		})
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, &ServiceError{Code: 400, Message: "计划审核状态已变更，请刷新后重试"}
+	}
+
+	crs.record(&models.CampaignReview{
+		CampaignID: campaign.ID,
+		Action:     models.ReviewActionApprove,
+		FromStatus: models.ReviewStatusPending,
+		ToStatus:   models.ReviewStatusApproved,
+		OperatorID: &operatorID,
+		ReviewerID: campaign.ReviewerID,
+		Reason:     remark,
+		Changes:    changes,
+	})
+	return crs.campaignRepo.GetByID(campaign.ID)
+}
+
+// Reject 驳回审核
+func (crs *CampaignReviewService) Reject(campaignID, operatorID uint, role int, reason string) (*models.Campaign, error) {
+	if reason == "" {
+		return nil, &ServiceError{Code: 400, Message: "请填写驳回原因"}
+	}
+
+	campaign, err := crs.campaignRepo.GetByID(campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.ReviewStatus != models.ReviewStatusPending {
+		return nil, &ServiceError{Code: 400, Message: "计划不在审核中"}
+	}
+	if err := crs.canReview(campaign, operatorID, role); err != nil {
+		return nil, err
+	}
+
+	updated, err := crs.campaignRepo.TransitionReview(campaign.ID,
+		[]string{models.ReviewStatusPending},
+		map[string]interface{}{
+			"review_status": models.ReviewStatusRejected,
+			"reject_reason": reason,
+		})
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, &ServiceError{Code: 400, Message: "计划审核状态已变更，请刷新后重试"}
+	}
+
+	crs.record(&models.CampaignReview{
+		CampaignID: campaign.ID,
+		Action:     models.ReviewActionReject,
+		FromStatus: models.ReviewStatusPending,
+		ToStatus:   models.ReviewStatusRejected,
+		OperatorID: &operatorID,
+		ReviewerID: campaign.ReviewerID,
+		Reason:     reason,
+	})
+	return crs.campaignRepo.GetByID(campaign.ID)
+}
+
+// Diff 获取计划相对上次审核通过的变更
+func (crs *CampaignReviewService) Diff(campaignID uint) (*CampaignReviewDiff, error) {
+	campaign, err := crs.campaignRepo.GetByID(campaignID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := crs.Snapshot(campaign)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CampaignReviewDiff{
+		CampaignID:   campaign.ID,
+		ReviewStatus: campaign.ReviewStatus,
+		ApprovedAt:   campaign.ApprovedAt,
+		ApprovedBy:   campaign.ApprovedBy,
+		HasBaseline:  campaign.ApprovedSnapshot != nil,
+		Changes:      models.DiffSnapshots(campaign.ApprovedSnapshot, snapshot),
+	}, nil
+}
+
+// History 获取计划审核记录
+func (crs *CampaignReviewService) History(campaignID uint, req *types.PageRequest) ([]*models.CampaignReview, int64, error) {
+	if _, err := crs.campaignRepo.GetByID(campaignID); err != nil {
+		return nil, 0, err
+	}
+	return crs.campaignRepo.ListReviews(campaignID, req)
+}
+
+// Pending 获取待审核计划
+func (crs *CampaignReviewService) Pending(req *types.PageRequest, reviewerID *uint) ([]*models.Campaign, int64, error) {
+	return crs.campaignRepo.ListPendingReviews(req, reviewerID)
+}
+
+// HandleContentChange 审核中或已通过的计划内容发生变更时退回草稿，投放中的计划同时暂停。
+// 只修改传入的计划对象，由调用方负责保存；返回是否退回
+func (crs *CampaignReviewService) HandleContentChange(campaign *models.Campaign, before, after models.CampaignSnapshot) bool {
+	if campaign.ReviewStatus != models.ReviewStatusPending && campaign.ReviewStatus != models.ReviewStatusApproved {
+		return false
+	}
+	changes := models.DiffSnapshots(before, after)
+	if len(changes) == 0 {
+		return false
+	}
+
+	fromReview := campaign.ReviewStatus
+	campaign.ReviewStatus = models.ReviewStatusDraft
+	campaign.ReviewerID = nil
+	crs.record(&models.CampaignReview{
+		CampaignID: campaign.ID,
+		Action:     models.ReviewActionReset,
+		FromStatus: fromReview,
+		ToStatus:   models.ReviewStatusDraft,
+		Reason:     "审核后内容变更，需要重新提交审核",
+		Changes:    changes,
+	})
+
+	if campaign.Status == models.CampaignStatusActive {
+		campaign.Status = models.CampaignStatusPaused
+		campaign.ScheduledPause = false
+		if err := crs.campaignRepo.CreateStatusLog(&models.CampaignStatusLog{
+			CampaignID: campaign.ID,
+			FromStatus: models.CampaignStatusActive,
+			ToStatus:   models.CampaignStatusPaused,
+			Source:     models.CampaignStatusSourceReview,
+			Reason:     "内容变更待重新审核",
+		}); err != nil {
+			log.Printf("Failed to record status log for campaign %d: %v", campaign.ID, err)
+		}
+	}
+	return true
+}
+
+// record 记录审核操作，失败只记录日志
+func (crs *CampaignReviewService) record(review *models.CampaignReview) {
+	if err := crs.campaignRepo.CreateReview(review); err != nil {
+		log.Printf("Failed to record review for campaign %d: %v", review.CampaignID, err)
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"backend/models"
+	"backend/repositories"
+)
+
+// campaignScheduleInterval 排期检查间隔
+const campaignScheduleInterval = time.Minute
+
+// CampaignScheduler 计划排期调度器，按开始/结束时间和投放时段自动启用、暂停、结束计划
+type CampaignScheduler struct {
+	campaignService *CampaignService
+	campaignRepo    *repositories.CampaignRepository
+}
+
+// NewCampaignScheduler 创建计划排期调度器
+func NewCampaignScheduler() *CampaignScheduler {
+	return &CampaignScheduler{
+		campaignService: NewCampaignService(),
+		campaignRepo:    repositories.NewCampaignRepository(),
+	}
+}
+
+// Start 在后台定时执行排期检查
+func (s *CampaignScheduler) Start() {
+	go func() {
+		ticker := time.NewTicker(campaignScheduleInterval)
+		defer ticker.Stop()
+
+		for {
+			s.safeRun(time.Now())
+			<-ticker.C
+		}
+	}()
+}
+
+// safeRun 执行一次排期检查并记录异常
+func (s *CampaignScheduler) safeRun(now time.Time) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("campaign scheduler panic: %v", r)
+		}
+	}()
+
+	if _, err := s.RunOnce(now); err != nil {
+		log.Printf("Campaign scheduler failed: %v", err)
+	}
+}
+
+// RunOnce 执行一次排期检查，返回发生状态变更的计划数
+func (s *CampaignScheduler) RunOnce(now time.Time) (int, error) {
+	campaigns, err := s.campaignRepo.GetScheduledCampaigns()
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, campaign := range campaigns {
+		status, scheduledPause, reason, ok := s.nextStatus(campaign, now)
+		if !ok {
+			continue
+		}
+		if err := s.campaignService.validateStatusChange(campaign, status); err != nil {
+			continue
+		}
+
+		updated, err := s.campaignRepo.TransitionStatus(campaign.ID, campaign.Status, status, scheduledPause)
+		if err != nil {
+			log.Printf("Failed to transition campaign %d: %v", campaign.ID, err)
+			continue
+		}
+		if !updated {
+			// 状态已被其他操作修改，下一轮再判断
+			continue
+		}
+
+		if err := s.campaignRepo.CreateStatusLog(&models.CampaignStatusLog{
+			CampaignID: campaign.ID,
+			FromStatus: campaign.Status,
+			ToStatus:   status,
+			Source:     models.CampaignStatusSourceScheduler,
+			Reason:     reason,
+		}); err != nil {
+			log.Printf("Failed to record status log for campaign %d: %v", campaign.ID, err)
+		}
+		changed++
+	}
+
+	if changed > 0 {
+		InvalidateAdCache()
+	}
+	return changed, nil
+}
+
+// nextStatus 根据排期计算计划应处于的状态
+func (s *CampaignScheduler) nextStatus(campaign *models.Campaign, now time.Time) (models.CampaignStatus, bool, string, bool) {
+	if campaign.IsExpired(now) {
+		return models.CampaignStatusEnded, false, "已到结束时间", true
+	}
+
+	inSchedule := campaign.InSchedule(now)
+	switch campaign.Status {
+	case models.CampaignStatusActive:
+		if !campaign.IsStarted(now) {
+			return models.CampaignStatusPaused, true, "未到开始时间", true
+		}
+		if !inSchedule {
+			return models.CampaignStatusPaused, true, "不在投放时段内", true
+		}
+	case models.CampaignStatusPaused:
+		// 手动暂停、手动停用的计划不自动恢复；启用时尚未开始的计划处于排期暂停，到开始时间后自动投放
+		if campaign.ScheduledPause && inSchedule {
+			return models.CampaignStatusActive, false, "进入投放时段", true
+		}
+	}
+
+	return campaign.Status, false, "", false
+}
@@ -5,18 +5,29 @@ import (
 	"backend/repositories"
 	"backend/types"
 	"fmt"
+	"math"
 	"time"
 )
 
 // CampaignService 计划服务
 type CampaignService struct {
-	campaignRepo *repositories.CampaignRepository
+	campaignRepo  *repositories.CampaignRepository
+	eventRepo     *repositories.CampaignEventRepository
+	agentRepo     *repositories.AgentRepository
+	customerRepo  *repositories.CustomerRepository
+	creativeRepo  *repositories.CampaignCreativeRepository
+	reviewService *CampaignReviewService
 }
 
 // NewCampaignService 创建计划服务
 func NewCampaignService() *CampaignService {
 	return &CampaignService{
-		campaignRepo: repositories.NewCampaignRepository(),
+		campaignRepo:  repositories.NewCampaignRepository(),
+		eventRepo:     repositories.NewCampaignEventRepository(),
+		agentRepo:     repositories.NewAgentRepository(),
+		customerRepo:  repositories.NewCustomerRepository(),
+		creativeRepo:  repositories.NewCampaignCreativeRepository(),
+		reviewService: NewCampaignReviewService(),
 	}
 }
 
@@ -32,11 +43,117 @@ func (cs *CampaignService) GetByID(id uint) (*models.Campaign, error) {
 
 // Create 创建计划
 func (cs *CampaignService) Create(campaign *models.Campaign) error {
+	defer InvalidateAdCache()
+
+	if err := cs.ValidateSchedule(campaign); err != nil {
+		return err
+	}
+	if err := cs.ValidateBudget(campaign); err != nil {
+		return err
+	}
+	if err := cs.ValidateTargeting(campaign); err != nil {
+		return err
+	}
+	if err := cs.ValidatePromotion(campaign); err != nil {
+		return err
+	}
+	// 新计划需要审核通过后才能启用
+	campaign.ReviewStatus = models.ReviewStatusDraft
+	if campaign.Status == models.CampaignStatusActive {
+		campaign.Status = models.CampaignStatusInactive
+	}
 	// 如果没有计划编号，自动生成
 	if campaign.CampaignNumber == "" {
 		campaign.CampaignNumber = cs.generateCampaignNumber()
 	}
-	return cs.campaignRepo.Create(campaign)
+	if err := cs.campaignRepo.Create(campaign); err != nil {
+		return err
+	}
+	syncAssetReferences(models.AssetOwnerCampaign, campaign.ID)
+	return nil
+}
+
+// UpdateVideo 更新计划视频及其元数据，只写入视频列（内容变更退回草稿时一并写入审核与状态列），
+// 不重新校验计划的其他配置
+func (cs *CampaignService) UpdateVideo(campaign *models.Campaign, video string, meta models.CampaignVideoMeta) error {
+	defer InvalidateAdCache()
+
+	creatives, err := cs.creativeRepo.ListByCampaign(campaign.ID)
+	if err != nil {
+		return err
+	}
+	before := campaign.ReviewSnapshot(creatives)
+	campaign.Video = video
+	campaign.VideoMeta = meta
+
+	fields := map[string]interface{}{
+		"video":      video,
+		"video_meta": meta,
+	}
+	if cs.reviewService.HandleContentChange(campaign, before, campaign.ReviewSnapshot(creatives)) {
+		fields["review_status"] = campaign.ReviewStatus
+		fields["reviewer_id"] = campaign.ReviewerID
+		fields["status"] = campaign.Status
+		fields["scheduled_pause"] = campaign.ScheduledPause
+	}
+	if err := cs.campaignRepo.UpdateFields(campaign.ID, fields); err != nil {
+		return err
+	}
+	syncAssetReferences(models.AssetOwnerCampaign, campaign.ID)
+	return nil
+}
+
+// CreateWithCreatives 校验并创建未激活的计划及其创意，用于复制计划和按模板创建
+func (cs *CampaignService) CreateWithCreatives(campaign *models.Campaign, creatives []models.CampaignCreative) error {
+	if err := cs.ValidateSchedule(campaign); err != nil {
+		return err
+	}
+	if err := cs.ValidateBudget(campaign); err != nil {
+		return err
+	}
+	if err := cs.ValidateTargeting(campaign); err != nil {
+		return err
+	}
+	if err := cs.ValidatePromotion(campaign); err != nil {
+		return err
+	}
+	campaign.Status = models.CampaignStatusInactive
+	campaign.ReviewStatus = models.ReviewStatusDraft
+	campaign.CampaignNumber = cs.generateCampaignNumber()
+	if err := cs.campaignRepo.CreateWithCreatives(campaign, creatives); err != nil {
+		return err
+	}
+	syncAssetReferences(models.AssetOwnerCampaign, campaign.ID)
+	return nil
+}
+
+// Clone 复制计划的投放内容、规则、定向与创意，生成新编号的未激活计划
+func (cs *CampaignService) Clone(id uint, name string) (*models.Campaign, error) {
+	source, err := cs.campaignRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	sourceCreatives, err := cs.creativeRepo.ListByCampaign(id)
+	if err != nil {
+		return nil, err
+	}
+
+	campaign := source.CopyContent()
+	if name != "" {
+		campaign.Name = name
+	} else {
+		campaign.Name = source.Name + " - 副本"
+	}
+
+	creatives := make([]models.CampaignCreative, 0, len(sourceCreatives))
+	for _, creative := range sourceCreatives {
+		creatives = append(creatives, creative.CopyContent())
+	}
+
+	if err := cs.CreateWithCreatives(campaign, creatives); err != nil {
+		return nil, err
+	}
+	return campaign, nil
 }
 
 // generateCampaignNumber 生成计划编号
@@ -52,26 +169,67 @@ func (cs *CampaignService) generateCampaignNumber() string {
 
 // Update 更新计划
 func (cs *CampaignService) Update(campaign *models.Campaign) error {
+	defer InvalidateAdCache()
+
+	if err := cs.ValidateSchedule(campaign); err != nil {
+		return err
+	}
+	if err := cs.ValidateBudget(campaign); err != nil {
+		return err
+	}
+	if err := cs.ValidateTargeting(campaign); err != nil {
+		return err
+	}
+	if err := cs.ValidatePromotion(campaign); err != nil {
+		return err
+	}
+
+	previous, err := cs.campaignRepo.GetByID(campaign.ID)
+	if err != nil {
+		return err
+	}
+	creatives, err := cs.creativeRepo.ListByCampaign(campaign.ID)
+	if err != nil {
+		return err
+	}
+	if campaign.Status == models.CampaignStatusActive && previous.Status != models.CampaignStatusActive && !campaign.IsApproved() {
+		return &ServiceError{Code: 400, Message: "计划未通过审核，不能启用"}
+	}
+	// 审核中或已通过的计划修改了需要审核的内容，退回草稿
+	cs.reviewService.HandleContentChange(campaign, previous.ReviewSnapshot(creatives), campaign.ReviewSnapshot(creatives))
+
 	// 如果计划编号为空，自动生成
 	if campaign.CampaignNumber == "" {
 		campaign.CampaignNumber = cs.generateCampaignNumber()
 	}
-	return cs.campaignRepo.Update(campaign)
+	if err := cs.campaignRepo.Update(campaign); err != nil {
+		return err
+	}
+	syncAssetReferences(models.AssetOwnerCampaign, campaign.ID)
+	return nil
 }
 
 // Delete 删除计划
 func (cs *CampaignService) Delete(id uint) error {
+	defer InvalidateAdCache()
+
 	// 检查计划是否存在
 	_, err := cs.campaignRepo.GetByID(id)
 	if err != nil {
 		return err
 	}
 
-	return cs.campaignRepo.Delete(id)
+	if err := cs.campaignRepo.Delete(id); err != nil {
+		return err
+	}
+	syncAssetReferences(models.AssetOwnerCampaign, id)
+	return nil
 }
 
 // UpdateStatus 更新计划状态
 func (cs *CampaignService) UpdateStatus(id uint, status models.CampaignStatus) error {
+	defer InvalidateAdCache()
+
 	campaign, err := cs.campaignRepo.GetByID(id)
 	if err != nil {
 		return err
@@ -82,17 +240,192 @@ func (cs *CampaignService) UpdateStatus(id uint, status models.CampaignStatus) e
 		return err
 	}
 
+	// 手动变更状态后不再视为排期暂停
 	campaign.Status = status
+	campaign.ScheduledPause = false
+	campaign.BudgetPaused = ""
+	// 启用时不在投放时段内（如未到开始时间），先排期暂停，由调度器按排期自动开始投放
+	if status == models.CampaignStatusActive && !campaign.InSchedule(time.Now()) {
+		campaign.Status = models.CampaignStatusPaused
+		campaign.ScheduledPause = true
+	}
 	return cs.campaignRepo.Update(campaign)
 }
 
-// GetCampaignStats 获取计划统计
-func (cs *CampaignService) GetCampaignStats(id uint) (map[string]interface{}, error) {
+// ValidateSchedule 校验计划排期配置
+func (cs *CampaignService) ValidateSchedule(campaign *models.Campaign) error {
+	if campaign.StartAt != nil && campaign.EndAt != nil && !campaign.EndAt.After(*campaign.StartAt) {
+		return &ServiceError{Code: 400, Message: "结束时间必须晚于开始时间"}
+	}
+
+	if campaign.Timezone != "" {
+		if _, err := time.LoadLocation(campaign.Timezone); err != nil {
+			return &ServiceError{Code: 400, Message: "无效的时区: " + campaign.Timezone}
+		}
+	}
+
+	for i, part := range campaign.DayParts {
+		if err := part.Validate(); err != nil {
+			return &ServiceError{Code: 400, Message: fmt.Sprintf("第%d个投放时段%s", i+1, err.Error())}
+		}
+	}
+
+	return nil
+}
+
+// ValidateBudget 校验计划预算配置
+func (cs *CampaignService) ValidateBudget(campaign *models.Campaign) error {
+	if campaign.TotalBudget < 0 || campaign.DailyBudget < 0 {
+		return &ServiceError{Code: 400, Message: "预算不能为负数"}
+	}
+	if campaign.TotalBudget > 0 && campaign.DailyBudget > campaign.TotalBudget {
+		return &ServiceError{Code: 400, Message: "日预算不能超过总预算"}
+	}
+	if campaign.Pacing == "" {
+		campaign.Pacing = models.PacingStandard
+	}
+	if campaign.Pacing != models.PacingStandard && campaign.Pacing != models.PacingAccelerated {
+		return &ServiceError{Code: 400, Message: "无效的消耗节奏"}
+	}
+	return nil
+}
+
+// ValidateTargeting 规范化并校验计划定向
+func (cs *CampaignService) ValidateTargeting(campaign *models.Campaign) error {
+	campaign.Targeting.Normalize()
+	if err := campaign.Targeting.Validate(); err != nil {
+		return &ServiceError{Code: 400, Message: err.Error()}
+	}
+
+	for _, agentID := range campaign.Targeting.AgentIDs {
+		if _, err := cs.agentRepo.GetByAdminID(agentID); err != nil {
+			return &ServiceError{Code: 400, Message: fmt.Sprintf("定向代理 %d 不存在", agentID)}
+		}
+	}
+	return nil
+}
+
+// ValidatePromotion 校验创意自动采用配置
+func (cs *CampaignService) ValidatePromotion(campaign *models.Campaign) error {
+	if campaign.PromoteMetric == "" {
+		campaign.PromoteMetric = models.CreativeMetricCTR
+	}
+	if campaign.PromoteMetric != models.CreativeMetricCTR && campaign.PromoteMetric != models.CreativeMetricCVR {
+		return &ServiceError{Code: 400, Message: "无效的创意对比指标"}
+	}
+	if campaign.PromoteMinSample <= 0 {
+		campaign.PromoteMinSample = defaultPromoteMinSample
+	}
+	if campaign.PromoteConfidence == 0 {
+		campaign.PromoteConfidence = defaultPromoteConfidence
+	}
+	if campaign.PromoteConfidence < 0.5 || campaign.PromoteConfidence >= 1 {
+		return &ServiceError{Code: 400, Message: "置信度必须在0.5到1之间"}
+	}
+	return nil
+}
+
+// PrepareTargetingContext 根据客户信息补全定向上下文中的标签与代理层级
+func (cs *CampaignService) PrepareTargetingContext(ctx *models.TargetingContext) error {
+	if ctx.CustomerID != nil {
+		customer, err := cs.customerRepo.GetByID(*ctx.CustomerID)
+		if err != nil {
+			return err
+		}
+		ctx.Tags = append(ctx.Tags, customer.GetTags()...)
+		if ctx.AgentAdminID == nil {
+			ctx.AgentAdminID = customer.AgentAdminID
+		}
+	}
+
+	ctx.AgentChain = nil
+	if ctx.AgentAdminID != nil {
+		chain, err := cs.agentRepo.GetAgentChain(*ctx.AgentAdminID)
+		if err != nil {
+			return err
+		}
+		ctx.AgentChain = chain
+	}
+	return nil
+}
+
+// MatchTargeting 判断上下文是否满足计划定向，上下文需先经 PrepareTargetingContext 补全
+func (cs *CampaignService) MatchTargeting(campaign *models.Campaign, ctx *models.TargetingContext) (bool, []string) {
+	targeting, _, _ := campaign.EffectiveTargeting()
+	return targeting.Match(ctx)
+}
+
+// EvaluateTargeting 评估客户上下文是否命中计划定向
+func (cs *CampaignService) EvaluateTargeting(id uint, ctx *models.TargetingContext) (map[string]interface{}, error) {
 	campaign, err := cs.campaignRepo.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := cs.PrepareTargetingContext(ctx); err != nil {
+		if err.Error() == "客户不存在" {
+			return nil, &ServiceError{Code: 400, Message: "客户不存在"}
+		}
+		return nil, err
+	}
+
+	targeting, legacy, skipped := campaign.EffectiveTargeting()
+	matched, reasons := targeting.Match(ctx)
+
+	return map[string]interface{}{
+		"campaign_id": campaign.ID,
+		"matched":     matched,
+		"reasons":     reasons,
+		"targeting":   targeting,
+		"legacy":      legacy,  // 是否由旧版用户定向字段解析得到
+		"skipped":     skipped, // 旧版字段中未生效的维度及原因
+		"context":     ctx,
+	}, nil
+}
+
+// GetStatusLogs 获取计划状态变更记录
+func (cs *CampaignService) GetStatusLogs(id uint, req *types.PageRequest) ([]*models.CampaignStatusLog, int64, error) {
+	if _, err := cs.campaignRepo.GetByID(id); err != nil {
+		return nil, 0, err
+	}
+	return cs.campaignRepo.ListStatusLogs(id, req)
+}
+
+// GetCampaignStats 获取计划统计，startDate/endDate 为空时统计全部数据
+func (cs *CampaignService) GetCampaignStats(id uint, startDate, endDate *time.Time) (map[string]interface{}, error) {
+	campaign, err := cs.campaignRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := cs.eventRepo.GetMetrics(campaign.ID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	daily, err := cs.eventRepo.GetDailyStats(campaign.ID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	trend := make([]map[string]interface{}, 0, len(daily))
+	for _, day := range daily {
+		dayMetrics := models.CampaignMetrics{
+			Impressions: day.Impressions,
+			Clicks:      day.Clicks,
+			Conversions: day.Conversions,
+			Spend:       day.Spend,
+		}
+		trend = append(trend, map[string]interface{}{
+			"date":        day.Date,
+			"impressions": day.Impressions,
+			"clicks":      day.Clicks,
+			"conversions": day.Conversions,
+			"click_rate":  roundStat(dayMetrics.CTR()),
+			"spend":       roundStat(day.Spend),
+		})
+	}
+
 	stats := map[string]interface{}{
 		"campaign_id":         campaign.ID,
 		"campaign_name":       campaign.Name,
@@ -101,30 +434,27 @@ func (cs *CampaignService) GetCampaignStats(id uint) (map[string]interface{}, er
 		"delivery_content":    campaign.DeliveryContent,
 		"delivery_rules":      campaign.DeliveryRules,
 		"user_targeting":      campaign.UserTargeting,
-		"impressions":         0,
-		"clicks":              0,
-		"conversions":         0,
-		"click_rate":          0.0,
-		"conversion_rate":     0.0,
-		"cost_per_click":      0.0,
-		"cost_per_conversion": 0.0,
-		"spend":               0.0,
-	}
-
-	// TODO: 这里应该从实际的数据分析系统获取展示、点击、转化等数据
-	// 暂时返回模拟数据
-	stats["impressions"] = 1234
-	stats["clicks"] = 56
-	stats["conversions"] = 8
-	stats["click_rate"] = 4.54
-	stats["conversion_rate"] = 14.29
-	stats["cost_per_click"] = 1.25
-	stats["cost_per_conversion"] = 8.75
-	stats["spend"] = 70.0
+		"billing_type":        campaign.BillingType,
+		"bid_price":           campaign.BidPrice,
+		"impressions":         metrics.Impressions,
+		"clicks":              metrics.Clicks,
+		"conversions":         metrics.Conversions,
+		"click_rate":          roundStat(metrics.CTR()),
+		"conversion_rate":     roundStat(metrics.CVR()),
+		"cost_per_click":      roundStat(metrics.CPC()),
+		"cost_per_conversion": roundStat(metrics.CPA()),
+		"spend":               roundStat(metrics.Spend),
+		"trend":               trend,
+	}
 
 	return stats, nil
 }
 
+// roundStat 统计数值保留两位小数
+func roundStat(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
 // GetActiveCampaigns 获取活动计划列表
 func (cs *CampaignService) GetActiveCampaigns() ([]*models.Campaign, error) {
 	return cs.campaignRepo.GetByStatus(models.CampaignStatusActive)
@@ -152,6 +482,17 @@ func (cs *CampaignService) EndCampaign(id uint) error {
 
 // BatchUpdateStatus 批量更新状态
 func (cs *CampaignService) BatchUpdateStatus(ids []uint, status models.CampaignStatus) error {
+	defer InvalidateAdCache()
+
+	// 启用需要逐个校验审核、排期与预算
+	if status == models.CampaignStatusActive {
+		for _, id := range ids {
+			if err := cs.UpdateStatus(id, status); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 	return cs.campaignRepo.BatchUpdateStatus(ids, status)
 }
 
@@ -172,5 +513,31 @@ func (cs *CampaignService) validateStatusChange(campaign *models.Campaign, newSt
 		}
 	}
 
+	// 未通过审核的计划不能启用
+	if newStatus == models.CampaignStatusActive && !campaign.IsApproved() {
+		return &ServiceError{
+			Code:    400,
+			Message: "计划未通过审核，不能启用",
+		}
+	}
+
+	// 已过结束时间的计划不能再启用
+	if newStatus == models.CampaignStatusActive && campaign.IsExpired(time.Now()) {
+		return &ServiceError{
+			Code:    400,
+			Message: "计划已过结束时间，不能启用",
+		}
+	}
+
+	// 预算用尽的计划不能启用
+	if newStatus == models.CampaignStatusActive {
+		switch cs.exhaustedBudget(campaign, time.Now()) {
+		case models.BudgetTypeTotal:
+			return &ServiceError{Code: 400, Message: "计划总预算已用完，请先调整预算"}
+		case models.BudgetTypeDaily:
+			return &ServiceError{Code: 400, Message: "计划今日预算已用完，请先调整预算"}
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"backend/models"
+	"backend/repositories"
+	"backend/types"
+)
+
+// CampaignTemplateService 计划模板服务
+type CampaignTemplateService struct {
+	templateRepo    *repositories.CampaignTemplateRepository
+	campaignRepo    *repositories.CampaignRepository
+	creativeRepo    *repositories.CampaignCreativeRepository
+	productRepo     *repositories.ProductRepository
+	campaignService *CampaignService
+}
+
+// NewCampaignTemplateService 创建计划模板服务
+func NewCampaignTemplateService() *CampaignTemplateService {
+	return &CampaignTemplateService{
+		templateRepo:    repositories.NewCampaignTemplateRepository(),
+		campaignRepo:    repositories.NewCampaignRepository(),
+		creativeRepo:    repositories.NewCampaignCreativeRepository(),
+		productRepo:     repositories.NewProductRepository(),
+		campaignService: NewCampaignService(),
+	}
+}
+
+// List 获取模板列表
+func (cts *CampaignTemplateService) List(req *types.PageRequest) ([]*models.CampaignTemplate, int64, error) {
+	return cts.templateRepo.List(req)
+}
+
+// GetByID 获取模板详情
+func (cts *CampaignTemplateService) GetByID(id uint) (*models.CampaignTemplate, error) {
+	return cts.templateRepo.GetByID(id)
+}
+
+// CreateFromCampaign 将计划保存为模板
+func (cts *CampaignTemplateService) CreateFromCampaign(campaignID uint, name, remark string, adminID uint) (*models.CampaignTemplate, error) {
+	campaign, creatives, err := cts.loadCampaign(campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	template := models.NewCampaignTemplate(campaign, creatives)
+	template.Name = name
+	if template.Name == "" {
+		template.Name = campaign.Name
+	}
+	template.Remark = remark
+	template.CreatedBy = adminID
+
+	if err := cts.templateRepo.Create(template); err != nil {
+		return nil, err
+	}
+	syncAssetReferences(models.AssetOwnerCampaignTemplate, template.ID)
+	return template, nil
+}
+
+// Update 更新模板名称与说明，传入 campaignID 时用该计划的当前配置覆盖模板内容
+func (cts *CampaignTemplateService) Update(id uint, name, remark string, campaignID *uint) (*models.CampaignTemplate, error) {
+	template, err := cts.templateRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if campaignID != nil {
+		campaign, creatives, err := cts.loadCampaign(*campaignID)
+		if err != nil {
+			return nil, err
+		}
+		template.Fill(campaign, creatives)
+	}
+	template.Name = name
+	template.Remark = remark
+
+	if err := cts.templateRepo.Update(template); err != nil {
+		return nil, err
+	}
+	syncAssetReferences(models.AssetOwnerCampaignTemplate, template.ID)
+	return template, nil
+}
+
+// Delete 删除模板
+func (cts *CampaignTemplateService) Delete(id uint) error {
+	if _, err := cts.templateRepo.GetByID(id); err != nil {
+		return err
+	}
+	if err := cts.templateRepo.Delete(id); err != nil {
+		return err
+	}
+	syncAssetReferences(models.AssetOwnerCampaignTemplate, id)
+	return nil
+}
+
+// Instantiate 按模板为指定产品创建未激活的计划
+func (cts *CampaignTemplateService) Instantiate(id, productID uint, name string) (*models.Campaign, error) {
+	template, err := cts.templateRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := cts.productRepo.GetByID(productID)
+	if err != nil {
+		if err.Error() == "产品不存在" {
+			return nil, &ServiceError{Code: 400, Message: "指定的产品不存在"}
+		}
+		return nil, err
+	}
+	if !product.IsActive() {
+		return nil, &ServiceError{Code: 400, Message: "只能为激活状态的产品创建计划"}
+	}
+
+	campaign, creatives := template.NewCampaign(productID, name)
+	if err := cts.campaignService.CreateWithCreatives(campaign, creatives); err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// loadCampaign 获取计划及其创意
+func (cts *CampaignTemplateService) loadCampaign(campaignID uint) (*models.Campaign, []*models.CampaignCreative, error) {
+	campaign, err := cts.campaignRepo.GetByID(campaignID)
+	if err != nil {
+		return nil, nil, err
+	}
+	creatives, err := cts.creativeRepo.ListByCampaign(campaignID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return campaign, creatives, nil
+}
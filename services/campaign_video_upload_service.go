@@ -0,0 +1,437 @@
+package services
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/configs"
+	"backend/models"
+	"backend/pkg/storage"
+	"backend/repositories"
+	"backend/types"
+	"backend/utils"
+
+	"github.com/google/uuid"
+)
+
+// tus 协议相关常量
+const (
+	TusVersion             = "1.0.0"
+	TusExtensions          = "creation,checksum,termination,expiration"
+	TusChecksumAlgorithms  = "sha256,sha1,md5"
+	videoUploadExpiry      = 24 * time.Hour
+	videoUploadMaxChunk    = 64 << 20 // 单次 PATCH 最多接收 64MB
+	videoUploadSweepPeriod = time.Hour
+	videoUploadSweepBatch  = 100
+	statusChecksumMismatch = 460 // tus 约定的校验失败状态码
+)
+
+// videoUploadLocks 同一会话的 PATCH 串行处理（配合数据库中的偏移量条件更新）
+var videoUploadLocks sync.Map
+
+// lockVideoUpload 获取会话锁，返回解锁函数
+func lockVideoUpload(uploadID string) func() {
+	value, _ := videoUploadLocks.LoadOrStore(uploadID, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// CampaignVideoUploadService 计划视频断点续传服务，实现 tus 1.0 的 creation、checksum、termination、expiration 扩展。
+// 未完成的数据暂存在 UPLOAD_TEMP_PATH 下，多实例部署时同一会话的请求需要路由到同一实例
+type CampaignVideoUploadService struct {
+	campaignService *CampaignService
+	assetService    *AssetService
+	campaignRepo    *repositories.CampaignRepository
+	uploadRepo      *repositories.CampaignVideoUploadRepository
+}
+
+// NewCampaignVideoUploadService 创建计划视频断点续传服务
+func NewCampaignVideoUploadService() *CampaignVideoUploadService {
+	return &CampaignVideoUploadService{
+		campaignService: NewCampaignService(),
+		assetService:    NewAssetService(),
+		campaignRepo:    repositories.NewCampaignRepository(),
+		uploadRepo:      repositories.NewCampaignVideoUploadRepository(),
+	}
+}
+
+// MaxVideoUploadSize 视频上传大小上限
+func MaxVideoUploadSize() int64 {
+	return utils.CurrentUploadPolicy().MaxVideoSize
+}
+
+// partPath 会话暂存文件路径
+func partPath(uploadID string) string {
+	return filepath.Join(configs.AppConfig.Upload.TempPath, "videos", uploadID+".part")
+}
+
+// parseChecksum 解析 "算法 base64摘要" 格式的校验值
+func parseChecksum(value string) (hash.Hash, []byte, error) {
+	algorithm, encoded, ok := strings.Cut(strings.TrimSpace(value), " ")
+	if !ok {
+		return nil, nil, &ServiceError{Code: 400, Message: "校验值格式应为: 算法 base64摘要"}
+	}
+	digest, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, nil, &ServiceError{Code: 400, Message: "校验值不是有效的 base64"}
+	}
+
+	var h hash.Hash
+	switch strings.ToLower(algorithm) {
+	case "sha256":
+		h = sha256.New()
+	case "sha1":
+		h = sha1.New()
+	case "md5":
+		h = md5.New()
+	default:
+		return nil, nil, &ServiceError{Code: 400, Message: "不支持的校验算法: " + algorithm}
+	}
+	if len(digest) != h.Size() {
+		return nil, nil, &ServiceError{Code: 400, Message: "校验值长度与算法不符"}
+	}
+	return h, digest, nil
+}
+
+// Create 创建上传会话；checksum 为整个文件的校验值，可为空
+func (cvus *CampaignVideoUploadService) Create(campaignID uint, filename string, length int64, checksum string, adminID uint) (*models.CampaignVideoUpload, error) {
+	if _, err := cvus.campaignRepo.GetByID(campaignID); err != nil {
+		return nil, err
+	}
+
+	filename = filepath.Base(strings.TrimSpace(filename))
+	if filename == "" || filename == "." {
+		return nil, &ServiceError{Code: 400, Message: "缺少文件名"}
+	}
+	if err := utils.CheckVideoFilename(filename); err != nil {
+		return nil, &ServiceError{Code: 400, Message: err.Error()}
+	}
+	if length <= 0 {
+		return nil, &ServiceError{Code: 400, Message: "文件大小无效"}
+	}
+	if maxSize := MaxVideoUploadSize(); length > maxSize {
+		return nil, &ServiceError{Code: 413, Message: fmt.Sprintf("视频大小不能超过 %d MB", maxSize/(1024*1024))}
+	}
+	if checksum != "" {
+		if _, _, err := parseChecksum(checksum); err != nil {
+			return nil, err
+		}
+	}
+
+	upload := &models.CampaignVideoUpload{
+		UploadID:   uuid.New().String(),
+		CampaignID: campaignID,
+		Filename:   filename,
+		Length:     length,
+		Checksum:   checksum,
+		Status:     models.VideoUploadStatusUploading,
+		CreatedBy:  adminID,
+		ExpiresAt:  time.Now().Add(videoUploadExpiry),
+	}
+
+	path := partPath(upload.UploadID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+
+	if err := cvus.uploadRepo.Create(upload); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return upload, nil
+}
+
+// Get 获取上传会话
+func (cvus *CampaignVideoUploadService) Get(campaignID uint, uploadID string) (*models.CampaignVideoUpload, error) {
+	return cvus.uploadRepo.GetByUploadID(campaignID, uploadID)
+}
+
+// Patch 从 offset 处追加一段数据；checksum 为本段数据的校验值，可为空。
+// 未提供校验值时中断的请求会保留已收到的部分，客户端可通过 HEAD 查询偏移量后续传；
+// 数据接收完整后立即校验、合并写入存储并更新计划视频
+func (cvus *CampaignVideoUploadService) Patch(campaignID uint, uploadID string, offset int64, checksum string, body io.Reader) (*models.CampaignVideoUpload, error) {
+	unlock := lockVideoUpload(uploadID)
+	defer unlock()
+
+	upload, err := cvus.uploadRepo.GetByUploadID(campaignID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if !upload.IsResumable(time.Now()) {
+		return nil, &ServiceError{Code: 410, Message: "上传会话已结束或已过期"}
+	}
+	if offset != upload.Offset {
+		return nil, &ServiceError{Code: 409, Message: fmt.Sprintf("上传偏移量不一致，当前为 %d", upload.Offset)}
+	}
+
+	var chunkHash hash.Hash
+	var expected []byte
+	if checksum != "" {
+		if chunkHash, expected, err = parseChecksum(checksum); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.OpenFile(partPath(uploadID), os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("上传暂存文件不存在: %v", err)
+	}
+	defer file.Close()
+	// 丢弃此前失败请求写入但未确认的数据
+	if err := file.Truncate(offset); err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	remaining := upload.Length - offset
+	limit := remaining
+	if limit > videoUploadMaxChunk {
+		limit = videoUploadMaxChunk
+	}
+	var writer io.Writer = file
+	if chunkHash != nil {
+		writer = io.MultiWriter(file, chunkHash)
+	}
+	written, copyErr := io.Copy(writer, io.LimitReader(body, limit))
+	if copyErr == nil && written == limit {
+		// 超出剩余长度或单次上限的数据不接收
+		var probe [1]byte
+		if n, _ := body.Read(probe[:]); n > 0 {
+			file.Truncate(offset)
+			if limit == remaining {
+				return nil, &ServiceError{Code: 413, Message: "上传数据超出文件大小"}
+			}
+			return nil, &ServiceError{Code: 413, Message: fmt.Sprintf("单次上传不能超过 %d MB", videoUploadMaxChunk/(1024*1024))}
+		}
+	}
+
+	if chunkHash != nil {
+		if copyErr != nil {
+			file.Truncate(offset)
+			return nil, copyErr
+		}
+		if subtle.ConstantTimeCompare(chunkHash.Sum(nil), expected) != 1 {
+			file.Truncate(offset)
+			return nil, &ServiceError{Code: statusChecksumMismatch, Message: "分片校验失败"}
+		}
+	}
+	if err := file.Sync(); err != nil {
+		return nil, err
+	}
+
+	if written > 0 {
+		ok, err := cvus.uploadRepo.AdvanceOffset(upload.ID, offset, offset+written)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, &ServiceError{Code: 409, Message: "上传偏移量已被其他请求修改"}
+		}
+		upload.Offset = offset + written
+	}
+	if copyErr != nil {
+		return upload, copyErr
+	}
+
+	if upload.Offset == upload.Length {
+		file.Close()
+		if err := cvus.complete(upload); err != nil {
+			return upload, err
+		}
+	}
+	return upload, nil
+}
+
+// complete 校验完整文件、读取视频元数据并写入存储，然后更新计划视频
+func (cvus *CampaignVideoUploadService) complete(upload *models.CampaignVideoUpload) error {
+	partFile := partPath(upload.UploadID)
+	file, err := os.Open(partFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if upload.Checksum != "" {
+		h, expected, err := parseChecksum(upload.Checksum)
+		if err != nil {
+			return cvus.fail(upload, err)
+		}
+		if _, err := io.Copy(h, file); err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare(h.Sum(nil), expected) != 1 {
+			return cvus.fail(upload, &ServiceError{Code: statusChecksumMismatch, Message: "文件校验失败，请重新上传"})
+		}
+	}
+
+	head := make([]byte, 512)
+	n, err := file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	contentType := utils.DetectContentType(head[:n])
+	if !utils.IsVideoFile(contentType) {
+		return cvus.fail(upload, &ServiceError{Code: 400, Message: "文件内容不是视频"})
+	}
+	if err := utils.ValidateUploadType(upload.Filename, contentType); err != nil {
+		return cvus.fail(upload, &ServiceError{Code: 400, Message: err.Error()})
+	}
+	info, err := utils.ProbeVideo(file, upload.Length)
+	if err != nil {
+		return cvus.fail(upload, &ServiceError{Code: 400, Message: err.Error()})
+	}
+
+	campaign, err := cvus.campaignRepo.GetByID(upload.CampaignID)
+	if err != nil {
+		return cvus.fail(upload, err)
+	}
+
+	key, _ := utils.NewUploadKey("campaigns/videos", upload.Filename)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	hasher := sha256.New()
+	if err := storage.Default().Put(key, io.TeeReader(file, hasher), upload.Length, contentType); err != nil {
+		// 暂存文件保留，客户端按当前偏移量发送空 PATCH 即可重试合并
+		return err
+	}
+	// 登记到素材库，内容相同的视频已存在时直接复用
+	stored := &types.UploadResponse{
+		URL:      storage.URL(key),
+		Filename: path.Base(key),
+		Size:     upload.Length,
+		MimeType: contentType,
+		Hash:     hex.EncodeToString(hasher.Sum(nil)),
+	}
+	cvus.assetService.Register(stored, upload.Filename, upload.CreatedBy)
+
+	meta := models.CampaignVideoMeta{
+		Format:   info.Format,
+		MimeType: contentType,
+		Size:     upload.Length,
+		Duration: info.Duration,
+		Width:    info.Width,
+		Height:   info.Height,
+	}
+	previousVideo := campaign.Video
+	if err := cvus.campaignService.UpdateVideo(campaign, stored.URL, meta); err != nil {
+		// 暂存文件保留，客户端按当前偏移量发送空 PATCH 即可重试；已登记的文件未被引用，由素材清理任务删除
+		return err
+	}
+	if previousVideo != "" && previousVideo != campaign.Video {
+		cvus.assetService.ReleaseFile(previousVideo)
+	}
+
+	now := time.Now()
+	upload.Status = models.VideoUploadStatusCompleted
+	upload.URL = campaign.Video
+	upload.Meta = meta
+	upload.CompletedAt = &now
+	if err := cvus.uploadRepo.Save(upload); err != nil {
+		return err
+	}
+	file.Close()
+	os.Remove(partFile)
+	// 会话已结束，后续请求会被拒绝，不再需要会话锁
+	videoUploadLocks.Delete(upload.UploadID)
+	return nil
+}
+
+// fail 标记会话失败并删除暂存文件，返回原错误
+func (cvus *CampaignVideoUploadService) fail(upload *models.CampaignVideoUpload, cause error) error {
+	upload.Status = models.VideoUploadStatusFailed
+	upload.Error = cause.Error()
+	if err := cvus.uploadRepo.Save(upload); err != nil {
+		log.Printf("Failed to mark video upload %s as failed: %v", upload.UploadID, err)
+	}
+	os.Remove(partPath(upload.UploadID))
+	videoUploadLocks.Delete(upload.UploadID)
+	return cause
+}
+
+// Cancel 取消未完成的上传并删除暂存数据
+func (cvus *CampaignVideoUploadService) Cancel(campaignID uint, uploadID string) error {
+	unlock := lockVideoUpload(uploadID)
+	defer unlock()
+
+	upload, err := cvus.uploadRepo.GetByUploadID(campaignID, uploadID)
+	if err != nil {
+		return err
+	}
+	if upload.Status != models.VideoUploadStatusUploading {
+		return &ServiceError{Code: 400, Message: "只能取消上传中的会话"}
+	}
+	upload.Status = models.VideoUploadStatusCancelled
+	if err := cvus.uploadRepo.Save(upload); err != nil {
+		return err
+	}
+	os.Remove(partPath(uploadID))
+	videoUploadLocks.Delete(uploadID)
+	return nil
+}
+
+// CleanupExpired 清理过期未完成的会话，返回清理数量
+func (cvus *CampaignVideoUploadService) CleanupExpired(now time.Time) (int, error) {
+	total := 0
+	for {
+		uploads, err := cvus.uploadRepo.ListExpired(now, videoUploadSweepBatch)
+		if err != nil {
+			return total, err
+		}
+		if len(uploads) == 0 {
+			return total, nil
+		}
+
+		ids := make([]uint, 0, len(uploads))
+		for _, upload := range uploads {
+			if err := os.Remove(partPath(upload.UploadID)); err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to remove expired video upload %s: %v", upload.UploadID, err)
+			}
+			videoUploadLocks.Delete(upload.UploadID)
+			ids = append(ids, upload.ID)
+		}
+		if err := cvus.uploadRepo.MarkExpired(ids); err != nil {
+			return total, err
+		}
+		total += len(uploads)
+	}
+}
+
+// StartCleanup 在后台定时清理过期的上传会话
+func (cvus *CampaignVideoUploadService) StartCleanup() {
+	go func() {
+		ticker := time.NewTicker(videoUploadSweepPeriod)
+		defer ticker.Stop()
+
+		for {
+			if n, err := cvus.CleanupExpired(time.Now()); err != nil {
+				log.Printf("Video upload cleanup failed: %v", err)
+			} else if n > 0 {
+				log.Printf("Cleaned up %d expired video uploads", n)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -1,19 +1,55 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
 	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
 	"time"
 
+	"backend/database"
 	"backend/models"
 	"backend/repositories"
 	"backend/types"
 )
 
+// 兑换码防爆破配置
+const (
+	promoRedeemFailUserLimit = 5                // 单用户失败次数上限
+	promoRedeemFailIPLimit   = 20               // 单IP失败次数上限
+	promoRedeemFailWindow    = 15 * time.Minute // 统计窗口
+	promoCodeMaxGenerate     = 10000            // 单次最多生成数量
+)
+
+// 优惠券叠加配置
+const (
+	defaultMaxCouponsPerOrder = 3  // 默认单笔订单最多使用的优惠券数量
+	maxCouponsPerOrderLimit   = 5  // 单笔订单可使用张数的上限（与配置项校验一致），限制最优组合的搜索规模
+	maxStackCandidates        = 20 // 计算最优组合时参与搜索的可叠加优惠券上限
+)
+
+// CouponCombination 优惠券组合计算结果
+type CouponCombination struct {
+	OrderAmount    float64              `json:"order_amount"`
+	DiscountAmount float64              `json:"discount_amount"`
+	PayableAmount  float64              `json:"payable_amount"`
+	MaxCoupons     int                  `json:"max_coupons"`
+	Coupons        []*models.UserCoupon `json:"coupons"`
+}
+
+// promoCodePattern 兑换码格式
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{4,32}$`)
+
 // CouponService 优惠券服务
 type CouponService struct {
 	couponRepo     *repositories.CouponRepository
 	userCouponRepo *repositories.UserCouponRepository
 	customerRepo   *repositories.CustomerRepository
+	promoCodeRepo  *repositories.CouponPromoCodeRepository
 }
 
 // NewCouponService 创建优惠券服务
@@ -22,6 +58,7 @@ func NewCouponService() *CouponService {
 		couponRepo:     repositories.NewCouponRepository(),
 		userCouponRepo: repositories.NewUserCouponRepository(),
 		customerRepo:   repositories.NewCustomerRepository(),
+		promoCodeRepo:  repositories.NewCouponPromoCodeRepository(),
 	}
 }
 
@@ -213,49 +250,85 @@ func (cs *CouponService) ClaimCoupon(userID uint, couponID uint) (*models.UserCo
 	return userCoupon, nil
 }
 
-// UseCoupon 使用优惠券
-func (cs *CouponService) UseCoupon(userID uint, userCouponID uint, orderAmount float64) (float64, error) {
-	// 获取用户优惠券
-	userCoupon, err := cs.userCouponRepo.GetByID(userCouponID)
-	if err != nil {
-		return 0, err
-	}
-
-	// 验证用户是否拥有此优惠券
-	if userCoupon.UserID != userID {
-		return 0, &ServiceError{
-			Code:    403,
-			Message: "无权使用此优惠券",
+// UseCoupon 使用优惠券；stackWith 为同一订单中一并使用的其他优惠券，
+// 须满足叠加规则与单笔订单张数上限，所有优惠券在一个事务中一起核销
+func (cs *CouponService) UseCoupon(userID uint, userCouponID uint, orderAmount float64, stackWith []uint) (float64, error) {
+	ids := []uint{userCouponID}
+	seen := map[uint]bool{userCouponID: true}
+	for _, id := range stackWith {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
 		}
 	}
-
-	// 检查优惠券是否可用
-	if !userCoupon.IsUsable() {
+	if maxCoupons := cs.getMaxCouponsPerOrder(); len(ids) > maxCoupons {
 		return 0, &ServiceError{
 			Code:    400,
-			Message: "优惠券不可用或已过期",
+			Message: fmt.Sprintf("单笔订单最多使用%d张优惠券", maxCoupons),
 		}
 	}
 
-	// 获取优惠券详情
-	coupon, err := cs.couponRepo.GetByID(userCoupon.CouponID)
-	if err != nil {
-		return 0, err
+	coupons := make([]*models.Coupon, 0, len(ids))
+	for _, id := range ids {
+		// 获取用户优惠券
+		userCoupon, err := cs.userCouponRepo.GetByID(id)
+		if err != nil {
+			return 0, err
+		}
+
+		// 验证用户是否拥有此优惠券
+		if userCoupon.UserID != userID {
+			return 0, &ServiceError{
+				Code:    403,
+				Message: "无权使用此优惠券",
+			}
+		}
+
+		// 检查优惠券是否可用
+		if !userCoupon.IsUsable() {
+			return 0, &ServiceError{
+				Code:    400,
+				Message: "优惠券不可用或已过期",
+			}
+		}
+
+		// 获取优惠券详情
+		coupon, err := cs.couponRepo.GetByID(userCoupon.CouponID)
+		if err != nil {
+			return 0, err
+		}
+		coupons = append(coupons, coupon)
 	}
 
 	// 计算折扣金额
-	discountAmount := coupon.GetDiscountAmount(orderAmount)
-	if discountAmount <= 0 {
-		return 0, &ServiceError{
-			Code:    400,
-			Message: fmt.Sprintf("订单金额不满足优惠券使用条件，最低消费金额：%.2f", coupon.MinAmount),
+	var discountAmount float64
+	if len(coupons) == 1 {
+		discountAmount = coupons[0].GetDiscountAmount(orderAmount)
+		if discountAmount <= 0 {
+			return 0, &ServiceError{
+				Code:    400,
+				Message: fmt.Sprintf("订单金额不满足优惠券使用条件，最低消费金额：%.2f", coupons[0].MinAmount),
+			}
+		}
+	} else {
+		discount, ok := models.CalculateCombinationDiscount(orderAmount, coupons)
+		if !ok {
+			return 0, &ServiceError{
+				Code:    400,
+				Message: "所选优惠券不能叠加使用或订单金额不满足使用条件",
+			}
+		}
+		if discount <= 0 {
+			return 0, &ServiceError{
+				Code:    400,
+				Message: "所选优惠券组合没有可减免的金额",
+			}
 		}
+		discountAmount = discount
 	}
 
 	// 标记优惠券为已使用
-	userCoupon.Use()
-	
-	if err := cs.userCouponRepo.Update(userCoupon); err != nil {
+	if err := cs.userCouponRepo.MarkUsed(userID, ids); err != nil {
 		return 0, err
 	}
 
@@ -269,30 +342,116 @@ func (cs *CouponService) GetUserCoupons(userID uint, req *types.FilterRequest, s
 
 // GetAvailableCoupons 获取用户可用的优惠券
 func (cs *CouponService) GetAvailableCoupons(userID uint, orderAmount float64) ([]*models.UserCoupon, error) {
-	userCoupons, err := cs.userCouponRepo.GetAvailableByUserID(userID)
+	return cs.userCouponRepo.GetUsableByUserID(userID, orderAmount)
+}
+
+// GetBestCouponCombination 根据订单金额计算最优优惠券组合
+func (cs *CouponService) GetBestCouponCombination(userID uint, orderAmount float64) (*CouponCombination, error) {
+	if orderAmount <= 0 {
+		return nil, &ServiceError{
+			Code:    400,
+			Message: "订单金额必须大于0",
+		}
+	}
+
+	userCoupons, err := cs.userCouponRepo.GetUsableByUserID(userID, orderAmount)
 	if err != nil {
 		return nil, err
 	}
 
-	var availableCoupons []*models.UserCoupon
-	for _, userCoupon := range userCoupons {
-		// 获取优惠券详情
-		coupon, err := cs.couponRepo.GetByID(userCoupon.CouponID)
-		if err != nil {
+	maxCoupons := cs.getMaxCouponsPerOrder()
+	result := &CouponCombination{
+		OrderAmount:   orderAmount,
+		PayableAmount: orderAmount,
+		MaxCoupons:    maxCoupons,
+		Coupons:       []*models.UserCoupon{},
+	}
+
+	// 单张优惠券（包括互斥券）
+	type candidate struct {
+		userCoupon *models.UserCoupon
+		discount   float64
+	}
+	var stackable []candidate
+	for _, uc := range userCoupons {
+		if uc.Coupon == nil {
 			continue
 		}
-
-		// 检查订单金额是否满足使用条件
-		if orderAmount > 0 && orderAmount < coupon.MinAmount {
+		discount, ok := models.CalculateCombinationDiscount(orderAmount, []*models.Coupon{uc.Coupon})
+		if !ok || discount <= 0 {
 			continue
 		}
+		if discount > result.DiscountAmount {
+			result.DiscountAmount = discount
+			result.Coupons = []*models.UserCoupon{uc}
+		}
+		if uc.Coupon.Stackable {
+			stackable = append(stackable, candidate{userCoupon: uc, discount: discount})
+		}
+	}
 
-		// 关联优惠券详情
-		userCoupon.Coupon = coupon
-		availableCoupons = append(availableCoupons, userCoupon)
+	// 可叠加优惠券组合，候选集按单张优惠金额取前若干张以控制搜索规模
+	if maxCoupons > 1 && len(stackable) > 1 {
+		sort.Slice(stackable, func(i, j int) bool {
+			return stackable[i].discount > stackable[j].discount
+		})
+		if len(stackable) > maxStackCandidates {
+			stackable = stackable[:maxStackCandidates]
+		}
+
+		chosen := make([]*models.UserCoupon, 0, maxCoupons)
+		var search func(start int)
+		search = func(start int) {
+			if len(chosen) > 1 {
+				coupons := make([]*models.Coupon, len(chosen))
+				for i, uc := range chosen {
+					coupons[i] = uc.Coupon
+				}
+				discount, ok := models.CalculateCombinationDiscount(orderAmount, coupons)
+				if ok && (discount > result.DiscountAmount ||
+					(discount == result.DiscountAmount && len(chosen) < len(result.Coupons))) {
+					result.DiscountAmount = discount
+					result.Coupons = append([]*models.UserCoupon{}, chosen...)
+				}
+			}
+			if len(chosen) >= maxCoupons {
+				return
+			}
+
+			for i := start; i < len(stackable); i++ {
+				next := stackable[i].userCoupon
+				compatible := true
+				for _, uc := range chosen {
+					if !uc.Coupon.CanStackWith(next.Coupon) {
+						compatible = false
+						break
+					}
+				}
+				if !compatible {
+					continue
+				}
+				chosen = append(chosen, next)
+				search(i + 1)
+				chosen = chosen[:len(chosen)-1]
+			}
+		}
+		search(0)
 	}
 
-	return availableCoupons, nil
+	result.PayableAmount = math.Round((orderAmount-result.DiscountAmount)*100) / 100
+	return result, nil
+}
+
+// getMaxCouponsPerOrder 获取单笔订单最多可使用的优惠券数量
+func (cs *CouponService) getMaxCouponsPerOrder() int {
+	value := ConfigInt(models.ConfigKeyCouponMaxPerOrder)
+	if value <= 0 {
+		return defaultMaxCouponsPerOrder
+	}
+	if value > maxCouponsPerOrderLimit {
+		return maxCouponsPerOrderLimit
+	}
+	return value
 }
 
 // GetStatistics 获取优惠券统计
@@ -359,4 +518,346 @@ func (cs *CouponService) isNewUser(customer *models.Customer) bool {
 	// 可以根据注册时间、交易记录等判断是否为新用户
 	// 这里简单判断注册时间在30天内的为新用户
 	return time.Since(customer.CreatedAt) <= 30*24*time.Hour
-}
\ No newline at end of file
+}
+// CreatePromoCode 创建兑换码（通用码）
+func (cs *CouponService) CreatePromoCode(promoCode *models.CouponPromoCode) error {
+	if _, err := cs.couponRepo.GetByID(promoCode.CouponID); err != nil {
+		return err
+	}
+
+	promoCode.Code = models.NormalizePromoCode(promoCode.Code)
+	if !promoCodePattern.MatchString(promoCode.Code) {
+		return &ServiceError{
+			Code:    400,
+			Message: "兑换码只能包含大写字母、数字、下划线和中划线，长度4-32位",
+		}
+	}
+
+	if promoCode.MaxUses < 0 {
+		return &ServiceError{
+			Code:    400,
+			Message: "最大使用次数不能小于0",
+		}
+	}
+
+	existing, err := cs.promoCodeRepo.ExistsCodes([]string{promoCode.Code})
+	if err != nil {
+		return err
+	}
+	if existing[promoCode.Code] {
+		return &ServiceError{
+			Code:    400,
+			Message: "兑换码已存在",
+		}
+	}
+
+	if promoCode.Type == 0 {
+		promoCode.Type = models.PromoCodeTypeShared
+	}
+	promoCode.Status = models.PromoCodeStatusActive
+
+	return cs.promoCodeRepo.Create(promoCode)
+}
+
+// GeneratePromoCodes 批量生成唯一兑换码（一码一人）
+func (cs *CouponService) GeneratePromoCodes(couponID uint, count int, prefix string, length int, expiredAt *time.Time, createdBy uint) (map[string]interface{}, error) {
+	if _, err := cs.couponRepo.GetByID(couponID); err != nil {
+		return nil, err
+	}
+
+	if count <= 0 || count > promoCodeMaxGenerate {
+		return nil, &ServiceError{
+			Code:    400,
+			Message: fmt.Sprintf("生成数量必须在1-%d之间", promoCodeMaxGenerate),
+		}
+	}
+
+	if length == 0 {
+		length = 10
+	}
+	if length < 6 || length > 16 {
+		return nil, &ServiceError{
+			Code:    400,
+			Message: "兑换码随机部分长度必须在6-16之间",
+		}
+	}
+
+	prefix = models.NormalizePromoCode(prefix)
+	if prefix != "" && !promoCodePattern.MatchString(prefix+"XXXX") {
+		return nil, &ServiceError{
+			Code:    400,
+			Message: "兑换码前缀格式无效",
+		}
+	}
+	if len(prefix)+length > 32 {
+		return nil, &ServiceError{
+			Code:    400,
+			Message: "兑换码总长度不能超过32位",
+		}
+	}
+
+	// 生成不重复的兑换码，与已有兑换码冲突时重新生成
+	codes := make(map[string]bool, count)
+	for attempt := 0; len(codes) < count && attempt < 5; attempt++ {
+		var candidates []string
+		for len(codes)+len(candidates) < count {
+			code := models.GeneratePromoCode(prefix, length)
+			if !codes[code] {
+				candidates = append(candidates, code)
+			}
+		}
+
+		existing, err := cs.promoCodeRepo.ExistsCodes(candidates)
+		if err != nil {
+			return nil, err
+		}
+		for _, code := range candidates {
+			if !existing[code] {
+				codes[code] = true
+			}
+		}
+	}
+
+	if len(codes) < count {
+		return nil, &ServiceError{
+			Code:    500,
+			Message: "兑换码生成冲突过多，请增加兑换码长度后重试",
+		}
+	}
+
+	batchNo := fmt.Sprintf("PC%s%03d", time.Now().Format("20060102150405"), time.Now().Nanosecond()/1e6)
+	promoCodes := make([]*models.CouponPromoCode, 0, count)
+	for code := range codes {
+		promoCodes = append(promoCodes, &models.CouponPromoCode{
+			CouponID:  couponID,
+			Code:      code,
+			Type:      models.PromoCodeTypeUnique,
+			Status:    models.PromoCodeStatusActive,
+			BatchNo:   batchNo,
+			MaxUses:   1,
+			ExpiredAt: expiredAt,
+			CreatedBy: createdBy,
+		})
+	}
+
+	if err := cs.promoCodeRepo.BatchCreate(promoCodes); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"batch_no": batchNo,
+		"count":    len(promoCodes),
+	}, nil
+}
+
+// ListPromoCodes 获取优惠券兑换码列表
+func (cs *CouponService) ListPromoCodes(couponID uint, req *types.FilterRequest, batchNo string) ([]*models.CouponPromoCode, int64, error) {
+	return cs.promoCodeRepo.ListByCoupon(couponID, req, batchNo)
+}
+
+// UpdatePromoCodeStatus 启用/停用兑换码
+func (cs *CouponService) UpdatePromoCodeStatus(couponID uint, promoCodeID uint, status models.PromoCodeStatus) error {
+	promoCode, err := cs.promoCodeRepo.GetByID(promoCodeID)
+	if err != nil {
+		return err
+	}
+	if promoCode.CouponID != couponID {
+		return fmt.Errorf("兑换码不存在")
+	}
+
+	return cs.promoCodeRepo.UpdateStatus(promoCodeID, status)
+}
+
+// ExportPromoCodes 导出兑换码为CSV
+func (cs *CouponService) ExportPromoCodes(couponID uint, batchNo string) ([]byte, error) {
+	if _, err := cs.couponRepo.GetByID(couponID); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	// 写入BOM，保证Excel打开中文不乱码
+	buf.WriteString("\xEF\xBB\xBF")
+	writer := csv.NewWriter(&buf)
+	writer.Write([]string{"兑换码", "类型", "批次号", "状态", "最大使用次数", "已使用次数", "兑换用户ID", "兑换时间", "过期时间", "创建时间"})
+
+	err := cs.promoCodeRepo.FindInBatchesByCoupon(couponID, batchNo, func(promoCodes []*models.CouponPromoCode) error {
+		for _, pc := range promoCodes {
+			status := "启用"
+			if pc.Status != models.PromoCodeStatusActive {
+				status = "停用"
+			}
+
+			redeemedBy, redeemedAt, expiredAt := "", "", ""
+			if pc.RedeemedBy != nil {
+				redeemedBy = strconv.FormatUint(uint64(*pc.RedeemedBy), 10)
+			}
+			if pc.RedeemedAt != nil {
+				redeemedAt = pc.RedeemedAt.Format("2006-01-02 15:04:05")
+			}
+			if pc.ExpiredAt != nil {
+				expiredAt = pc.ExpiredAt.Format("2006-01-02 15:04:05")
+			}
+
+			writer.Write([]string{
+				pc.Code,
+				pc.GetTypeString(),
+				pc.BatchNo,
+				status,
+				strconv.Itoa(pc.MaxUses),
+				strconv.Itoa(pc.UsedCount),
+				redeemedBy,
+				redeemedAt,
+				expiredAt,
+				pc.CreatedAt.Format("2006-01-02 15:04:05"),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// RedeemPromoCode 用户使用兑换码兑换优惠券
+func (cs *CouponService) RedeemPromoCode(userID uint, code string, clientIP string) (*models.UserCoupon, error) {
+	// 防爆破：失败次数过多时拒绝兑换
+	if cs.isPromoRedeemBlocked(userID, clientIP) {
+		return nil, &ServiceError{
+			Code:    429,
+			Message: "兑换失败次数过多，请稍后再试",
+		}
+	}
+
+	code = models.NormalizePromoCode(code)
+	if !promoCodePattern.MatchString(code) {
+		cs.recordPromoRedeemFailure(userID, clientIP)
+		return nil, &ServiceError{
+			Code:    400,
+			Message: "兑换码无效",
+		}
+	}
+
+	promoCode, err := cs.promoCodeRepo.GetByCode(code)
+	if err != nil {
+		if err.Error() == "兑换码不存在" {
+			cs.recordPromoRedeemFailure(userID, clientIP)
+			return nil, &ServiceError{
+				Code:    400,
+				Message: "兑换码无效",
+			}
+		}
+		return nil, err
+	}
+
+	if !promoCode.IsUsable() {
+		return nil, &ServiceError{
+			Code:    400,
+			Message: "兑换码已被使用或已失效",
+		}
+	}
+
+	coupon := promoCode.Coupon
+	if coupon == nil || !coupon.IsAvailable() {
+		return nil, &ServiceError{
+			Code:    400,
+			Message: "优惠券不可用或已过期",
+		}
+	}
+
+	// 检查用户是否存在
+	customer, err := cs.customerRepo.GetByID(userID)
+	if err != nil {
+		return nil, &ServiceError{
+			Code:    400,
+			Message: "用户不存在",
+		}
+	}
+
+	// 检查新用户限制
+	if !coupon.CanUseForNewUser(cs.isNewUser(customer)) {
+		return nil, &ServiceError{
+			Code:    400,
+			Message: "此优惠券仅限新用户领取",
+		}
+	}
+
+	userCoupon := &models.UserCoupon{
+		UserID:   userID,
+		CouponID: coupon.ID,
+		Status:   models.UserCouponStatusUnused,
+	}
+	userCoupon.CalculateExpiredAt(coupon)
+
+	if err := cs.promoCodeRepo.Redeem(promoCode, userCoupon); err != nil {
+		switch err.Error() {
+		case "兑换码已被使用或已失效", "您已经领取过此优惠券", "优惠券已被领完":
+			return nil, &ServiceError{
+				Code:    400,
+				Message: err.Error(),
+			}
+		}
+		return nil, err
+	}
+
+	userCoupon.Coupon = coupon
+	return userCoupon, nil
+}
+
+// GetPromoCodeRedemptions 获取优惠券的兑换码兑换记录
+func (cs *CouponService) GetPromoCodeRedemptions(couponID uint, req *types.FilterRequest) ([]*repositories.PromoCodeRedemption, int64, error) {
+	if _, err := cs.couponRepo.GetByID(couponID); err != nil {
+		return nil, 0, err
+	}
+	return cs.promoCodeRepo.GetRedemptions(couponID, req)
+}
+
+// promoRedeemFailKeys 兑换失败计数的Redis键
+func promoRedeemFailKeys(userID uint, clientIP string) (string, string) {
+	return fmt.Sprintf("promo_redeem_fail:user:%d", userID), fmt.Sprintf("promo_redeem_fail:ip:%s", clientIP)
+}
+
+// isPromoRedeemBlocked 检查用户或IP是否因失败次数过多被限制（Redis不可用时不限制）
+func (cs *CouponService) isPromoRedeemBlocked(userID uint, clientIP string) bool {
+	redisClient := database.GetRedis()
+	if redisClient == nil {
+		return false
+	}
+
+	ctx := context.Background()
+	userKey, ipKey := promoRedeemFailKeys(userID, clientIP)
+
+	if count, err := redisClient.Get(ctx, userKey).Int(); err == nil && count >= promoRedeemFailUserLimit {
+		return true
+	}
+	if count, err := redisClient.Get(ctx, ipKey).Int(); err == nil && count >= promoRedeemFailIPLimit {
+		return true
+	}
+	return false
+}
+
+// recordPromoRedeemFailure 记录一次兑换失败
+func (cs *CouponService) recordPromoRedeemFailure(userID uint, clientIP string) {
+	redisClient := database.GetRedis()
+	if redisClient == nil {
+		return
+	}
+
+	ctx := context.Background()
+	userKey, ipKey := promoRedeemFailKeys(userID, clientIP)
+	for _, key := range []string{userKey, ipKey} {
+		count, err := redisClient.Incr(ctx, key).Result()
+		if err != nil {
+			continue
+		}
+		if count == 1 {
+			redisClient.Expire(ctx, key, promoRedeemFailWindow)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"backend/models"
 	"backend/repositories"
 	"backend/types"
+	"backend/utils"
 )
 
 // CustomerService 客户服务
@@ -11,6 +12,7 @@ type CustomerService struct {
 	customerRepo    *repositories.CustomerRepository
 	transactionRepo *repositories.TransactionRepository
 	userCouponRepo  *repositories.UserCouponRepository
+	agentRepo       *repositories.AgentRepository
 }
 
 // NewCustomerService 创建客户服务
@@ -19,6 +21,7 @@ func NewCustomerService() *CustomerService {
 		customerRepo:    repositories.NewCustomerRepository(),
 		transactionRepo: repositories.NewTransactionRepository(),
 		userCouponRepo:  repositories.NewUserCouponRepository(),
+		agentRepo:       repositories.NewAgentRepository(),
 	}
 }
 
@@ -39,13 +42,8 @@ func (cs *CustomerService) GetByEmail(email string) (*models.Customer, error) {
 
 // Create 创建客户
 func (cs *CustomerService) Create(customer *models.Customer) error {
-	// 检查邮箱是否已存在
-	existingCustomer, _ := cs.customerRepo.GetByEmail(customer.Email)
-	if existingCustomer != nil {
-		return &ServiceError{
-			Code:    400,
-			Message: "邮箱已存在",
-		}
+	if err := cs.ValidateCustomer(customer); err != nil {
+		return err
 	}
 
 	return cs.customerRepo.Create(customer)
@@ -53,6 +51,25 @@ func (cs *CustomerService) Create(customer *models.Customer) error {
 
 // Update 更新客户
 func (cs *CustomerService) Update(customer *models.Customer) error {
+	if err := cs.ValidateCustomer(customer); err != nil {
+		return err
+	}
+
+	return cs.customerRepo.Update(customer)
+}
+
+// ValidateCustomer 校验客户信息（创建、更新和批量导入共用）
+func (cs *CustomerService) ValidateCustomer(customer *models.Customer) error {
+	if customer.Name == "" {
+		return &ServiceError{Code: 400, Message: "客户姓名不能为空"}
+	}
+	if !utils.ValidateEmail(customer.Email) {
+		return &ServiceError{Code: 400, Message: "邮箱格式不正确"}
+	}
+	if customer.Phone != "" && cs.phoneChanged(customer) && !utils.ValidatePhoneNumber(customer.Phone) {
+		return &ServiceError{Code: 400, Message: "电话号码格式不正确"}
+	}
+
 	// 检查邮箱是否被其他客户使用
 	existingCustomer, _ := cs.customerRepo.GetByEmail(customer.Email)
 	if existingCustomer != nil && existingCustomer.ID != customer.ID {
@@ -62,7 +79,23 @@ func (cs *CustomerService) Update(customer *models.Customer) error {
 		}
 	}
 
-	return cs.customerRepo.Update(customer)
+	// 检查归属代理
+	if customer.AgentAdminID != nil {
+		if _, err := cs.agentRepo.GetByAdminID(*customer.AgentAdminID); err != nil {
+			return &ServiceError{Code: 400, Message: "归属代理不存在"}
+		}
+	}
+
+	return nil
+}
+
+// phoneChanged 手机号是否为新录入或已修改；历史客户未修改手机号时不按新规则校验，避免无法保存其他资料
+func (cs *CustomerService) phoneChanged(customer *models.Customer) bool {
+	if customer.ID == 0 {
+		return true
+	}
+	existing, err := cs.customerRepo.GetByID(customer.ID)
+	return err != nil || existing.Phone != customer.Phone
 }
 
 // Delete 删除客户
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"backend/database"
 	"backend/models"
+	"backend/repositories"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"time"
@@ -236,28 +237,39 @@ func (s *DashboardService) GetCampaignPerformance(limit int) []gin.H {
 	
 	var campaigns []models.Campaign
 	s.db.Preload("Product").
-		Where("status = ?", "running").
+		Where("status = ?", models.CampaignStatusActive).
 		Order("created_at DESC").
 		Limit(limit).
 		Find(&campaigns)
+	
+	ids := make([]uint, 0, len(campaigns))
+	for _, c := range campaigns {
+		ids = append(ids, c.ID)
+	}
+	metricsMap, err := repositories.NewCampaignEventRepository().GetMetricsByCampaigns(ids, nil, nil)
+	if err != nil {
+		metricsMap = map[uint]models.CampaignMetrics{}
+	}
 		
 	for _, c := range campaigns {
 		productName := ""
-		if c.Product.ID != 0 {
+		if c.Product != nil {
 			productName = c.Product.Name
 		}
 		
-		// 这里简化处理，实际应该有更详细的统计数据
+		metrics := metricsMap[c.ID]
 		performance = append(performance, gin.H{
 			"id":          c.ID,
 			"name":        c.Name,
 			"product":     productName,
 			"status":      c.Status,
-			"impressions": 0, // 需要实际统计
-			"clicks":      0, // 需要实际统计
-			"conversions": 0, // 需要实际统计
-			"ctr":         0.0, // 点击率
-			"cvr":         0.0, // 转化率
+			"impressions": metrics.Impressions,
+			"clicks":      metrics.Clicks,
+			"conversions": metrics.Conversions,
+			"ctr":         roundStat(metrics.CTR()), // 点击率
+			"cvr":         roundStat(metrics.CVR()), // 转化率
+			"cpc":         roundStat(metrics.CPC()), // 平均点击成本
+			"spend":       roundStat(metrics.Spend),
 		})
 	}
 	
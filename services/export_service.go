@@ -0,0 +1,507 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/configs"
+	"backend/models"
+	"backend/repositories"
+	"backend/types"
+	"backend/utils"
+
+	"github.com/google/uuid"
+)
+
+// 导出对象
+const (
+	ExportResourceCustomers    = "customers"
+	ExportResourceTransactions = "transactions"
+	ExportResourceAuthCodes    = "auth_codes"
+)
+
+// defaultExportAsyncThreshold 默认超过该行数转为异步导出
+const defaultExportAsyncThreshold = 5000
+
+const exportTimeLayout = "2006-01-02 15:04:05"
+
+// ExportColumn 导出列定义
+type ExportColumn struct {
+	Key     string `json:"key"`
+	Title   string `json:"title"`    // 中文表头
+	TitleEn string `json:"title_en"` // 英文表头
+}
+
+// ExportRequest 导出请求
+type ExportRequest struct {
+	Resource string              `json:"resource"`
+	Format   string              `json:"format"`  // csv/xlsx
+	Columns  []string            `json:"columns"` // 为空时导出全部列
+	Lang     string              `json:"lang"`    // zh/en
+	Async    bool                `json:"async"`   // 强制异步导出
+	Filter   types.FilterRequest `json:"filter"`
+}
+
+// exportResource 导出对象定义
+type exportResource struct {
+	columns []ExportColumn
+	count   func(es *ExportService, req *types.FilterRequest) (int64, error)
+	each    func(es *ExportService, req *types.FilterRequest, lang string, fn func(values map[string]string) error) error
+}
+
+var exportResources = map[string]*exportResource{
+	ExportResourceCustomers: {
+		columns: []ExportColumn{
+			{"id", "ID", "ID"},
+			{"name", "姓名", "Name"},
+			{"email", "邮箱", "Email"},
+			{"phone", "电话", "Phone"},
+			{"company", "公司", "Company"},
+			{"status", "状态", "Status"},
+			{"balance", "余额", "Balance"},
+			{"address", "地址", "Address"},
+			{"notes", "备注", "Notes"},
+			{"last_login_at", "最后登录时间", "Last Login"},
+			{"created_at", "创建时间", "Created At"},
+		},
+		count: func(es *ExportService, req *types.FilterRequest) (int64, error) {
+			return es.customerRepo.CountByFilter(req)
+		},
+		each: func(es *ExportService, req *types.FilterRequest, lang string, fn func(values map[string]string) error) error {
+			return es.customerRepo.Cursor(req, func(customer *models.Customer) error {
+				return fn(map[string]string{
+					"id":            strconv.FormatUint(uint64(customer.ID), 10),
+					"name":          customer.Name,
+					"email":         customer.Email,
+					"phone":         customer.Phone,
+					"company":       customer.Company,
+					"status":        localizeExportLabel(customer.GetStatusString(), lang),
+					"balance":       strconv.FormatFloat(customer.Balance, 'f', 2, 64),
+					"address":       customer.Address,
+					"notes":         customer.Notes,
+					"last_login_at": formatExportTime(customer.LastLoginAt),
+					"created_at":    customer.CreatedAt.Format(exportTimeLayout),
+				})
+			})
+		},
+	},
+	ExportResourceTransactions: {
+		columns: []ExportColumn{
+			{"id", "ID", "ID"},
+			{"order_no", "订单号", "Order No"},
+			{"user_id", "客户ID", "Customer ID"},
+			{"type", "类型", "Type"},
+			{"amount", "金额", "Amount"},
+			{"status", "状态", "Status"},
+			{"payment_method", "支付方式", "Payment Method"},
+			{"payment_id", "支付流水号", "Payment ID"},
+			{"balance_before", "交易前余额", "Balance Before"},
+			{"balance_after", "交易后余额", "Balance After"},
+			{"description", "描述", "Description"},
+			{"processed_at", "处理时间", "Processed At"},
+			{"created_at", "创建时间", "Created At"},
+		},
+		count: func(es *ExportService, req *types.FilterRequest) (int64, error) {
+			return es.transactionRepo.CountByFilter(req)
+		},
+		each: func(es *ExportService, req *types.FilterRequest, lang string, fn func(values map[string]string) error) error {
+			return es.transactionRepo.Cursor(req, func(t *models.Transaction) error {
+				return fn(map[string]string{
+					"id":             strconv.FormatUint(uint64(t.ID), 10),
+					"order_no":       t.OrderNo,
+					"user_id":        strconv.FormatUint(uint64(t.UserID), 10),
+					"type":           localizeExportLabel(t.GetTypeString(), lang),
+					"amount":         strconv.FormatFloat(t.Amount, 'f', 2, 64),
+					"status":         localizeExportLabel(t.GetStatusString(), lang),
+					"payment_method": t.PaymentMethod,
+					"payment_id":     t.PaymentID,
+					"balance_before": strconv.FormatFloat(t.BalanceBefore, 'f', 2, 64),
+					"balance_after":  strconv.FormatFloat(t.BalanceAfter, 'f', 2, 64),
+					"description":    t.Description,
+					"processed_at":   formatExportTime(t.ProcessedAt),
+					"created_at":     t.CreatedAt.Format(exportTimeLayout),
+				})
+			})
+		},
+	},
+	ExportResourceAuthCodes: {
+		columns: []ExportColumn{
+			{"code", "授权码", "Code"},
+			{"batch_id", "批次ID", "Batch ID"},
+			{"status", "状态", "Status"},
+			{"max_uses", "最大兑换次数", "Max Uses"},
+			{"used_count", "已兑换次数", "Used Count"},
+			{"remaining_uses", "剩余次数", "Remaining Uses"},
+			{"per_customer_uses", "每客户兑换次数", "Uses Per Customer"},
+			{"used_at", "最后兑换时间", "Last Redeemed At"},
+			{"expired_at", "过期时间", "Expires At"},
+			{"created_at", "创建时间", "Created At"},
+		},
+		count: func(es *ExportService, req *types.FilterRequest) (int64, error) {
+			return es.authCodeRepo.CountByFilter(req)
+		},
+		each: func(es *ExportService, req *types.FilterRequest, lang string, fn func(values map[string]string) error) error {
+			return es.authCodeRepo.Cursor(req, func(ac *models.AuthCode) error {
+				batchID := ""
+				if ac.BatchID != nil {
+					batchID = strconv.FormatUint(uint64(*ac.BatchID), 10)
+				}
+				return fn(map[string]string{
+					"code":              ac.Code,
+					"batch_id":          batchID,
+					"status":            localizeExportLabel(authCodeStatusText(ac), lang),
+					"max_uses":          strconv.Itoa(ac.MaxUses),
+					"used_count":        strconv.Itoa(ac.UsedCount),
+					"remaining_uses":    strconv.Itoa(ac.GetRemainingUses()),
+					"per_customer_uses": strconv.Itoa(ac.PerCustomerUses),
+					"used_at":           formatExportTime(ac.UsedAt),
+					"expired_at":        ac.ExpiredAt.Format(exportTimeLayout),
+					"created_at":        ac.CreatedAt.Format(exportTimeLayout),
+				})
+			})
+		},
+	},
+}
+
+// exportLabelsEn 状态/类型文本（GetStatusString/GetTypeString）的英文对照
+var exportLabelsEn = map[string]string{
+	// 客户状态
+	"激活":  "Active",
+	"未激活": "Inactive",
+	"已阻止": "Blocked",
+	// 交易类型
+	"充值": "Recharge",
+	"提现": "Withdraw",
+	"消费": "Consume",
+	"退款": "Refund",
+	"奖励": "Reward",
+	// 交易状态
+	"待处理": "Pending",
+	"成功":  "Success",
+	"失败":  "Failed",
+	"已取消": "Cancelled",
+	"处理中": "Processing",
+	// 授权码状态
+	"未使用":  "Unused",
+	"已使用":  "Used",
+	"部分兑换": "Partially Redeemed",
+	"已过期":  "Expired",
+	"未知":   "Unknown",
+}
+
+// localizeExportLabel 按语言转换状态/类型文本
+func localizeExportLabel(label, lang string) string {
+	if lang == "en" {
+		if en, ok := exportLabelsEn[label]; ok {
+			return en
+		}
+	}
+	return label
+}
+
+// formatExportTime 格式化可空时间
+func formatExportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(exportTimeLayout)
+}
+
+// ExportService 导出服务
+type ExportService struct {
+	exportJobRepo   *repositories.ExportJobRepository
+	customerRepo    *repositories.CustomerRepository
+	transactionRepo *repositories.TransactionRepository
+	authCodeRepo    *repositories.AuthCodeRepository
+}
+
+// NewExportService 创建导出服务
+func NewExportService() *ExportService {
+	return &ExportService{
+		exportJobRepo:   repositories.NewExportJobRepository(),
+		customerRepo:    repositories.NewCustomerRepository(),
+		transactionRepo: repositories.NewTransactionRepository(),
+		authCodeRepo:    repositories.NewAuthCodeRepository(),
+	}
+}
+
+// GetColumns 获取导出对象的可选列
+func (es *ExportService) GetColumns(resource string) ([]ExportColumn, error) {
+	res, ok := exportResources[resource]
+	if !ok {
+		return nil, &ServiceError{Code: 400, Message: "不支持的导出对象"}
+	}
+	return res.columns, nil
+}
+
+// Validate 校验并补全导出请求
+func (es *ExportService) Validate(req *ExportRequest) error {
+	res, ok := exportResources[req.Resource]
+	if !ok {
+		return &ServiceError{Code: 400, Message: "不支持的导出对象"}
+	}
+
+	if req.Format == "" {
+		req.Format = utils.ExportFormatCSV
+	}
+	if !utils.IsValidExportFormat(req.Format) {
+		return &ServiceError{Code: 400, Message: "导出格式只支持 csv 或 xlsx"}
+	}
+
+	if req.Lang != "en" {
+		req.Lang = "zh"
+	}
+
+	if len(req.Columns) == 0 {
+		for _, column := range res.columns {
+			req.Columns = append(req.Columns, column.Key)
+		}
+		return nil
+	}
+
+	known := make(map[string]bool, len(res.columns))
+	for _, column := range res.columns {
+		known[column.Key] = true
+	}
+	for _, key := range req.Columns {
+		if !known[key] {
+			return &ServiceError{Code: 400, Message: fmt.Sprintf("未知的导出列: %s", key)}
+		}
+	}
+	return nil
+}
+
+// ShouldRunAsync 判断是否需要转为异步导出，返回预计行数
+func (es *ExportService) ShouldRunAsync(req *ExportRequest) (bool, int64, error) {
+	total, err := exportResources[req.Resource].count(es, &req.Filter)
+	if err != nil {
+		return false, 0, err
+	}
+	return req.Async || total > int64(es.getAsyncThreshold()), total, nil
+}
+
+// getAsyncThreshold 获取异步导出阈值
+func (es *ExportService) getAsyncThreshold() int {
+	if value := ConfigInt(models.ConfigKeyExportAsyncThreshold); value > 0 {
+		return value
+	}
+	return defaultExportAsyncThreshold
+}
+
+// FileName 生成导出文件名
+func (es *ExportService) FileName(req *ExportRequest) string {
+	return fmt.Sprintf("%s_%s.%s", req.Resource, time.Now().Format("20060102150405"), req.Format)
+}
+
+// Stream 将导出数据逐行写入w，返回导出行数
+func (es *ExportService) Stream(req *ExportRequest, w io.Writer) (int64, error) {
+	return es.write(req, w, nil)
+}
+
+// write 写入表头与数据行，progress 每写入1000行回调一次
+func (es *ExportService) write(req *ExportRequest, w io.Writer, progress func(rows int64)) (int64, error) {
+	res := exportResources[req.Resource]
+
+	writer, err := utils.NewExportWriter(req.Format, w)
+	if err != nil {
+		return 0, err
+	}
+
+	titles := make(map[string]ExportColumn, len(res.columns))
+	for _, column := range res.columns {
+		titles[column.Key] = column
+	}
+	header := make([]string, len(req.Columns))
+	for i, key := range req.Columns {
+		if req.Lang == "en" {
+			header[i] = titles[key].TitleEn
+		} else {
+			header[i] = titles[key].Title
+		}
+	}
+	if err := writer.WriteRow(header); err != nil {
+		return 0, err
+	}
+
+	var count int64
+	row := make([]string, len(req.Columns))
+	err = res.each(es, &req.Filter, req.Lang, func(values map[string]string) error {
+		for i, key := range req.Columns {
+			row[i] = values[key]
+		}
+		if err := writer.WriteRow(row); err != nil {
+			return err
+		}
+		count++
+		if progress != nil && count%1000 == 0 {
+			progress(count)
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	return count, writer.Close()
+}
+
+// CreateJob 创建异步导出任务并在后台执行
+func (es *ExportService) CreateJob(req *ExportRequest, totalRows int64, createdBy uint) (*models.ExportJob, error) {
+	params, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &models.ExportJob{
+		JobNo:     strings.ReplaceAll(uuid.New().String(), "-", ""),
+		Resource:  req.Resource,
+		Format:    req.Format,
+		Params:    string(params),
+		Status:    models.ExportJobStatusPending,
+		TotalRows: totalRows,
+		FileName:  es.FileName(req),
+		CreatedBy: createdBy,
+	}
+	if err := es.exportJobRepo.Create(job); err != nil {
+		return nil, err
+	}
+
+	go es.runJob(job.ID, *req)
+
+	return job, nil
+}
+
+// runJob 执行导出任务，将结果写入导出目录
+func (es *ExportService) runJob(jobID uint, req ExportRequest) {
+	filePath := ""
+	fail := func(err error) {
+		log.Printf("Export job %d failed: %v", jobID, err)
+		if filePath != "" {
+			os.Remove(filePath)
+		}
+		now := time.Now()
+		es.exportJobRepo.UpdateFields(jobID, map[string]interface{}{
+			"status":      models.ExportJobStatusFailed,
+			"error":       err.Error(),
+			"finished_at": now,
+		})
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			fail(fmt.Errorf("%v", r))
+		}
+	}()
+
+	// 顺带清理过期的导出文件
+	es.CleanExpiredFiles()
+
+	job, err := es.exportJobRepo.GetByID(jobID)
+	if err != nil {
+		log.Printf("Export job %d not found: %v", jobID, err)
+		return
+	}
+
+	now := time.Now()
+	if err := es.exportJobRepo.UpdateFields(jobID, map[string]interface{}{
+		"status":     models.ExportJobStatusRunning,
+		"started_at": now,
+	}); err != nil {
+		fail(err)
+		return
+	}
+
+	dir := configs.AppConfig.Export.Path
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fail(err)
+		return
+	}
+	filePath = filepath.Join(dir, job.JobNo+"."+req.Format)
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	count, err := es.write(&req, file, func(rows int64) {
+		es.exportJobRepo.UpdateFields(jobID, map[string]interface{}{"processed_rows": rows})
+	})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	var fileSize int64
+	if info, err := os.Stat(filePath); err == nil {
+		fileSize = info.Size()
+	}
+
+	finishedAt := time.Now()
+	expiredAt := finishedAt.Add(time.Duration(configs.AppConfig.Export.RetentionHours) * time.Hour)
+	if err := es.exportJobRepo.UpdateFields(jobID, map[string]interface{}{
+		"status":         models.ExportJobStatusCompleted,
+		"processed_rows": count,
+		"file_path":      filePath,
+		"file_size":      fileSize,
+		"finished_at":    finishedAt,
+		"expired_at":     expiredAt,
+	}); err != nil {
+		fail(err)
+	}
+}
+
+// ListJobs 获取管理员的导出任务列表
+func (es *ExportService) ListJobs(req *types.FilterRequest, adminID uint) ([]*models.ExportJob, int64, error) {
+	return es.exportJobRepo.List(req, adminID)
+}
+
+// GetJob 获取导出任务
+func (es *ExportService) GetJob(id, adminID uint) (*models.ExportJob, error) {
+	job, err := es.exportJobRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.CreatedBy != adminID {
+		return nil, fmt.Errorf("导出任务不存在")
+	}
+	return job, nil
+}
+
+// GetDownloadJob 获取可下载的导出任务
+func (es *ExportService) GetDownloadJob(id, adminID uint) (*models.ExportJob, error) {
+	job, err := es.GetJob(id, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if !job.IsDownloadable() {
+		return nil, &ServiceError{Code: 400, Message: "导出文件尚未生成或已过期"}
+	}
+	if _, err := os.Stat(job.FilePath); err != nil {
+		return nil, &ServiceError{Code: 400, Message: "导出文件已被清理"}
+	}
+	return job, nil
+}
+
+// CleanExpiredFiles 删除已过期的导出文件
+func (es *ExportService) CleanExpiredFiles() {
+	jobs, err := es.exportJobRepo.GetExpired(time.Now())
+	if err != nil {
+		return
+	}
+	for _, job := range jobs {
+		if err := os.Remove(job.FilePath); err != nil && !os.IsNotExist(err) {
+			continue
+		}
+		es.exportJobRepo.UpdateFields(job.ID, map[string]interface{}{"file_path": ""})
+	}
+}
@@ -0,0 +1,485 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/configs"
+	"backend/models"
+	"backend/repositories"
+	"backend/types"
+	"backend/utils"
+
+	"github.com/google/uuid"
+)
+
+// ImportResourceCustomers 客户导入
+const ImportResourceCustomers = "customers"
+
+// customerImportColumns 客户导入列（第一项为标准列名，其余为可识别的表头别名）
+var customerImportColumns = [][]string{
+	{"name", "姓名", "名称"},
+	{"email", "邮箱"},
+	{"phone", "电话", "手机号"},
+	{"company", "公司"},
+	{"status", "状态"},
+	{"address", "地址"},
+	{"notes", "备注"},
+	{"agent_invite_code", "代理邀请码"},
+}
+
+// customerStatusLabels 客户状态文本（与 GetStatusString 及导出英文表头一致）
+var customerStatusLabels = map[string]models.CustomerStatus{
+	"0":        models.CustomerStatusInactive,
+	"1":        models.CustomerStatusActive,
+	"2":        models.CustomerStatusBlocked,
+	"未激活":      models.CustomerStatusInactive,
+	"激活":       models.CustomerStatusActive,
+	"已阻止":      models.CustomerStatusBlocked,
+	"inactive": models.CustomerStatusInactive,
+	"active":   models.CustomerStatusActive,
+	"blocked":  models.CustomerStatusBlocked,
+}
+
+// ImportService 批量导入服务
+type ImportService struct {
+	importJobRepo   *repositories.ImportJobRepository
+	customerRepo    *repositories.CustomerRepository
+	agentRepo       *repositories.AgentRepository
+	customerService *CustomerService
+}
+
+// NewImportService 创建批量导入服务
+func NewImportService() *ImportService {
+	return &ImportService{
+		importJobRepo:   repositories.NewImportJobRepository(),
+		customerRepo:    repositories.NewCustomerRepository(),
+		agentRepo:       repositories.NewAgentRepository(),
+		customerService: NewCustomerService(),
+	}
+}
+
+// importDir 导入文件与错误报告存放目录
+func importDir() string {
+	return filepath.Join(configs.AppConfig.Export.Path, "imports")
+}
+
+// CustomerImportTemplate 客户导入模板表头
+func (is *ImportService) CustomerImportTemplate() []string {
+	header := make([]string, len(customerImportColumns))
+	for i, aliases := range customerImportColumns {
+		header[i] = aliases[0]
+	}
+	return header
+}
+
+// CreateCustomerImport 保存上传文件并创建客户导入任务
+func (is *ImportService) CreateCustomerImport(file *multipart.FileHeader, mode string, dryRun bool, agentAdminID *uint, createdBy uint) (*models.ImportJob, error) {
+	format := utils.ImportFormatFromFilename(file.Filename)
+	if format == "" {
+		return nil, &ServiceError{Code: 400, Message: "只支持导入 csv 或 xlsx 文件"}
+	}
+	if file.Size > configs.AppConfig.Upload.MaxFileSize {
+		return nil, &ServiceError{Code: 400, Message: fmt.Sprintf("文件大小不能超过 %d MB", configs.AppConfig.Upload.MaxFileSize/(1024*1024))}
+	}
+
+	if mode == "" {
+		mode = models.ImportModeCreate
+	}
+	if mode != models.ImportModeCreate && mode != models.ImportModeUpsert {
+		return nil, &ServiceError{Code: 400, Message: "导入模式只支持 create 或 upsert"}
+	}
+
+	if agentAdminID != nil {
+		if err := is.checkAgent(*agentAdminID); err != nil {
+			return nil, err
+		}
+	}
+
+	jobNo := strings.ReplaceAll(uuid.New().String(), "-", "")
+	if err := os.MkdirAll(importDir(), 0755); err != nil {
+		return nil, err
+	}
+	filePath := filepath.Join(importDir(), jobNo+"."+format)
+	if err := saveMultipartFile(file, filePath); err != nil {
+		return nil, err
+	}
+
+	job := &models.ImportJob{
+		JobNo:        jobNo,
+		Resource:     ImportResourceCustomers,
+		Mode:         mode,
+		DryRun:       dryRun,
+		AgentAdminID: agentAdminID,
+		Status:       models.ImportJobStatusPending,
+		FileName:     filepath.Base(file.Filename),
+		FilePath:     filePath,
+		CreatedBy:    createdBy,
+	}
+	if err := is.importJobRepo.Create(job); err != nil {
+		os.Remove(filePath)
+		return nil, err
+	}
+
+	go is.runCustomerImport(job.ID)
+
+	return job, nil
+}
+
+// saveMultipartFile 保存上传文件到指定路径
+func saveMultipartFile(file *multipart.FileHeader, filePath string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// checkAgent 检查代理商是否存在且可用
+func (is *ImportService) checkAgent(adminID uint) error {
+	agent, err := is.agentRepo.GetByAdminID(adminID)
+	if err != nil {
+		return &ServiceError{Code: 400, Message: "归属代理不存在"}
+	}
+	if !agent.IsActive() {
+		return &ServiceError{Code: 400, Message: "归属代理已被禁用"}
+	}
+	return nil
+}
+
+// customerImportReport 导入错误报告（仅在出现失败行时创建）
+type customerImportReport struct {
+	path   string
+	file   *os.File
+	writer utils.ExportWriter
+	header []string
+}
+
+// add 写入一条失败记录：行号、原始数据、错误原因
+func (r *customerImportReport) add(line int, record []string, reason string) error {
+	if r.writer == nil {
+		file, err := os.Create(r.path)
+		if err != nil {
+			return err
+		}
+		writer, err := utils.NewExportWriter(utils.ExportFormatCSV, file)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		r.file, r.writer = file, writer
+
+		header := append([]string{"行号"}, r.header...)
+		if err := r.writer.WriteRow(append(header, "错误原因")); err != nil {
+			return err
+		}
+	}
+
+	row := make([]string, 0, len(r.header)+2)
+	row = append(row, strconv.Itoa(line))
+	for i := range r.header {
+		value := ""
+		if i < len(record) {
+			value = record[i]
+		}
+		row = append(row, value)
+	}
+	return r.writer.WriteRow(append(row, reason))
+}
+
+// close 关闭报告文件
+func (r *customerImportReport) close() error {
+	if r.writer == nil {
+		return nil
+	}
+	err := r.writer.Close()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// runCustomerImport 逐行校验并导入客户
+func (is *ImportService) runCustomerImport(jobID uint) {
+	fail := func(err error) {
+		log.Printf("Import job %d failed: %v", jobID, err)
+		is.importJobRepo.UpdateFields(jobID, map[string]interface{}{
+			"status":      models.ImportJobStatusFailed,
+			"error":       err.Error(),
+			"finished_at": time.Now(),
+		})
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			fail(fmt.Errorf("%v", r))
+		}
+	}()
+
+	job, err := is.importJobRepo.GetByID(jobID)
+	if err != nil {
+		log.Printf("Import job %d not found: %v", jobID, err)
+		return
+	}
+
+	if err := is.importJobRepo.UpdateFields(jobID, map[string]interface{}{
+		"status":     models.ImportJobStatusRunning,
+		"started_at": time.Now(),
+	}); err != nil {
+		fail(err)
+		return
+	}
+
+	reader, err := utils.OpenImportFile(job.FilePath)
+	if err != nil {
+		fail(err)
+		return
+	}
+	defer reader.Close()
+
+	header, err := reader.Read()
+	if err != nil {
+		fail(fmt.Errorf("无法读取表头: %v", err))
+		return
+	}
+	columns := mapCustomerImportHeader(header)
+	if _, ok := columns["email"]; !ok {
+		fail(fmt.Errorf("缺少必需列: email"))
+		return
+	}
+	if _, ok := columns["name"]; !ok {
+		fail(fmt.Errorf("缺少必需列: name"))
+		return
+	}
+
+	report := &customerImportReport{
+		path:   filepath.Join(importDir(), job.JobNo+"_report.csv"),
+		header: header,
+	}
+
+	var total, created, updated, failed int64
+	seenEmails := make(map[string]int)
+	agentsByInviteCode := make(map[string]*models.Agent)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			report.close()
+			fail(err)
+			return
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+		total++
+
+		get := func(key string) string {
+			if index, ok := columns[key]; ok && index < len(record) {
+				return strings.TrimSpace(record[index])
+			}
+			return ""
+		}
+
+		isUpdate, err := is.importCustomerRow(job, get, seenEmails, reader.Line(), agentsByInviteCode)
+		if err != nil {
+			failed++
+			if err := report.add(reader.Line(), record, err.Error()); err != nil {
+				report.close()
+				fail(err)
+				return
+			}
+		} else if isUpdate {
+			updated++
+		} else {
+			created++
+		}
+
+		if total%200 == 0 {
+			is.importJobRepo.UpdateFields(jobID, map[string]interface{}{
+				"total_rows":   total,
+				"created_rows": created,
+				"updated_rows": updated,
+				"failed_rows":  failed,
+			})
+		}
+	}
+
+	if err := report.close(); err != nil {
+		fail(err)
+		return
+	}
+
+	reportPath := ""
+	if report.writer != nil {
+		reportPath = report.path
+	}
+	if err := is.importJobRepo.UpdateFields(jobID, map[string]interface{}{
+		"status":       models.ImportJobStatusCompleted,
+		"total_rows":   total,
+		"created_rows": created,
+		"updated_rows": updated,
+		"failed_rows":  failed,
+		"report_path":  reportPath,
+		"finished_at":  time.Now(),
+	}); err != nil {
+		fail(err)
+	}
+}
+
+// importCustomerRow 校验并导入一行客户数据，返回是否为更新已有客户
+func (is *ImportService) importCustomerRow(job *models.ImportJob, get func(key string) string, seenEmails map[string]int, line int, agents map[string]*models.Agent) (bool, error) {
+	email := get("email")
+	if email == "" {
+		return false, fmt.Errorf("邮箱不能为空")
+	}
+	if firstLine, ok := seenEmails[strings.ToLower(email)]; ok {
+		return false, fmt.Errorf("邮箱与第%d行重复", firstLine)
+	}
+	seenEmails[strings.ToLower(email)] = line
+
+	existing, err := is.customerRepo.GetByEmail(email)
+	if err != nil {
+		return false, err
+	}
+
+	customer := &models.Customer{Email: email, Status: models.CustomerStatusActive, AgentAdminID: job.AgentAdminID}
+	isUpdate := existing != nil
+	if isUpdate {
+		if job.Mode != models.ImportModeUpsert {
+			return false, fmt.Errorf("邮箱已存在")
+		}
+		customer = existing
+	}
+
+	// 更新模式下空单元格保留原值
+	if value := get("name"); value != "" || !isUpdate {
+		customer.Name = value
+	}
+	if value := get("phone"); value != "" || !isUpdate {
+		customer.Phone = value
+	}
+	if value := get("company"); value != "" || !isUpdate {
+		customer.Company = value
+	}
+	if value := get("address"); value != "" || !isUpdate {
+		customer.Address = value
+	}
+	if value := get("notes"); value != "" || !isUpdate {
+		customer.Notes = value
+	}
+	if value := get("status"); value != "" {
+		status, ok := customerStatusLabels[strings.ToLower(value)]
+		if !ok {
+			return false, fmt.Errorf("无效的状态: %s", value)
+		}
+		customer.Status = status
+	}
+
+	// 行内代理邀请码优先于任务默认代理
+	if inviteCode := get("agent_invite_code"); inviteCode != "" {
+		agent, ok := agents[inviteCode]
+		if !ok {
+			agent, _ = is.agentRepo.GetByInviteCode(inviteCode)
+			agents[inviteCode] = agent
+		}
+		if agent == nil {
+			return false, fmt.Errorf("代理邀请码不存在: %s", inviteCode)
+		}
+		if !agent.IsActive() {
+			return false, fmt.Errorf("代理已被禁用: %s", inviteCode)
+		}
+		customer.AgentAdminID = &agent.AdminID
+	} else if isUpdate && job.AgentAdminID != nil {
+		customer.AgentAdminID = job.AgentAdminID
+	}
+
+	if err := is.customerService.ValidateCustomer(customer); err != nil {
+		return false, err
+	}
+
+	if job.DryRun {
+		return isUpdate, nil
+	}
+	if isUpdate {
+		return true, is.customerRepo.Update(customer)
+	}
+	return false, is.customerRepo.Create(customer)
+}
+
+// mapCustomerImportHeader 将表头映射为标准列名到列序号
+func mapCustomerImportHeader(header []string) map[string]int {
+	columns := make(map[string]int)
+	for index, title := range header {
+		title = strings.ToLower(strings.TrimSpace(title))
+		for _, aliases := range customerImportColumns {
+			for _, alias := range aliases {
+				if title == alias {
+					if _, exists := columns[aliases[0]]; !exists {
+						columns[aliases[0]] = index
+					}
+				}
+			}
+		}
+	}
+	return columns
+}
+
+// isBlankRecord 检查是否为空行
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ListJobs 获取管理员的导入任务列表
+func (is *ImportService) ListJobs(req *types.FilterRequest, adminID uint) ([]*models.ImportJob, int64, error) {
+	return is.importJobRepo.List(req, adminID)
+}
+
+// GetJob 获取导入任务
+func (is *ImportService) GetJob(id, adminID uint) (*models.ImportJob, error) {
+	job, err := is.importJobRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.CreatedBy != adminID {
+		return nil, fmt.Errorf("导入任务不存在")
+	}
+	return job, nil
+}
+
+// GetReportJob 获取存在错误报告的导入任务
+func (is *ImportService) GetReportJob(id, adminID uint) (*models.ImportJob, error) {
+	job, err := is.GetJob(id, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if job.ReportPath == "" {
+		return nil, &ServiceError{Code: 400, Message: "该导入任务没有错误报告"}
+	}
+	if _, err := os.Stat(job.ReportPath); err != nil {
+		return nil, &ServiceError{Code: 400, Message: "错误报告文件已被清理"}
+	}
+	return job, nil
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/database"
+	"backend/models"
+	"backend/repositories"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// maintenanceChannel 维护配置变更通知频道，各实例收到后从数据库重新加载
+	maintenanceChannel = "system:maintenance"
+	// maintenanceRefreshPeriod 定时重新加载的间隔，Redis 不可用或漏收通知时兜底
+	maintenanceRefreshPeriod     = time.Minute
+	defaultMaintenanceRetryAfter = 600
+)
+
+var maintenanceConfigKeys = []string{
+	models.ConfigKeyMaintenanceMode,
+	models.ConfigKeyMaintenanceMessage,
+	models.ConfigKeyMaintenanceRetryAfter,
+	models.ConfigKeyMaintenanceAllowedIPs,
+	models.ConfigKeyMaintenanceAllowedCustomers,
+	models.ConfigKeyMaintenanceWindows,
+}
+
+// MaintenanceStatus 维护模式配置及当前状态
+type MaintenanceStatus struct {
+	Enabled            bool                       `json:"enabled"` // 手动开关
+	Active             bool                       `json:"active"`  // 当前是否处于维护中（手动开启或处于计划时段）
+	Message            string                     `json:"message"`
+	RetryAfter         int                        `json:"retry_after"`
+	AllowedIPs         []string                   `json:"allowed_ips"`
+	AllowedCustomerIDs []uint                     `json:"allowed_customer_ids"`
+	Windows            []models.MaintenanceWindow `json:"windows"`
+	CurrentWindow      *models.MaintenanceWindow  `json:"current_window,omitempty"`
+	NextWindow         *models.MaintenanceWindow  `json:"next_window,omitempty"`
+}
+
+// MaintenanceSettings 维护模式设置，为空的字段保持不变
+type MaintenanceSettings struct {
+	Enabled            *bool                       `json:"enabled"`
+	Message            *string                     `json:"message" binding:"omitempty,max=500"`
+	RetryAfter         *int                        `json:"retry_after" binding:"omitempty,min=0"`
+	AllowedIPs         *[]string                   `json:"allowed_ips"`
+	AllowedCustomerIDs *[]uint                     `json:"allowed_customer_ids"`
+	Windows            *[]models.MaintenanceWindow `json:"windows"`
+}
+
+// MaintenanceBlock 维护期间被拦截的请求的响应内容
+type MaintenanceBlock struct {
+	Message    string
+	RetryAfter int // 秒
+}
+
+// maintenanceState 维护配置的进程内缓存，请求时只读内存
+type maintenanceState struct {
+	enabled          bool
+	message          string
+	retryAfter       int
+	allowedIPs       []*net.IPNet
+	allowedIPText    []string
+	allowedCustomers map[uint]bool
+	customerIDs      []uint
+	windows          []models.MaintenanceWindow
+}
+
+var currentMaintenance = struct {
+	sync.RWMutex
+	state *maintenanceState
+}{state: &maintenanceState{}}
+
+// maintenanceStateFrom 按系统配置生成维护状态；无效的配置项记录日志后忽略
+func maintenanceStateFrom(values map[string]*models.SystemConfig) *maintenanceState {
+	value := func(key string) string {
+		if config, ok := values[key]; ok && config != nil {
+			return strings.TrimSpace(config.Value)
+		}
+		return ""
+	}
+
+	state := &maintenanceState{
+		message:          value(models.ConfigKeyMaintenanceMessage),
+		retryAfter:       defaultMaintenanceRetryAfter,
+		allowedCustomers: make(map[uint]bool),
+	}
+	if config, ok := values[models.ConfigKeyMaintenanceMode]; ok && config != nil {
+		state.enabled = config.IsEnabled()
+	}
+	if state.message == "" {
+		state.message = models.DefaultMaintenanceMessage
+	}
+	if raw := value(models.ConfigKeyMaintenanceRetryAfter); raw != "" {
+		if seconds, err := strconv.Atoi(raw); err == nil && seconds >= 0 {
+			state.retryAfter = seconds
+		} else {
+			log.Printf("Invalid %s %q, using %d", models.ConfigKeyMaintenanceRetryAfter, raw, defaultMaintenanceRetryAfter)
+		}
+	}
+	if networks, err := models.ParseMaintenanceAllowedIPs(value(models.ConfigKeyMaintenanceAllowedIPs)); err == nil {
+		state.allowedIPs = networks
+		for _, item := range strings.Split(value(models.ConfigKeyMaintenanceAllowedIPs), ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				state.allowedIPText = append(state.allowedIPText, item)
+			}
+		}
+	} else {
+		log.Printf("Invalid %s: %v", models.ConfigKeyMaintenanceAllowedIPs, err)
+	}
+	if ids, err := models.ParseMaintenanceAllowedCustomers(value(models.ConfigKeyMaintenanceAllowedCustomers)); err == nil {
+		state.customerIDs = ids
+		for _, id := range ids {
+			state.allowedCustomers[id] = true
+		}
+	} else {
+		log.Printf("Invalid %s: %v", models.ConfigKeyMaintenanceAllowedCustomers, err)
+	}
+	if windows, err := models.ParseMaintenanceWindows(value(models.ConfigKeyMaintenanceWindows)); err == nil {
+		state.windows = windows
+	} else {
+		log.Printf("Invalid %s: %v", models.ConfigKeyMaintenanceWindows, err)
+	}
+	return state
+}
+
+// activeWindow 当前所在的计划维护时段
+func (ms *maintenanceState) activeWindow(now time.Time) *models.MaintenanceWindow {
+	for i := range ms.windows {
+		if ms.windows[i].Contains(now) {
+			return &ms.windows[i]
+		}
+	}
+	return nil
+}
+
+// nextWindow 下一个尚未开始的计划维护时段
+func (ms *maintenanceState) nextWindow(now time.Time) *models.MaintenanceWindow {
+	for i := range ms.windows {
+		if ms.windows[i].Start.After(now) {
+			return &ms.windows[i]
+		}
+	}
+	return nil
+}
+
+// allowsIP IP 是否在维护白名单内
+func (ms *maintenanceState) allowsIP(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range ms.allowedIPs {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// status 生成对外展示的维护状态
+func (ms *maintenanceState) status(now time.Time) *MaintenanceStatus {
+	status := &MaintenanceStatus{
+		Enabled:            ms.enabled,
+		Message:            ms.message,
+		RetryAfter:         ms.retryAfter,
+		AllowedIPs:         append([]string{}, ms.allowedIPText...),
+		AllowedCustomerIDs: append([]uint{}, ms.customerIDs...),
+		Windows:            append([]models.MaintenanceWindow{}, ms.windows...),
+		CurrentWindow:      ms.activeWindow(now),
+		NextWindow:         ms.nextWindow(now),
+	}
+	status.Active = status.Enabled || status.CurrentWindow != nil
+	return status
+}
+
+// LoadMaintenance 从系统配置加载维护状态到进程内缓存；读取失败时保留原有状态
+func LoadMaintenance() {
+	values, err := repositories.NewSystemConfigRepository().GetAllConfigs()
+	if err != nil {
+		log.Printf("Failed to load maintenance configs: %v", err)
+		return
+	}
+	state := maintenanceStateFrom(values)
+
+	currentMaintenance.Lock()
+	currentMaintenance.state = state
+	currentMaintenance.Unlock()
+}
+
+// ReloadMaintenance 重新加载本实例的维护状态，并通过 Redis 通知其他实例
+func ReloadMaintenance() {
+	LoadMaintenance()
+	if redisClient := database.GetRedis(); redisClient != nil {
+		if err := redisClient.Publish(context.Background(), maintenanceChannel, time.Now().Unix()).Err(); err != nil {
+			log.Printf("Failed to publish maintenance change: %v", err)
+		}
+	}
+}
+
+// StartMaintenanceSync 在后台订阅维护配置变更通知，并定时重新加载兜底
+func StartMaintenanceSync() {
+	var messages <-chan *redis.Message
+	if redisClient := database.GetRedis(); redisClient != nil {
+		messages = redisClient.Subscribe(context.Background(), maintenanceChannel).Channel()
+	}
+
+	go func() {
+		ticker := time.NewTicker(maintenanceRefreshPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-messages:
+			case <-ticker.C:
+			}
+			LoadMaintenance()
+		}
+	}()
+}
+
+// touchesMaintenance 配置修改是否涉及维护模式
+func touchesMaintenance(changes map[string]string) bool {
+	for _, key := range maintenanceConfigKeys {
+		if _, ok := changes[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckMaintenance 判断客户端请求是否因维护被拦截，放行时返回 nil；
+// customerID 仅在维护中且 IP 不在白名单时才调用，用于按客户放行
+func CheckMaintenance(now time.Time, ip string, customerID func() uint) *MaintenanceBlock {
+	currentMaintenance.RLock()
+	state := currentMaintenance.state
+	currentMaintenance.RUnlock()
+
+	window := state.activeWindow(now)
+	if !state.enabled && window == nil {
+		return nil
+	}
+	if state.allowsIP(ip) {
+		return nil
+	}
+	if len(state.allowedCustomers) > 0 {
+		if id := customerID(); id != 0 && state.allowedCustomers[id] {
+			return nil
+		}
+	}
+
+	block := &MaintenanceBlock{Message: state.message, RetryAfter: state.retryAfter}
+	if window != nil {
+		if window.Message != "" {
+			block.Message = window.Message
+		}
+		// 仅处于计划时段时按时段结束时间计算；手动开启的维护没有明确结束时间
+		if !state.enabled {
+			block.RetryAfter = int((window.End.Sub(now) + time.Second - 1) / time.Second)
+		}
+	}
+	return block
+}
+
+// GetMaintenanceStatus 获取维护模式配置及当前状态（读取数据库中的最新配置）
+func (ss *SystemService) GetMaintenanceStatus() (*MaintenanceStatus, error) {
+	values, err := ss.systemConfigRepo.GetAllConfigs()
+	if err != nil {
+		return nil, err
+	}
+	return maintenanceStateFrom(values).status(time.Now()), nil
+}
+
+// UpdateMaintenance 更新维护模式设置，保存后各实例立即生效
+func (ss *SystemService) UpdateMaintenance(settings *MaintenanceSettings, changedBy uint) (*MaintenanceStatus, error) {
+	changes := make(map[string]string)
+	if settings.Enabled != nil {
+		changes[models.ConfigKeyMaintenanceMode] = strconv.FormatBool(*settings.Enabled)
+	}
+	if settings.Message != nil {
+		changes[models.ConfigKeyMaintenanceMessage] = strings.TrimSpace(*settings.Message)
+	}
+	if settings.RetryAfter != nil {
+		changes[models.ConfigKeyMaintenanceRetryAfter] = strconv.Itoa(*settings.RetryAfter)
+	}
+	if settings.AllowedIPs != nil {
+		value := strings.Join(*settings.AllowedIPs, ",")
+		if _, err := models.ParseMaintenanceAllowedIPs(value); err != nil {
+			return nil, &ServiceError{Code: 400, Message: err.Error()}
+		}
+		changes[models.ConfigKeyMaintenanceAllowedIPs] = value
+	}
+	if settings.AllowedCustomerIDs != nil {
+		ids := make([]string, len(*settings.AllowedCustomerIDs))
+		for i, id := range *settings.AllowedCustomerIDs {
+			ids[i] = strconv.FormatUint(uint64(id), 10)
+		}
+		value := strings.Join(ids, ",")
+		if _, err := models.ParseMaintenanceAllowedCustomers(value); err != nil {
+			return nil, &ServiceError{Code: 400, Message: err.Error()}
+		}
+		changes[models.ConfigKeyMaintenanceAllowedCustomers] = value
+	}
+	if settings.Windows != nil {
+		for _, window := range *settings.Windows {
+			if window.Start.IsZero() || window.End.IsZero() || !window.End.After(window.Start) {
+				return nil, &ServiceError{Code: 400, Message: "维护时段的结束时间必须晚于开始时间"}
+			}
+		}
+		data, err := json.Marshal(*settings.Windows)
+		if err != nil {
+			return nil, err
+		}
+		changes[models.ConfigKeyMaintenanceWindows] = string(data)
+	}
+
+	if len(changes) > 0 {
+		if err := ss.UpdateConfigs(changes, changedBy); err != nil {
+			return nil, err
+		}
+	}
+	return ss.GetMaintenanceStatus()
+}
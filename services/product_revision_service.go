@@ -0,0 +1,134 @@
+package services
+
+import (
+	"log"
+
+	"backend/models"
+	"backend/repositories"
+	"backend/types"
+)
+
+// ProductRevisionService 产品版本服务
+type ProductRevisionService struct {
+	productRepo  *repositories.ProductRepository
+	revisionRepo *repositories.ProductRevisionRepository
+}
+
+// NewProductRevisionService 创建产品版本服务
+func NewProductRevisionService() *ProductRevisionService {
+	return &ProductRevisionService{
+		productRepo:  repositories.NewProductRepository(),
+		revisionRepo: repositories.NewProductRevisionRepository(),
+	}
+}
+
+// ProductRevisionDiff 两个产品版本之间的差异，To 为 0 表示产品当前内容
+type ProductRevisionDiff struct {
+	ProductID uint                        `json:"product_id"`
+	From      int                         `json:"from"`
+	To        int                         `json:"to"`
+	Changes   models.CampaignFieldChanges `json:"changes"`
+}
+
+// Record 重新读取产品并追加版本；内容与最新版本相同时不记录。operatorID 为 0 表示系统操作
+func (prs *ProductRevisionService) Record(productID uint, action string, operatorID uint, remark string) {
+	product, err := prs.productRepo.GetByID(productID)
+	if err != nil {
+		log.Printf("Failed to load product %d for revision: %v", productID, err)
+		return
+	}
+	latest, err := prs.revisionRepo.Latest(productID)
+	if err != nil {
+		log.Printf("Failed to load latest revision of product %d: %v", productID, err)
+		return
+	}
+
+	snapshot := product.RevisionSnapshot()
+	var previous models.ProductSnapshot
+	if latest != nil {
+		previous = latest.Snapshot
+	}
+	changes := models.DiffSnapshots(previous, snapshot)
+	if latest != nil && len(changes) == 0 {
+		return
+	}
+
+	revision := &models.ProductRevision{
+		ProductID: productID,
+		Action:    action,
+		Snapshot:  snapshot,
+		Changes:   changes,
+		Remark:    remark,
+	}
+	if operatorID > 0 {
+		revision.AdminID = &operatorID
+	}
+	if err := prs.revisionRepo.Create(revision); err != nil {
+		log.Printf("Failed to record revision for product %d: %v", productID, err)
+	}
+}
+
+// List 获取产品的版本列表
+func (prs *ProductRevisionService) List(productID uint, req *types.PageRequest) ([]*models.ProductRevision, int64, error) {
+	if _, err := prs.productRepo.GetByID(productID); err != nil {
+		return nil, 0, err
+	}
+	return prs.revisionRepo.List(productID, req)
+}
+
+// Get 获取产品的指定版本（含快照）
+func (prs *ProductRevisionService) Get(productID uint, version int) (*models.ProductRevision, error) {
+	if _, err := prs.productRepo.GetByID(productID); err != nil {
+		return nil, err
+	}
+	return prs.revisionRepo.GetByVersion(productID, version)
+}
+
+// Diff 比较两个版本；to 为 0 时与产品当前内容比较，from 为 0 时取 to 的上一版本
+func (prs *ProductRevisionService) Diff(productID uint, from, to int) (*ProductRevisionDiff, error) {
+	product, err := prs.productRepo.GetByID(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	var current models.ProductSnapshot
+	if to > 0 {
+		revision, err := prs.revisionRepo.GetByVersion(productID, to)
+		if err != nil {
+			return nil, err
+		}
+		current = revision.Snapshot
+	} else {
+		current = product.RevisionSnapshot()
+	}
+
+	if from == 0 {
+		if to > 1 {
+			from = to - 1
+		} else if to == 0 {
+			latest, err := prs.revisionRepo.Latest(productID)
+			if err != nil {
+				return nil, err
+			}
+			if latest != nil {
+				from = latest.Version
+			}
+		}
+	}
+
+	var previous models.ProductSnapshot
+	if from > 0 {
+		revision, err := prs.revisionRepo.GetByVersion(productID, from)
+		if err != nil {
+			return nil, err
+		}
+		previous = revision.Snapshot
+	}
+
+	return &ProductRevisionDiff{
+		ProductID: productID,
+		From:      from,
+		To:        to,
+		Changes:   models.DiffSnapshots(previous, current),
+	}, nil
+}
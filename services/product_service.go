@@ -1,20 +1,25 @@
 package services
 
 import (
+	"fmt"
+
 	"backend/models"
 	"backend/repositories"
 	"backend/types"
+	"backend/utils"
 )
 
 // ProductService 产品服务
 type ProductService struct {
-	productRepo *repositories.ProductRepository
+	productRepo     *repositories.ProductRepository
+	revisionService *ProductRevisionService
 }
 
 // NewProductService 创建产品服务
 func NewProductService() *ProductService {
 	return &ProductService{
-		productRepo: repositories.NewProductRepository(),
+		productRepo:     repositories.NewProductRepository(),
+		revisionService: NewProductRevisionService(),
 	}
 }
 
@@ -28,18 +33,99 @@ func (ps *ProductService) GetByID(id uint) (*models.Product, error) {
 	return ps.productRepo.GetByID(id)
 }
 
-// Create 创建产品
-func (ps *ProductService) Create(product *models.Product) error {
-	return ps.productRepo.Create(product)
+// Create 创建产品，product.Images 中的图片按顺序一并保存
+func (ps *ProductService) Create(product *models.Product, operatorID uint) error {
+	if err := ps.applyStoreLinks(product, nil); err != nil {
+		return err
+	}
+	for i := range product.Images {
+		product.Images[i].Sort = i
+	}
+	if err := ps.productRepo.Create(product); err != nil {
+		return err
+	}
+
+	ps.revisionService.Record(product.ID, models.ProductRevisionCreate, operatorID, "")
+	syncAssetReferences(models.AssetOwnerProduct, product.ID)
+	return nil
+}
+
+// Update 更新产品（不修改图片）并记录版本
+func (ps *ProductService) Update(product *models.Product, operatorID uint) error {
+	return ps.save(product, nil, models.ProductRevisionUpdate, operatorID, "")
+}
+
+// UpdateWithImages 更新产品并按给定顺序替换全部图片，只记录一个版本
+func (ps *ProductService) UpdateWithImages(product *models.Product, images []models.ProductImage, operatorID uint) error {
+	if images == nil {
+		images = []models.ProductImage{}
+	}
+	return ps.save(product, images, models.ProductRevisionUpdate, operatorID, "")
+}
+
+// save 保存产品，images 不为 nil 时替换全部图片，完成后记录版本
+func (ps *ProductService) save(product *models.Product, images []models.ProductImage, action string, operatorID uint, remark string) error {
+	defer InvalidateAdCache()
+
+	existing, err := ps.productRepo.GetByID(product.ID)
+	if err != nil {
+		return err
+	}
+	if err := ps.applyStoreLinks(product, existing); err != nil {
+		return err
+	}
+	if images != nil {
+		if err := ps.prepareImages(product.ID, images); err != nil {
+			return err
+		}
+	}
+	// 产品与图片在同一事务中写入，失败时不会留下只改了一半的内容
+	if err := ps.productRepo.UpdateWithImages(product, images); err != nil {
+		return err
+	}
+	if images != nil {
+		product.Images = images
+	}
+
+	ps.revisionService.Record(product.ID, action, operatorID, remark)
+	syncAssetReferences(models.AssetOwnerProduct, product.ID)
+	return nil
 }
 
-// Update 更新产品
-func (ps *ProductService) Update(product *models.Product) error {
-	return ps.productRepo.Update(product)
+// applyStoreLinks 校验商店链接并提取包名与 App Store 应用ID；
+// existing 为更新前的产品，未变更的链接不再校验，避免历史数据因规则收紧而无法保存其他修改
+func (ps *ProductService) applyStoreLinks(product *models.Product, existing *models.Product) error {
+	if existing != nil && product.GooglePayLink == existing.GooglePayLink {
+		if product.GooglePayLink != "" {
+			product.PackageName = existing.PackageName
+		}
+	} else if product.GooglePayLink != "" {
+		packageName, err := utils.ParseGooglePlayLink(product.GooglePayLink)
+		if err != nil {
+			return &ServiceError{Code: 400, Message: err.Error()}
+		}
+		product.PackageName = packageName
+	}
+
+	if existing != nil && product.AppStoreLink == existing.AppStoreLink {
+		product.AppStoreID = existing.AppStoreID
+		return nil
+	}
+	product.AppStoreID = ""
+	if product.AppStoreLink != "" {
+		appStoreID, err := utils.ParseAppStoreLink(product.AppStoreLink)
+		if err != nil {
+			return &ServiceError{Code: 400, Message: err.Error()}
+		}
+		product.AppStoreID = appStoreID
+	}
+	return nil
 }
 
 // Delete 删除产品
 func (ps *ProductService) Delete(id uint) error {
+	defer InvalidateAdCache()
+
 	// 检查是否有关联的活动计划
 	campaigns, err := ps.productRepo.GetCampaignsByProductID(id)
 	if err != nil {
@@ -58,18 +144,50 @@ func (ps *ProductService) Delete(id uint) error {
 		}
 	}
 
-	return ps.productRepo.Delete(id)
+	if err := ps.productRepo.Delete(id); err != nil {
+		return err
+	}
+	syncAssetReferences(models.AssetOwnerProduct, id)
+	return nil
 }
 
 // UpdateStatus 更新产品状态
-func (ps *ProductService) UpdateStatus(id uint, status models.ProductStatus) error {
+func (ps *ProductService) UpdateStatus(id uint, status models.ProductStatus, operatorID uint) (*models.Product, error) {
 	product, err := ps.productRepo.GetByID(id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	product.Status = status
-	return ps.productRepo.Update(product)
+	if err := ps.save(product, nil, models.ProductRevisionStatus, operatorID, ""); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// GetTrackingKey 获取产品事件上报密钥，旧数据没有密钥时自动生成
+func (ps *ProductService) GetTrackingKey(id uint) (string, error) {
+	product, err := ps.productRepo.GetByID(id)
+	if err != nil {
+		return "", err
+	}
+	if product.TrackingKey != "" {
+		return product.TrackingKey, nil
+	}
+	return ps.RotateTrackingKey(id)
+}
+
+// RotateTrackingKey 重新生成产品事件上报密钥，旧密钥立即失效
+func (ps *ProductService) RotateTrackingKey(id uint) (string, error) {
+	if _, err := ps.productRepo.GetByID(id); err != nil {
+		return "", err
+	}
+
+	trackingKey := models.GenerateTrackingKey()
+	if err := ps.productRepo.UpdateTrackingKey(id, trackingKey); err != nil {
+		return "", err
+	}
+	return trackingKey, nil
 }
 
 // GetStatistics 获取产品统计
@@ -103,11 +221,201 @@ func (ps *ProductService) GetActiveProducts() ([]*models.Product, error) {
 }
 
 // BatchUpdateStatus 批量更新状态
-func (ps *ProductService) BatchUpdateStatus(ids []uint, status models.ProductStatus) error {
-	return ps.productRepo.BatchUpdateStatus(ids, status)
+func (ps *ProductService) BatchUpdateStatus(ids []uint, status models.ProductStatus, operatorID uint) error {
+	defer InvalidateAdCache()
+
+	if err := ps.productRepo.BatchUpdateStatus(ids, status); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		ps.revisionService.Record(id, models.ProductRevisionStatus, operatorID, "")
+	}
+	return nil
 }
 
 // GetProductWithCampaigns 获取产品及其计划列表
 func (ps *ProductService) GetProductWithCampaigns(id uint) (*models.Product, error) {
 	return ps.productRepo.GetWithCampaigns(id)
 }
+
+// ListImages 获取产品图片列表
+func (ps *ProductService) ListImages(productID uint) ([]models.ProductImage, error) {
+	if _, err := ps.productRepo.GetByID(productID); err != nil {
+		return nil, err
+	}
+	return ps.productRepo.ListImages(productID)
+}
+
+// prepareImages 按给定顺序设置产品图片的排序，未提供尺寸时沿用同一 URL 原有图片的尺寸
+func (ps *ProductService) prepareImages(productID uint, images []models.ProductImage) error {
+	existing, err := ps.productRepo.ListImages(productID)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]models.ProductImage, len(existing))
+	for _, image := range existing {
+		known[image.URL] = image
+	}
+
+	for i := range images {
+		images[i].ID = 0
+		images[i].ProductID = productID
+		images[i].Sort = i
+		if old, ok := known[images[i].URL]; ok && images[i].Width == 0 && images[i].Height == 0 {
+			images[i].Width = old.Width
+			images[i].Height = old.Height
+		}
+	}
+	return nil
+}
+
+// AddImages 在产品图片末尾追加图片
+func (ps *ProductService) AddImages(productID uint, images []models.ProductImage, operatorID uint) ([]models.ProductImage, error) {
+	if _, err := ps.productRepo.GetByID(productID); err != nil {
+		return nil, err
+	}
+
+	maxSort, err := ps.productRepo.MaxImageSort(productID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range images {
+		images[i].ProductID = productID
+		images[i].Sort = maxSort + 1 + i
+	}
+
+	if err := ps.productRepo.CreateImages(images); err != nil {
+		return nil, err
+	}
+
+	ps.revisionService.Record(productID, models.ProductRevisionImages, operatorID, "")
+	syncAssetReferences(models.AssetOwnerProduct, productID)
+	return images, nil
+}
+
+// UpdateImage 更新产品图片的替代文本与排序值
+func (ps *ProductService) UpdateImage(productID, imageID uint, alt *string, sort *int, operatorID uint) (*models.ProductImage, error) {
+	image, err := ps.productRepo.GetImage(productID, imageID)
+	if err != nil {
+		return nil, err
+	}
+	if alt != nil {
+		image.Alt = *alt
+	}
+	if sort != nil {
+		image.Sort = *sort
+	}
+
+	if err := ps.productRepo.UpdateImage(image); err != nil {
+		return nil, err
+	}
+
+	ps.revisionService.Record(productID, models.ProductRevisionImages, operatorID, "")
+	return image, nil
+}
+
+// DeleteImage 删除产品图片
+func (ps *ProductService) DeleteImage(productID, imageID, operatorID uint) error {
+	if err := ps.productRepo.DeleteImage(productID, imageID); err != nil {
+		return err
+	}
+
+	ps.revisionService.Record(productID, models.ProductRevisionImages, operatorID, "")
+	syncAssetReferences(models.AssetOwnerProduct, productID)
+	return nil
+}
+
+// ReorderImages 按给定 ID 顺序重新排列产品图片，必须包含产品的全部图片
+func (ps *ProductService) ReorderImages(productID uint, ids []uint, operatorID uint) ([]models.ProductImage, error) {
+	existing, err := ps.ListImages(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := make(map[uint]bool, len(existing))
+	for _, image := range existing {
+		remaining[image.ID] = true
+	}
+	for _, id := range ids {
+		if !remaining[id] {
+			return nil, &ServiceError{Code: 400, Message: fmt.Sprintf("图片 %d 不属于该产品或重复", id)}
+		}
+		delete(remaining, id)
+	}
+	if len(remaining) > 0 {
+		return nil, &ServiceError{Code: 400, Message: "排序必须包含产品的全部图片"}
+	}
+
+	if err := ps.productRepo.UpdateImageSorts(productID, ids); err != nil {
+		return nil, err
+	}
+
+	ps.revisionService.Record(productID, models.ProductRevisionImages, operatorID, "")
+	return ps.productRepo.ListImages(productID)
+}
+
+// ApplyAppManifest 将安装包清单写入产品：Android 包名须与 Google Play 链接一致，解析结果合并到 AppInfo
+func (ps *ProductService) ApplyAppManifest(productID uint, manifest *utils.AppManifest, operatorID uint) (*models.Product, error) {
+	product, err := ps.productRepo.GetByID(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch manifest.Platform {
+	case utils.AppPlatformAndroid:
+		if product.GooglePayLink != "" && product.PackageName != "" && product.PackageName != manifest.PackageName {
+			return nil, &ServiceError{
+				Code:    400,
+				Message: fmt.Sprintf("安装包包名 %s 与 Google Play 链接中的包名 %s 不一致", manifest.PackageName, product.PackageName),
+			}
+		}
+		product.PackageName = manifest.PackageName
+		product.SetAppInfo("Android包名", manifest.PackageName)
+		setAppInfoIfPresent(product, "Android版本", manifest.VersionName)
+		setAppInfoIfPresent(product, "Android版本号", manifest.VersionCode)
+		setAppInfoIfPresent(product, "Android最低SDK", manifest.MinOS)
+		setAppInfoIfPresent(product, "Android目标SDK", manifest.TargetSDK)
+	case utils.AppPlatformIOS:
+		product.BundleID = manifest.PackageName
+		product.SetAppInfo("iOS Bundle ID", manifest.PackageName)
+		setAppInfoIfPresent(product, "iOS版本", manifest.VersionName)
+		setAppInfoIfPresent(product, "iOS构建号", manifest.VersionCode)
+		setAppInfoIfPresent(product, "iOS最低系统版本", manifest.MinOS)
+	}
+	setAppInfoIfPresent(product, "应用名称", manifest.AppName)
+
+	if err := ps.save(product, nil, models.ProductRevisionManifest, operatorID, ""); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// setAppInfoIfPresent 值非空时写入应用信息
+func setAppInfoIfPresent(product *models.Product, key, value string) {
+	if value != "" {
+		product.SetAppInfo(key, value)
+	}
+}
+
+// Restore 将产品内容恢复到指定版本（状态保持不变），并记录为新版本
+func (ps *ProductService) Restore(productID uint, version int, operatorID uint) (*models.Product, error) {
+	product, err := ps.productRepo.GetByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := ps.revisionService.Get(productID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := revision.Snapshot.ApplyTo(product)
+	if err != nil {
+		return nil, err
+	}
+
+	remark := fmt.Sprintf("恢复到版本 %d", version)
+	if err := ps.save(product, images, models.ProductRevisionRestore, operatorID, remark); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	"backend/configs"
+	"backend/models"
+	"backend/pkg/storage"
+)
+
+// storageConfigKeys 影响存储后端的配置键
+var storageConfigKeys = []string{
+	models.ConfigKeyStorageProvider,
+	models.ConfigKeyStorageEndpoint,
+	models.ConfigKeyStorageBucket,
+	models.ConfigKeyStorageRegion,
+	models.ConfigKeyStorageAccessKey,
+	models.ConfigKeyStorageSecretKey,
+}
+
+// storageConfig 根据系统配置（可叠加未保存的修改）生成存储配置
+func storageConfig(values map[string]*models.SystemConfig, overrides map[string]string) storage.Config {
+	value := func(key string) string {
+		if v, ok := overrides[key]; ok {
+			return strings.TrimSpace(v)
+		}
+		if config, ok := values[key]; ok {
+			return strings.TrimSpace(config.Value)
+		}
+		return ""
+	}
+
+	return storage.Config{
+		Provider:  value(models.ConfigKeyStorageProvider),
+		LocalRoot: configs.AppConfig.Upload.Path,
+		Endpoint:  value(models.ConfigKeyStorageEndpoint),
+		Region:    value(models.ConfigKeyStorageRegion),
+		Bucket:    value(models.ConfigKeyStorageBucket),
+		AccessKey: value(models.ConfigKeyStorageAccessKey),
+		SecretKey: value(models.ConfigKeyStorageSecretKey),
+	}
+}
+
+// loadedStorage 当前存储后端对应的配置，配置未变化时不重复创建
+var loadedStorage = struct {
+	sync.Mutex
+	config *storage.Config
+}{}
+
+// LoadStorage 按系统配置初始化上传文件存储；配置无效时回退到本地存储
+func LoadStorage() {
+	values, err := loadConfigs()
+	if err != nil {
+		log.Printf("Failed to load storage configs, using local storage: %v", err)
+		values = map[string]*models.SystemConfig{}
+	}
+
+	cfg := storageConfig(values, nil)
+	loadedStorage.Lock()
+	defer loadedStorage.Unlock()
+	if loadedStorage.config != nil && *loadedStorage.config == cfg {
+		return
+	}
+	loadedStorage.config = &cfg
+
+	s, err := storage.New(cfg)
+	if err != nil {
+		log.Printf("Invalid storage config, using local storage: %v", err)
+		s = storage.NewLocalStorage(cfg.LocalRoot)
+	}
+	storage.SetDefault(s)
+	log.Printf("Upload storage: %s", s.Provider())
+}
+
+// touchesStorage 配置修改是否涉及存储后端
+func touchesStorage(changes map[string]string) bool {
+	for _, key := range storageConfigKeys {
+		if _, ok := changes[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkStorageConfig 保存前校验修改后的存储配置能否创建存储后端
+func checkStorageConfig(changes map[string]string) error {
+	if !touchesStorage(changes) {
+		return nil
+	}
+	values, err := loadConfigs()
+	if err != nil {
+		return err
+	}
+	if _, err := storage.New(storageConfig(values, changes)); err != nil {
+		return &ServiceError{Code: 400, Message: err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,576 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/configs"
+	"backend/repositories"
+
+	"github.com/google/uuid"
+)
+
+// 备份类型
+const (
+	BackupKindManual     = "manual"      // 管理员手动创建
+	BackupKindPreRestore = "pre_restore" // 恢复前自动创建的安全备份
+)
+
+const (
+	backupFormat        = "backend-logical-backup"
+	backupFormatVersion = 1
+	backupFileExt       = ".jsonl.gz"
+	backupInfoExt       = ".json"
+)
+
+// backupFilePattern 备份文件名，下载、删除、恢复前校验以防路径穿越
+var backupFilePattern = regexp.MustCompile(`^backup_\d{8}_\d{6}_[0-9a-f]{8}\.jsonl\.gz$`)
+
+// backupMu 备份、恢复、删除备份与数据导入互斥执行
+var backupMu sync.Mutex
+
+var errBackupBusy = &ServiceError{Code: 409, Message: "已有备份、恢复或导入任务正在执行，请稍后再试"}
+
+// BackupTable 备份中一张表的信息
+type BackupTable struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+}
+
+// BackupInfo 备份信息，与备份文件同名另存为 .json
+type BackupInfo struct {
+	Filename    string        `json:"filename"`
+	Kind        string        `json:"kind"`
+	Description string        `json:"description"`
+	Tables      []BackupTable `json:"tables"`
+	TotalRows   int64         `json:"total_rows"`
+	Size        int64         `json:"size"`
+	Checksum    string        `json:"checksum"` // 备份文件的 SHA-256（十六进制）
+	Version     int           `json:"version"`
+	Source      string        `json:"source,omitempty"` // 恢复前自动备份所对应的待恢复备份
+	CreatedBy   uint          `json:"created_by"`
+	CreatedAt   time.Time     `json:"created_at"`
+	DurationMs  int64         `json:"duration_ms"`
+}
+
+// BackupRestoreResult 恢复结果
+type BackupRestoreResult struct {
+	Filename     string        `json:"filename"`
+	SafetyBackup string        `json:"safety_backup"` // 恢复前自动创建的备份，可用于撤销本次恢复
+	Tables       []BackupTable `json:"tables"`
+	DurationMs   int64         `json:"duration_ms"`
+}
+
+// backupHeader 备份文件首行
+type backupHeader struct {
+	Format      string    `json:"format"`
+	Version     int       `json:"version"`
+	Kind        string    `json:"kind"`
+	Description string    `json:"description"`
+	Tables      []string  `json:"tables"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// backupTableHeader 每张表数据之前的表头行，其后每行是一条记录的值数组
+type backupTableHeader struct {
+	Table   string                      `json:"table"`
+	Columns []repositories.BackupColumn `json:"columns"`
+}
+
+// backupDir 备份文件目录
+func backupDir() string {
+	return configs.AppConfig.Backup.Path
+}
+
+// backupFilePath 校验备份文件名并返回完整路径
+func backupFilePath(filename string) (string, error) {
+	if !backupFilePattern.MatchString(filename) {
+		return "", &ServiceError{Code: 400, Message: "备份文件名无效"}
+	}
+	return filepath.Join(backupDir(), filename), nil
+}
+
+// resolveBackupTables 校验要备份的表，为空时备份全部表；按模型顺序返回
+func (ss *SystemService) resolveBackupTables(tables []string) ([]string, error) {
+	known, err := ss.backupRepo.TableNames()
+	if err != nil {
+		return nil, err
+	}
+	if len(tables) == 0 {
+		return known, nil
+	}
+
+	selected := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		selected[table] = true
+	}
+	var result []string
+	for _, table := range known {
+		if selected[table] {
+			result = append(result, table)
+			delete(selected, table)
+		}
+	}
+	if len(selected) > 0 {
+		var unknown []string
+		for table := range selected {
+			unknown = append(unknown, table)
+		}
+		sort.Strings(unknown)
+		return nil, &ServiceError{Code: 400, Message: "未知的数据表: " + strings.Join(unknown, ", ")}
+	}
+	return result, nil
+}
+
+// CreateBackup 备份选定的数据表（为空时备份全部表），完成后按保留策略清理旧备份
+func (ss *SystemService) CreateBackup(description string, tables []string, createdBy uint) (*BackupInfo, error) {
+	if !backupMu.TryLock() {
+		return nil, errBackupBusy
+	}
+	defer backupMu.Unlock()
+
+	tables, err := ss.resolveBackupTables(tables)
+	if err != nil {
+		return nil, err
+	}
+	info, err := ss.writeBackup(BackupKindManual, description, "", tables, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	ss.applyBackupRetention("")
+	return info, nil
+}
+
+// writeBackup 在一致性快照中逐表读取数据，写入 gzip 压缩的 JSON Lines 文件并记录校验和
+func (ss *SystemService) writeBackup(kind, description, source string, tables []string, createdBy uint) (info *BackupInfo, err error) {
+	if err := os.MkdirAll(backupDir(), 0750); err != nil {
+		return nil, err
+	}
+
+	startedAt := time.Now()
+	filename := fmt.Sprintf("backup_%s_%s%s", startedAt.Format("20060102_150405"), strings.ReplaceAll(uuid.NewString(), "-", "")[:8], backupFileExt)
+	path := filepath.Join(backupDir(), filename)
+	tmpPath := path + ".tmp"
+
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	hasher := sha256.New()
+	gz := gzip.NewWriter(io.MultiWriter(file, hasher))
+	buf := bufio.NewWriterSize(gz, 256*1024)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+
+	if err = enc.Encode(backupHeader{
+		Format:      backupFormat,
+		Version:     backupFormatVersion,
+		Kind:        kind,
+		Description: description,
+		Tables:      tables,
+		CreatedAt:   startedAt,
+	}); err != nil {
+		return nil, err
+	}
+
+	var tableInfos []BackupTable
+	var totalRows int64
+	err = ss.backupRepo.Dump(tables,
+		func(table string, columns []repositories.BackupColumn) error {
+			tableInfos = append(tableInfos, BackupTable{Name: table})
+			return enc.Encode(backupTableHeader{Table: table, Columns: columns})
+		},
+		func(values []interface{}) error {
+			tableInfos[len(tableInfos)-1].Rows++
+			totalRows++
+			return enc.Encode(values)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = buf.Flush(); err != nil {
+		return nil, err
+	}
+	if err = gz.Close(); err != nil {
+		return nil, err
+	}
+	if err = file.Sync(); err != nil {
+		return nil, err
+	}
+	if err = file.Close(); err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		return nil, err
+	}
+
+	info = &BackupInfo{
+		Filename:    filename,
+		Kind:        kind,
+		Description: description,
+		Tables:      tableInfos,
+		TotalRows:   totalRows,
+		Size:        stat.Size(),
+		Checksum:    hex.EncodeToString(hasher.Sum(nil)),
+		Version:     backupFormatVersion,
+		Source:      source,
+		CreatedBy:   createdBy,
+		CreatedAt:   startedAt,
+		DurationMs:  time.Since(startedAt).Milliseconds(),
+	}
+	if err = writeBackupInfo(path, info); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return info, nil
+}
+
+// writeBackupInfo 保存备份信息文件
+func writeBackupInfo(path string, info *BackupInfo) error {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := path + backupInfoExt + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0640); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path+backupInfoExt)
+}
+
+// GetBackup 获取备份信息及备份文件路径
+func (ss *SystemService) GetBackup(filename string) (*BackupInfo, string, error) {
+	path, err := backupFilePath(filename)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := os.ReadFile(path + backupInfoExt)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", &ServiceError{Code: 404, Message: "备份不存在"}
+		}
+		return nil, "", err
+	}
+	var info BackupInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, "", fmt.Errorf("备份信息文件损坏: %w", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", &ServiceError{Code: 404, Message: "备份文件不存在"}
+		}
+		return nil, "", err
+	}
+	return &info, path, nil
+}
+
+// GetBackupList 获取备份列表，最新的在前
+func (ss *SystemService) GetBackupList() ([]*BackupInfo, error) {
+	backups := []*BackupInfo{}
+	entries, err := os.ReadDir(backupDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return backups, nil
+		}
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), backupInfoExt)
+		if entry.IsDir() || name == entry.Name() || !backupFilePattern.MatchString(name) {
+			continue
+		}
+		info, _, err := ss.GetBackup(name)
+		if err != nil {
+			log.Printf("Skipping backup %s: %v", name, err)
+			continue
+		}
+		backups = append(backups, info)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// DeleteBackup 删除备份文件及其信息文件
+func (ss *SystemService) DeleteBackup(filename string) error {
+	path, err := backupFilePath(filename)
+	if err != nil {
+		return err
+	}
+	if !backupMu.TryLock() {
+		return errBackupBusy
+	}
+	defer backupMu.Unlock()
+
+	if _, err := os.Stat(path + backupInfoExt); os.IsNotExist(err) {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return &ServiceError{Code: 404, Message: "备份不存在"}
+		}
+	}
+	return removeBackupFiles(path)
+}
+
+// removeBackupFiles 删除备份文件及其信息文件
+func removeBackupFiles(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(path + backupInfoExt); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// verifyBackup 校验备份文件的 SHA-256 与记录一致
+func verifyBackup(path string, info *BackupInfo) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return err
+	}
+	if hex.EncodeToString(hasher.Sum(nil)) != info.Checksum {
+		return &ServiceError{Code: 422, Message: "备份文件校验失败，文件可能已损坏或被修改"}
+	}
+	return nil
+}
+
+// RestoreFromBackup 从备份恢复数据：校验文件后先为涉及的表创建安全备份，
+// 再在一个事务中清空这些表并写回备份数据，任一步失败则整体回滚
+func (ss *SystemService) RestoreFromBackup(filename string, restoredBy uint) (*BackupRestoreResult, error) {
+	if !backupMu.TryLock() {
+		return nil, errBackupBusy
+	}
+	defer backupMu.Unlock()
+
+	startedAt := time.Now()
+	info, path, err := ss.GetBackup(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyBackup(path, info); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(info.Tables))
+	for i, table := range info.Tables {
+		names[i] = table.Name
+	}
+	tables, err := ss.resolveBackupTables(names)
+	if err != nil {
+		return nil, err
+	}
+
+	safety, err := ss.writeBackup(BackupKindPreRestore, fmt.Sprintf("恢复 %s 前自动备份", filename), filename, tables, restoredBy)
+	if err != nil {
+		return nil, fmt.Errorf("创建恢复前备份失败: %w", err)
+	}
+	// 清理旧备份时保留本次恢复所用的备份
+	defer ss.applyBackupRetention(filename)
+
+	if err := ss.loadBackup(path, info); err != nil {
+		return nil, err
+	}
+
+	// 恢复后的数据可能涉及投放缓存与存储、上传、维护配置
+	InvalidateAdCache()
+	InvalidateConfigCache()
+	LoadStorage()
+	LoadUploadPolicy()
+	ReloadMaintenance()
+
+	log.Printf("Restored backup %s (%d rows), safety backup %s", filename, info.TotalRows, safety.Filename)
+	return &BackupRestoreResult{
+		Filename:     filename,
+		SafetyBackup: safety.Filename,
+		Tables:       info.Tables,
+		DurationMs:   time.Since(startedAt).Milliseconds(),
+	}, nil
+}
+
+// loadBackup 读取备份文件并在事务中写回，写入行数与备份记录不一致时回滚
+func (ss *SystemService) loadBackup(path string, info *BackupInfo) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(bufio.NewReader(file))
+	if err != nil {
+		return &ServiceError{Code: 422, Message: "备份文件格式无效"}
+	}
+	defer gz.Close()
+
+	dec := json.NewDecoder(gz)
+	var header backupHeader
+	if err := dec.Decode(&header); err != nil || header.Format != backupFormat {
+		return &ServiceError{Code: 422, Message: "备份文件格式无效"}
+	}
+	if header.Version > backupFormatVersion {
+		return &ServiceError{Code: 422, Message: fmt.Sprintf("不支持的备份格式版本: %d", header.Version)}
+	}
+
+	expected := make(map[string]int64, len(info.Tables))
+	for _, table := range info.Tables {
+		expected[table.Name] = table.Rows
+	}
+
+	_, err = ss.backupRepo.Restore(func(loader *repositories.BackupLoader) error {
+		var binary []bool
+		for {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return fmt.Errorf("读取备份文件失败: %w", err)
+			}
+
+			raw = bytes.TrimSpace(raw)
+			if len(raw) > 0 && raw[0] == '{' {
+				var tableHeader backupTableHeader
+				if err := json.Unmarshal(raw, &tableHeader); err != nil {
+					return fmt.Errorf("读取备份文件失败: %w", err)
+				}
+				if _, ok := expected[tableHeader.Table]; !ok {
+					return fmt.Errorf("备份文件包含未记录的表 %s", tableHeader.Table)
+				}
+				columns := make([]string, len(tableHeader.Columns))
+				binary = make([]bool, len(tableHeader.Columns))
+				for i, column := range tableHeader.Columns {
+					columns[i] = column.Name
+					binary[i] = column.Binary
+				}
+				if err := loader.Begin(tableHeader.Table, columns); err != nil {
+					return err
+				}
+				continue
+			}
+
+			values, err := decodeBackupRow(raw, binary)
+			if err != nil {
+				return err
+			}
+			if err := loader.Insert(values); err != nil {
+				return err
+			}
+		}
+
+		rows := loader.Rows()
+		for table, count := range expected {
+			if written, ok := rows[table]; !ok || written != count {
+				return fmt.Errorf("表 %s 恢复的行数 %d 与备份记录 %d 不一致", table, written, count)
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// decodeBackupRow 解析一行数据，二进制列从 base64 还原
+func decodeBackupRow(raw []byte, binary []bool) ([]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var values []interface{}
+	if err := dec.Decode(&values); err != nil {
+		return nil, fmt.Errorf("读取备份文件失败: %w", err)
+	}
+	if len(values) != len(binary) {
+		return nil, fmt.Errorf("备份数据列数与表头不一致")
+	}
+
+	for i, value := range values {
+		switch v := value.(type) {
+		case json.Number:
+			values[i] = v.String()
+		case string:
+			if binary[i] {
+				data, err := base64.StdEncoding.DecodeString(v)
+				if err != nil {
+					return nil, fmt.Errorf("备份中的二进制数据无效: %w", err)
+				}
+				values[i] = data
+			}
+		}
+	}
+	return values, nil
+}
+
+// applyBackupRetention 按保留策略清理旧备份：手动备份与恢复前备份分别计数，
+// 超出保留份数或超过保留天数的删除，每类至少保留最新一份；protect 指定的备份不删除
+func (ss *SystemService) applyBackupRetention(protect string) {
+	backups, err := ss.GetBackupList()
+	if err != nil {
+		log.Printf("Failed to list backups for retention: %v", err)
+		return
+	}
+
+	cfg := configs.AppConfig.Backup
+	keep := map[string]int{
+		BackupKindManual:     cfg.KeepCount,
+		BackupKindPreRestore: cfg.SafetyKeepCount,
+	}
+	var cutoff time.Time
+	if cfg.RetentionDays > 0 {
+		cutoff = time.Now().AddDate(0, 0, -cfg.RetentionDays)
+	}
+
+	seen := make(map[string]int)
+	for _, backup := range backups {
+		index := seen[backup.Kind]
+		seen[backup.Kind]++
+		if index == 0 || backup.Filename == protect {
+			continue
+		}
+		tooMany := keep[backup.Kind] > 0 && index >= keep[backup.Kind]
+		tooOld := !cutoff.IsZero() && backup.CreatedAt.Before(cutoff)
+		if !tooMany && !tooOld {
+			continue
+		}
+
+		path, err := backupFilePath(backup.Filename)
+		if err != nil {
+			continue
+		}
+		if err := removeBackupFiles(path); err != nil {
+			log.Printf("Failed to remove expired backup %s: %v", backup.Filename, err)
+			continue
+		}
+		log.Printf("Removed expired backup %s", backup.Filename)
+	}
+}
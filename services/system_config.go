@@ -0,0 +1,109 @@
+package services
+
+import (
+	"log"
+
+	"backend/models"
+	"backend/repositories"
+	"backend/types"
+	"backend/utils"
+)
+
+// maskConfig 返回掩码后的配置副本，非敏感配置原样返回
+func maskConfig(config *models.SystemConfig) *models.SystemConfig {
+	definition, ok := models.GetConfigDefinition(config.Key)
+	if !ok || !definition.Sensitive {
+		return config
+	}
+	masked := *config
+	masked.Value = definition.Mask(config.Value)
+	return &masked
+}
+
+// applyConfigChanges 配置保存后清空缓存，并重新加载依赖配置的存储、上传限制与维护状态
+func applyConfigChanges(changes map[string]string) {
+	InvalidateConfigCache()
+	if touchesStorage(changes) {
+		LoadStorage()
+	}
+	if touchesUploadPolicy(changes) {
+		LoadUploadPolicy()
+	}
+	if touchesMaintenance(changes) {
+		ReloadMaintenance()
+	}
+}
+
+// GetConfigDefinitions 获取全部已登记的配置项定义
+func (ss *SystemService) GetConfigDefinitions() []models.ConfigDefinition {
+	return models.GetConfigDefinitions()
+}
+
+// ListConfigHistory 获取配置的变更历史，敏感配置的值已掩码
+func (ss *SystemService) ListConfigHistory(key string, req *types.PageRequest) ([]*models.SystemConfigHistory, int64, error) {
+	definition, ok := models.GetConfigDefinition(key)
+	if !ok {
+		return nil, 0, &ServiceError{Code: 404, Message: "配置不存在"}
+	}
+
+	histories, total, err := ss.systemConfigRepo.ListHistory(key, req)
+	if err != nil {
+		return nil, 0, err
+	}
+	if definition.Sensitive {
+		for _, history := range histories {
+			history.NewValue = definition.Mask(history.NewValue)
+			if history.OldValue != nil {
+				masked := definition.Mask(*history.OldValue)
+				history.OldValue = &masked
+			}
+		}
+	}
+	return histories, total, nil
+}
+
+// RestoreConfigVersion 将配置恢复为指定历史版本的值，恢复本身记为一个新版本
+func (ss *SystemService) RestoreConfigVersion(key string, version int, changedBy uint) error {
+	if _, ok := models.GetConfigDefinition(key); !ok {
+		return &ServiceError{Code: 404, Message: "配置不存在"}
+	}
+	history, err := ss.systemConfigRepo.GetHistoryVersion(key, version)
+	if err != nil {
+		return &ServiceError{Code: 404, Message: err.Error()}
+	}
+
+	value := history.NewValue
+	if models.IsSensitiveConfig(key) {
+		if value, err = utils.DecryptSecret(value); err != nil {
+			return &ServiceError{Code: 422, Message: err.Error()}
+		}
+	}
+	return ss.UpdateConfigs(map[string]string{key: value}, changedBy)
+}
+
+// EncryptPlaintextSecrets 将以明文保存的敏感配置加密，用于升级前已存在的数据
+func EncryptPlaintextSecrets() {
+	repo := repositories.NewSystemConfigRepository()
+	configs, err := repo.GetAllConfigs()
+	if err != nil {
+		log.Printf("Failed to load configs for encryption: %v", err)
+		return
+	}
+
+	for key, config := range configs {
+		if !models.IsSensitiveConfig(key) || config.Value == "" || utils.IsEncryptedSecret(config.Value) {
+			continue
+		}
+		encrypted, err := utils.EncryptSecret(config.Value)
+		if err != nil {
+			log.Printf("Failed to encrypt config %s: %v", key, err)
+			continue
+		}
+		config.Value = encrypted
+		if err := repo.Update(config); err != nil {
+			log.Printf("Failed to save encrypted config %s: %v", key, err)
+			continue
+		}
+		log.Printf("Encrypted plaintext config %s", key)
+	}
+}
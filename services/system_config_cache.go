@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"backend/database"
+	"backend/models"
+	"backend/repositories"
+	"backend/utils"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// configCacheChannel 系统配置变更通知频道，各实例收到后清空本地缓存
+	configCacheChannel = "system:configs"
+	// configCacheRefreshPeriod 定时清空缓存的间隔，Redis 不可用或漏收通知时兜底
+	configCacheRefreshPeriod = time.Minute
+)
+
+// configCache 系统配置的进程内缓存，敏感配置已解密；为 nil 时下次读取从数据库加载。
+// generation 每次清空时递增，开始于清空之前的加载结果可能已过期，不写入缓存
+var configCache = struct {
+	sync.RWMutex
+	values     map[string]*models.SystemConfig
+	generation uint64
+}{}
+
+// loadConfigs 获取全部系统配置（敏感配置为明文），返回的 map 为共享缓存，调用方不得修改
+func loadConfigs() (map[string]*models.SystemConfig, error) {
+	configCache.RLock()
+	values := configCache.values
+	generation := configCache.generation
+	configCache.RUnlock()
+	if values != nil {
+		return values, nil
+	}
+
+	stored, err := repositories.NewSystemConfigRepository().GetAllConfigs()
+	if err != nil {
+		return nil, err
+	}
+	values = make(map[string]*models.SystemConfig, len(stored))
+	for key, config := range stored {
+		if models.IsSensitiveConfig(key) {
+			plain, err := utils.DecryptSecret(config.Value)
+			if err != nil {
+				log.Printf("Failed to decrypt config %s: %v", key, err)
+				plain = ""
+			}
+			decrypted := *config
+			decrypted.Value = plain
+			config = &decrypted
+		}
+		values[key] = config
+	}
+
+	configCache.Lock()
+	if configCache.generation == generation {
+		configCache.values = values
+	}
+	configCache.Unlock()
+	return values, nil
+}
+
+// invalidateLocalConfigCache 清空本实例的配置缓存
+func invalidateLocalConfigCache() {
+	configCache.Lock()
+	configCache.values = nil
+	configCache.generation++
+	configCache.Unlock()
+}
+
+// InvalidateConfigCache 清空本实例的配置缓存，并通过 Redis 通知其他实例
+func InvalidateConfigCache() {
+	invalidateLocalConfigCache()
+	if redisClient := database.GetRedis(); redisClient != nil {
+		if err := redisClient.Publish(context.Background(), configCacheChannel, time.Now().Unix()).Err(); err != nil {
+			log.Printf("Failed to publish config change: %v", err)
+		}
+	}
+}
+
+// StartConfigSync 在后台订阅配置变更通知，收到通知或定时清空缓存，并重新加载依赖配置的存储与上传限制
+func StartConfigSync() {
+	var messages <-chan *redis.Message
+	if redisClient := database.GetRedis(); redisClient != nil {
+		messages = redisClient.Subscribe(context.Background(), configCacheChannel).Channel()
+	}
+
+	go func() {
+		ticker := time.NewTicker(configCacheRefreshPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-messages:
+			case <-ticker.C:
+			}
+			invalidateLocalConfigCache()
+			LoadStorage()
+			LoadUploadPolicy()
+		}
+	}()
+}
+
+// ConfigValue 获取配置值（敏感配置为明文），未设置或读取失败时返回登记的默认值
+func ConfigValue(key string) string {
+	values, err := loadConfigs()
+	if err == nil {
+		if config, ok := values[key]; ok {
+			return config.Value
+		}
+	} else {
+		log.Printf("Failed to load configs: %v", err)
+	}
+	definition, _ := models.GetConfigDefinition(key)
+	return definition.Default
+}
+
+// ConfigInt 获取整数配置，值无效时返回登记的默认值
+func ConfigInt(key string) int {
+	if value, err := strconv.Atoi(ConfigValue(key)); err == nil {
+		return value
+	}
+	definition, _ := models.GetConfigDefinition(key)
+	value, _ := strconv.Atoi(definition.Default)
+	return value
+}
+
+// ConfigBool 获取布尔配置
+func ConfigBool(key string) bool {
+	value := ConfigValue(key)
+	return value == "true" || value == "1"
+}
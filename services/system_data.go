@@ -0,0 +1,743 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/repositories"
+)
+
+// 数据导出格式
+const (
+	DataFormatJSONL = "jsonl" // 单个 JSON Lines 文件：首行为清单，其后每行一条记录，末行为结束标记
+	DataFormatCSV   = "csv"   // zip 压缩包：每张表一个 CSV 文件，附 manifest.json 清单
+)
+
+const (
+	dataExportFormat  = "backend-data-export"
+	dataExportVersion = 2 // 2: JSON Lines 末行为带各表行数的结束标记
+	dataManifestName  = "manifest.json"
+	dataCSVNull       = `\N` // CSV 中表示 NULL 的值；内容为 \N、\\N…的文本写出时多加一个反斜杠
+)
+
+// dataImportProtectedTables 管理员账号与权限相关的表，不允许通过数据导入写入，避免覆盖密码或提升权限
+var dataImportProtectedTables = map[string]bool{
+	"admins":           true,
+	"admin_roles":      true,
+	"roles":            true,
+	"role_permissions": true,
+	"permissions":      true,
+}
+
+// DataExportTable 导出文件中一张表的定义
+type DataExportTable struct {
+	Name          string                     `json:"name"`
+	SchemaVersion string                     `json:"schema_version"` // 按列名与列类型计算，导入时须与目标库一致
+	Columns       []repositories.TableColumn `json:"columns"`
+	Rows          int64                      `json:"rows,omitempty"` // 仅 CSV 清单记录
+}
+
+// DataExportManifest 导出清单：JSON Lines 文件的首行，CSV 压缩包中的 manifest.json
+type DataExportManifest struct {
+	Format     string             `json:"format"`
+	Version    int                `json:"version"`
+	ExportedAt time.Time          `json:"exported_at"`
+	Tables     []*DataExportTable `json:"tables"`
+}
+
+// dataExportRow JSON Lines 中的一条记录；末行为结束标记，End 为 true，Rows 为各表行数
+type dataExportRow struct {
+	Table string                 `json:"table,omitempty"`
+	Data  map[string]interface{} `json:"data,omitempty"`
+	End   bool                   `json:"end,omitempty"`
+	Rows  map[string]int64       `json:"rows,omitempty"`
+}
+
+// DataImportResult 导入结果，按表汇总导入、跳过与失败的行数
+type DataImportResult struct {
+	Format     string                            `json:"format"`
+	Mode       string                            `json:"mode"`
+	Tables     []*repositories.ImportTableResult `json:"tables"`
+	Imported   int64                             `json:"imported"`
+	Skipped    int64                             `json:"skipped"`
+	Errors     int64                             `json:"errors"`
+	DurationMs int64                             `json:"duration_ms"`
+}
+
+// tableSchemaVersion 由列名与列类型计算表结构版本，与列顺序无关
+func tableSchemaVersion(columns []repositories.TableColumn) string {
+	lines := make([]string, len(columns))
+	for i, column := range columns {
+		lines[i] = column.Name + " " + column.Type
+	}
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// GetDataTables 可导出的数据表及其当前结构版本，可用于比对两个环境的表结构
+func (ss *SystemService) GetDataTables() ([]*DataExportTable, error) {
+	tables, err := ss.resolveBackupTables(nil)
+	if err != nil {
+		return nil, err
+	}
+	return ss.loadDataTables(tables)
+}
+
+// loadDataTables 读取各表的当前列定义与结构版本
+func (ss *SystemService) loadDataTables(tables []string) ([]*DataExportTable, error) {
+	result := make([]*DataExportTable, 0, len(tables))
+	for _, table := range tables {
+		columns, err := ss.backupRepo.TableSchema(table)
+		if err != nil {
+			return nil, fmt.Errorf("读取表 %s 结构失败: %w", table, err)
+		}
+		result = append(result, &DataExportTable{
+			Name:          table,
+			SchemaVersion: tableSchemaVersion(columns),
+			Columns:       columns,
+		})
+	}
+	return result, nil
+}
+
+// PrepareDataExport 校验导出格式与数据表（为空时导出全部表）并生成清单，在写出任何数据之前调用
+func (ss *SystemService) PrepareDataExport(tables []string, format string) (*DataExportManifest, error) {
+	if format != DataFormatJSONL && format != DataFormatCSV {
+		return nil, &ServiceError{Code: 400, Message: "导出格式只支持 jsonl 或 csv"}
+	}
+	tables, err := ss.resolveBackupTables(tables)
+	if err != nil {
+		return nil, err
+	}
+	dataTables, err := ss.loadDataTables(tables)
+	if err != nil {
+		return nil, err
+	}
+	return &DataExportManifest{
+		Format:     dataExportFormat,
+		Version:    dataExportVersion,
+		ExportedAt: time.Now(),
+		Tables:     dataTables,
+	}, nil
+}
+
+// DataExportFileName 导出文件名；CSV 格式为 zip 压缩包
+func DataExportFileName(manifest *DataExportManifest, format string) string {
+	ext := "jsonl"
+	if format == DataFormatCSV {
+		ext = "zip"
+	}
+	return fmt.Sprintf("data_export_%s.%s", manifest.ExportedAt.Format("20060102_150405"), ext)
+}
+
+// ExportData 在一致性快照中读取清单中的各表并写出
+func (ss *SystemService) ExportData(manifest *DataExportManifest, format string, w io.Writer) error {
+	names := make([]string, len(manifest.Tables))
+	for i, table := range manifest.Tables {
+		names[i] = table.Name
+	}
+	if format == DataFormatCSV {
+		return ss.exportCSV(manifest, names, w)
+	}
+	return ss.exportJSONL(manifest, names, w)
+}
+
+// exportJSONL 写出 JSON Lines：首行为清单（不含行数），其后每行一条记录，末行为带各表行数的结束标记
+func (ss *SystemService) exportJSONL(manifest *DataExportManifest, names []string, w io.Writer) error {
+	buf := bufio.NewWriterSize(w, 64*1024)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(manifest); err != nil {
+		return err
+	}
+
+	var table string
+	var columns []repositories.BackupColumn
+	rows := make(map[string]int64, len(names))
+	err := ss.backupRepo.Dump(names,
+		func(name string, cols []repositories.BackupColumn) error {
+			table, columns = name, cols
+			rows[name] = 0
+			return nil
+		},
+		func(values []interface{}) error {
+			data := make(map[string]interface{}, len(columns))
+			for i, column := range columns {
+				data[column.Name] = values[i]
+			}
+			rows[table]++
+			return enc.Encode(dataExportRow{Table: table, Data: data})
+		})
+	if err != nil {
+		return err
+	}
+	if err := enc.Encode(dataExportRow{End: true, Rows: rows}); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// exportCSV 写出 zip 压缩包：每张表一个 CSV 文件（首行为列名），最后写入带行数的清单
+func (ss *SystemService) exportCSV(manifest *DataExportManifest, names []string, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	var current *DataExportTable
+	var writer *csv.Writer
+	var columns []repositories.BackupColumn
+
+	err := ss.backupRepo.Dump(names,
+		func(name string, cols []repositories.BackupColumn) error {
+			if writer != nil {
+				if writer.Flush(); writer.Error() != nil {
+					return writer.Error()
+				}
+			}
+			for _, table := range manifest.Tables {
+				if table.Name == name {
+					current = table
+				}
+			}
+			entry, err := zw.Create(name + ".csv")
+			if err != nil {
+				return err
+			}
+			writer = csv.NewWriter(entry)
+			columns = cols
+			header := make([]string, len(cols))
+			for i, column := range cols {
+				header[i] = column.Name
+			}
+			return writer.Write(header)
+		},
+		func(values []interface{}) error {
+			record := make([]string, len(values))
+			for i, value := range values {
+				record[i] = formatDataCSVValue(value, columns[i].Binary)
+			}
+			current.Rows++
+			return writer.Write(record)
+		})
+	if err != nil {
+		return err
+	}
+	if writer != nil {
+		if writer.Flush(); writer.Error() != nil {
+			return writer.Error()
+		}
+	}
+
+	entry, err := zw.Create(dataManifestName)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(entry)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// formatDataCSVValue 把导出的值转为 CSV 单元格，NULL 写为 \N，二进制写为 base64
+func formatDataCSVValue(value interface{}, binary bool) string {
+	switch v := value.(type) {
+	case nil:
+		return dataCSVNull
+	case []byte:
+		if binary {
+			return base64.StdEncoding.EncodeToString(v)
+		}
+		return escapeDataCSVText(string(v))
+	case string:
+		return escapeDataCSVText(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// isDataCSVNullLike 文本是否形如 \N、\\N…（一个或多个反斜杠后跟 N）
+func isDataCSVNullLike(text string) bool {
+	return len(text) >= 2 && text[len(text)-1] == 'N' && strings.Trim(text[:len(text)-1], `\`) == ""
+}
+
+// escapeDataCSVText 形如 \N 的文本多加一个反斜杠，与表示 NULL 的 \N 区分
+func escapeDataCSVText(text string) string {
+	if isDataCSVNullLike(text) {
+		return `\` + text
+	}
+	return text
+}
+
+// unescapeDataCSVText 还原 escapeDataCSVText 转义的文本
+func unescapeDataCSVText(text string) string {
+	if len(text) > len(dataCSVNull) && isDataCSVNullLike(text) {
+		return text[1:]
+	}
+	return text
+}
+
+// ImportData 导入 ExportData 生成的文件。format 为空时按文件扩展名判断；
+// 导入前校验每张表的结构版本与当前库一致，全部数据在一个事务中写入，冲突按 mode 处理
+func (ss *SystemService) ImportData(file io.ReaderAt, size int64, filename, format, mode string) (*DataImportResult, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".jsonl":
+			format = DataFormatJSONL
+		case ".zip":
+			format = DataFormatCSV
+		}
+	}
+	if format != DataFormatJSONL && format != DataFormatCSV {
+		return nil, &ServiceError{Code: 400, Message: "导入文件只支持 jsonl 或 csv 导出的 zip 压缩包"}
+	}
+	if mode == "" {
+		mode = repositories.ImportConflictSkip
+	}
+	if mode != repositories.ImportConflictSkip && mode != repositories.ImportConflictOverride && mode != repositories.ImportConflictFail {
+		return nil, &ServiceError{Code: 400, Message: "冲突处理方式只支持 skip、override 或 fail"}
+	}
+
+	if !backupMu.TryLock() {
+		return nil, errBackupBusy
+	}
+	defer backupMu.Unlock()
+
+	startedAt := time.Now()
+	var results []*repositories.ImportTableResult
+	var err error
+	if format == DataFormatCSV {
+		results, err = ss.importCSV(file, size, mode)
+	} else {
+		results, err = ss.importJSONL(file, size, mode)
+	}
+	if err != nil {
+		var conflict *repositories.ImportConflictError
+		if errors.As(err, &conflict) {
+			return nil, &ServiceError{Code: 409, Message: conflict.Error() + "，导入已取消"}
+		}
+		return nil, err
+	}
+
+	result := &DataImportResult{
+		Format:     format,
+		Mode:       mode,
+		Tables:     results,
+		DurationMs: time.Since(startedAt).Milliseconds(),
+	}
+	for _, table := range results {
+		result.Imported += table.Imported
+		result.Skipped += table.Skipped
+		result.Errors += table.Errors
+	}
+
+	if result.Imported > 0 {
+		// 导入的数据可能涉及投放缓存与存储、上传、维护配置
+		InvalidateAdCache()
+		InvalidateConfigCache()
+		LoadStorage()
+		LoadUploadPolicy()
+		ReloadMaintenance()
+	}
+	return result, nil
+}
+
+// importTable 导入文件中一张表的定义及其在当前库中的主键
+type importTable struct {
+	columns     map[string]repositories.TableColumn
+	primaryKeys []string
+}
+
+// checkDataManifest 校验导入清单的格式版本及各表结构版本，返回各表定义；
+// 任一表结构不一致时拒绝导入，并列出差异
+func (ss *SystemService) checkDataManifest(manifest *DataExportManifest) (map[string]*importTable, error) {
+	if manifest.Format != dataExportFormat {
+		return nil, &ServiceError{Code: 422, Message: "不是有效的数据导出文件"}
+	}
+	if manifest.Version > dataExportVersion {
+		return nil, &ServiceError{Code: 422, Message: fmt.Sprintf("不支持的导出格式版本: %d", manifest.Version)}
+	}
+	if len(manifest.Tables) == 0 {
+		return nil, &ServiceError{Code: 422, Message: "导入文件不包含任何数据表"}
+	}
+
+	names := make([]string, len(manifest.Tables))
+	for i, table := range manifest.Tables {
+		names[i] = table.Name
+	}
+	if _, err := ss.resolveBackupTables(names); err != nil {
+		return nil, err
+	}
+	var protected []string
+	for _, name := range names {
+		if dataImportProtectedTables[name] {
+			protected = append(protected, name)
+		}
+	}
+	if len(protected) > 0 {
+		return nil, &ServiceError{Code: 422, Message: "不允许导入管理员与权限相关的数据表: " + strings.Join(protected, ", ") + "，请导出时排除这些表"}
+	}
+
+	tables := make(map[string]*importTable, len(manifest.Tables))
+	var mismatches []string
+	for _, table := range manifest.Tables {
+		current, err := ss.backupRepo.TableSchema(table.Name)
+		if err != nil {
+			return nil, fmt.Errorf("读取表 %s 结构失败: %w", table.Name, err)
+		}
+		if table.SchemaVersion != tableSchemaVersion(current) || tableSchemaVersion(table.Columns) != table.SchemaVersion {
+			mismatches = append(mismatches, fmt.Sprintf("%s（%s）", table.Name, describeSchemaDiff(table.Columns, current)))
+			continue
+		}
+
+		info := &importTable{columns: make(map[string]repositories.TableColumn, len(table.Columns))}
+		for _, column := range table.Columns {
+			info.columns[column.Name] = column
+		}
+		for _, column := range current {
+			if column.PrimaryKey {
+				info.primaryKeys = append(info.primaryKeys, column.Name)
+			}
+		}
+		tables[table.Name] = info
+	}
+	if len(mismatches) > 0 {
+		return nil, &ServiceError{Code: 422, Message: "数据表结构版本与当前库不一致: " + strings.Join(mismatches, "；")}
+	}
+	return tables, nil
+}
+
+// describeSchemaDiff 描述导入文件与当前库的列差异
+func describeSchemaDiff(imported, current []repositories.TableColumn) string {
+	currentTypes := make(map[string]string, len(current))
+	for _, column := range current {
+		currentTypes[column.Name] = column.Type
+	}
+	var diffs []string
+	seen := make(map[string]bool, len(imported))
+	for _, column := range imported {
+		seen[column.Name] = true
+		currentType, ok := currentTypes[column.Name]
+		if !ok {
+			diffs = append(diffs, "当前库缺少列 "+column.Name)
+		} else if currentType != column.Type {
+			diffs = append(diffs, fmt.Sprintf("列 %s 类型 %s，当前库为 %s", column.Name, column.Type, currentType))
+		}
+	}
+	for _, column := range current {
+		if !seen[column.Name] {
+			diffs = append(diffs, "导入文件缺少列 "+column.Name)
+		}
+	}
+	if len(diffs) == 0 {
+		return "结构版本不匹配"
+	}
+	return strings.Join(diffs, "，")
+}
+
+// convertImportValue 还原导入的值：数字转为字符串交由数据库转换，二进制列从 base64 还原
+func convertImportValue(value interface{}, column repositories.TableColumn) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		return v.String(), nil
+	case string:
+		if column.Binary {
+			data, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, fmt.Errorf("列 %s 的二进制数据无效", column.Name)
+			}
+			return data, nil
+		}
+		return v, nil
+	case nil, bool:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("列 %s 的值类型无效", column.Name)
+	}
+}
+
+// importJSONL 导入 JSON Lines 文件，清单中的列顺序即写入的列顺序
+func (ss *SystemService) importJSONL(file io.ReaderAt, size int64, mode string) ([]*repositories.ImportTableResult, error) {
+	reader := bufio.NewReader(io.NewSectionReader(file, 0, size))
+	line, err := reader.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	var manifest DataExportManifest
+	if err := json.Unmarshal(line, &manifest); err != nil {
+		return nil, &ServiceError{Code: 422, Message: "不是有效的数据导出文件"}
+	}
+	tables, err := ss.checkDataManifest(&manifest)
+	if err != nil {
+		return nil, err
+	}
+	order := make(map[string][]repositories.TableColumn, len(manifest.Tables))
+	for _, table := range manifest.Tables {
+		order[table.Name] = table.Columns
+	}
+
+	return ss.backupRepo.Import(mode, func(importer *repositories.DataImporter) error {
+		for _, table := range manifest.Tables {
+			if err := importer.Begin(table.Name, columnNames(table.Columns), tables[table.Name].primaryKeys); err != nil {
+				return err
+			}
+		}
+
+		current := ""
+		var end *dataExportRow
+		var total int64
+		counted := make(map[string]int64, len(manifest.Tables))
+		for lineNo := 2; ; lineNo++ {
+			line, err := reader.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				return err
+			}
+			if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+				if end != nil {
+					return &ServiceError{Code: 422, Message: fmt.Sprintf("第 %d 行位于结束标记之后，文件无效", lineNo)}
+				}
+				row := decodeJSONLRow(trimmed)
+				if row != nil && row.End {
+					end = row
+				} else {
+					total++
+					if row != nil {
+						counted[row.Table]++
+					}
+					if err := importJSONLRow(importer, row, lineNo, &current, tables, order); err != nil {
+						return err
+					}
+				}
+			}
+			if err == io.EOF {
+				if manifest.Version < 2 {
+					return nil // 旧版本导出文件没有结束标记
+				}
+				return checkJSONLEnd(end, total, counted)
+			}
+		}
+	})
+}
+
+// decodeJSONLRow 解析一行记录，无法解析时返回 nil
+func decodeJSONLRow(line []byte) *dataExportRow {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var row dataExportRow
+	if err := dec.Decode(&row); err != nil {
+		return nil
+	}
+	return &row
+}
+
+// checkJSONLEnd 校验结束标记中的各表行数，缺少结束标记或行数不符说明文件被截断或损坏，整体拒绝导入
+func checkJSONLEnd(end *dataExportRow, total int64, counted map[string]int64) error {
+	if end == nil {
+		return &ServiceError{Code: 422, Message: "导入文件缺少结束标记，文件可能不完整"}
+	}
+	var expected int64
+	for _, rows := range end.Rows {
+		expected += rows
+	}
+	if total != expected {
+		return &ServiceError{Code: 422, Message: fmt.Sprintf("导入文件共 %d 行记录，结束标记为 %d 行，文件可能不完整", total, expected)}
+	}
+	for table, rows := range counted {
+		if rows > end.Rows[table] {
+			return &ServiceError{Code: 422, Message: fmt.Sprintf("数据表 %s 共 %d 行记录，结束标记为 %d 行", table, rows, end.Rows[table])}
+		}
+	}
+	return nil
+}
+
+// importJSONLRow 写入一行记录；row 为 nil 表示无法解析，计为失败行
+func importJSONLRow(importer *repositories.DataImporter, row *dataExportRow, lineNo int, current *string, tables map[string]*importTable, order map[string][]repositories.TableColumn) error {
+	if row == nil {
+		importer.Fail(lineNo, "无法解析的记录")
+		return nil
+	}
+	table, ok := tables[row.Table]
+	if !ok {
+		return &ServiceError{Code: 422, Message: fmt.Sprintf("第 %d 行的数据表 %s 不在导入清单中", lineNo, row.Table)}
+	}
+	if row.Table != *current {
+		if err := importer.Begin(row.Table, columnNames(order[row.Table]), table.primaryKeys); err != nil {
+			return err
+		}
+		*current = row.Table
+	}
+
+	columns := order[row.Table]
+	if len(row.Data) != len(columns) {
+		importer.Fail(lineNo, "记录的列与清单不一致")
+		return nil
+	}
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		raw, ok := row.Data[column.Name]
+		if !ok {
+			importer.Fail(lineNo, "记录缺少列 "+column.Name)
+			return nil
+		}
+		value, err := convertImportValue(raw, column)
+		if err != nil {
+			importer.Fail(lineNo, err.Error())
+			return nil
+		}
+		values[i] = value
+	}
+	return importer.Insert(lineNo, values)
+}
+
+// importCSV 导入 CSV 格式的 zip 压缩包，按清单顺序逐表读取
+func (ss *SystemService) importCSV(file io.ReaderAt, size int64, mode string) ([]*repositories.ImportTableResult, error) {
+	zr, err := zip.NewReader(file, size)
+	if err != nil {
+		return nil, &ServiceError{Code: 422, Message: "不是有效的 zip 压缩包"}
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	manifestFile, ok := files[dataManifestName]
+	if !ok {
+		return nil, &ServiceError{Code: 422, Message: "压缩包中缺少 " + dataManifestName}
+	}
+	var manifest DataExportManifest
+	if err := readZipJSON(manifestFile, &manifest); err != nil {
+		return nil, &ServiceError{Code: 422, Message: "不是有效的数据导出文件"}
+	}
+	tables, err := ss.checkDataManifest(&manifest)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range manifest.Tables {
+		if _, ok := files[table.Name+".csv"]; !ok {
+			return nil, &ServiceError{Code: 422, Message: "压缩包中缺少 " + table.Name + ".csv"}
+		}
+	}
+
+	return ss.backupRepo.Import(mode, func(importer *repositories.DataImporter) error {
+		for _, table := range manifest.Tables {
+			if err := importCSVTable(importer, files[table.Name+".csv"], table.Name, tables[table.Name], manifest.Version >= 2); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// readZipJSON 读取压缩包中的 JSON 文件
+func readZipJSON(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return json.NewDecoder(rc).Decode(v)
+}
+
+// importCSVTable 导入一张表的 CSV 文件，首行列名须与清单一致（顺序可以不同）；
+// escaped 为 false 时是旧版本导出的文件，形如 \N 的文本未转义
+func importCSVTable(importer *repositories.DataImporter, f *zip.File, name string, table *importTable, escaped bool) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	reader := csv.NewReader(rc)
+	header, err := reader.Read()
+	if err != nil {
+		return &ServiceError{Code: 422, Message: fmt.Sprintf("%s.csv 缺少表头", name)}
+	}
+	columns := make([]repositories.TableColumn, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, columnName := range header {
+		column, ok := table.columns[columnName]
+		if !ok || seen[columnName] {
+			return &ServiceError{Code: 422, Message: fmt.Sprintf("%s.csv 的列 %s 与清单不一致", name, columnName)}
+		}
+		seen[columnName] = true
+		columns[i] = column
+	}
+	if len(columns) != len(table.columns) {
+		return &ServiceError{Code: 422, Message: fmt.Sprintf("%s.csv 的列与清单不一致", name)}
+	}
+	if err := importer.Begin(name, header, table.primaryKeys); err != nil {
+		return err
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+				importer.Fail(parseErr.StartLine, "列数与表头不一致")
+				continue
+			}
+			return &ServiceError{Code: 422, Message: fmt.Sprintf("%s.csv 解析失败: %v", name, err)}
+		}
+		line, _ := reader.FieldPos(0)
+
+		values := make([]interface{}, len(record))
+		valid := true
+		for i, cell := range record {
+			if cell == dataCSVNull {
+				values[i] = nil
+				continue
+			}
+			if escaped {
+				cell = unescapeDataCSVText(cell)
+			}
+			value, err := convertImportValue(cell, columns[i])
+			if err != nil {
+				importer.Fail(line, err.Error())
+				valid = false
+				break
+			}
+			values[i] = value
+		}
+		if !valid {
+			continue
+		}
+		if err := importer.Insert(line, values); err != nil {
+			return err
+		}
+	}
+}
+
+// columnNames 列名列表
+func columnNames(columns []repositories.TableColumn) []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+	return names
+}
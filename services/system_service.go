@@ -1,15 +1,11 @@
 package services
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"path/filepath"
 	"runtime"
-	"time"
 
 	"backend/models"
 	"backend/repositories"
+	"backend/utils"
 )
 
 // SystemService 系统服务
@@ -21,6 +17,7 @@ type SystemService struct {
 	transactionRepo  *repositories.TransactionRepository
 	couponRepo       *repositories.CouponRepository
 	authCodeRepo     *repositories.AuthCodeRepository
+	backupRepo       *repositories.BackupRepository
 }
 
 // NewSystemService 创建系统服务
@@ -33,42 +30,96 @@ func NewSystemService() *SystemService {
 		transactionRepo:  repositories.NewTransactionRepository(),
 		couponRepo:       repositories.NewCouponRepository(),
 		authCodeRepo:     repositories.NewAuthCodeRepository(),
+		backupRepo:       repositories.NewBackupRepository(),
 	}
 }
 
-// GetAllConfigs 获取所有系统配置
+// GetAllConfigs 获取所有系统配置（含尚未保存的登记配置的默认值），敏感配置已掩码
 func (ss *SystemService) GetAllConfigs() (map[string]*models.SystemConfig, error) {
-	return ss.systemConfigRepo.GetAllConfigs()
-}
+	stored, err := ss.systemConfigRepo.GetAllConfigs()
+	if err != nil {
+		return nil, err
+	}
 
-// GetConfigByKey 根据键获取配置
-func (ss *SystemService) GetConfigByKey(key string) (*models.SystemConfig, error) {
-	return ss.systemConfigRepo.GetByKey(key)
+	configs := make(map[string]*models.SystemConfig, len(stored))
+	for _, definition := range models.GetConfigDefinitions() {
+		configs[definition.Key] = &models.SystemConfig{
+			Key:         definition.Key,
+			Value:       definition.Default,
+			Description: definition.Description,
+		}
+	}
+	for key, config := range stored {
+		configs[key] = maskConfig(config)
+	}
+	return configs, nil
 }
 
-// UpdateConfigs 批量更新配置
-func (ss *SystemService) UpdateConfigs(configs map[string]string) error {
-	return ss.systemConfigRepo.UpdateConfigs(configs)
+// GetConfigByKey 根据键获取配置，敏感配置已掩码
+func (ss *SystemService) GetConfigByKey(key string) (*models.SystemConfig, error) {
+	config, err := ss.systemConfigRepo.GetByKey(key)
+	if err != nil {
+		definition, ok := models.GetConfigDefinition(key)
+		if !ok {
+			return nil, err
+		}
+		return &models.SystemConfig{Key: key, Value: definition.Default, Description: definition.Description}, nil
+	}
+	return maskConfig(config), nil
 }
 
-// UpdateConfig 更新单个配置
-func (ss *SystemService) UpdateConfig(key, value, description string) error {
-	config, err := ss.systemConfigRepo.GetByKey(key)
+// UpdateConfigs 批量更新配置：只接受已登记的配置键，按定义校验，敏感配置加密保存并记录变更历史
+func (ss *SystemService) UpdateConfigs(configs map[string]string, changedBy uint) error {
+	current, err := loadConfigs()
 	if err != nil {
-		// 如果配置不存在，创建新的
-		config = &models.SystemConfig{
-			Key:         key,
-			Value:       value,
-			Description: description,
+		return err
+	}
+
+	changes := make(map[string]string, len(configs))
+	for key, value := range configs {
+		if models.IsSensitiveConfig(key) {
+			// 掩码或与当前值相同表示不修改，避免重复加密产生无意义的历史版本
+			if config, ok := current[key]; value == models.ConfigMaskedValue || ok && config.Value == value {
+				continue
+			}
+		}
+		if err := ss.systemConfigRepo.ValidateConfig(key, value); err != nil {
+			return &ServiceError{Code: 400, Message: err.Error()}
 		}
-		return ss.systemConfigRepo.Create(config)
+		changes[key] = value
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	if err := checkStorageConfig(changes); err != nil {
+		return err
 	}
 
-	config.Value = value
+	stored := make(map[string]string, len(changes))
+	for key, value := range changes {
+		if models.IsSensitiveConfig(key) {
+			if value, err = utils.EncryptSecret(value); err != nil {
+				return err
+			}
+		}
+		stored[key] = value
+	}
+	if err := ss.systemConfigRepo.UpdateConfigs(stored, changedBy); err != nil {
+		return err
+	}
+	applyConfigChanges(changes)
+	return nil
+}
+
+// UpdateConfig 更新单个配置
+func (ss *SystemService) UpdateConfig(key, value, description string, changedBy uint) error {
+	if err := ss.UpdateConfigs(map[string]string{key: value}, changedBy); err != nil {
+		return err
+	}
 	if description != "" {
-		config.Description = description
+		return ss.systemConfigRepo.UpdateDescription(key, description)
 	}
-	return ss.systemConfigRepo.Update(config)
+	return nil
 }
 
 // GetSystemStats 获取系统统计
@@ -125,101 +176,6 @@ func (ss *SystemService) GetDashboardData() (map[string]interface{}, error) {
 	}, nil
 }
 
-// CreateBackup 创建系统备份
-func (ss *SystemService) CreateBackup(description string, tables []string) (map[string]interface{}, error) {
-	timestamp := time.Now().Format("20060102_150405")
-	filename := fmt.Sprintf("backup_%s.sql", timestamp)
-	backupPath := filepath.Join("backups", filename)
-
-	// 确保备份目录存在
-	if err := os.MkdirAll("backups", 0755); err != nil {
-		return nil, err
-	}
-
-	// 这里应该实现实际的数据库备份逻辑
-	// 暂时创建一个简单的备份信息文件
-	backupInfo := map[string]interface{}{
-		"filename":    filename,
-		"path":        backupPath,
-		"description": description,
-		"tables":      tables,
-		"created_at":  time.Now(),
-		"size":        "0 MB", // 实际实现时应该计算真实大小
-	}
-
-	// 保存备份信息
-	backupInfoJSON, _ := json.Marshal(backupInfo)
-	if err := os.WriteFile(backupPath+".info", backupInfoJSON, 0644); err != nil {
-		return nil, err
-	}
-
-	return backupInfo, nil
-}
-
-// RestoreFromBackup 从备份恢复系统
-func (ss *SystemService) RestoreFromBackup(backupFile string) error {
-	backupPath := filepath.Join("backups", backupFile)
-	
-	// 检查备份文件是否存在
-	if _, err := os.Stat(backupPath); os.IsNotExist(err) {
-		return &ServiceError{
-			Code:    404,
-			Message: "备份文件不存在",
-		}
-	}
-
-	// 这里应该实现实际的数据库恢复逻辑
-	// 暂时返回成功
-	return nil
-}
-
-// GetBackupList 获取备份列表
-func (ss *SystemService) GetBackupList() ([]map[string]interface{}, error) {
-	backupsDir := "backups"
-	var backups []map[string]interface{}
-
-	// 确保备份目录存在
-	if err := os.MkdirAll(backupsDir, 0755); err != nil {
-		return backups, nil
-	}
-
-	files, err := os.ReadDir(backupsDir)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, file := range files {
-		if filepath.Ext(file.Name()) == ".info" {
-			infoPath := filepath.Join(backupsDir, file.Name())
-			data, err := os.ReadFile(infoPath)
-			if err != nil {
-				continue
-			}
-
-			var backupInfo map[string]interface{}
-			if err := json.Unmarshal(data, &backupInfo); err != nil {
-				continue
-			}
-
-			backups = append(backups, backupInfo)
-		}
-	}
-
-	return backups, nil
-}
-
-// DeleteBackup 删除备份
-func (ss *SystemService) DeleteBackup(filename string) error {
-	backupPath := filepath.Join("backups", filename)
-	infoPath := backupPath + ".info"
-
-	// 删除备份文件和信息文件
-	os.Remove(backupPath)
-	os.Remove(infoPath)
-
-	return nil
-}
-
 // GetSystemInfo 获取系统信息
 func (ss *SystemService) GetSystemInfo() (map[string]interface{}, error) {
 	configs, err := ss.systemConfigRepo.GetAllConfigs()
@@ -348,11 +304,14 @@ func (ss *SystemService) GetHealthStatus() (map[string]interface{}, error) {
 func (ss *SystemService) InitializeSystem() error {
 	// 初始化默认配置
 	defaultConfigs := models.GetDefaultConfigs()
+	changes := make(map[string]string, len(defaultConfigs))
 	for _, config := range defaultConfigs {
 		if err := ss.systemConfigRepo.CreateOrUpdate(&config); err != nil {
 			return err
 		}
+		changes[config.Key] = config.Value
 	}
+	applyConfigChanges(changes)
 
 	return nil
 }
@@ -372,51 +331,6 @@ func (ss *SystemService) ResetSystem(resetData, resetConfig bool) error {
 	return nil
 }
 
-// ExportData 导出数据
-func (ss *SystemService) ExportData(tables []string, format string) (map[string]interface{}, error) {
-	timestamp := time.Now().Format("20060102_150405")
-	filename := fmt.Sprintf("export_%s.%s", timestamp, format)
-	exportPath := filepath.Join("exports", filename)
-
-	// 确保导出目录存在
-	if err := os.MkdirAll("exports", 0755); err != nil {
-		return nil, err
-	}
-
-	// 这里应该实现实际的数据导出逻辑
-	exportInfo := map[string]interface{}{
-		"filename":   filename,
-		"path":       exportPath,
-		"format":     format,
-		"tables":     tables,
-		"created_at": time.Now(),
-		"size":       "0 MB",
-	}
-
-	return exportInfo, nil
-}
-
-// ImportData 导入数据
-func (ss *SystemService) ImportData(filePath, format string, override bool) (map[string]interface{}, error) {
-	// 检查文件是否存在
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return nil, &ServiceError{
-			Code:    404,
-			Message: "导入文件不存在",
-		}
-	}
-
-	// 这里应该实现实际的数据导入逻辑
-	result := map[string]interface{}{
-		"imported_records": 0,
-		"skipped_records":  0,
-		"error_records":    0,
-		"success":          true,
-	}
-
-	return result, nil
-}
-
 // 辅助方法
 
 func (ss *SystemService) getSystemRuntime() map[string]interface{} {
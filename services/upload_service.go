@@ -0,0 +1,71 @@
+package services
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"backend/configs"
+	"backend/models"
+	"backend/utils"
+)
+
+// uploadPolicyConfigKeys 影响上传限制的配置键
+var uploadPolicyConfigKeys = []string{
+	models.ConfigKeyMaxUploadSize,
+	models.ConfigKeyMaxVideoUploadSize,
+	models.ConfigKeyAllowedFileTypes,
+}
+
+// defaultMaxVideoUploadSize 视频断点续传默认大小上限
+const defaultMaxVideoUploadSize = 1 << 30
+
+// uploadPolicy 根据系统配置生成上传限制，缺失或无效的配置使用默认值
+func uploadPolicy(values map[string]*models.SystemConfig) utils.UploadPolicy {
+	policy := utils.UploadPolicy{
+		MaxSize:           configs.AppConfig.Upload.MaxFileSize,
+		MaxVideoSize:      defaultMaxVideoUploadSize,
+		AllowedExtensions: utils.ParseFileTypes(models.DefaultAllowedFileTypes),
+	}
+
+	sizeConfig := func(key string, target *int64) {
+		config, ok := values[key]
+		if !ok {
+			return
+		}
+		size, err := strconv.ParseInt(strings.TrimSpace(config.Value), 10, 64)
+		if err == nil && size > 0 {
+			*target = size
+		} else {
+			log.Printf("Invalid %s %q, using %d", key, config.Value, *target)
+		}
+	}
+	sizeConfig(models.ConfigKeyMaxUploadSize, &policy.MaxSize)
+	sizeConfig(models.ConfigKeyMaxVideoUploadSize, &policy.MaxVideoSize)
+	if config, ok := values[models.ConfigKeyAllowedFileTypes]; ok {
+		if types := utils.ParseFileTypes(config.Value); len(types) > 0 {
+			policy.AllowedExtensions = types
+		}
+	}
+	return policy
+}
+
+// LoadUploadPolicy 按系统配置加载上传大小与扩展名限制
+func LoadUploadPolicy() {
+	values, err := loadConfigs()
+	if err != nil {
+		log.Printf("Failed to load upload configs, using defaults: %v", err)
+		values = map[string]*models.SystemConfig{}
+	}
+	utils.SetUploadPolicy(uploadPolicy(values))
+}
+
+// touchesUploadPolicy 配置修改是否涉及上传限制
+func touchesUploadPolicy(changes map[string]string) bool {
+	for _, key := range uploadPolicyConfigKeys {
+		if _, ok := changes[key]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -100,9 +100,16 @@ type StatusRequest struct {
 
 // UploadResponse 上传响应
 type UploadResponse struct {
-	URL      string `json:"url"`
-	Filename string `json:"filename"`
-	Size     int64  `json:"size"`
+	URL       string `json:"url"`
+	Filename  string `json:"filename"`
+	Size      int64  `json:"size"`
+	MimeType  string `json:"mime_type"`           // 按文件内容检测的类型
+	Width     int    `json:"width,omitempty"`     // 图片宽度
+	Height    int    `json:"height,omitempty"`    // 图片高度
+	Thumbnail string `json:"thumbnail,omitempty"` // 缩略图地址
+	WebP      string `json:"webp,omitempty"`      // WebP 变体地址
+	Hash      string `json:"hash,omitempty"`      // 内容 SHA-256（十六进制）
+	AssetID   uint   `json:"asset_id,omitempty"`  // 素材库中的素材ID
 }
 
 // StatisticsResponse 统计响应
@@ -0,0 +1,562 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// 安装包平台
+const (
+	AppPlatformAndroid = "android"
+	AppPlatformIOS     = "ios"
+)
+
+// maxManifestSize 清单文件解压后的最大字节数，防止压缩炸弹
+const maxManifestSize = 8 << 20
+
+// ipaInfoPlistRegex IPA 中主应用的 Info.plist 路径
+var ipaInfoPlistRegex = regexp.MustCompile(`^Payload/[^/]+\.app/Info\.plist$`)
+
+// AppManifest 从安装包清单中解析出的应用信息
+type AppManifest struct {
+	Platform    string `json:"platform"`     // android / ios
+	PackageName string `json:"package_name"` // Android package 或 iOS CFBundleIdentifier
+	AppName     string `json:"app_name"`     // 应用名称（Android 仅支持字面量 label）
+	VersionName string `json:"version_name"` // versionName / CFBundleShortVersionString
+	VersionCode string `json:"version_code"` // versionCode / CFBundleVersion
+	MinOS       string `json:"min_os"`       // minSdkVersion / MinimumOSVersion
+	TargetSDK   string `json:"target_sdk"`   // targetSdkVersion（仅 Android）
+}
+
+// ParseAppPackage 离线解析 APK/IPA 安装包清单，根据包内文件自动识别平台
+func ParseAppPackage(r io.ReaderAt, size int64) (*AppManifest, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("安装包不是有效的 APK/IPA 文件")
+	}
+
+	for _, file := range archive.File {
+		if file.Name == "AndroidManifest.xml" {
+			data, err := readZipFile(file)
+			if err != nil {
+				return nil, err
+			}
+			return parseAndroidManifest(data)
+		}
+	}
+	for _, file := range archive.File {
+		if ipaInfoPlistRegex.MatchString(file.Name) {
+			data, err := readZipFile(file)
+			if err != nil {
+				return nil, err
+			}
+			return parseInfoPlist(data)
+		}
+	}
+	return nil, fmt.Errorf("安装包中未找到 AndroidManifest.xml 或 Info.plist")
+}
+
+// readZipFile 读取压缩包中的单个文件
+func readZipFile(file *zip.File) ([]byte, error) {
+	if file.UncompressedSize64 > maxManifestSize {
+		return nil, fmt.Errorf("清单文件过大: %s", file.Name)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("读取清单文件失败: %v", err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxManifestSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取清单文件失败: %v", err)
+	}
+	if len(data) > maxManifestSize {
+		return nil, fmt.Errorf("清单文件过大: %s", file.Name)
+	}
+	return data, nil
+}
+
+// Android 二进制 XML（AXML）块类型
+const (
+	axmlChunkStringPool   = 0x0001
+	axmlChunkXML          = 0x0003
+	axmlChunkResourceMap  = 0x0180
+	axmlChunkStartElement = 0x0102
+)
+
+// AXML 属性值类型
+const (
+	axmlTypeReference = 0x01
+	axmlTypeString    = 0x03
+	axmlTypeIntDec    = 0x10
+	axmlTypeIntHex    = 0x11
+	axmlTypeBoolean   = 0x12
+)
+
+// android 命名空间属性的资源ID，用于属性名被混淆时识别
+var axmlAttrResourceIDs = map[uint32]string{
+	0x01010001: "label",
+	0x0101020c: "minSdkVersion",
+	0x0101021b: "versionCode",
+	0x0101021c: "versionName",
+	0x01010270: "targetSdkVersion",
+}
+
+// axmlParser AXML 解析状态
+type axmlParser struct {
+	data        []byte
+	strings     []string
+	resourceIDs []uint32
+}
+
+// parseAndroidManifest 解析 APK 中的二进制 AndroidManifest.xml
+func parseAndroidManifest(data []byte) (*AppManifest, error) {
+	if len(data) < 8 || binary.LittleEndian.Uint16(data) != axmlChunkXML {
+		return nil, fmt.Errorf("AndroidManifest.xml 不是有效的二进制 XML")
+	}
+
+	p := &axmlParser{data: data}
+	manifest := &AppManifest{Platform: AppPlatformAndroid}
+
+	offset := int(binary.LittleEndian.Uint16(data[2:]))
+	for offset+8 <= len(data) {
+		chunkType := binary.LittleEndian.Uint16(data[offset:])
+		headerSize := int(binary.LittleEndian.Uint16(data[offset+2:]))
+		chunkSize := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		if chunkSize < 8 || headerSize > chunkSize || offset+chunkSize > len(data) {
+			return nil, fmt.Errorf("AndroidManifest.xml 数据损坏")
+		}
+		chunk := data[offset : offset+chunkSize]
+
+		switch chunkType {
+		case axmlChunkStringPool:
+			if err := p.readStringPool(chunk); err != nil {
+				return nil, err
+			}
+		case axmlChunkResourceMap:
+			for i := headerSize; i+4 <= len(chunk); i += 4 {
+				p.resourceIDs = append(p.resourceIDs, binary.LittleEndian.Uint32(chunk[i:]))
+			}
+		case axmlChunkStartElement:
+			name, attrs, err := p.readElement(chunk, headerSize)
+			if err != nil {
+				return nil, err
+			}
+			switch name {
+			case "manifest":
+				manifest.PackageName = attrs["package"]
+				manifest.VersionCode = attrs["versionCode"]
+				manifest.VersionName = attrs["versionName"]
+			case "uses-sdk":
+				manifest.MinOS = attrs["minSdkVersion"]
+				manifest.TargetSDK = attrs["targetSdkVersion"]
+			case "application":
+				manifest.AppName = attrs["label"]
+			}
+		}
+		offset += chunkSize
+	}
+
+	if manifest.PackageName == "" {
+		return nil, fmt.Errorf("AndroidManifest.xml 中缺少 package")
+	}
+	return manifest, nil
+}
+
+// readStringPool 读取字符串池（支持 UTF-8 与 UTF-16）
+func (p *axmlParser) readStringPool(chunk []byte) error {
+	if len(chunk) < 28 {
+		return fmt.Errorf("AndroidManifest.xml 字符串池损坏")
+	}
+	headerSize := int(binary.LittleEndian.Uint16(chunk[2:]))
+	count := int(binary.LittleEndian.Uint32(chunk[8:]))
+	isUTF8 := binary.LittleEndian.Uint32(chunk[16:])&(1<<8) != 0
+	stringsStart := int(binary.LittleEndian.Uint32(chunk[20:]))
+	if headerSize+count*4 > len(chunk) || stringsStart > len(chunk) {
+		return fmt.Errorf("AndroidManifest.xml 字符串池损坏")
+	}
+
+	p.strings = make([]string, count)
+	for i := 0; i < count; i++ {
+		pos := stringsStart + int(binary.LittleEndian.Uint32(chunk[headerSize+i*4:]))
+		if pos >= len(chunk) {
+			continue
+		}
+		if isUTF8 {
+			p.strings[i] = decodeAXMLUTF8(chunk[pos:])
+		} else {
+			p.strings[i] = decodeAXMLUTF16(chunk[pos:])
+		}
+	}
+	return nil
+}
+
+// decodeAXMLUTF8 解码 UTF-8 字符串：UTF-16 长度 + UTF-8 字节长度 + 内容
+func decodeAXMLUTF8(b []byte) string {
+	_, n := axmlUTF8Length(b)
+	if n == 0 {
+		return ""
+	}
+	length, m := axmlUTF8Length(b[n:])
+	if m == 0 || n+m+length > len(b) {
+		return ""
+	}
+	return string(b[n+m : n+m+length])
+}
+
+// axmlUTF8Length 读取 1~2 字节的长度前缀
+func axmlUTF8Length(b []byte) (int, int) {
+	if len(b) < 1 {
+		return 0, 0
+	}
+	if b[0]&0x80 == 0 {
+		return int(b[0]), 1
+	}
+	if len(b) < 2 {
+		return 0, 0
+	}
+	return int(b[0]&0x7f)<<8 | int(b[1]), 2
+}
+
+// decodeAXMLUTF16 解码 UTF-16LE 字符串：2~4 字节长度前缀 + 内容
+func decodeAXMLUTF16(b []byte) string {
+	if len(b) < 2 {
+		return ""
+	}
+	length := int(binary.LittleEndian.Uint16(b))
+	pos := 2
+	if length&0x8000 != 0 {
+		if len(b) < 4 {
+			return ""
+		}
+		length = (length&0x7fff)<<16 | int(binary.LittleEndian.Uint16(b[2:]))
+		pos = 4
+	}
+	if pos+length*2 > len(b) {
+		return ""
+	}
+	units := make([]uint16, length)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[pos+i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// str 按索引取字符串池中的字符串
+func (p *axmlParser) str(index uint32) string {
+	if int64(index) >= int64(len(p.strings)) {
+		return ""
+	}
+	return p.strings[index]
+}
+
+// readElement 读取开始标签的名称与属性，属性值统一转为字符串；引用类型的值被忽略
+func (p *axmlParser) readElement(chunk []byte, headerSize int) (string, map[string]string, error) {
+	if headerSize+20 > len(chunk) {
+		return "", nil, fmt.Errorf("AndroidManifest.xml 元素损坏")
+	}
+	ext := chunk[headerSize:]
+	name := p.str(binary.LittleEndian.Uint32(ext[4:]))
+	attrStart := int(binary.LittleEndian.Uint16(ext[8:]))
+	attrSize := int(binary.LittleEndian.Uint16(ext[10:]))
+	attrCount := int(binary.LittleEndian.Uint16(ext[12:]))
+	if attrSize < 20 || headerSize+attrStart+attrCount*attrSize > len(chunk) {
+		return "", nil, fmt.Errorf("AndroidManifest.xml 元素属性损坏")
+	}
+
+	attrs := make(map[string]string, attrCount)
+	for i := 0; i < attrCount; i++ {
+		attr := ext[attrStart+i*attrSize:]
+		nameIndex := binary.LittleEndian.Uint32(attr[4:])
+		attrName := p.str(nameIndex)
+		if int64(nameIndex) < int64(len(p.resourceIDs)) {
+			if known, ok := axmlAttrResourceIDs[p.resourceIDs[nameIndex]]; ok {
+				attrName = known
+			}
+		}
+		if attrName == "" {
+			continue
+		}
+
+		rawValue := binary.LittleEndian.Uint32(attr[8:])
+		dataType := attr[15]
+		value := binary.LittleEndian.Uint32(attr[16:])
+		switch dataType {
+		case axmlTypeString:
+			attrs[attrName] = p.str(value)
+		case axmlTypeIntDec, axmlTypeIntHex:
+			attrs[attrName] = strconv.FormatInt(int64(int32(value)), 10)
+		case axmlTypeBoolean:
+			attrs[attrName] = strconv.FormatBool(value != 0)
+		case axmlTypeReference:
+			// 资源引用（如 @string/app_name）离线无法解析
+		default:
+			if rawValue != math.MaxUint32 {
+				attrs[attrName] = p.str(rawValue)
+			}
+		}
+	}
+	return name, attrs, nil
+}
+
+// parseInfoPlist 解析 IPA 中的 Info.plist（XML 或二进制格式）
+func parseInfoPlist(data []byte) (*AppManifest, error) {
+	var values map[string]string
+	var err error
+	if bytes.HasPrefix(data, []byte("bplist00")) {
+		values, err = parseBinaryPlist(data)
+	} else {
+		values, err = parseXMLPlist(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &AppManifest{
+		Platform:    AppPlatformIOS,
+		PackageName: values["CFBundleIdentifier"],
+		AppName:     values["CFBundleDisplayName"],
+		VersionName: values["CFBundleShortVersionString"],
+		VersionCode: values["CFBundleVersion"],
+		MinOS:       values["MinimumOSVersion"],
+	}
+	if manifest.AppName == "" {
+		manifest.AppName = values["CFBundleName"]
+	}
+	if manifest.PackageName == "" {
+		return nil, fmt.Errorf("Info.plist 中缺少 CFBundleIdentifier")
+	}
+	return manifest, nil
+}
+
+// parseXMLPlist 读取 XML plist 顶层字典中的标量值
+func parseXMLPlist(data []byte) (map[string]string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	values := make(map[string]string)
+	depth := 0
+	key := ""
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Info.plist 格式错误: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "dict" && depth == 0 {
+				depth = 1
+				continue
+			}
+			if depth != 1 {
+				continue
+			}
+			switch t.Name.Local {
+			case "key":
+				var text string
+				if err := decoder.DecodeElement(&text, &t); err != nil {
+					return nil, fmt.Errorf("Info.plist 格式错误: %v", err)
+				}
+				key = text
+			case "string", "integer", "real":
+				var text string
+				if err := decoder.DecodeElement(&text, &t); err != nil {
+					return nil, fmt.Errorf("Info.plist 格式错误: %v", err)
+				}
+				values[key] = strings.TrimSpace(text)
+				key = ""
+			case "true", "false":
+				values[key] = t.Name.Local
+				key = ""
+				decoder.Skip()
+			default:
+				// 嵌套的字典、数组等不需要
+				key = ""
+				decoder.Skip()
+			}
+		case xml.EndElement:
+			if t.Name.Local == "dict" && depth == 1 {
+				return values, nil
+			}
+		}
+	}
+	if depth == 0 {
+		return nil, fmt.Errorf("Info.plist 中没有字典")
+	}
+	return values, nil
+}
+
+// bplistReader 二进制 plist 解析状态
+type bplistReader struct {
+	data       []byte
+	offsets    []uint64
+	refSize    int
+	numObjects uint64
+}
+
+// parseBinaryPlist 读取二进制 plist（bplist00）顶层字典中的标量值
+func parseBinaryPlist(data []byte) (map[string]string, error) {
+	if len(data) < 8+32 {
+		return nil, fmt.Errorf("Info.plist 二进制格式损坏")
+	}
+	trailer := data[len(data)-32:]
+	offsetSize := int(trailer[6])
+	refSize := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:])
+	topObject := binary.BigEndian.Uint64(trailer[16:])
+	tableOffset := binary.BigEndian.Uint64(trailer[24:])
+	// 偏移表位于尾部信息之前；先比较再相除，避免伪造的超大值相乘相加时溢出
+	tableEnd := uint64(len(data) - 32)
+	if offsetSize < 1 || offsetSize > 8 || refSize < 1 || refSize > 8 ||
+		numObjects == 0 || topObject >= numObjects ||
+		tableOffset >= tableEnd || numObjects > (tableEnd-tableOffset)/uint64(offsetSize) {
+		return nil, fmt.Errorf("Info.plist 二进制格式损坏")
+	}
+
+	r := &bplistReader{data: data, refSize: refSize, numObjects: numObjects}
+	r.offsets = make([]uint64, numObjects)
+	for i := range r.offsets {
+		start := tableOffset + uint64(i)*uint64(offsetSize)
+		r.offsets[i] = readBigEndian(data[start : start+uint64(offsetSize)])
+	}
+
+	keys, valueRefs, err := r.dict(topObject)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(keys))
+	for i, keyRef := range keys {
+		key, ok := r.scalar(keyRef)
+		if !ok {
+			continue
+		}
+		if value, ok := r.scalar(valueRefs[i]); ok {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+// readBigEndian 读取 1~8 字节的大端无符号整数
+func readBigEndian(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// object 返回对象的标记字节与内容起始位置
+func (r *bplistReader) object(ref uint64) (byte, int, error) {
+	if ref >= r.numObjects || r.offsets[ref] >= uint64(len(r.data)) {
+		return 0, 0, fmt.Errorf("Info.plist 对象引用越界")
+	}
+	pos := int(r.offsets[ref])
+	return r.data[pos], pos + 1, nil
+}
+
+// count 读取对象长度，低 4 位为 0xF 时长度由后续整数对象给出
+func (r *bplistReader) count(marker byte, pos int) (int, int, error) {
+	n := int(marker & 0x0f)
+	if n != 0x0f {
+		return n, pos, nil
+	}
+	if pos >= len(r.data) || r.data[pos]>>4 != 0x1 {
+		return 0, 0, fmt.Errorf("Info.plist 对象长度损坏")
+	}
+	size := 1 << (r.data[pos] & 0x0f)
+	if size > 8 || pos+1+size > len(r.data) {
+		return 0, 0, fmt.Errorf("Info.plist 对象长度损坏")
+	}
+	value := readBigEndian(r.data[pos+1 : pos+1+size])
+	if value > uint64(len(r.data)) {
+		return 0, 0, fmt.Errorf("Info.plist 对象长度损坏")
+	}
+	return int(value), pos + 1 + size, nil
+}
+
+// dict 读取字典对象的键、值引用
+func (r *bplistReader) dict(ref uint64) ([]uint64, []uint64, error) {
+	marker, pos, err := r.object(ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	if marker>>4 != 0xd {
+		return nil, nil, fmt.Errorf("Info.plist 顶层对象不是字典")
+	}
+	n, pos, err := r.count(marker, pos)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pos+2*n*r.refSize > len(r.data) {
+		return nil, nil, fmt.Errorf("Info.plist 字典损坏")
+	}
+
+	keys := make([]uint64, n)
+	values := make([]uint64, n)
+	for i := 0; i < n; i++ {
+		keys[i] = readBigEndian(r.data[pos+i*r.refSize : pos+(i+1)*r.refSize])
+		valuePos := pos + (n+i)*r.refSize
+		values[i] = readBigEndian(r.data[valuePos : valuePos+r.refSize])
+	}
+	return keys, values, nil
+}
+
+// scalar 读取字符串、整数、布尔对象并转为字符串；其他类型返回 false
+func (r *bplistReader) scalar(ref uint64) (string, bool) {
+	marker, pos, err := r.object(ref)
+	if err != nil {
+		return "", false
+	}
+
+	switch marker >> 4 {
+	case 0x0:
+		switch marker {
+		case 0x08:
+			return "false", true
+		case 0x09:
+			return "true", true
+		}
+	case 0x1:
+		size := 1 << (marker & 0x0f)
+		if size > 8 || pos+size > len(r.data) {
+			return "", false
+		}
+		value := readBigEndian(r.data[pos : pos+size])
+		if size == 8 {
+			return strconv.FormatInt(int64(value), 10), true
+		}
+		return strconv.FormatUint(value, 10), true
+	case 0x5:
+		n, pos, err := r.count(marker, pos)
+		if err != nil || pos+n > len(r.data) {
+			return "", false
+		}
+		return string(r.data[pos : pos+n]), true
+	case 0x6:
+		n, pos, err := r.count(marker, pos)
+		if err != nil || pos+2*n > len(r.data) {
+			return "", false
+		}
+		units := make([]uint16, n)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(r.data[pos+i*2:])
+		}
+		return string(utf16.Decode(units)), true
+	}
+	return "", false
+}
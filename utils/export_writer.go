@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// 导出文件格式
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+// ExportWriter 表格导出写入器，逐行写入，不在内存中缓存全部数据
+type ExportWriter interface {
+	WriteRow(values []string) error
+	Close() error
+}
+
+// IsValidExportFormat 检查导出格式是否支持
+func IsValidExportFormat(format string) bool {
+	return format == ExportFormatCSV || format == ExportFormatXLSX
+}
+
+// ExportContentType 获取导出格式对应的Content-Type
+func ExportContentType(format string) string {
+	if format == ExportFormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// NewExportWriter 根据格式创建导出写入器
+func NewExportWriter(format string, w io.Writer) (ExportWriter, error) {
+	switch format {
+	case ExportFormatCSV:
+		return newCSVExportWriter(w)
+	case ExportFormatXLSX:
+		return newXLSXExportWriter(w)
+	default:
+		return nil, fmt.Errorf("不支持的导出格式: %s", format)
+	}
+}
+
+// sanitizeExportCell 防止 CSV 被表格软件打开时的公式注入；以 + - 开头的纯数字（如负数、带区号的电话）保持原样。
+// XLSX 使用内联字符串单元格，不会作为公式计算，无需处理
+func sanitizeExportCell(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '@', '\t', '\r':
+		return "'" + value
+	case '+', '-':
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "'" + value
+		}
+	}
+	return value
+}
+
+// csvExportWriter CSV导出写入器
+type csvExportWriter struct {
+	writer *csv.Writer
+	rows   int
+}
+
+func newCSVExportWriter(w io.Writer) (*csvExportWriter, error) {
+	// 写入BOM，保证Excel打开中文不乱码
+	if _, err := io.WriteString(w, "\xEF\xBB\xBF"); err != nil {
+		return nil, err
+	}
+	return &csvExportWriter{writer: csv.NewWriter(w)}, nil
+}
+
+// WriteRow 写入一行
+func (cw *csvExportWriter) WriteRow(values []string) error {
+	row := make([]string, len(values))
+	for i, value := range values {
+		row[i] = sanitizeExportCell(value)
+	}
+	if err := cw.writer.Write(row); err != nil {
+		return err
+	}
+
+	// 定期刷新，让数据尽快写到下游
+	cw.rows++
+	if cw.rows%500 == 0 {
+		cw.writer.Flush()
+		return cw.writer.Error()
+	}
+	return nil
+}
+
+// Close 刷新缓冲区
+func (cw *csvExportWriter) Close() error {
+	cw.writer.Flush()
+	return cw.writer.Error()
+}
+
+// xlsxExportWriter XLSX导出写入器（手工生成最小化的OOXML，工作表以流方式写入zip）
+type xlsxExportWriter struct {
+	zip   *zip.Writer
+	sheet io.Writer
+	rows  int
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`
+
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`
+
+func newXLSXExportWriter(w io.Writer) (*xlsxExportWriter, error) {
+	zw := zip.NewWriter(w)
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	// 工作表作为最后一个条目，后续行直接写入
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, err
+	}
+
+	return &xlsxExportWriter{zip: zw, sheet: sheet}, nil
+}
+
+// WriteRow 写入一行（第一行为表头，使用粗体样式）
+func (xw *xlsxExportWriter) WriteRow(values []string) error {
+	xw.rows++
+	style := ""
+	if xw.rows == 1 {
+		style = ` s="1"`
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<row r="`)
+	sb.WriteString(strconv.Itoa(xw.rows))
+	sb.WriteString(`">`)
+	for i, value := range values {
+		sb.WriteString(`<c r="`)
+		sb.WriteString(xlsxColumnName(i))
+		sb.WriteString(strconv.Itoa(xw.rows))
+		sb.WriteString(`" t="inlineStr"`)
+		sb.WriteString(style)
+		sb.WriteString(`><is><t xml:space="preserve">`)
+		if err := xml.EscapeText(&sb, []byte(value)); err != nil {
+			return err
+		}
+		sb.WriteString(`</t></is></c>`)
+	}
+	sb.WriteString(`</row>`)
+
+	_, err := io.WriteString(xw.sheet, sb.String())
+	return err
+}
+
+// Close 结束工作表并写入zip目录
+func (xw *xlsxExportWriter) Close() error {
+	if _, err := io.WriteString(xw.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return xw.zip.Close()
+}
+
+// xlsxColumnName 列序号转换为Excel列名（0 -> A, 26 -> AA）
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
@@ -0,0 +1,347 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+)
+
+// ThumbnailMaxSize 缩略图最长边
+const ThumbnailMaxSize = 320
+
+// MaxImagePixels 允许解码的最大像素数（4000 万），防止文件头伪造超大尺寸的小文件在解码时占用大量内存
+const MaxImagePixels = 40000000
+
+// pngSignature PNG 文件签名
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadataChunks 需要移除的 PNG 元数据块
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// StripImageMetadata 移除图片中的 EXIF、XMP 等元数据（含拍摄设备、GPS 位置），保留 ICC 色彩配置。
+// JPEG 带有旋转方向时会先按方向旋正再重新编码，其余情况只删除元数据段、不改动像素数据
+func StripImageMetadata(data []byte, mimeType string) ([]byte, error) {
+	switch mimeType {
+	case "image/jpeg":
+		stripped, orientation, err := stripJPEGMetadata(data)
+		if err != nil || orientation <= 1 {
+			return stripped, err
+		}
+		if err := checkImagePixels(data, mimeType); err != nil {
+			return nil, err
+		}
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("图片解码失败: %v", err)
+		}
+		var out bytes.Buffer
+		if err := jpeg.Encode(&out, applyOrientation(img, orientation), &jpeg.Options{Quality: 92}); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	case "image/png":
+		return stripPNGMetadata(data)
+	case "image/webp":
+		return stripWebPMetadata(data)
+	}
+	return data, nil
+}
+
+// stripJPEGMetadata 删除 APP1（EXIF/XMP）与 APP13（IPTC）段，并返回 EXIF 中的旋转方向
+func stripJPEGMetadata(data []byte) ([]byte, int, error) {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil, 0, fmt.Errorf("不是有效的 JPEG 文件")
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xff, 0xd8)
+	orientation := 0
+	pos := 2
+	for pos < len(data) {
+		if data[pos] != 0xff {
+			return nil, 0, fmt.Errorf("JPEG 数据损坏")
+		}
+		// 跳过填充字节
+		for pos+1 < len(data) && data[pos+1] == 0xff {
+			pos++
+		}
+		if pos+1 >= len(data) {
+			break
+		}
+		marker := data[pos+1]
+
+		// 扫描数据开始（SOS）或结束（EOI）后原样保留
+		if marker == 0xda || marker == 0xd9 {
+			out = append(out, data[pos:]...)
+			return out, orientation, nil
+		}
+		// 无长度字段的独立标记
+		if marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) {
+			out = append(out, data[pos:pos+2]...)
+			pos += 2
+			continue
+		}
+
+		if pos+4 > len(data) {
+			return nil, 0, fmt.Errorf("JPEG 数据损坏")
+		}
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:pos+4]))
+		if end > len(data) {
+			return nil, 0, fmt.Errorf("JPEG 数据损坏")
+		}
+		payload := data[pos+4 : end]
+
+		switch marker {
+		case 0xe1: // APP1
+			if bytes.HasPrefix(payload, []byte("Exif\x00\x00")) && orientation == 0 {
+				orientation = exifOrientation(payload[6:])
+			}
+		case 0xed: // APP13
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	return out, orientation, nil
+}
+
+// exifOrientation 从 TIFF 结构的 IFD0 中读取 Orientation（0x0112），读取失败返回 0
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[offset : offset+2]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			value := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if value >= 1 && value <= 8 {
+				return value
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// stripPNGMetadata 删除 PNG 中的 EXIF 与文本块
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, fmt.Errorf("不是有效的 PNG 文件")
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	pos := len(pngSignature)
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, fmt.Errorf("PNG 数据损坏")
+		}
+		chunkType := string(data[pos+4 : pos+8])
+		if !pngMetadataChunks[chunkType] {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+		if chunkType == "IEND" {
+			break
+		}
+	}
+	return out, nil
+}
+
+// stripWebPMetadata 删除 WebP 扩展格式中的 EXIF、XMP 块，并清除 VP8X 中对应的标志位
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 20 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, fmt.Errorf("不是有效的 WebP 文件")
+	}
+	if string(data[12:16]) != "VP8X" {
+		return data, nil // 简单格式不含元数据
+	}
+
+	var out bytes.Buffer
+	out.WriteString("RIFF\x00\x00\x00\x00WEBP")
+	pos := 12
+	for pos+8 <= len(data) {
+		chunkType := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		end := pos + 8 + size + size&1
+		if size < 0 || pos+8+size > len(data) {
+			return nil, fmt.Errorf("WebP 数据损坏")
+		}
+		if end > len(data) {
+			end = len(data)
+		}
+
+		switch chunkType {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[pos:end]...)
+			chunk[8] &^= 0x08 | 0x04 // EXIF、XMP 标志
+			out.Write(chunk)
+		default:
+			out.Write(data[pos:end])
+		}
+		pos = end
+	}
+
+	result := out.Bytes()
+	binary.LittleEndian.PutUint32(result[4:8], uint32(len(result)-8))
+	return result, nil
+}
+
+// toNRGBA 将图片转换为起点为 (0,0) 的 NRGBA
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Rect.Min == (image.Point{}) {
+		return nrgba
+	}
+	bounds := img.Bounds()
+	nrgba := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+	return nrgba
+}
+
+// applyOrientation 按 EXIF 方向（1~8）旋转/翻转图片，使其正向显示
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	src := toNRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+// ResizeToFit 等比缩小到 maxSize×maxSize 以内（按面积平均采样），不放大
+func ResizeToFit(img image.Image, maxSize int) *image.NRGBA {
+	src := toNRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	if w <= maxSize && h <= maxSize {
+		return src
+	}
+
+	dw, dh := maxSize, h*maxSize/w
+	if h > w {
+		dw, dh = w*maxSize/h, maxSize
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, (y+1)*h/dh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, (x+1)*w/dw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			// 按透明度加权，避免透明像素的颜色渗入边缘
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[src.PixOffset(x0, sy):src.PixOffset(x1, sy)]
+				for i := 0; i < len(row); i += 4 {
+					alpha := uint64(row[i+3])
+					r += uint64(row[i]) * alpha
+					g += uint64(row[i+1]) * alpha
+					b += uint64(row[i+2]) * alpha
+					a += alpha
+					n++
+				}
+			}
+			p := dst.Pix[dst.PixOffset(x, y) : dst.PixOffset(x, y)+4]
+			if a > 0 {
+				p[0], p[1], p[2] = uint8(r/a), uint8(g/a), uint8(b/a)
+			}
+			p[3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// isOpaque 图片是否不含透明像素
+func isOpaque(img *image.NRGBA) bool {
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+// EncodeThumbnail 编码缩略图：不透明图片用 JPEG，含透明度的用 PNG，返回数据与扩展名
+func EncodeThumbnail(img *image.NRGBA) ([]byte, string, error) {
+	var out bytes.Buffer
+	if isOpaque(img) {
+		if err := jpeg.Encode(&out, img, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", err
+		}
+		return out.Bytes(), ".jpg", nil
+	}
+	if err := png.Encode(&out, img); err != nil {
+		return nil, "", err
+	}
+	return out.Bytes(), ".png", nil
+}
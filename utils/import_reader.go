@@ -0,0 +1,314 @@
+package utils
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ImportReader 表格导入读取器，逐行读取，读完返回 io.EOF
+type ImportReader interface {
+	Read() ([]string, error)
+	Line() int // 当前行在原文件中的行号（从1开始）
+	Close() error
+}
+
+// ImportFormatFromFilename 根据文件名判断导入格式
+func ImportFormatFromFilename(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return ExportFormatCSV
+	case ".xlsx":
+		return ExportFormatXLSX
+	default:
+		return ""
+	}
+}
+
+// OpenImportFile 打开CSV/XLSX导入文件
+func OpenImportFile(filePath string) (ImportReader, error) {
+	switch ImportFormatFromFilename(filePath) {
+	case ExportFormatCSV:
+		return openCSVImportReader(filePath)
+	case ExportFormatXLSX:
+		return openXLSXImportReader(filePath)
+	default:
+		return nil, fmt.Errorf("只支持导入 csv 或 xlsx 文件")
+	}
+}
+
+// csvImportReader CSV导入读取器
+type csvImportReader struct {
+	file   *os.File
+	reader *csv.Reader
+	line   int
+}
+
+func openCSVImportReader(filePath string) (*csvImportReader, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// 跳过Excel导出的BOM
+	buffered := bufio.NewReader(file)
+	if bom, err := buffered.Peek(3); err == nil && bytes.Equal(bom, []byte("\xEF\xBB\xBF")) {
+		buffered.Discard(3)
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	return &csvImportReader{file: file, reader: reader}, nil
+}
+
+// Read 读取一行
+func (cr *csvImportReader) Read() ([]string, error) {
+	record, err := cr.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	cr.line, _ = cr.reader.FieldPos(0)
+	return record, nil
+}
+
+// Line 当前行号
+func (cr *csvImportReader) Line() int {
+	return cr.line
+}
+
+// Close 关闭文件
+func (cr *csvImportReader) Close() error {
+	return cr.file.Close()
+}
+
+// xlsxImportReader XLSX导入读取器（只读取第一个工作表）
+type xlsxImportReader struct {
+	archive       *zip.ReadCloser
+	sheet         io.ReadCloser
+	decoder       *xml.Decoder
+	sharedStrings []string
+	line          int
+}
+
+type xlsxRichText struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+// text 拼接纯文本与富文本片段
+func (rt *xlsxRichText) text() string {
+	if len(rt.R) == 0 {
+		return rt.T
+	}
+	var sb strings.Builder
+	sb.WriteString(rt.T)
+	for _, r := range rt.R {
+		sb.WriteString(r.T)
+	}
+	return sb.String()
+}
+
+type xlsxRow struct {
+	R int `xml:"r,attr"`
+	C []struct {
+		R  string       `xml:"r,attr"`
+		T  string       `xml:"t,attr"`
+		V  string       `xml:"v"`
+		Is xlsxRichText `xml:"is"`
+	} `xml:"c"`
+}
+
+func openXLSXImportReader(filePath string) (*xlsxImportReader, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("无法解析xlsx文件: %v", err)
+	}
+
+	xr := &xlsxImportReader{archive: archive}
+	if err := xr.loadSharedStrings(); err != nil {
+		archive.Close()
+		return nil, err
+	}
+
+	sheetPath := xr.firstSheetPath()
+	for _, f := range archive.File {
+		if f.Name == sheetPath {
+			if xr.sheet, err = f.Open(); err != nil {
+				archive.Close()
+				return nil, err
+			}
+			break
+		}
+	}
+	if xr.sheet == nil {
+		archive.Close()
+		return nil, fmt.Errorf("xlsx文件中没有工作表")
+	}
+	xr.decoder = xml.NewDecoder(xr.sheet)
+
+	return xr, nil
+}
+
+// openPart 打开zip中的文件
+func (xr *xlsxImportReader) openPart(name string) (io.ReadCloser, error) {
+	for _, f := range xr.archive.File {
+		if f.Name == name {
+			return f.Open()
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+// loadSharedStrings 加载共享字符串表
+func (xr *xlsxImportReader) loadSharedStrings() error {
+	part, err := xr.openPart("xl/sharedStrings.xml")
+	if err != nil {
+		return nil
+	}
+	defer part.Close()
+
+	decoder := xml.NewDecoder(part)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("无法解析xlsx共享字符串: %v", err)
+		}
+		if se, ok := token.(xml.StartElement); ok && se.Name.Local == "si" {
+			var si xlsxRichText
+			if err := decoder.DecodeElement(&si, &se); err != nil {
+				return fmt.Errorf("无法解析xlsx共享字符串: %v", err)
+			}
+			xr.sharedStrings = append(xr.sharedStrings, si.text())
+		}
+	}
+}
+
+// firstSheetPath 通过workbook关系找到第一个工作表
+func (xr *xlsxImportReader) firstSheetPath() string {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	var workbook struct {
+		Sheets []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := xr.decodePart("xl/workbook.xml", &workbook); err != nil || len(workbook.Sheets) == 0 {
+		return fallback
+	}
+	if err := xr.decodePart("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return fallback
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[0].ID {
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/")
+			}
+			return path.Join("xl", rel.Target)
+		}
+	}
+	return fallback
+}
+
+// decodePart 解析zip中的xml文件
+func (xr *xlsxImportReader) decodePart(name string, v interface{}) error {
+	part, err := xr.openPart(name)
+	if err != nil {
+		return err
+	}
+	defer part.Close()
+	return xml.NewDecoder(part).Decode(v)
+}
+
+// Read 读取一行
+func (xr *xlsxImportReader) Read() ([]string, error) {
+	for {
+		token, err := xr.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		se, ok := token.(xml.StartElement)
+		if !ok || se.Name.Local != "row" {
+			continue
+		}
+
+		var row xlsxRow
+		if err := xr.decoder.DecodeElement(&row, &se); err != nil {
+			return nil, fmt.Errorf("无法解析xlsx行: %v", err)
+		}
+		if row.R > 0 {
+			xr.line = row.R
+		} else {
+			xr.line++
+		}
+
+		var values []string
+		for i, cell := range row.C {
+			index := i
+			if n := xlsxColumnIndex(cell.R); n >= 0 {
+				index = n
+			}
+			for len(values) <= index {
+				values = append(values, "")
+			}
+
+			switch cell.T {
+			case "s":
+				if n, err := strconv.Atoi(cell.V); err == nil && n >= 0 && n < len(xr.sharedStrings) {
+					values[index] = xr.sharedStrings[n]
+				}
+			case "inlineStr":
+				values[index] = cell.Is.text()
+			default:
+				values[index] = cell.V
+			}
+		}
+		return values, nil
+	}
+}
+
+// Line 当前行号
+func (xr *xlsxImportReader) Line() int {
+	return xr.line
+}
+
+// Close 关闭文件
+func (xr *xlsxImportReader) Close() error {
+	xr.sheet.Close()
+	return xr.archive.Close()
+}
+
+// xlsxColumnIndex 单元格引用转换为列序号（A1 -> 0, AA3 -> 26）
+func xlsxColumnIndex(ref string) int {
+	index := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		index = index*26 + int(ch-'A'+1)
+	}
+	return index - 1
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+
+	"backend/configs"
+)
+
+// secretPrefix 加密值前缀，带版本号便于以后更换算法
+const secretPrefix = "enc:v1:"
+
+// secretCipher 使用配置密钥（为空时使用 JWT 密钥）派生的 AES-256-GCM
+func secretCipher() (cipher.AEAD, error) {
+	key := configs.AppConfig.Security.ConfigSecretKey
+	if key == "" {
+		key = configs.AppConfig.JWT.Secret
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// IsEncryptedSecret 值是否为 EncryptSecret 生成的密文
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, secretPrefix)
+}
+
+// EncryptSecret 加密敏感值，空值不加密
+func EncryptSecret(plain string) (string, error) {
+	if plain == "" || IsEncryptedSecret(plain) {
+		return plain, nil
+	}
+	aead, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), nil)
+	return secretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret 解密 EncryptSecret 生成的密文，非密文（如历史明文数据）原样返回
+func DecryptSecret(value string) (string, error) {
+	if !IsEncryptedSecret(value) {
+		return value, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, secretPrefix))
+	if err != nil {
+		return "", errors.New("密文格式错误")
+	}
+	aead, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("密文格式错误")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("解密失败，请检查 CONFIG_SECRET_KEY 是否变更")
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// androidPackageRegex Android 包名：至少两段，每段以字母开头
+var androidPackageRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*(\.[A-Za-z][A-Za-z0-9_]*)+$`)
+
+// appStoreIDRegex App Store 链接路径中的应用ID，如 /cn/app/wechat/id414478124
+var appStoreIDRegex = regexp.MustCompile(`^id(\d{1,15})$`)
+
+// ValidAndroidPackage 检查是否为合法的 Android 包名
+func ValidAndroidPackage(packageName string) bool {
+	return len(packageName) <= 255 && androidPackageRegex.MatchString(packageName)
+}
+
+// ParseGooglePlayLink 校验 Google Play 链接并提取包名
+// 支持 https://play.google.com/store/apps/details?id=xxx 与 market://details?id=xxx
+func ParseGooglePlayLink(link string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return "", fmt.Errorf("Google Play 链接格式错误")
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if strings.ToLower(u.Hostname()) != "play.google.com" || strings.TrimSuffix(u.Path, "/") != "/store/apps/details" {
+			return "", fmt.Errorf("Google Play 链接必须是 play.google.com/store/apps/details 应用详情页")
+		}
+	case "market":
+		if u.Host != "details" {
+			return "", fmt.Errorf("Google Play 链接必须是 market://details 应用详情页")
+		}
+	default:
+		return "", fmt.Errorf("Google Play 链接协议不支持")
+	}
+
+	packageName := u.Query().Get("id")
+	if packageName == "" {
+		return "", fmt.Errorf("Google Play 链接缺少 id 参数")
+	}
+	if !ValidAndroidPackage(packageName) {
+		return "", fmt.Errorf("Google Play 链接中的包名无效: %s", packageName)
+	}
+	return packageName, nil
+}
+
+// ParseAppStoreLink 校验 App Store 链接并提取应用ID
+// 支持 https://apps.apple.com/{country}/app/{name}/id123 与 itunes.apple.com 旧链接
+func ParseAppStoreLink(link string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return "", fmt.Errorf("App Store 链接格式错误")
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" && scheme != "itms-apps" {
+		return "", fmt.Errorf("App Store 链接协议不支持")
+	}
+	host := strings.ToLower(u.Hostname())
+	if host != "apps.apple.com" && host != "itunes.apple.com" {
+		return "", fmt.Errorf("App Store 链接必须是 apps.apple.com 应用详情页")
+	}
+
+	for _, segment := range strings.Split(u.Path, "/") {
+		if matches := appStoreIDRegex.FindStringSubmatch(segment); matches != nil {
+			return matches[1], nil
+		}
+	}
+	// 部分旧链接使用 ?id=123 形式
+	if id := u.Query().Get("id"); id != "" {
+		if matches := appStoreIDRegex.FindStringSubmatch("id" + id); matches != nil {
+			return matches[1], nil
+		}
+	}
+	return "", fmt.Errorf("App Store 链接缺少应用ID")
+}
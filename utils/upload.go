@@ -1,15 +1,26 @@
 package utils
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 	"io"
 	"mime/multipart"
-	"os"
+	"net/http"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"backend/configs"
+	"backend/models"
+	"backend/pkg/storage"
 	"backend/types"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -24,71 +35,343 @@ var allowedImageTypes = map[string]bool{
 }
 
 var allowedVideoTypes = map[string]bool{
-	"video/mp4":  true,
-	"video/avi":  true,
-	"video/mov":  true,
-	"video/webm": true,
+	"video/mp4":       true,
+	"video/avi":       true,
+	"video/mov":       true,
+	"video/quicktime": true,
+	"video/webm":      true,
 }
 
-// SaveUploadedFile 保存上传的文件
+// extensionMIMETypes 扩展名对应的可接受内容类型（按文件内容检测），不在表中的扩展名无法校验内容，一律拒绝
+var extensionMIMETypes = map[string][]string{
+	"jpg":  {"image/jpeg"},
+	"jpeg": {"image/jpeg"},
+	"png":  {"image/png"},
+	"gif":  {"image/gif"},
+	"webp": {"image/webp"},
+	"mp4":  {"video/mp4"},
+	"m4v":  {"video/mp4"},
+	"mov":  {"video/quicktime", "video/mp4"},
+	"webm": {"video/webm"},
+	"avi":  {"video/avi"},
+	"pdf":  {"application/pdf"},
+	"doc":  {"application/x-ole-storage"},
+	"xls":  {"application/x-ole-storage"},
+	"ppt":  {"application/x-ole-storage"},
+	"docx": {"application/zip"},
+	"xlsx": {"application/zip"},
+	"pptx": {"application/zip"},
+	"csv":  {"text/plain"},
+	"txt":  {"text/plain"},
+}
+
+// UploadPolicy 上传限制，来自系统配置 max_upload_size 与 allowed_file_types
+type UploadPolicy struct {
+	MaxSize           int64           // 单个文件最大字节数
+	MaxVideoSize      int64           // 视频断点续传最大字节数
+	AllowedExtensions map[string]bool // 允许的扩展名（小写、不含点）
+}
+
+var (
+	uploadPolicyMu sync.RWMutex
+	uploadPolicy   *UploadPolicy
+)
+
+// SetUploadPolicy 设置上传限制
+func SetUploadPolicy(policy UploadPolicy) {
+	uploadPolicyMu.Lock()
+	defer uploadPolicyMu.Unlock()
+	uploadPolicy = &policy
+}
+
+// CurrentUploadPolicy 返回当前上传限制，未设置时使用配置文件的大小限制与默认扩展名
+func CurrentUploadPolicy() UploadPolicy {
+	uploadPolicyMu.RLock()
+	defer uploadPolicyMu.RUnlock()
+	if uploadPolicy != nil {
+		return *uploadPolicy
+	}
+	return UploadPolicy{
+		MaxSize:           configs.AppConfig.Upload.MaxFileSize,
+		MaxVideoSize:      1 << 30,
+		AllowedExtensions: ParseFileTypes(models.DefaultAllowedFileTypes),
+	}
+}
+
+// ParseFileTypes 解析逗号分隔的扩展名列表，如 "jpg, .PNG,mp4"
+func ParseFileTypes(value string) map[string]bool {
+	types := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(item)), ".")
+		if item != "" {
+			types[item] = true
+		}
+	}
+	return types
+}
+
+// DetectContentType 按文件头检测内容类型；在 http.DetectContentType 基础上补充 QuickTime 与 Office 97-2003 文档
+func DetectContentType(head []byte) string {
+	if len(head) >= 12 && string(head[4:8]) == "ftyp" && string(head[8:12]) == "qt  " {
+		return "video/quicktime"
+	}
+	if bytes.HasPrefix(head, []byte{0xd0, 0xcf, 0x11, 0xe0, 0xa1, 0xb1, 0x1a, 0xe1}) {
+		return "application/x-ole-storage"
+	}
+	contentType := http.DetectContentType(head)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return contentType
+}
+
+// checkFileType 校验扩展名在白名单内，且与检测到的内容类型一致
+func checkFileType(filename, contentType string, policy UploadPolicy) error {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	if ext == "" {
+		return fmt.Errorf("文件缺少扩展名")
+	}
+	if !policy.AllowedExtensions[ext] {
+		return fmt.Errorf("不支持的文件类型: .%s", ext)
+	}
+	accepted, ok := extensionMIMETypes[ext]
+	if !ok {
+		return fmt.Errorf("无法校验 .%s 文件的内容", ext)
+	}
+	for _, t := range accepted {
+		if t == contentType {
+			return nil
+		}
+	}
+	return fmt.Errorf("文件内容（%s）与扩展名 .%s 不符", contentType, ext)
+}
+
+// ValidateUploadType 按当前上传限制校验文件名与检测到的内容类型
+func ValidateUploadType(filename, contentType string) error {
+	return checkFileType(filename, contentType, CurrentUploadPolicy())
+}
+
+// CheckVideoFilename 校验文件名是允许上传的视频格式（用于内容尚未上传时的预检）
+func CheckVideoFilename(filename string) error {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	if accepted, ok := extensionMIMETypes[ext]; !ok || !IsVideoFile(accepted[0]) {
+		return fmt.Errorf("只能上传视频文件")
+	}
+	if !CurrentUploadPolicy().AllowedExtensions[ext] {
+		return fmt.Errorf("不支持的文件类型: .%s", ext)
+	}
+	return nil
+}
+
+// NewUploadKey 为上传文件生成对象路径：<subDir>/<时间>_<随机串><扩展名>，同时返回不含扩展名的文件名
+func NewUploadKey(subDir, filename string) (key, base string) {
+	base = fmt.Sprintf("%s_%s",
+		time.Now().Format("20060102_150405"),
+		uuid.New().String()[:8])
+	return path.Join(subDir, base+strings.ToLower(filepath.Ext(filename))), base
+}
+
+// sniffUpload 读取文件头检测内容类型
+func sniffUpload(src io.ReadSeeker) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return DetectContentType(head[:n]), nil
+}
+
+// DetectUploadType 检测上传文件的实际内容类型（不信任客户端提供的 Content-Type）
+func DetectUploadType(file *multipart.FileHeader) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	return sniffUpload(src)
+}
+
+// SaveUploadedFile 保存上传的文件：按系统配置校验大小与扩展名、按文件内容校验类型，图片会移除 EXIF 等元数据并记录宽高
 func SaveUploadedFile(c *gin.Context, file *multipart.FileHeader, subDir string) (*types.UploadResponse, error) {
-	// 检查文件大小
-	if file.Size > configs.AppConfig.Upload.MaxFileSize {
-		return nil, fmt.Errorf("文件大小不能超过 %d MB", configs.AppConfig.Upload.MaxFileSize/(1024*1024))
+	return saveUpload(file, subDir, false)
+}
+
+// SaveUploadedImage 保存上传的图片，并生成缩略图与 WebP 变体；非图片文件会被拒绝
+func SaveUploadedImage(c *gin.Context, file *multipart.FileHeader, subDir string) (*types.UploadResponse, error) {
+	return saveUpload(file, subDir, true)
+}
+
+// saveUpload 校验并写入存储，withVariants 为 true 时只接受图片并生成变体
+func saveUpload(file *multipart.FileHeader, subDir string, withVariants bool) (*types.UploadResponse, error) {
+	policy := CurrentUploadPolicy()
+	if policy.MaxSize > 0 && file.Size > policy.MaxSize {
+		return nil, fmt.Errorf("文件大小不能超过 %s", formatFileSize(policy.MaxSize))
 	}
 
-	// 检查文件类型
 	src, err := file.Open()
 	if err != nil {
 		return nil, err
 	}
 	defer src.Close()
 
-	// 读取文件头来检测MIME类型
-	buffer := make([]byte, 512)
-	_, err = src.Read(buffer)
+	contentType, err := sniffUpload(src)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkFileType(file.Filename, contentType, policy); err != nil {
+		return nil, err
+	}
+	if withVariants && !IsImageFile(contentType) {
+		return nil, fmt.Errorf("只能上传 JPEG、PNG、GIF 或 WebP 图片")
+	}
 
-	// 重置文件指针
-	src.Seek(0, io.SeekStart)
+	key, base := NewUploadKey(subDir, file.Filename)
+	response := &types.UploadResponse{
+		Filename: path.Base(key),
+		Size:     file.Size,
+		MimeType: contentType,
+	}
 
-	// 检查MIME类型
-	contentType := file.Header.Get("Content-Type")
-	if !isAllowedFileType(contentType) {
-		return nil, fmt.Errorf("不支持的文件类型: %s", contentType)
+	if !IsImageFile(contentType) {
+		// 非图片直接流式写入存储后端，同时计算内容哈希
+		hasher := sha256.New()
+		if err := storage.Default().Put(key, io.TeeReader(src, hasher), file.Size, contentType); err != nil {
+			return nil, err
+		}
+		response.URL = storage.URL(key)
+		response.Hash = hex.EncodeToString(hasher.Sum(nil))
+		return response, nil
 	}
 
-	// 生成文件名
-	ext := filepath.Ext(file.Filename)
-	filename := fmt.Sprintf("%s_%s%s", 
-		time.Now().Format("20060102_150405"), 
-		uuid.New().String()[:8], 
-		ext)
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkImagePixels(data, contentType); err != nil {
+		return nil, err
+	}
+	data, err = StripImageMetadata(data, contentType)
+	if err != nil {
+		return nil, err
+	}
+	response.Width, response.Height = imageSize(data, contentType)
+	if response.Width == 0 {
+		return nil, fmt.Errorf("图片已损坏或格式不受支持")
+	}
 
-	// 创建保存路径
-	uploadPath := filepath.Join(configs.AppConfig.Upload.Path, subDir)
-	if err := os.MkdirAll(uploadPath, 0755); err != nil {
+	if err := storage.Default().Put(key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
 		return nil, err
 	}
+	response.URL = storage.URL(key)
+	response.Size = int64(len(data))
+	response.Hash = ContentHash(data)
+
+	if withVariants {
+		if err := saveImageVariants(response, data, contentType, subDir, base); err != nil {
+			_ = DeleteFile(response.URL)
+			return nil, err
+		}
+	}
+	return response, nil
+}
 
-	// 完整的文件路径
-	fullPath := filepath.Join(uploadPath, filename)
+// ContentHash 计算内容的 SHA-256（十六进制），用于素材去重
+func ContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
 
-	// 保存文件
-	if err := c.SaveUploadedFile(file, fullPath); err != nil {
-		return nil, err
+// imageSize 读取图片宽高，无法识别时返回 0
+func imageSize(data []byte, contentType string) (int, int) {
+	if contentType == "image/webp" {
+		width, height, err := WebPDimensions(data)
+		if err != nil {
+			return 0, 0
+		}
+		return width, height
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0
 	}
+	return config.Width, config.Height
+}
 
-	// 生成访问URL
-	url := fmt.Sprintf("/uploads/%s/%s", subDir, filename)
+// checkImagePixels 解码前按文件头中的宽高检查像素数，超过 MaxImagePixels 时拒绝
+func checkImagePixels(data []byte, contentType string) error {
+	width, height := imageSize(data, contentType)
+	if width == 0 {
+		return fmt.Errorf("图片已损坏或格式不受支持")
+	}
+	if int64(width)*int64(height) > MaxImagePixels {
+		return fmt.Errorf("图片尺寸过大（%dx%d），像素数不能超过 %d 万", width, height, MaxImagePixels/10000)
+	}
+	return nil
+}
 
-	return &types.UploadResponse{
-		URL:      url,
-		Filename: filename,
-		Size:     file.Size,
-	}, nil
+// imageVariantKeys 图片变体的对象路径：缩略图在 thumbs/ 下（JPEG 或 PNG），WebP 在 webp/ 下
+func imageVariantKeys(subDir, base string) (thumbJPEG, thumbPNG, webp string) {
+	return path.Join(subDir, "thumbs", base+".jpg"),
+		path.Join(subDir, "thumbs", base+".png"),
+		path.Join(subDir, "webp", base+".webp")
+}
+
+// saveImageVariants 生成缩略图与 WebP 变体。标准库无法解码 WebP，WebP 原图不生成缩略图；
+// GIF 只取第一帧生成缩略图，不生成 WebP（会丢失动画）；WebP 变体不比原图小时不保存
+func saveImageVariants(response *types.UploadResponse, data []byte, contentType, subDir, base string) error {
+	if contentType == "image/webp" {
+		return nil
+	}
+	if err := checkImagePixels(data, contentType); err != nil {
+		return err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("图片解码失败: %v", err)
+	}
+
+	thumbJPEG, thumbPNG, webpKey := imageVariantKeys(subDir, base)
+	thumb, thumbExt, err := EncodeThumbnail(ResizeToFit(img, ThumbnailMaxSize))
+	if err != nil {
+		return err
+	}
+	thumbKey, thumbType := thumbJPEG, "image/jpeg"
+	if thumbExt == ".png" {
+		thumbKey, thumbType = thumbPNG, "image/png"
+	}
+	if err := storage.Default().Put(thumbKey, bytes.NewReader(thumb), int64(len(thumb)), thumbType); err != nil {
+		return err
+	}
+	response.Thumbnail = storage.URL(thumbKey)
+
+	if contentType == "image/gif" {
+		return nil
+	}
+	variant, err := EncodeWebPLossless(img)
+	if err != nil || len(variant) >= len(data) {
+		return nil
+	}
+	if err := storage.Default().Put(webpKey, bytes.NewReader(variant), int64(len(variant)), "image/webp"); err != nil {
+		return err
+	}
+	response.WebP = storage.URL(webpKey)
+	return nil
+}
+
+// formatFileSize 将字节数格式化为便于阅读的大小
+func formatFileSize(size int64) string {
+	switch {
+	case size >= 1024*1024 && size%(1024*1024) == 0:
+		return fmt.Sprintf("%d MB", size/(1024*1024))
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%d KB", size/1024)
+	}
+	return fmt.Sprintf("%d B", size)
 }
 
 // SaveMultipleFiles 保存多个文件
@@ -106,12 +389,6 @@ func SaveMultipleFiles(c *gin.Context, files []*multipart.FileHeader, subDir str
 	return responses, nil
 }
 
-// isAllowedFileType 检查是否为允许的文件类型
-func isAllowedFileType(contentType string) bool {
-	contentType = strings.ToLower(contentType)
-	return allowedImageTypes[contentType] || allowedVideoTypes[contentType]
-}
-
 // IsImageFile 检查是否为图片文件
 func IsImageFile(contentType string) bool {
 	return allowedImageTypes[strings.ToLower(contentType)]
@@ -122,18 +399,33 @@ func IsVideoFile(contentType string) bool {
 	return allowedVideoTypes[strings.ToLower(contentType)]
 }
 
-// DeleteFile 删除文件
+// DeleteFile 删除上传的文件，参数为访问URL（/uploads/...）或对象路径
 func DeleteFile(filePath string) error {
-	// 构建完整路径
-	fullPath := filepath.Join(configs.AppConfig.Upload.Path, filePath)
-	
-	// 检查文件是否存在
-	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-		return nil // 文件不存在，认为删除成功
+	if filePath == "" || strings.HasPrefix(filePath, "http://") || strings.HasPrefix(filePath, "https://") {
+		return nil // 外部地址不由本系统管理
 	}
 
-	// 删除文件
-	return os.Remove(fullPath)
+	key, ok := storage.KeyFromURL(filePath)
+	if !ok {
+		cleaned, err := storage.CleanKey(filePath)
+		if err != nil {
+			return err
+		}
+		key = cleaned
+	}
+
+	// 图片同时删除缩略图与 WebP 变体，对象不存在时同样视为删除成功
+	dir, name := path.Split(key)
+	ext := strings.ToLower(path.Ext(name))
+	if accepted, ok := extensionMIMETypes[strings.TrimPrefix(ext, ".")]; ok && IsImageFile(accepted[0]) {
+		thumbJPEG, thumbPNG, webp := imageVariantKeys(dir, strings.TrimSuffix(name, path.Ext(name)))
+		for _, variant := range []string{thumbJPEG, thumbPNG, webp} {
+			if err := storage.Default().Delete(variant); err != nil {
+				return err
+			}
+		}
+	}
+	return storage.Default().Delete(key)
 }
 
 // GetFileURL 获取文件的完整URL
@@ -153,4 +445,4 @@ func GetFileURL(relativePath string) string {
 	}
 	
 	return relativePath
-}
\ No newline at end of file
+}
@@ -20,6 +20,16 @@ func ValidatePhone(phone string) bool {
 	return re.MatchString(phone)
 }
 
+// ValidatePhoneNumber 验证电话号码格式（不限国家和地区，含座机）：可选的 + 前缀，可含空格、连字符或括号，总长 5-20 个字符
+func ValidatePhoneNumber(phone string) bool {
+	if len(phone) < 5 || len(phone) > 20 {
+		return false
+	}
+	const phoneNumberRegex = `^\+?[0-9(][0-9 ()-]*[0-9]$`
+	re := regexp.MustCompile(phoneNumberRegex)
+	return re.MatchString(phone)
+}
+
 // ValidatePassword 验证密码强度
 func ValidatePassword(password string) (bool, string) {
 	if len(password) < 6 {
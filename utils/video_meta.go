@@ -0,0 +1,452 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// VideoInfo 视频元数据
+type VideoInfo struct {
+	Format   string  `json:"format"`   // mp4/quicktime/webm/matroska/avi
+	Duration float64 `json:"duration"` // 时长（秒），无法获取时为 0
+	Width    int     `json:"width"`    // 显示宽度（已按旋转矩阵调整）
+	Height   int     `json:"height"`   // 显示高度
+}
+
+// ProbeVideo 读取视频时长与分辨率，只解析容器头部（MP4/MOV 的 moov、WebM/MKV 的 Info 与 Tracks、AVI 的 avih），不解码画面
+func ProbeVideo(r io.ReaderAt, size int64) (*VideoInfo, error) {
+	head := make([]byte, 12)
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return nil, fmt.Errorf("无法读取视频文件: %v", err)
+	}
+	switch {
+	case string(head[4:8]) == "ftyp":
+		return probeMP4(r, size, string(head[8:12]))
+	case binary.BigEndian.Uint32(head[0:4]) == ebmlHeaderID:
+		return probeMatroska(r, size)
+	case string(head[0:4]) == "RIFF" && string(head[8:12]) == "AVI ":
+		return probeAVI(r)
+	}
+	return nil, fmt.Errorf("不支持的视频格式")
+}
+
+// mp4Box ISO BMFF box
+type mp4Box struct {
+	boxType string
+	offset  int64 // 内容起始位置
+	size    int64 // 内容长度
+}
+
+// readMP4Boxes 读取 [start, end) 范围内的 box 列表
+func readMP4Boxes(r io.ReaderAt, start, end int64) ([]mp4Box, error) {
+	var boxes []mp4Box
+	header := make([]byte, 16)
+	for pos := start; pos+8 <= end; {
+		if _, err := r.ReadAt(header[:8], pos); err != nil {
+			return nil, err
+		}
+		boxSize := int64(binary.BigEndian.Uint32(header[0:4]))
+		headerSize := int64(8)
+		switch boxSize {
+		case 0: // 延伸到结尾
+			boxSize = end - pos
+		case 1: // 64 位长度
+			if _, err := r.ReadAt(header[8:16], pos+8); err != nil {
+				return nil, err
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if boxSize < headerSize || pos+boxSize > end {
+			return nil, fmt.Errorf("视频文件结构损坏")
+		}
+		boxes = append(boxes, mp4Box{
+			boxType: string(header[4:8]),
+			offset:  pos + headerSize,
+			size:    boxSize - headerSize,
+		})
+		pos += boxSize
+	}
+	return boxes, nil
+}
+
+// readBoxContent 读取 box 内容（限制长度，避免异常文件占用过多内存）
+func readBoxContent(r io.ReaderAt, box mp4Box, limit int64) ([]byte, error) {
+	n := box.size
+	if n > limit {
+		n = limit
+	}
+	buf := make([]byte, n)
+	if _, err := r.ReadAt(buf, box.offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// findMP4Box 查找第一个指定类型的 box
+func findMP4Box(boxes []mp4Box, boxType string) (mp4Box, bool) {
+	for _, box := range boxes {
+		if box.boxType == boxType {
+			return box, true
+		}
+	}
+	return mp4Box{}, false
+}
+
+// probeMP4 解析 MP4/MOV：mvhd 取时长，handler 为 vide 的轨道 tkhd 取分辨率
+func probeMP4(r io.ReaderAt, size int64, brand string) (*VideoInfo, error) {
+	info := &VideoInfo{Format: "mp4"}
+	if brand == "qt  " {
+		info.Format = "quicktime"
+	}
+
+	top, err := readMP4Boxes(r, 0, size)
+	if err != nil {
+		return nil, err
+	}
+	moov, ok := findMP4Box(top, "moov")
+	if !ok {
+		return nil, fmt.Errorf("视频缺少 moov 信息，文件可能不完整")
+	}
+	children, err := readMP4Boxes(r, moov.offset, moov.offset+moov.size)
+	if err != nil {
+		return nil, err
+	}
+
+	if mvhd, ok := findMP4Box(children, "mvhd"); ok {
+		data, err := readBoxContent(r, mvhd, 32)
+		if err != nil {
+			return nil, err
+		}
+		var timescale, duration uint64
+		if len(data) >= 32 && data[0] == 1 {
+			timescale = uint64(binary.BigEndian.Uint32(data[20:24]))
+			duration = binary.BigEndian.Uint64(data[24:32])
+		} else if len(data) >= 20 {
+			timescale = uint64(binary.BigEndian.Uint32(data[12:16]))
+			duration = uint64(binary.BigEndian.Uint32(data[16:20]))
+		}
+		if timescale > 0 && duration != math.MaxUint32 && duration != math.MaxUint64 {
+			info.Duration = roundDuration(float64(duration) / float64(timescale))
+		}
+	}
+
+	for _, trak := range children {
+		if trak.boxType != "trak" {
+			continue
+		}
+		trakChildren, err := readMP4Boxes(r, trak.offset, trak.offset+trak.size)
+		if err != nil {
+			return nil, err
+		}
+		if !isMP4VideoTrack(r, trakChildren) {
+			continue
+		}
+		tkhd, ok := findMP4Box(trakChildren, "tkhd")
+		if !ok {
+			continue
+		}
+		data, err := readBoxContent(r, tkhd, 92)
+		if err != nil {
+			return nil, err
+		}
+		// version 0 与 1 的时间字段长度不同，矩阵与宽高位于末尾
+		matrixOffset := 40
+		if len(data) > 0 && data[0] == 1 {
+			matrixOffset = 52
+		}
+		if len(data) < matrixOffset+44 {
+			continue
+		}
+		matrix := data[matrixOffset : matrixOffset+36]
+		info.Width = int(binary.BigEndian.Uint32(data[matrixOffset+36:matrixOffset+40]) >> 16)
+		info.Height = int(binary.BigEndian.Uint32(data[matrixOffset+40:matrixOffset+44]) >> 16)
+		// 旋转 90°/270° 时（矩阵 a、d 为 0）交换宽高
+		if binary.BigEndian.Uint32(matrix[0:4]) == 0 && binary.BigEndian.Uint32(matrix[16:20]) == 0 {
+			info.Width, info.Height = info.Height, info.Width
+		}
+		break
+	}
+	return info, nil
+}
+
+// isMP4VideoTrack 检查 trak/mdia/hdlr 的类型是否为 vide
+func isMP4VideoTrack(r io.ReaderAt, trakChildren []mp4Box) bool {
+	mdia, ok := findMP4Box(trakChildren, "mdia")
+	if !ok {
+		return false
+	}
+	mdiaChildren, err := readMP4Boxes(r, mdia.offset, mdia.offset+mdia.size)
+	if err != nil {
+		return false
+	}
+	hdlr, ok := findMP4Box(mdiaChildren, "hdlr")
+	if !ok {
+		return false
+	}
+	data, err := readBoxContent(r, hdlr, 12)
+	return err == nil && len(data) >= 12 && string(data[8:12]) == "vide"
+}
+
+// Matroska/WebM 元素 ID
+const (
+	ebmlHeaderID        = 0x1a45dfa3
+	mkvSegmentID        = 0x18538067
+	mkvInfoID           = 0x1549a966
+	mkvTimecodeScaleID  = 0x2ad7b1
+	mkvDurationID       = 0x4489
+	mkvTracksID         = 0x1654ae6b
+	mkvTrackEntryID     = 0xae
+	mkvTrackTypeID      = 0x83
+	mkvVideoID          = 0xe0
+	mkvPixelWidthID     = 0xb0
+	mkvPixelHeightID    = 0xba
+	mkvDocTypeID        = 0x4282
+	mkvClusterID        = 0x1f43b675
+	mkvTrackTypeVideo   = 1
+	mkvUnknownSize      = -1
+	mkvMaxElementLength = 1 << 20
+)
+
+// mkvElement EBML 元素
+type mkvElement struct {
+	id     uint32
+	offset int64 // 内容起始位置
+	size   int64 // 内容长度，未知长度为 mkvUnknownSize
+}
+
+// readVint 读取 EBML 变长整数；keepMarker 为 true 时保留长度标记位（用于元素 ID）
+func readVint(r io.ReaderAt, pos int64, keepMarker bool) (uint64, int, error) {
+	first := make([]byte, 1)
+	if _, err := r.ReadAt(first, pos); err != nil {
+		return 0, 0, err
+	}
+	length := 1
+	for mask := byte(0x80); length <= 8 && first[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > 8 {
+		return 0, 0, fmt.Errorf("视频文件结构损坏")
+	}
+
+	buf := make([]byte, length)
+	if _, err := r.ReadAt(buf, pos); err != nil {
+		return 0, 0, err
+	}
+	value := uint64(buf[0])
+	if !keepMarker {
+		value &= uint64(0xff >> length)
+	}
+	allOnes := value == uint64(0xff>>length)
+	for _, b := range buf[1:] {
+		value = value<<8 | uint64(b)
+		allOnes = allOnes && b == 0xff
+	}
+	if !keepMarker && allOnes {
+		return math.MaxUint64, length, nil
+	}
+	return value, length, nil
+}
+
+// readMkvElements 读取 [start, end) 范围内的子元素，遇到 Cluster 时停止（元数据都在 Cluster 之前）
+func readMkvElements(r io.ReaderAt, start, end int64) ([]mkvElement, error) {
+	var elements []mkvElement
+	for pos := start; pos < end; {
+		id, idLength, err := readVint(r, pos, true)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		size, sizeLength, err := readVint(r, pos+int64(idLength), false)
+		if err != nil {
+			return nil, err
+		}
+		element := mkvElement{id: uint32(id), offset: pos + int64(idLength+sizeLength)}
+		if size == math.MaxUint64 {
+			element.size = mkvUnknownSize
+		} else {
+			element.size = int64(size)
+		}
+		elements = append(elements, element)
+		if element.id == mkvClusterID || element.size == mkvUnknownSize {
+			break
+		}
+		pos = element.offset + element.size
+	}
+	return elements, nil
+}
+
+// readMkvData 读取元素内容
+func readMkvData(r io.ReaderAt, element mkvElement) ([]byte, error) {
+	if element.size < 0 || element.size > mkvMaxElementLength {
+		return nil, fmt.Errorf("视频文件结构损坏")
+	}
+	buf := make([]byte, element.size)
+	if _, err := r.ReadAt(buf, element.offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// mkvUint 解析无符号整数元素
+func mkvUint(data []byte) uint64 {
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+// probeMatroska 解析 WebM/MKV：Info 中的 Duration×TimecodeScale 为时长，视频轨道的 PixelWidth/PixelHeight 为分辨率
+func probeMatroska(r io.ReaderAt, size int64) (*VideoInfo, error) {
+	info := &VideoInfo{Format: "matroska"}
+	top, err := readMkvElements(r, 0, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var segment *mkvElement
+	for i := range top {
+		switch top[i].id {
+		case ebmlHeaderID:
+			children, err := readMkvElements(r, top[i].offset, top[i].offset+top[i].size)
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range children {
+				if child.id == mkvDocTypeID {
+					if data, err := readMkvData(r, child); err == nil && string(data) == "webm" {
+						info.Format = "webm"
+					}
+				}
+			}
+		case mkvSegmentID:
+			segment = &top[i]
+		}
+	}
+	if segment == nil {
+		return nil, fmt.Errorf("视频缺少 Segment 信息")
+	}
+	segmentEnd := size
+	if segment.size != mkvUnknownSize && segment.offset+segment.size < size {
+		segmentEnd = segment.offset + segment.size
+	}
+
+	sections, err := readMkvElements(r, segment.offset, segmentEnd)
+	if err != nil {
+		return nil, err
+	}
+	timecodeScale := uint64(1000000)
+	var duration float64
+	for _, section := range sections {
+		if (section.id != mkvInfoID && section.id != mkvTracksID) || section.size == mkvUnknownSize {
+			continue
+		}
+		children, err := readMkvElements(r, section.offset, section.offset+section.size)
+		if err != nil {
+			return nil, err
+		}
+		switch section.id {
+		case mkvInfoID:
+			for _, child := range children {
+				data, err := readMkvData(r, child)
+				if err != nil {
+					continue
+				}
+				switch child.id {
+				case mkvTimecodeScaleID:
+					timecodeScale = mkvUint(data)
+				case mkvDurationID:
+					if len(data) == 4 {
+						duration = float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+					} else if len(data) == 8 {
+						duration = math.Float64frombits(binary.BigEndian.Uint64(data))
+					}
+				}
+			}
+		case mkvTracksID:
+			for _, entry := range children {
+				if entry.id == mkvTrackEntryID && info.Width == 0 {
+					probeMkvTrack(r, entry, info)
+				}
+			}
+		}
+	}
+	info.Duration = roundDuration(duration * float64(timecodeScale) / 1e9)
+	return info, nil
+}
+
+// probeMkvTrack 读取视频轨道的分辨率
+func probeMkvTrack(r io.ReaderAt, entry mkvElement, info *VideoInfo) {
+	if entry.size == mkvUnknownSize {
+		return
+	}
+	fields, err := readMkvElements(r, entry.offset, entry.offset+entry.size)
+	if err != nil {
+		return
+	}
+	isVideo := false
+	var video *mkvElement
+	for i, field := range fields {
+		switch field.id {
+		case mkvTrackTypeID:
+			data, err := readMkvData(r, field)
+			isVideo = err == nil && mkvUint(data) == mkvTrackTypeVideo
+		case mkvVideoID:
+			video = &fields[i]
+		}
+	}
+	if !isVideo || video == nil || video.size == mkvUnknownSize {
+		return
+	}
+
+	settings, err := readMkvElements(r, video.offset, video.offset+video.size)
+	if err != nil {
+		return
+	}
+	for _, setting := range settings {
+		data, err := readMkvData(r, setting)
+		if err != nil {
+			continue
+		}
+		switch setting.id {
+		case mkvPixelWidthID:
+			info.Width = int(mkvUint(data))
+		case mkvPixelHeightID:
+			info.Height = int(mkvUint(data))
+		}
+	}
+}
+
+// probeAVI 解析 AVI 主头 avih：每帧微秒数×总帧数为时长
+func probeAVI(r io.ReaderAt) (*VideoInfo, error) {
+	// RIFF 头 12 字节后应为 LIST hdrl，其中第一个块为 avih
+	header := make([]byte, 32+40)
+	if _, err := r.ReadAt(header, 12); err != nil {
+		return nil, fmt.Errorf("无法读取 AVI 头: %v", err)
+	}
+	if string(header[0:4]) != "LIST" || string(header[8:12]) != "hdrl" || string(header[12:16]) != "avih" {
+		return nil, fmt.Errorf("AVI 文件缺少主头信息")
+	}
+	avih := header[20:]
+	microSecPerFrame := binary.LittleEndian.Uint32(avih[0:4])
+	totalFrames := binary.LittleEndian.Uint32(avih[16:20])
+	return &VideoInfo{
+		Format:   "avi",
+		Duration: roundDuration(float64(microSecPerFrame) * float64(totalFrames) / 1e6),
+		Width:    int(binary.LittleEndian.Uint32(avih[32:36])),
+		Height:   int(binary.LittleEndian.Uint32(avih[36:40])),
+	}, nil
+}
+
+// roundDuration 时长保留 3 位小数
+func roundDuration(seconds float64) float64 {
+	if seconds <= 0 || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return 0
+	}
+	return math.Round(seconds*1000) / 1000
+}
@@ -0,0 +1,607 @@
+package utils
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// 标准库没有 WebP 编码器，这里实现一个精简的无损 WebP（VP8L）编码：
+// 减绿变换 + 按块选择的预测变换 + 左侧/上方重复段引用 + 规范 Huffman 编码，不做完整 LZ77 与颜色缓存。
+// 压缩率不及 libwebp，调用方应在结果不比原图小时放弃该变体。
+
+// VP8L 常量
+const (
+	vp8lSignature      = 0x2f
+	vp8lMaxDimension   = 1 << 14
+	vp8lPredictorBits  = 5 // 预测块大小 32x32
+	vp8lGreenAlphabet  = 256 + 24
+	vp8lDistAlphabet   = 40
+	vp8lMaxCodeLength  = 15
+	vp8lMaxCodeLenCode = 7
+)
+
+// VP8L 变换类型
+const (
+	vp8lTransformPredictor     = 0
+	vp8lTransformSubtractGreen = 2
+)
+
+// vp8lCodeLengthOrder 码长码的写入顺序
+var vp8lCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// EncodeWebPLossless 将图片编码为无损 WebP
+func EncodeWebPLossless(img image.Image) ([]byte, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > vp8lMaxDimension || height > vp8lMaxDimension {
+		return nil, fmt.Errorf("WebP 不支持该图片尺寸: %dx%d", width, height)
+	}
+
+	nrgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+
+	pixels := make([]uint32, width*height)
+	alphaUsed := false
+	for i := range pixels {
+		p := nrgba.Pix[i*4 : i*4+4]
+		if p[3] != 0xff {
+			alphaUsed = true
+		}
+		pixels[i] = uint32(p[3])<<24 | uint32(p[0])<<16 | uint32(p[1])<<8 | uint32(p[2])
+	}
+
+	w := &bitWriter{}
+	w.writeBits(vp8lSignature, 8)
+	w.writeBits(uint32(width-1), 14)
+	w.writeBits(uint32(height-1), 14)
+	if alphaUsed {
+		w.writeBits(1, 1)
+	} else {
+		w.writeBits(0, 1)
+	}
+	w.writeBits(0, 3) // version
+
+	// 减绿变换
+	w.writeBits(1, 1)
+	w.writeBits(vp8lTransformSubtractGreen, 2)
+	for i, argb := range pixels {
+		green := (argb >> 8) & 0xff
+		red := ((argb >> 16) - green) & 0xff
+		blue := (argb - green) & 0xff
+		pixels[i] = argb&0xff00ff00 | red<<16 | blue
+	}
+
+	// 预测变换
+	w.writeBits(1, 1)
+	w.writeBits(vp8lTransformPredictor, 2)
+	w.writeBits(vp8lPredictorBits-2, 3)
+	modes, tilesX := vp8lPredict(pixels, width, height)
+	vp8lWriteImage(w, modes, tilesX, false)
+
+	w.writeBits(0, 1) // 变换结束
+	vp8lWriteImage(w, pixels, width, true)
+
+	data := w.bytes()
+	var out bytes.Buffer
+	chunkSize := len(data)
+	padded := chunkSize + chunkSize&1
+	out.WriteString("RIFF")
+	binary.Write(&out, binary.LittleEndian, uint32(4+8+padded))
+	out.WriteString("WEBPVP8L")
+	binary.Write(&out, binary.LittleEndian, uint32(chunkSize))
+	out.Write(data)
+	if chunkSize&1 == 1 {
+		out.WriteByte(0)
+	}
+	return out.Bytes(), nil
+}
+
+// vp8lPredict 为每个块选择残差最小的预测模式（1 左、2 上、7 左上平均），原地替换为残差，返回模式子图
+func vp8lPredict(pixels []uint32, width, height int) ([]uint32, int) {
+	blockSize := 1 << vp8lPredictorBits
+	tilesX := (width + blockSize - 1) / blockSize
+	tilesY := (height + blockSize - 1) / blockSize
+	original := append([]uint32(nil), pixels...)
+
+	candidates := []uint32{1, 2, 7}
+	modes := make([]uint32, tilesX*tilesY)
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			bestMode, bestCost := candidates[0], -1
+			for _, mode := range candidates {
+				cost := 0
+				for y := ty * blockSize; y < height && y < (ty+1)*blockSize; y++ {
+					for x := tx * blockSize; x < width && x < (tx+1)*blockSize; x++ {
+						residual := vp8lSub(original[y*width+x], vp8lPredictPixel(original, width, x, y, mode))
+						cost += vp8lResidualCost(residual)
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					bestMode, bestCost = mode, cost
+				}
+			}
+			modes[ty*tilesX+tx] = 0xff000000 | bestMode<<8
+		}
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			mode := (modes[(y>>vp8lPredictorBits)*tilesX+(x>>vp8lPredictorBits)] >> 8) & 0xff
+			pixels[y*width+x] = vp8lSub(original[y*width+x], vp8lPredictPixel(original, width, x, y, mode))
+		}
+	}
+	return modes, tilesX
+}
+
+// vp8lPredictPixel 计算预测值；首行、首列按规范固定使用左、上预测
+func vp8lPredictPixel(pixels []uint32, width, x, y int, mode uint32) uint32 {
+	switch {
+	case x == 0 && y == 0:
+		return 0xff000000
+	case y == 0:
+		return pixels[x-1]
+	case x == 0:
+		return pixels[(y-1)*width]
+	}
+	left, top := pixels[y*width+x-1], pixels[(y-1)*width+x]
+	switch mode {
+	case 1:
+		return left
+	case 2:
+		return top
+	default:
+		return vp8lAverage(left, top)
+	}
+}
+
+// vp8lAverage 按通道取平均
+func vp8lAverage(a, b uint32) uint32 {
+	return (((a ^ b) & 0xfefefefe) >> 1) + (a & b)
+}
+
+// vp8lSub 按通道相减（模 256）
+func vp8lSub(a, b uint32) uint32 {
+	alphaGreen := 0x00ff00ff + (a & 0xff00ff00) - (b & 0xff00ff00)
+	redBlue := 0xff00ff00 + (a & 0x00ff00ff) - (b & 0x00ff00ff)
+	return alphaGreen&0xff00ff00 | redBlue&0x00ff00ff
+}
+
+// vp8lResidualCost 残差的粗略代价：各通道与 0 的距离之和
+func vp8lResidualCost(residual uint32) int {
+	cost := 0
+	for shift := 0; shift < 32; shift += 8 {
+		v := int((residual >> shift) & 0xff)
+		if v > 128 {
+			v = 256 - v
+		}
+		cost += v
+	}
+	return cost
+}
+
+// vp8lToken 熵编码单元：字面像素，或引用左侧/上方像素的重复段
+type vp8lToken struct {
+	argb     uint32
+	length   int // 大于 0 时为后向引用
+	distCode int // 距离码：1 为上方像素，2 为左侧像素
+}
+
+// vp8lMaxCopyLength 后向引用的最大长度
+const vp8lMaxCopyLength = 4096
+
+// vp8lTokenize 将像素拆成字面量与后向引用，只使用左侧、上方两种距离，覆盖纯色与重复行
+func vp8lTokenize(pixels []uint32, width int) []vp8lToken {
+	var tokens []vp8lToken
+	for i := 0; i < len(pixels); {
+		bestLength, bestCode := 0, 0
+		if i >= 1 {
+			n := vp8lMatchLength(pixels, i, 1)
+			if n > bestLength {
+				bestLength, bestCode = n, 2
+			}
+		}
+		if i >= width {
+			n := vp8lMatchLength(pixels, i, width)
+			if n > bestLength {
+				bestLength, bestCode = n, 1
+			}
+		}
+		if bestLength >= 3 {
+			tokens = append(tokens, vp8lToken{length: bestLength, distCode: bestCode})
+			i += bestLength
+			continue
+		}
+		tokens = append(tokens, vp8lToken{argb: pixels[i]})
+		i++
+	}
+	return tokens
+}
+
+// vp8lMatchLength 计算从 i 开始与 i-distance 处相同的像素个数
+func vp8lMatchLength(pixels []uint32, i, distance int) int {
+	n := 0
+	for i+n < len(pixels) && n < vp8lMaxCopyLength && pixels[i+n] == pixels[i+n-distance] {
+		n++
+	}
+	return n
+}
+
+// vp8lPrefixEncode 将长度或距离值拆为前缀码与额外位
+func vp8lPrefixEncode(value int) (code, extraBits, extra int) {
+	d := value - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	highest := 0
+	for d>>(highest+1) != 0 {
+		highest++
+	}
+	second := (d >> (highest - 1)) & 1
+	extraBits = highest - 1
+	return 2*highest + second, extraBits, d & (1<<extraBits - 1)
+}
+
+// vp8lWriteImage 写入熵编码图像：不使用颜色缓存，主图不使用元前缀码
+func vp8lWriteImage(w *bitWriter, pixels []uint32, width int, mainImage bool) {
+	w.writeBits(0, 1) // 不使用颜色缓存
+	if mainImage {
+		w.writeBits(0, 1) // 不使用元前缀码
+	}
+
+	tokens := vp8lTokenize(pixels, width)
+	histograms := [5][]int{
+		make([]int, vp8lGreenAlphabet),
+		make([]int, 256),
+		make([]int, 256),
+		make([]int, 256),
+		make([]int, vp8lDistAlphabet),
+	}
+	for _, t := range tokens {
+		if t.length > 0 {
+			lengthCode, _, _ := vp8lPrefixEncode(t.length)
+			distCode, _, _ := vp8lPrefixEncode(t.distCode)
+			histograms[0][256+lengthCode]++
+			histograms[4][distCode]++
+			continue
+		}
+		histograms[0][(t.argb>>8)&0xff]++
+		histograms[1][(t.argb>>16)&0xff]++
+		histograms[2][t.argb&0xff]++
+		histograms[3][t.argb>>24]++
+	}
+
+	var codes [5]huffmanCode
+	for i, histogram := range histograms {
+		codes[i] = vp8lWriteHuffmanCode(w, histogram)
+	}
+
+	for _, t := range tokens {
+		if t.length > 0 {
+			code, extraBits, extra := vp8lPrefixEncode(t.length)
+			codes[0].write(w, 256+code)
+			w.writeBits(uint32(extra), uint(extraBits))
+			code, extraBits, extra = vp8lPrefixEncode(t.distCode)
+			codes[4].write(w, code)
+			w.writeBits(uint32(extra), uint(extraBits))
+			continue
+		}
+		codes[0].write(w, int((t.argb>>8)&0xff))
+		codes[1].write(w, int((t.argb>>16)&0xff))
+		codes[2].write(w, int(t.argb&0xff))
+		codes[3].write(w, int(t.argb>>24))
+	}
+}
+
+// huffmanCode 规范 Huffman 码表
+type huffmanCode struct {
+	lengths []int
+	codes   []uint32 // 已按位反转，可直接低位优先写入
+	single  bool
+}
+
+// write 写入符号；只有一个符号的码不占位
+func (h huffmanCode) write(w *bitWriter, symbol int) {
+	if h.lengths[symbol] > 0 && !h.single {
+		w.writeBits(h.codes[symbol], uint(h.lengths[symbol]))
+	}
+}
+
+// vp8lWriteHuffmanCode 写入一个前缀码并返回码表；不超过两个 8 位以内的符号时使用简单码
+func vp8lWriteHuffmanCode(w *bitWriter, histogram []int) huffmanCode {
+	var used []int
+	for symbol, count := range histogram {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+
+	if len(used) <= 2 && (len(used) == 0 || used[len(used)-1] < 256) {
+		lengths := make([]int, len(histogram))
+		if len(used) == 0 {
+			used = []int{0}
+		}
+		w.writeBits(1, 1) // 简单码
+		w.writeBits(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			w.writeBits(0, 1)
+			w.writeBits(uint32(used[0]), 1)
+		} else {
+			w.writeBits(1, 1)
+			w.writeBits(uint32(used[0]), 8)
+		}
+		if len(used) == 2 {
+			w.writeBits(uint32(used[1]), 8)
+			lengths[used[0]], lengths[used[1]] = 1, 1
+		}
+		return newHuffmanCode(lengths)
+	}
+
+	lengths := huffmanLengths(histogram, vp8lMaxCodeLength)
+	code := newHuffmanCode(lengths)
+	w.writeBits(0, 1) // 普通码
+
+	// 码长序列：非零值直接写，连续的 0 用 17（3~10 个）、18（11~138 个）压缩
+	type token struct{ symbol, extra, extraBits int }
+	var tokens []token
+	for i := 0; i < len(lengths); {
+		if lengths[i] != 0 {
+			tokens = append(tokens, token{lengths[i], 0, 0})
+			i++
+			continue
+		}
+		run := 0
+		for i+run < len(lengths) && lengths[i+run] == 0 {
+			run++
+		}
+		i += run
+		for run > 0 {
+			switch {
+			case run >= 11:
+				n := run
+				if n > 138 {
+					n = 138
+				}
+				tokens = append(tokens, token{18, n - 11, 7})
+				run -= n
+			case run >= 3:
+				tokens = append(tokens, token{17, run - 3, 3})
+				run = 0
+			default:
+				tokens = append(tokens, token{0, 0, 0})
+				run--
+			}
+		}
+	}
+
+	tokenHistogram := make([]int, 19)
+	for _, t := range tokens {
+		tokenHistogram[t.symbol]++
+	}
+	codeLengthLengths := huffmanLengths(tokenHistogram, vp8lMaxCodeLenCode)
+	// 只有一个码长符号时补一个，避免出现 0 位码
+	if nonZeroCount(codeLengthLengths) == 1 {
+		for _, symbol := range []int{0, 8} {
+			if codeLengthLengths[symbol] == 0 {
+				codeLengthLengths[symbol] = 1
+				break
+			}
+		}
+		for symbol := range codeLengthLengths {
+			if codeLengthLengths[symbol] > 0 {
+				codeLengthLengths[symbol] = 1
+			}
+		}
+	}
+	codeLengthCode := newHuffmanCode(codeLengthLengths)
+
+	numCodes := 19
+	for numCodes > 4 && codeLengthLengths[vp8lCodeLengthOrder[numCodes-1]] == 0 {
+		numCodes--
+	}
+	w.writeBits(uint32(numCodes-4), 4)
+	for i := 0; i < numCodes; i++ {
+		w.writeBits(uint32(codeLengthLengths[vp8lCodeLengthOrder[i]]), 3)
+	}
+	w.writeBits(0, 1) // 使用完整字母表
+
+	for _, t := range tokens {
+		codeLengthCode.write(w, t.symbol)
+		if t.extraBits > 0 {
+			w.writeBits(uint32(t.extra), uint(t.extraBits))
+		}
+	}
+	return code
+}
+
+// nonZeroCount 统计非零码长个数
+func nonZeroCount(lengths []int) int {
+	n := 0
+	for _, length := range lengths {
+		if length > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// newHuffmanCode 由码长生成规范 Huffman 码（按码长、符号值顺序分配）
+func newHuffmanCode(lengths []int) huffmanCode {
+	code := huffmanCode{lengths: lengths, codes: make([]uint32, len(lengths)), single: nonZeroCount(lengths) == 1}
+	maxLength := 0
+	for _, length := range lengths {
+		if length > maxLength {
+			maxLength = length
+		}
+	}
+
+	countPerLength := make([]int, maxLength+1)
+	for _, length := range lengths {
+		if length > 0 {
+			countPerLength[length]++
+		}
+	}
+	nextCode := make([]uint32, maxLength+2)
+	var value uint32
+	for length := 1; length <= maxLength; length++ {
+		value = (value + uint32(countPerLength[length-1])) << 1
+		if length == 1 {
+			value = 0
+		}
+		nextCode[length] = value
+	}
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+		code.codes[symbol] = reverseBits(nextCode[length], length)
+		nextCode[length]++
+	}
+	return code
+}
+
+// reverseBits 反转低 n 位
+func reverseBits(v uint32, n int) uint32 {
+	var r uint32
+	for i := 0; i < n; i++ {
+		r = r<<1 | (v & 1)
+		v >>= 1
+	}
+	return r
+}
+
+// huffmanNode Huffman 树节点
+type huffmanNode struct {
+	count       int
+	symbol      int
+	left, right *huffmanNode
+}
+
+// huffmanHeap 按频次（相同时按符号）排序的最小堆
+type huffmanHeap []*huffmanNode
+
+func (h huffmanHeap) Len() int { return len(h) }
+func (h huffmanHeap) Less(i, j int) bool {
+	if h[i].count != h[j].count {
+		return h[i].count < h[j].count
+	}
+	return h[i].symbol < h[j].symbol
+}
+func (h huffmanHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *huffmanHeap) Push(x interface{}) { *h = append(*h, x.(*huffmanNode)) }
+func (h *huffmanHeap) Pop() interface{} {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+// huffmanLengths 计算不超过 maxLength 的码长；超长时压平频次后重建
+func huffmanLengths(histogram []int, maxLength int) []int {
+	counts := append([]int(nil), histogram...)
+	for {
+		lengths := make([]int, len(counts))
+		h := &huffmanHeap{}
+		for symbol, count := range counts {
+			if count > 0 {
+				heap.Push(h, &huffmanNode{count: count, symbol: symbol})
+			}
+		}
+		if h.Len() == 0 {
+			return lengths
+		}
+		if h.Len() == 1 {
+			lengths[(*h)[0].symbol] = 1
+			return lengths
+		}
+
+		next := len(counts)
+		for h.Len() > 1 {
+			a := heap.Pop(h).(*huffmanNode)
+			b := heap.Pop(h).(*huffmanNode)
+			heap.Push(h, &huffmanNode{count: a.count + b.count, symbol: next, left: a, right: b})
+			next++
+		}
+
+		tooLong := false
+		var walk func(n *huffmanNode, depth int)
+		walk = func(n *huffmanNode, depth int) {
+			if n.left == nil {
+				lengths[n.symbol] = depth
+				if depth > maxLength {
+					tooLong = true
+				}
+				return
+			}
+			walk(n.left, depth+1)
+			walk(n.right, depth+1)
+		}
+		walk((*h)[0], 0)
+		if !tooLong {
+			return lengths
+		}
+
+		for symbol, count := range counts {
+			if count > 0 {
+				counts[symbol] = count/2 + 1
+			}
+		}
+	}
+}
+
+// bitWriter 低位优先的位写入器
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+// writeBits 写入 v 的低 n 位
+func (w *bitWriter) writeBits(v uint32, n uint) {
+	w.acc |= uint64(v&(1<<n-1)) << w.nbits
+	w.nbits += n
+	for w.nbits >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nbits -= 8
+	}
+}
+
+// bytes 返回写入的数据（不足一字节的部分补 0）
+func (w *bitWriter) bytes() []byte {
+	if w.nbits > 0 {
+		return append(w.buf, byte(w.acc))
+	}
+	return w.buf
+}
+
+// WebPDimensions 读取 WebP 的宽高（支持 VP8、VP8L、VP8X）
+func WebPDimensions(data []byte) (int, int, error) {
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0, fmt.Errorf("不是有效的 WebP 文件")
+	}
+	chunk := data[12:]
+	switch string(chunk[0:4]) {
+	case "VP8X":
+		width := 1 + int(chunk[12]) | int(chunk[13])<<8 | int(chunk[14])<<16
+		height := 1 + int(chunk[15]) | int(chunk[16])<<8 | int(chunk[17])<<16
+		return width, height, nil
+	case "VP8L":
+		if chunk[8] != vp8lSignature {
+			return 0, 0, fmt.Errorf("WebP 数据损坏")
+		}
+		bits := binary.LittleEndian.Uint32(chunk[9:13])
+		return int(bits&0x3fff) + 1, int((bits>>14)&0x3fff) + 1, nil
+	case "VP8 ":
+		if chunk[11] != 0x9d || chunk[12] != 0x01 || chunk[13] != 0x2a {
+			return 0, 0, fmt.Errorf("WebP 数据损坏")
+		}
+		width := int(binary.LittleEndian.Uint16(chunk[14:16]) & 0x3fff)
+		height := int(binary.LittleEndian.Uint16(chunk[16:18]) & 0x3fff)
+		return width, height, nil
+	}
+	return 0, 0, fmt.Errorf("不支持的 WebP 格式")
+}
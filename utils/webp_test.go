@@ -0,0 +1,474 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+// 标准库没有 WebP 解码器，这里按 VP8L 规范实现一个只用于测试的解码器，
+// 支持编码器会输出的子集：减绿与预测变换（模式 0/1/2/7）、简单码与普通码、
+// 距离码 1（上方）与 2（左侧）的后向引用；不支持颜色缓存与元前缀码
+
+// vp8lBitReader 低位优先的位读取器
+type vp8lBitReader struct {
+	data []byte
+	pos  int // 位偏移
+}
+
+func (r *vp8lBitReader) read(n int) (uint32, error) {
+	var v uint32
+	for i := 0; i < n; i++ {
+		if r.pos >= len(r.data)*8 {
+			return 0, fmt.Errorf("unexpected end of bitstream")
+		}
+		bit := uint32(r.data[r.pos/8]>>(r.pos%8)) & 1
+		v |= bit << i
+		r.pos++
+	}
+	return v, nil
+}
+
+// vp8lDecodeCode 按码长生成的规范 Huffman 码
+type vp8lDecodeCode struct {
+	symbols map[[2]uint32]int // (码长, 码值) -> 符号
+	single  int               // 只有一个符号时为该符号，不占位
+	maxLen  int
+}
+
+func newVP8LDecodeCode(lengths []int) (*vp8lDecodeCode, error) {
+	code := &vp8lDecodeCode{symbols: make(map[[2]uint32]int), single: -1}
+	used := 0
+	for symbol, length := range lengths {
+		if length > 0 {
+			used++
+			code.single = symbol
+			if length > code.maxLen {
+				code.maxLen = length
+			}
+		}
+	}
+	if used == 0 {
+		return nil, fmt.Errorf("empty prefix code")
+	}
+	if used == 1 {
+		return code, nil
+	}
+	code.single = -1
+
+	countPerLength := make([]uint32, code.maxLen+1)
+	for _, length := range lengths {
+		if length > 0 {
+			countPerLength[length]++
+		}
+	}
+	nextCode := make([]uint32, code.maxLen+1)
+	var value uint32
+	for length := 1; length <= code.maxLen; length++ {
+		value = (value + countPerLength[length-1]) << 1
+		if length == 1 {
+			value = 0
+		}
+		nextCode[length] = value
+	}
+	// Kraft 等式：完整的前缀码恰好用满码空间
+	if value+countPerLength[code.maxLen] != 1<<code.maxLen {
+		return nil, fmt.Errorf("incomplete prefix code")
+	}
+	for symbol, length := range lengths {
+		if length > 0 {
+			code.symbols[[2]uint32{uint32(length), nextCode[length]}] = symbol
+			nextCode[length]++
+		}
+	}
+	return code, nil
+}
+
+func (c *vp8lDecodeCode) read(r *vp8lBitReader) (int, error) {
+	if c.single >= 0 {
+		return c.single, nil
+	}
+	var value uint32
+	for length := 1; length <= c.maxLen; length++ {
+		bit, err := r.read(1)
+		if err != nil {
+			return 0, err
+		}
+		value = value<<1 | bit
+		if symbol, ok := c.symbols[[2]uint32{uint32(length), value}]; ok {
+			return symbol, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid prefix code")
+}
+
+// readVP8LCode 读取一个前缀码（简单码或普通码）
+func readVP8LCode(r *vp8lBitReader, alphabetSize int) (*vp8lDecodeCode, error) {
+	lengths := make([]int, alphabetSize)
+	simple, err := r.read(1)
+	if err != nil {
+		return nil, err
+	}
+	if simple == 1 {
+		numSymbols, _ := r.read(1)
+		firstBits, _ := r.read(1)
+		symbol0, err := r.read(1 + 7*int(firstBits))
+		if err != nil {
+			return nil, err
+		}
+		lengths[symbol0] = 1
+		if numSymbols == 1 {
+			symbol1, err := r.read(8)
+			if err != nil {
+				return nil, err
+			}
+			lengths[symbol1] = 1
+		}
+		return newVP8LDecodeCode(lengths)
+	}
+
+	numCodes, err := r.read(4)
+	if err != nil {
+		return nil, err
+	}
+	codeLengthLengths := make([]int, 19)
+	for i := 0; i < int(numCodes)+4; i++ {
+		length, err := r.read(3)
+		if err != nil {
+			return nil, err
+		}
+		codeLengthLengths[vp8lCodeLengthOrder[i]] = int(length)
+	}
+	codeLengthCode, err := newVP8LDecodeCode(codeLengthLengths)
+	if err != nil {
+		return nil, err
+	}
+
+	maxSymbol := alphabetSize
+	if limited, _ := r.read(1); limited == 1 {
+		lengthBits, _ := r.read(3)
+		n, err := r.read(2 + 2*int(lengthBits))
+		if err != nil {
+			return nil, err
+		}
+		maxSymbol = 2 + int(n)
+	}
+
+	previous := 8
+	for symbol := 0; symbol < alphabetSize; {
+		if maxSymbol == 0 {
+			break
+		}
+		maxSymbol--
+		cls, err := codeLengthCode.read(r)
+		if err != nil {
+			return nil, err
+		}
+		repeat, value := 1, 0
+		switch {
+		case cls < 16:
+			value = cls
+			if cls != 0 {
+				previous = cls
+			}
+		case cls == 16:
+			extra, _ := r.read(2)
+			repeat, value = 3+int(extra), previous
+		case cls == 17:
+			extra, _ := r.read(3)
+			repeat = 3 + int(extra)
+		default:
+			extra, _ := r.read(7)
+			repeat = 11 + int(extra)
+		}
+		if symbol+repeat > alphabetSize {
+			return nil, fmt.Errorf("code lengths overflow alphabet")
+		}
+		for ; repeat > 0; repeat-- {
+			lengths[symbol] = value
+			symbol++
+		}
+	}
+	return newVP8LDecodeCode(lengths)
+}
+
+// readVP8LPrefixValue 读取长度或距离的前缀值
+func readVP8LPrefixValue(r *vp8lBitReader, prefix int) (int, error) {
+	if prefix < 4 {
+		return prefix + 1, nil
+	}
+	extraBits := (prefix - 2) >> 1
+	offset := (2 + prefix&1) << extraBits
+	extra, err := r.read(extraBits)
+	return offset + int(extra) + 1, err
+}
+
+// readVP8LImage 读取熵编码图像
+func readVP8LImage(r *vp8lBitReader, width, height int, mainImage bool) ([]uint32, error) {
+	if cache, _ := r.read(1); cache == 1 {
+		return nil, fmt.Errorf("color cache not supported")
+	}
+	if mainImage {
+		if meta, _ := r.read(1); meta == 1 {
+			return nil, fmt.Errorf("meta prefix codes not supported")
+		}
+	}
+
+	var codes [5]*vp8lDecodeCode
+	for i, size := range []int{256 + 24, 256, 256, 256, 40} {
+		code, err := readVP8LCode(r, size)
+		if err != nil {
+			return nil, fmt.Errorf("prefix code %d: %w", i, err)
+		}
+		codes[i] = code
+	}
+
+	pixels := make([]uint32, width*height)
+	for i := 0; i < len(pixels); {
+		green, err := codes[0].read(r)
+		if err != nil {
+			return nil, err
+		}
+		if green < 256 {
+			red, _ := codes[1].read(r)
+			blue, _ := codes[2].read(r)
+			alpha, err := codes[3].read(r)
+			if err != nil {
+				return nil, err
+			}
+			pixels[i] = uint32(alpha)<<24 | uint32(red)<<16 | uint32(green)<<8 | uint32(blue)
+			i++
+			continue
+		}
+
+		length, err := readVP8LPrefixValue(r, green-256)
+		if err != nil {
+			return nil, err
+		}
+		distSymbol, err := codes[4].read(r)
+		if err != nil {
+			return nil, err
+		}
+		distCode, err := readVP8LPrefixValue(r, distSymbol)
+		if err != nil {
+			return nil, err
+		}
+		var distance int
+		switch distCode {
+		case 1:
+			distance = width // (0, 1) 上方像素
+		case 2:
+			distance = 1 // (1, 0) 左侧像素
+		default:
+			return nil, fmt.Errorf("distance code %d not supported", distCode)
+		}
+		if distance > i || i+length > len(pixels) {
+			return nil, fmt.Errorf("backward reference out of range at pixel %d", i)
+		}
+		for ; length > 0; length-- {
+			pixels[i] = pixels[i-distance]
+			i++
+		}
+	}
+	return pixels, nil
+}
+
+// vp8lAddPixels 按通道相加（模 256）
+func vp8lAddPixels(a, b uint32) uint32 {
+	alphaGreen := (a&0xff00ff00 + b&0xff00ff00) & 0xff00ff00
+	redBlue := (a&0x00ff00ff + b&0x00ff00ff) & 0x00ff00ff
+	return alphaGreen | redBlue
+}
+
+// decodeWebPLosslessForTest 解码 EncodeWebPLossless 的输出，返回 ARGB 像素
+func decodeWebPLosslessForTest(data []byte) (int, int, []uint32, error) {
+	if len(data) < 21 || string(data[0:4]) != "RIFF" || string(data[8:16]) != "WEBPVP8L" {
+		return 0, 0, nil, fmt.Errorf("not a VP8L WebP file")
+	}
+	if riffSize := binary.LittleEndian.Uint32(data[4:8]); int(riffSize) != len(data)-8 {
+		return 0, 0, nil, fmt.Errorf("RIFF size %d, file has %d bytes", riffSize, len(data)-8)
+	}
+	chunkSize := int(binary.LittleEndian.Uint32(data[16:20]))
+	if 20+chunkSize > len(data) {
+		return 0, 0, nil, fmt.Errorf("chunk size %d exceeds file", chunkSize)
+	}
+
+	r := &vp8lBitReader{data: data[20 : 20+chunkSize]}
+	if signature, _ := r.read(8); signature != vp8lSignature {
+		return 0, 0, nil, fmt.Errorf("bad VP8L signature %#x", signature)
+	}
+	w, _ := r.read(14)
+	h, _ := r.read(14)
+	width, height := int(w)+1, int(h)+1
+	r.read(1) // alpha_is_used 仅为提示
+	if version, _ := r.read(3); version != 0 {
+		return 0, 0, nil, fmt.Errorf("bad VP8L version %d", version)
+	}
+
+	type predictor struct {
+		bits  int
+		modes []uint32
+	}
+	var transforms []interface{}
+	for {
+		present, err := r.read(1)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		if present == 0 {
+			break
+		}
+		kind, _ := r.read(2)
+		switch kind {
+		case vp8lTransformPredictor:
+			bits, _ := r.read(3)
+			p := predictor{bits: int(bits) + 2}
+			blockSize := 1 << p.bits
+			tilesX := (width + blockSize - 1) / blockSize
+			tilesY := (height + blockSize - 1) / blockSize
+			if p.modes, err = readVP8LImage(r, tilesX, tilesY, false); err != nil {
+				return 0, 0, nil, fmt.Errorf("predictor image: %w", err)
+			}
+			transforms = append(transforms, p)
+		case vp8lTransformSubtractGreen:
+			transforms = append(transforms, vp8lTransformSubtractGreen)
+		default:
+			return 0, 0, nil, fmt.Errorf("transform %d not supported", kind)
+		}
+	}
+
+	pixels, err := readVP8LImage(r, width, height, true)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("main image: %w", err)
+	}
+
+	// 按与写入相反的顺序还原变换
+	for t := len(transforms) - 1; t >= 0; t-- {
+		switch transform := transforms[t].(type) {
+		case predictor:
+			tilesX := (width + 1<<transform.bits - 1) >> transform.bits
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					i := y*width + x
+					var predicted uint32
+					switch {
+					case x == 0 && y == 0:
+						predicted = 0xff000000
+					case y == 0:
+						predicted = pixels[i-1]
+					case x == 0:
+						predicted = pixels[i-width]
+					default:
+						mode := (transform.modes[(y>>transform.bits)*tilesX+x>>transform.bits] >> 8) & 0xf
+						switch mode {
+						case 0:
+							predicted = 0xff000000
+						case 1:
+							predicted = pixels[i-1]
+						case 2:
+							predicted = pixels[i-width]
+						case 7:
+							predicted = vp8lAverage(pixels[i-1], pixels[i-width])
+						default:
+							return 0, 0, nil, fmt.Errorf("predictor mode %d not supported", mode)
+						}
+					}
+					pixels[i] = vp8lAddPixels(pixels[i], predicted)
+				}
+			}
+		default:
+			for i, argb := range pixels {
+				green := (argb >> 8) & 0xff
+				red := ((argb >> 16) + green) & 0xff
+				blue := (argb + green) & 0xff
+				pixels[i] = argb&0xff00ff00 | red<<16 | blue
+			}
+		}
+	}
+	return width, height, pixels, nil
+}
+
+// webpTestImages 覆盖纯色、重复行、渐变、噪点与半透明，以及跨预测块边界的尺寸
+func webpTestImages() map[string]*image.NRGBA {
+	images := make(map[string]*image.NRGBA)
+	fill := func(name string, width, height int, pixel func(x, y int) color.NRGBA) {
+		img := image.NewNRGBA(image.Rect(0, 0, width, height))
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				img.SetNRGBA(x, y, pixel(x, y))
+			}
+		}
+		images[name] = img
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	fill("1x1", 1, 1, func(x, y int) color.NRGBA { return color.NRGBA{200, 10, 30, 255} })
+	fill("solid", 40, 40, func(x, y int) color.NRGBA { return color.NRGBA{12, 34, 56, 255} })
+	fill("stripes", 70, 33, func(x, y int) color.NRGBA {
+		return color.NRGBA{uint8(x * 3), uint8(x * 7), uint8(x * 11), 255}
+	})
+	fill("gradient", 97, 65, func(x, y int) color.NRGBA {
+		return color.NRGBA{uint8(x * 2), uint8(y * 3), uint8(x + y), 255}
+	})
+	fill("noise-alpha", 50, 37, func(x, y int) color.NRGBA {
+		return color.NRGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256))}
+	})
+	fill("tall", 1, 100, func(x, y int) color.NRGBA { return color.NRGBA{uint8(y), uint8(y / 2), 0, uint8(255 - y)} })
+	return images
+}
+
+func TestEncodeWebPLosslessRoundTrip(t *testing.T) {
+	for name, img := range webpTestImages() {
+		t.Run(name, func(t *testing.T) {
+			data, err := EncodeWebPLossless(img)
+			if err != nil {
+				t.Fatalf("EncodeWebPLossless: %v", err)
+			}
+			width, height, pixels, err := decodeWebPLosslessForTest(data)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			bounds := img.Bounds()
+			if width != bounds.Dx() || height != bounds.Dy() {
+				t.Fatalf("decoded size %dx%d, want %dx%d", width, height, bounds.Dx(), bounds.Dy())
+			}
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					c := img.NRGBAAt(x, y)
+					want := uint32(c.A)<<24 | uint32(c.R)<<16 | uint32(c.G)<<8 | uint32(c.B)
+					if got := pixels[y*width+x]; got != want {
+						t.Fatalf("pixel (%d,%d) = %#08x, want %#08x", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeWebPLosslessRejectsInvalidSize(t *testing.T) {
+	for _, rect := range []image.Rectangle{
+		image.Rect(0, 0, 0, 10),
+		image.Rect(0, 0, vp8lMaxDimension+1, 1),
+	} {
+		if _, err := EncodeWebPLossless(image.NewNRGBA(rect)); err == nil {
+			t.Errorf("EncodeWebPLossless(%v) succeeded, want error", rect)
+		}
+	}
+}
+
+func TestEncodeWebPLosslessHeader(t *testing.T) {
+	data, err := EncodeWebPLossless(webpTestImages()["gradient"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data)%2 != 0 {
+		t.Fatalf("RIFF file length %d is not even", len(data))
+	}
+	if !bytes.HasPrefix(data, []byte("RIFF")) || !bytes.Equal(data[8:16], []byte("WEBPVP8L")) {
+		t.Fatalf("unexpected header %q", data[:16])
+	}
+}